@@ -0,0 +1,634 @@
+// Package ipc contains result encoders and decoders that use
+// the Arrow IPC streaming format.
+//
+// Each table is written as its own Arrow stream: a schema message,
+// one record batch for each buffer of the table and an end of stream
+// marker. The streams of all tables are written one after another.
+// The result name and the group key of the table are stored in the
+// metadata of the schema so the tables can be decoded without
+// reading any of the record batches.
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	stdarrow "github.com/apache/arrow/go/v7/arrow"
+	arrowarray "github.com/apache/arrow/go/v7/arrow/array"
+	arrowipc "github.com/apache/arrow/go/v7/arrow/ipc"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/iocounter"
+	"github.com/influxdata/flux/values"
+)
+
+const (
+	// ResultMetadataKey is the schema metadata key
+	// that holds the name of the result of the table.
+	ResultMetadataKey = "flux.result"
+	// GroupKeyMetadataKey is the schema metadata key that holds
+	// the labels of the group key columns as a JSON array.
+	GroupKeyMetadataKey = "flux.groupKey"
+	// GroupKeyValuesMetadataKey is the schema metadata key that holds
+	// the values of the group key as a JSON array of strings.
+	// Null values are encoded as JSON null.
+	GroupKeyValuesMetadataKey = "flux.groupKeyValues"
+	// ErrorMetadataKey is the schema metadata key that holds
+	// an error that occurred while the results were produced.
+	// A stream with an error has no columns or records.
+	ErrorMetadataKey = "flux.error"
+)
+
+// timestampType is the arrow type used for time columns.
+var timestampType = &stdarrow.TimestampType{Unit: stdarrow.Nanosecond, TimeZone: "UTC"}
+
+// ResultEncoder encodes a result as a sequence of Arrow IPC streams.
+type ResultEncoder struct {
+	mem memory.Allocator
+}
+
+// NewResultEncoder creates a new ResultEncoder.
+func NewResultEncoder() *ResultEncoder {
+	return &ResultEncoder{
+		mem: memory.DefaultAllocator,
+	}
+}
+
+// NewMultiResultEncoder creates a new encoder for multiple results.
+// The streams of each result directly follow the streams of the previous one.
+func NewMultiResultEncoder() flux.MultiResultEncoder {
+	return &flux.DelimitedMultiResultEncoder{
+		Encoder: NewResultEncoder(),
+	}
+}
+
+type arrowEncoderError struct {
+	err error
+}
+
+func (e *arrowEncoderError) Error() string {
+	return e.err.Error()
+}
+
+func (e *arrowEncoderError) IsEncoderError() bool {
+	return true
+}
+
+func (e *arrowEncoderError) Unwrap() error {
+	return e.err
+}
+
+func wrapEncodingError(err error) error {
+	if err == nil {
+		return err
+	}
+	return &arrowEncoderError{err: err}
+}
+
+func (e *ResultEncoder) Encode(w io.Writer, result flux.Result) (int64, error) {
+	wc := &iocounter.Writer{Writer: w}
+	err := result.Tables().Do(func(tbl flux.Table) error {
+		return e.encodeTable(wc, result.Name(), tbl)
+	})
+	return wc.Count(), err
+}
+
+// encodeTable writes the table as a single Arrow stream.
+func (e *ResultEncoder) encodeTable(w io.Writer, name string, tbl flux.Table) error {
+	schema, err := newSchema(name, tbl.Key(), tbl.Cols())
+	if err != nil {
+		return wrapEncodingError(err)
+	}
+	iw := arrowipc.NewWriter(w, arrowipc.WithSchema(schema), arrowipc.WithAllocator(e.mem))
+	err = tbl.Do(func(cr flux.ColReader) error {
+		return wrapEncodingError(e.writeRecord(iw, schema, cr))
+	})
+	// The stream is always closed so the end of stream marker is
+	// written and an error that follows can still be decoded.
+	cerr := iw.Close()
+	if err != nil {
+		return err
+	}
+	return wrapEncodingError(cerr)
+}
+
+func (e *ResultEncoder) writeRecord(iw *arrowipc.Writer, schema *stdarrow.Schema, cr flux.ColReader) error {
+	n := cr.Len()
+	if n == 0 {
+		return nil
+	}
+	cols := make([]stdarrow.Array, len(cr.Cols()))
+	for j, c := range cr.Cols() {
+		cols[j] = e.toArrowArray(table.Values(cr, j), c.Type, n)
+	}
+	rec := arrowarray.NewRecord(schema, cols, int64(n))
+	for _, col := range cols {
+		col.Release()
+	}
+	defer rec.Release()
+	return iw.Write(rec)
+}
+
+// toArrowArray returns an arrow array with the type from the schema
+// and the same contents as arr. The returned array must be released.
+func (e *ResultEncoder) toArrowArray(arr array.Array, typ flux.ColType, n int) stdarrow.Array {
+	switch typ {
+	case flux.TString:
+		if s := arr.(*array.String); s.IsConstant() {
+			// Constant strings do not have any arrow data
+			// so they need to be materialized.
+			b := arrowarray.NewStringBuilder(e.mem)
+			defer b.Release()
+			b.Reserve(n)
+			v := s.Value(0)
+			for i := 0; i < n; i++ {
+				b.Append(v)
+			}
+			return b.NewArray()
+		}
+		return retype(arr.Data(), stdarrow.BinaryTypes.String)
+	case flux.TTime:
+		return retype(arr.Data(), timestampType)
+	default:
+		arr.Retain()
+		return arr.(stdarrow.Array)
+	}
+}
+
+// retype returns an array that uses the buffers of data with a
+// different type that has the same layout.
+func retype(data stdarrow.ArrayData, typ stdarrow.DataType) stdarrow.Array {
+	nd := arrowarray.NewData(typ, data.Len(), data.Buffers(), nil, data.NullN(), data.Offset())
+	defer nd.Release()
+	return arrowarray.MakeFromData(nd)
+}
+
+// EncodeError encodes an error as a stream without any columns.
+func (e *ResultEncoder) EncodeError(w io.Writer, err error) error {
+	md := stdarrow.NewMetadata([]string{ErrorMetadataKey}, []string{err.Error()})
+	schema := stdarrow.NewSchema(nil, &md)
+	iw := arrowipc.NewWriter(w, arrowipc.WithSchema(schema), arrowipc.WithAllocator(e.mem))
+	return iw.Close()
+}
+
+// newSchema returns the arrow schema for a table
+// with the result name and group key in its metadata.
+func newSchema(name string, key flux.GroupKey, cols []flux.ColMeta) (*stdarrow.Schema, error) {
+	fields := make([]stdarrow.Field, len(cols))
+	for j, c := range cols {
+		var typ stdarrow.DataType
+		switch c.Type {
+		case flux.TInt:
+			typ = stdarrow.PrimitiveTypes.Int64
+		case flux.TUInt:
+			typ = stdarrow.PrimitiveTypes.Uint64
+		case flux.TFloat:
+			typ = stdarrow.PrimitiveTypes.Float64
+		case flux.TString:
+			typ = stdarrow.BinaryTypes.String
+		case flux.TBool:
+			typ = stdarrow.FixedWidthTypes.Boolean
+		case flux.TTime:
+			typ = timestampType
+		default:
+			return nil, errors.Newf(codes.Invalid, "cannot encode column %q of type %s", c.Label, c.Type)
+		}
+		fields[j] = stdarrow.Field{Name: c.Label, Type: typ, Nullable: true}
+	}
+
+	labels := make([]string, len(key.Cols()))
+	vals := make([]*string, len(key.Cols()))
+	for j, c := range key.Cols() {
+		labels[j] = c.Label
+		vals[j] = encodeKeyValue(key.Value(j))
+	}
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return nil, err
+	}
+	valsJSON, err := json.Marshal(vals)
+	if err != nil {
+		return nil, err
+	}
+	md := stdarrow.NewMetadata(
+		[]string{ResultMetadataKey, GroupKeyMetadataKey, GroupKeyValuesMetadataKey},
+		[]string{name, string(labelsJSON), string(valsJSON)},
+	)
+	return stdarrow.NewSchema(fields, &md), nil
+}
+
+// encodeKeyValue returns the string form of a group key value
+// or nil if the value is null.
+func encodeKeyValue(v values.Value) *string {
+	if v.IsNull() {
+		return nil
+	}
+	var s string
+	switch typ := flux.ColumnType(v.Type()); typ {
+	case flux.TInt:
+		s = strconv.FormatInt(v.Int(), 10)
+	case flux.TUInt:
+		s = strconv.FormatUint(v.UInt(), 10)
+	case flux.TFloat:
+		s = strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case flux.TString:
+		s = v.Str()
+	case flux.TBool:
+		s = strconv.FormatBool(v.Bool())
+	case flux.TTime:
+		s = v.Time().Time().Format(time.RFC3339Nano)
+	}
+	return &s
+}
+
+// decodeKeyValue parses a group key value that was
+// encoded with encodeKeyValue.
+func decodeKeyValue(s *string, typ flux.ColType) (values.Value, error) {
+	if s == nil {
+		return values.NewNull(flux.SemanticType(typ)), nil
+	}
+	switch typ {
+	case flux.TInt:
+		v, err := strconv.ParseInt(*s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return values.NewInt(v), nil
+	case flux.TUInt:
+		v, err := strconv.ParseUint(*s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return values.NewUInt(v), nil
+	case flux.TFloat:
+		v, err := strconv.ParseFloat(*s, 64)
+		if err != nil {
+			return nil, err
+		}
+		return values.NewFloat(v), nil
+	case flux.TString:
+		return values.NewString(*s), nil
+	case flux.TBool:
+		v, err := strconv.ParseBool(*s)
+		if err != nil {
+			return nil, err
+		}
+		return values.NewBool(v), nil
+	case flux.TTime:
+		v, err := time.Parse(time.RFC3339Nano, *s)
+		if err != nil {
+			return nil, err
+		}
+		return values.NewTime(values.ConvertTime(v)), nil
+	default:
+		return nil, errors.Newf(codes.Internal, "unsupported group key type %s", typ)
+	}
+}
+
+// ResultDecoderConfig are options that can be specified on the decoders.
+type ResultDecoderConfig struct {
+	// Allocator is the memory allocator that will be used during decoding.
+	// The default is to use an unlimited allocator when this is not set.
+	Allocator memory.Allocator
+}
+
+// ResultDecoder decodes a single result from Arrow IPC streams.
+type ResultDecoder struct {
+	c ResultDecoderConfig
+}
+
+// NewResultDecoder creates a new ResultDecoder.
+func NewResultDecoder(c ResultDecoderConfig) *ResultDecoder {
+	return &ResultDecoder{c: c}
+}
+
+// Decode decodes the first result in r.
+func (d *ResultDecoder) Decode(r io.Reader) (flux.Result, error) {
+	results := newResultIterator(d.c, io.NopCloser(r))
+	if !results.More() {
+		if err := results.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(codes.Invalid, "no result found in arrow stream")
+	}
+	return results.Next(), nil
+}
+
+// MultiResultDecoder decodes multiple results from Arrow IPC streams.
+// A result ends when a stream has a different result name.
+type MultiResultDecoder struct {
+	c ResultDecoderConfig
+}
+
+// NewMultiResultDecoder creates a new MultiResultDecoder.
+func NewMultiResultDecoder(c ResultDecoderConfig) *MultiResultDecoder {
+	return &MultiResultDecoder{c: c}
+}
+
+func (d *MultiResultDecoder) Decode(r io.ReadCloser) (flux.ResultIterator, error) {
+	return newResultIterator(d.c, r), nil
+}
+
+// resultIterator iterates through the results encoded in r.
+type resultIterator struct {
+	c  ResultDecoderConfig
+	r  io.ReadCloser
+	br *bufio.Reader
+
+	// peek is the stream that has been started
+	// but not yet decoded.
+	peek *tableStream
+	next *resultDecoder
+	err  error
+
+	released bool
+}
+
+func newResultIterator(c ResultDecoderConfig, r io.ReadCloser) *resultIterator {
+	if c.Allocator == nil {
+		c.Allocator = memory.DefaultAllocator
+	}
+	return &resultIterator{
+		c:  c,
+		r:  r,
+		br: bufio.NewReader(r),
+	}
+}
+
+func (r *resultIterator) More() bool {
+	if r.released {
+		return false
+	}
+	// Skip the tables of the previous result
+	// that were not read.
+	if r.next != nil {
+		if err := r.next.Do(func(tbl flux.Table) error {
+			tbl.Done()
+			return nil
+		}); err != nil {
+			r.err = err
+		}
+		r.next = nil
+	}
+	if r.err == nil {
+		s, err := r.peekStream()
+		if err != nil {
+			r.err = err
+		} else if s != nil && s.err != nil {
+			r.err = s.err
+		} else if s != nil {
+			r.next = &resultDecoder{name: s.name, it: r}
+			return true
+		}
+	}
+
+	// Release the resources for this query.
+	r.Release()
+	return false
+}
+
+func (r *resultIterator) Next() flux.Result {
+	return r.next
+}
+
+func (r *resultIterator) Release() {
+	if r.released {
+		return
+	}
+	if r.peek != nil {
+		r.peek.release()
+		r.peek = nil
+	}
+	if err := r.r.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	r.released = true
+}
+
+func (r *resultIterator) Err() error {
+	return r.err
+}
+
+func (r *resultIterator) Statistics() flux.Statistics {
+	return flux.Statistics{}
+}
+
+// peekStream starts reading the next stream if it has not been
+// started yet. It returns nil at the end of the input.
+func (r *resultIterator) peekStream() (*tableStream, error) {
+	if r.peek != nil {
+		return r.peek, nil
+	}
+	if _, err := r.br.Peek(1); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	s, err := newTableStream(r.br, r.c.Allocator)
+	if err != nil {
+		return nil, err
+	}
+	r.peek = s
+	return s, nil
+}
+
+// resultDecoder reads the tables of a single result.
+type resultDecoder struct {
+	name string
+	it   *resultIterator
+	done bool
+}
+
+func (r *resultDecoder) Name() string {
+	return r.name
+}
+
+func (r *resultDecoder) Tables() flux.TableIterator {
+	return r
+}
+
+func (r *resultDecoder) Do(f func(flux.Table) error) error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	for {
+		s, err := r.it.peekStream()
+		if err != nil {
+			r.it.err = err
+			return err
+		}
+		// An error that follows the tables of this result
+		// is reported by the result iterator.
+		if s == nil || s.err != nil || s.name != r.name {
+			return nil
+		}
+		r.it.peek = nil
+		tbl, err := s.readTable()
+		s.release()
+		if err != nil {
+			r.it.err = err
+			return err
+		}
+		if err := f(tbl); err != nil {
+			return err
+		}
+	}
+}
+
+// tableStream is a single Arrow stream that holds one table.
+type tableStream struct {
+	rr   *arrowipc.Reader
+	name string
+	key  flux.GroupKey
+	cols []flux.ColMeta
+	err  error
+}
+
+// newTableStream reads the schema of the next stream in r.
+func newTableStream(r io.Reader, mem memory.Allocator) (*tableStream, error) {
+	rr, err := arrowipc.NewReader(r, arrowipc.WithAllocator(mem))
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Invalid, "could not read arrow stream")
+	}
+	s := &tableStream{rr: rr}
+	schema := rr.Schema()
+	md := schema.Metadata()
+	if i := md.FindKey(ErrorMetadataKey); i >= 0 {
+		s.err = errors.New(codes.Unknown, md.Values()[i])
+		return s, nil
+	}
+	if i := md.FindKey(ResultMetadataKey); i >= 0 {
+		s.name = md.Values()[i]
+	}
+
+	s.cols = make([]flux.ColMeta, len(schema.Fields()))
+	for j, f := range schema.Fields() {
+		typ, err := columnType(f.Type)
+		if err != nil {
+			s.release()
+			return nil, errors.Wrapf(err, codes.Inherit, "cannot decode column %q", f.Name)
+		}
+		s.cols[j] = flux.ColMeta{Label: f.Name, Type: typ}
+	}
+
+	s.key, err = decodeGroupKey(md, s.cols)
+	if err != nil {
+		s.release()
+		return nil, err
+	}
+	return s, nil
+}
+
+// decodeGroupKey reads the group key from the schema metadata.
+func decodeGroupKey(md stdarrow.Metadata, cols []flux.ColMeta) (flux.GroupKey, error) {
+	var (
+		labels []string
+		vals   []*string
+	)
+	if i := md.FindKey(GroupKeyMetadataKey); i >= 0 {
+		if err := json.Unmarshal([]byte(md.Values()[i]), &labels); err != nil {
+			return nil, errors.Wrap(err, codes.Invalid, "invalid group key in arrow stream")
+		}
+	}
+	if i := md.FindKey(GroupKeyValuesMetadataKey); i >= 0 {
+		if err := json.Unmarshal([]byte(md.Values()[i]), &vals); err != nil {
+			return nil, errors.Wrap(err, codes.Invalid, "invalid group key values in arrow stream")
+		}
+	}
+	if len(labels) != len(vals) {
+		return nil, errors.Newf(codes.Invalid, "arrow stream has %d group key columns but %d group key values", len(labels), len(vals))
+	}
+
+	keyCols := make([]flux.ColMeta, len(labels))
+	keyVals := make([]values.Value, len(labels))
+	for j, label := range labels {
+		idx := execute.ColIdx(label, cols)
+		if idx < 0 {
+			return nil, errors.Newf(codes.Invalid, "group key column %q is not in the arrow stream", label)
+		}
+		v, err := decodeKeyValue(vals[j], cols[idx].Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, codes.Invalid, "invalid value for group key column %q", label)
+		}
+		keyCols[j], keyVals[j] = cols[idx], v
+	}
+	return execute.NewGroupKey(keyCols, keyVals), nil
+}
+
+// readTable reads all of the records in the stream into a table.
+func (s *tableStream) readTable() (flux.Table, error) {
+	tbl := &table.BufferedTable{
+		GroupKey: s.key,
+		Columns:  s.cols,
+	}
+	for s.rr.Next() {
+		rec := s.rr.Record()
+		buffer := &arrow.TableBuffer{
+			GroupKey: s.key,
+			Columns:  s.cols,
+			Values:   make([]array.Array, len(s.cols)),
+		}
+		for j := range buffer.Values {
+			buffer.Values[j] = fromArrowArray(rec.Column(j))
+		}
+		tbl.Buffers = append(tbl.Buffers, buffer)
+	}
+	if err := s.rr.Err(); err != nil && err != io.EOF {
+		tbl.Done()
+		return nil, errors.Wrap(err, codes.Invalid, "could not read arrow stream")
+	}
+	return tbl, nil
+}
+
+func (s *tableStream) release() {
+	s.rr.Release()
+}
+
+// columnType returns the column type for an arrow type.
+func columnType(typ stdarrow.DataType) (flux.ColType, error) {
+	switch typ.ID() {
+	case stdarrow.INT64:
+		return flux.TInt, nil
+	case stdarrow.UINT64:
+		return flux.TUInt, nil
+	case stdarrow.FLOAT64:
+		return flux.TFloat, nil
+	case stdarrow.STRING, stdarrow.BINARY:
+		return flux.TString, nil
+	case stdarrow.BOOL:
+		return flux.TBool, nil
+	case stdarrow.TIMESTAMP:
+		if typ.(*stdarrow.TimestampType).Unit == stdarrow.Nanosecond {
+			return flux.TTime, nil
+		}
+	}
+	return flux.TInvalid, errors.Newf(codes.Invalid, "unsupported arrow type %s", typ)
+}
+
+// fromArrowArray converts an array read from a stream
+// into the array type that flux uses for it.
+func fromArrowArray(arr stdarrow.Array) array.Array {
+	switch arr.DataType().ID() {
+	case stdarrow.STRING, stdarrow.BINARY:
+		data := arrowarray.NewData(stdarrow.BinaryTypes.Binary, arr.Len(), arr.Data().Buffers(), nil, arr.NullN(), arr.Data().Offset())
+		defer data.Release()
+		bin := arrowarray.NewBinaryData(data)
+		defer bin.Release()
+		return array.NewStringFromBinaryArray(bin)
+	case stdarrow.TIMESTAMP:
+		return retype(arr.Data(), stdarrow.PrimitiveTypes.Int64)
+	default:
+		arr.Retain()
+		return arr
+	}
+}
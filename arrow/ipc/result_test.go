@@ -0,0 +1,178 @@
+package ipc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/arrow/ipc"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/values"
+)
+
+func ts(sec int) values.Time {
+	return values.ConvertTime(time.Date(2018, 4, 17, 0, 0, sec, 0, time.UTC))
+}
+
+func testResults() []*executetest.Result {
+	cols := []flux.ColMeta{
+		{Label: "_start", Type: flux.TTime},
+		{Label: "_time", Type: flux.TTime},
+		{Label: "host", Type: flux.TString},
+		{Label: "_value", Type: flux.TFloat},
+		{Label: "n", Type: flux.TInt},
+		{Label: "u", Type: flux.TUInt},
+		{Label: "ok", Type: flux.TBool},
+	}
+	return []*executetest.Result{
+		{
+			Nm: "_result",
+			Tbls: []*executetest.Table{
+				{
+					KeyCols: []string{"_start", "host"},
+					ColMeta: cols,
+					Data: [][]interface{}{
+						{ts(0), ts(0), "A", 42.0, int64(1), uint64(2), true},
+						{ts(0), ts(1), "A", 0.5, nil, nil, nil},
+					},
+				},
+				{
+					KeyCols: []string{"_start", "host"},
+					ColMeta: cols,
+					Data: [][]interface{}{
+						{ts(0), ts(2), nil, 43.0, int64(-1), uint64(0), false},
+					},
+				},
+				{
+					KeyCols:   []string{"_start", "host"},
+					KeyValues: []interface{}{ts(0), "B"},
+					ColMeta:   cols,
+				},
+			},
+		},
+		{
+			Nm: "other",
+			Tbls: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{"a"},
+						{"b"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func encode(t *testing.T, results []*executetest.Result) []byte {
+	t.Helper()
+	rs := make([]flux.Result, len(results))
+	for i, r := range results {
+		rs[i] = r
+	}
+	var buf bytes.Buffer
+	n, err := ipc.NewMultiResultEncoder().Encode(&buf, flux.NewSliceResultIterator(rs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := int64(buf.Len()), n; want != got {
+		t.Errorf("unexpected encoding count -want/+got:\n%s", cmp.Diff(want, got))
+	}
+	return buf.Bytes()
+}
+
+func decode(t *testing.T, encoded []byte) ([]*executetest.Result, error) {
+	t.Helper()
+	results, err := ipc.NewMultiResultDecoder(ipc.ResultDecoderConfig{}).Decode(io.NopCloser(bytes.NewReader(encoded)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*executetest.Result
+	for results.More() {
+		res := executetest.ConvertResult(results.Next())
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		res.Normalize()
+		got = append(got, res)
+	}
+	return got, results.Err()
+}
+
+func TestMultiResultEncoder_RoundTrip(t *testing.T) {
+	encoded := encode(t, testResults())
+	got, err := decode(t, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := testResults()
+	for _, r := range want {
+		r.Normalize()
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("unexpected results -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestMultiResultEncoder_Error(t *testing.T) {
+	results := testResults()
+	results[1].Err = errors.New("test error")
+	encoded := encode(t, results)
+
+	got, err := decode(t, encoded)
+	if err == nil {
+		t.Fatal("expected error")
+	} else if want, got := "test error", err.Error(); want != got {
+		t.Errorf("unexpected error -want/+got:\n%s", cmp.Diff(want, got))
+	}
+	if want, got := 1, len(got); want != got {
+		t.Fatalf("unexpected number of results -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestMultiResultDecoder_SkipResult(t *testing.T) {
+	encoded := encode(t, testResults())
+	results, err := ipc.NewMultiResultDecoder(ipc.ResultDecoderConfig{}).Decode(io.NopCloser(bytes.NewReader(encoded)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read the names of the results without reading their tables.
+	var names []string
+	for results.More() {
+		names = append(names, results.Next().Name())
+	}
+	if err := results.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"_result", "other"}; !cmp.Equal(want, names) {
+		t.Errorf("unexpected result names -want/+got:\n%s", cmp.Diff(want, names))
+	}
+}
+
+func TestResultDecoder(t *testing.T) {
+	encoded := encode(t, testResults())
+	result, err := ipc.NewResultDecoder(ipc.ResultDecoderConfig{}).Decode(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := executetest.ConvertResult(result)
+	if got.Err != nil {
+		t.Fatal(got.Err)
+	}
+	got.Normalize()
+
+	want := testResults()[0]
+	want.Normalize()
+	if !cmp.Equal(want, got) {
+		t.Errorf("unexpected result -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
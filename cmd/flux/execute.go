@@ -6,9 +6,12 @@ import (
 	"os"
 
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/arrow/ipc"
 	"github.com/influxdata/flux/csv"
 	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/json"
 	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/flux/line"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/runtime"
 )
@@ -49,6 +52,26 @@ func executeE(ctx context.Context, script, format string) error {
 		if err != nil {
 			return err
 		}
+	} else if format == "json" || format == "ndjson" {
+		encoder := json.NewMultiResultEncoder(json.ResultEncoderConfig{
+			NDJSON: format == "ndjson",
+		})
+		_, err := encoder.Encode(os.Stdout, results)
+		if err != nil {
+			return err
+		}
+	} else if format == "arrow" {
+		encoder := ipc.NewMultiResultEncoder()
+		_, err := encoder.Encode(os.Stdout, results)
+		if err != nil {
+			return err
+		}
+	} else if format == "line" {
+		encoder := line.NewMultiResultEncoder(line.DefaultEncoderConfig())
+		_, err := encoder.Encode(os.Stdout, results)
+		if err != nil {
+			return err
+		}
 	}
 	results.Release()
 	return results.Err()
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	fluxcmd "github.com/influxdata/flux/cmd/flux/cmd"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/fluxinit"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/spf13/cobra"
+)
+
+var explainFlags struct {
+	ExecScript bool
+	Format     string
+}
+
+// explainAnalyzer is implemented by programs that can
+// be explained after they are executed.
+type explainAnalyzer interface {
+	ExplainAnalyze(ctx context.Context, alloc memory.Allocator) (*plan.Explanation, error)
+}
+
+func explain(cmd *cobra.Command, args []string) error {
+	script := args[0]
+	if !explainFlags.ExecScript {
+		content, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		script = string(content)
+	}
+
+	if explainFlags.Format != "text" && explainFlags.Format != "json" {
+		return errors.Newf(codes.Invalid, "unknown explain format: %s", explainFlags.Format)
+	}
+
+	fluxinit.FluxInit()
+	ctx, span := injectDependencies(context.Background())
+	defer span.Finish()
+
+	ctx, err := fluxcmd.WithFeatureFlags(ctx, flags.Features)
+	if err != nil {
+		return err
+	}
+	return explainE(ctx, script, explainFlags.Format)
+}
+
+func explainE(ctx context.Context, script, format string) error {
+	c := lang.FluxCompiler{
+		Query: script,
+	}
+	prog, err := c.Compile(ctx, runtime.Default)
+	if err != nil {
+		return err
+	}
+
+	ea, ok := prog.(explainAnalyzer)
+	if !ok {
+		return errors.Newf(codes.Internal, "program of type %T cannot be explained", prog)
+	}
+	explanation, err := ea.ExplainAnalyze(ctx, &memory.ResourceAllocator{})
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(explanation)
+	}
+	_, err = fmt.Fprint(os.Stdout, explanation)
+	return err
+}
@@ -121,7 +121,7 @@ func main() {
 	fluxCmd.Flags().BoolVarP(&flags.ExecScript, "exec", "e", false, "Interpret file argument as a raw flux script")
 	fluxCmd.Flags().BoolVarP(&flags.EnableSuggestions, "enable-suggestions", "", false, "enable suggestions in the repl")
 	fluxCmd.Flags().StringVar(&flags.Trace, "trace", "", "Trace query execution")
-	fluxCmd.Flags().StringVarP(&flags.Format, "format", "", "cli", "Output format one of: cli,csv. Defaults to cli")
+	fluxCmd.Flags().StringVarP(&flags.Format, "format", "", "cli", "Output format one of: cli,csv,json,ndjson,arrow,line. Defaults to cli")
 	fluxCmd.Flag("trace").NoOptDefVal = "jaeger"
 	fluxCmd.Flags().StringVar(&flags.Features, "features", "", "JSON object specifying the features to execute with. See internal/feature/flags.yml for a list of the current features")
 
@@ -136,6 +136,17 @@ func main() {
 	fmtCmd.Flags().BoolVarP(&fmtFlags.AnalyzeCurrentDirectory, "analyze-current-directory", "c", false, "analyze the current <directory | file> and report if file(s) are not formatted")
 	fluxCmd.AddCommand(fmtCmd)
 
+	explainCmd := &cobra.Command{
+		Use:   "explain",
+		Short: "Execute a Flux script and explain its plan",
+		Long:  "Execute a Flux script and print its plan with the statistics collected for each node (flux explain [-e] [--format text|json] <file | script>)",
+		Args:  cobra.ExactArgs(1),
+		RunE:  explain,
+	}
+	explainCmd.Flags().BoolVarP(&explainFlags.ExecScript, "exec", "e", false, "Interpret file argument as a raw flux script")
+	explainCmd.Flags().StringVar(&explainFlags.Format, "format", "text", "Output format one of: text,json")
+	fluxCmd.AddCommand(explainCmd)
+
 	testCmd := fluxcmd.TestCommand(NewTestExecutor)
 	fluxCmd.AddCommand(testCmd)
 
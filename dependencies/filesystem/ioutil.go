@@ -30,6 +30,15 @@ func OpenFile(ctx context.Context, filename string) (File, error) {
 	return fs.Open(filename)
 }
 
+// CreateFile will create or truncate the file with the WritableService.
+func CreateFile(ctx context.Context, filename string) (io.WriteCloser, error) {
+	fs, err := GetWritable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Create(filename)
+}
+
 // Stat will retrieve the os.FileInfo for a file.
 func Stat(ctx context.Context, filename string) (os.FileInfo, error) {
 	fs, err := Get(ctx)
@@ -20,9 +20,22 @@ type Service interface {
 	Open(fpath string) (File, error)
 }
 
+// WritableService is the service for creating files.
+//
+// It is a separate dependency from the Service so that queries
+// can only write files when writing has been enabled explicitly.
+type WritableService interface {
+	Create(fpath string) (io.WriteCloser, error)
+	Rename(oldpath, newpath string) error
+	Remove(fpath string) error
+}
+
 type key int
 
-const serviceKey key = iota
+const (
+	serviceKey key = iota
+	writableServiceKey
+)
 
 // Dependency will inject the filesystem Service into the dependency chain.
 type Dependency struct {
@@ -50,3 +63,30 @@ func Get(ctx context.Context) (Service, error) {
 	}
 	return s.(Service), nil
 }
+
+// WritableDependency will inject the filesystem WritableService into the dependency chain.
+type WritableDependency struct {
+	FS WritableService
+}
+
+// Inject will inject the filesystem WritableService into the dependency chain.
+func (d WritableDependency) Inject(ctx context.Context) context.Context {
+	if d.FS != nil {
+		ctx = InjectWritable(ctx, d.FS)
+	}
+	return ctx
+}
+
+// InjectWritable will inject this filesystem WritableService into the context.
+func InjectWritable(ctx context.Context, fs WritableService) context.Context {
+	return context.WithValue(ctx, writableServiceKey, fs)
+}
+
+// GetWritable will retrieve a filesystem WritableService from the context.Context.
+func GetWritable(ctx context.Context) (WritableService, error) {
+	s := ctx.Value(writableServiceKey)
+	if s == nil {
+		return nil, errors.New(codes.Unimplemented, "writable filesystem service is uninitialized")
+	}
+	return s.(WritableService), nil
+}
@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"io"
 	"os"
 )
 
@@ -17,3 +18,27 @@ func (systemFS) Open(fpath string) (File, error) {
 	}
 	return f, nil
 }
+
+// WritableSystemFS implements the filesystem.WritableService by creating,
+// renaming and removing files on the filesystem. Any path the process can
+// write to can be changed so it should only be injected when queries are
+// trusted to write files.
+var WritableSystemFS WritableService = writableSystemFS{}
+
+type writableSystemFS struct{}
+
+func (writableSystemFS) Create(fpath string) (io.WriteCloser, error) {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (writableSystemFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (writableSystemFS) Remove(fpath string) error {
+	return os.Remove(fpath)
+}
@@ -94,3 +94,36 @@ func TestSystemFS_ReadFile(t *testing.T) {
 		t.Fatalf("unexpected file contents -want/+got:\n\t- %q\n\t+ %q", want, got)
 	}
 }
+
+func TestSystemFS_CreateFile(t *testing.T) {
+	dir := t.TempDir()
+	fpath := filepath.Join(dir, "out.txt")
+
+	ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+	if _, err := filesystem.CreateFile(ctx, fpath); err == nil {
+		t.Fatal("expected an error when the writable service is not injected")
+	}
+	if _, err := os.Stat(fpath); !os.IsNotExist(err) {
+		t.Fatalf("expected the file not to be created, got %v", err)
+	}
+
+	ctx = filesystem.InjectWritable(ctx, filesystem.WritableSystemFS)
+	f, err := filesystem.CreateFile(ctx, fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(f, "Hello, World!"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := filesystem.ReadFile(ctx, fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "Hello, World!"; got != want {
+		t.Fatalf("unexpected file contents -want/+got:\n\t- %q\n\t+ %q", want, got)
+	}
+}
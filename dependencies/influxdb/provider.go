@@ -107,6 +107,23 @@ type Reader interface {
 	Read(ctx context.Context, f func(flux.Table) error, mem memory.Allocator) error
 }
 
+// Statistics holds estimates about the data a Reader will produce.
+// A zero value for a field means it is unknown.
+type Statistics struct {
+	// Rows is the estimated number of rows.
+	Rows int64
+	// Series is the estimated number of series.
+	Series int64
+}
+
+// StatisticsReader is an optional interface that a Reader
+// can implement to report estimates about the data it will read
+// without reading it. The planner uses these to choose between
+// alternative plans.
+type StatisticsReader interface {
+	ReadStatistics(ctx context.Context) (Statistics, error)
+}
+
 // Reference the fields we use from the line protocol v1 library.
 // We're going to migrate to v2, but first we need to update references
 // to the external library to our own internal one to avoid breaking changes.
@@ -14,6 +14,7 @@ import (
 	"github.com/influxdata/flux/internal/feature"
 	fluxmemory "github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/values"
 )
 
 // AggregateTransformation implements a transformation that aggregates
@@ -74,6 +75,10 @@ func (t *aggregateTransformation) ProcessMessage(m Message) error {
 		return t.processChunk(m.TableChunk())
 	case FlushKeyMsg:
 		return t.flushKey(m.Key())
+	case UpdateWatermarkMsg:
+		return t.updateWatermark(m.WatermarkTime())
+	case UpdateProcessingTimeMsg:
+		return t.d.UpdateProcessingTime(m.ProcessingTime())
 	case ProcessMsg:
 		panic("unreachable")
 	}
@@ -122,6 +127,25 @@ func (t *aggregateTransformation) flushKey(key flux.GroupKey) error {
 	return nil
 }
 
+// updateWatermark computes the aggregate for each group key that
+// cannot receive more data now that the watermark has reached mark
+// and then sends the watermark downstream.
+func (t *aggregateTransformation) updateWatermark(mark Time) error {
+	var closed []flux.GroupKey
+	_ = t.d.Range(func(key flux.GroupKey, value interface{}) error {
+		if closedByWatermark(key, mark) {
+			closed = append(closed, key)
+		}
+		return nil
+	})
+	for _, key := range closed {
+		if err := t.flushKey(key); err != nil {
+			return err
+		}
+	}
+	return t.d.UpdateWatermark(mark)
+}
+
 // Finish is implemented to remain compatible with legacy upstreams.
 func (t *aggregateTransformation) Finish(id DatasetID, err error) {
 	if err == nil {
@@ -756,6 +780,58 @@ func (t *simpleAggregateTransformation2) Close() error {
 	return nil
 }
 
+var simpleAggregates = make(map[string]func() SimpleAggregate)
+
+// RegisterSimpleAggregate registers the function that creates the SimpleAggregate
+// of an aggregate function so that other transformations, such as rolling,
+// can compute the aggregate over their own sets of values.
+func RegisterSimpleAggregate(name string, newAgg func() SimpleAggregate) {
+	if simpleAggregates[name] != nil {
+		panic(errors.Newf(codes.Internal, "duplicate registration for simple aggregate %q", name))
+	}
+	simpleAggregates[name] = newAgg
+}
+
+// LookupSimpleAggregate returns the function registered with RegisterSimpleAggregate
+// that creates the SimpleAggregate of the named aggregate function.
+func LookupSimpleAggregate(name string) (func() SimpleAggregate, bool) {
+	newAgg, ok := simpleAggregates[name]
+	return newAgg, ok
+}
+
+// AggregateFloats computes the aggregate of the float values.
+// The result is a null value if the aggregate is null.
+func AggregateFloats(agg SimpleAggregate, vs []float64, mem fluxmemory.Allocator) (values.Value, error) {
+	vf := agg.NewFloatAgg()
+	if vf == nil {
+		return nil, errors.New(codes.FailedPrecondition, "aggregate does not support float values")
+	}
+	arr := arrow.NewFloat(vs, mem)
+	vf.DoFloat(arr)
+	arr.Release()
+	if c, ok := vf.(Closer); ok {
+		defer c.Close()
+	}
+
+	if vf.IsNull() {
+		return values.NewNull(flux.SemanticType(vf.Type())), nil
+	}
+	switch vf.Type() {
+	case flux.TBool:
+		return values.NewBool(vf.(BoolValueFunc).ValueBool()), nil
+	case flux.TInt:
+		return values.NewInt(vf.(IntValueFunc).ValueInt()), nil
+	case flux.TUInt:
+		return values.NewUInt(vf.(UIntValueFunc).ValueUInt()), nil
+	case flux.TFloat:
+		return values.NewFloat(vf.(FloatValueFunc).ValueFloat()), nil
+	case flux.TString:
+		return values.NewString(vf.(StringValueFunc).ValueString()), nil
+	default:
+		return nil, errors.Newf(codes.Internal, "unsupported aggregate type %v", vf.Type())
+	}
+}
+
 type SimpleAggregate interface {
 	NewBoolAgg() DoBoolAgg
 	NewIntAgg() DoIntAgg
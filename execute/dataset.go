@@ -236,7 +236,8 @@ func (d *PassthroughDataset) SetTriggerSpec(t plan.TriggerSpec) {
 //
 // This Dataset also implements a shim for execute.Dataset
 // so it can be integrated with the existing execution engine.
-// These methods are stubs and do not do anything.
+// Watermark and processing time updates are sent to the downstream
+// transports. The other methods are stubs and do not do anything.
 type TransportDataset struct {
 	id         DatasetID
 	transports []Transport
@@ -319,8 +320,25 @@ func (d *TransportDataset) Range(f func(key flux.GroupKey, value interface{}) er
 }
 
 func (d *TransportDataset) RetractTable(key flux.GroupKey) error { return nil }
-func (d *TransportDataset) UpdateProcessingTime(t Time) error    { return nil }
-func (d *TransportDataset) UpdateWatermark(mark Time) error      { return nil }
+
+// UpdateProcessingTime sends the processing time to the downstream transports.
+func (d *TransportDataset) UpdateProcessingTime(t Time) error {
+	m := &updateProcessingTimeMsg{
+		srcMessage: srcMessage(d.id),
+		time:       t,
+	}
+	return d.sendMessage(m)
+}
+
+// UpdateWatermark sends the watermark to the downstream transports.
+func (d *TransportDataset) UpdateWatermark(mark Time) error {
+	m := &updateWatermarkMsg{
+		srcMessage: srcMessage(d.id),
+		time:       mark,
+	}
+	return d.sendMessage(m)
+}
+
 func (d *TransportDataset) Finish(err error) {
 	m := &finishMsg{
 		srcMessage: srcMessage(d.id),
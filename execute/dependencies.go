@@ -16,7 +16,12 @@ import (
 
 type key int
 
-const executionDependenciesKey key = iota
+const (
+	executionDependenciesKey key = iota
+	nodeStatisticsKey
+	partialResultsKey
+	streamingKey
+)
 
 type ExecutionOptions struct {
 	OperatorProfiler *OperatorProfiler
@@ -38,6 +43,16 @@ type ExecutionDependencies struct {
 	Metadata metadata.SyncMetadata
 
 	ExecutionOptions *ExecutionOptions
+
+	// SpillDir is the directory where transformations write buffered
+	// data that does not fit within the memory limit of the query.
+	// If empty, buffered data is never written to disk.
+	SpillDir string
+
+	// NodeMemoryLimit is the maximum number of bytes that a single
+	// node in the plan may allocate. If zero, each node may use
+	// as much memory as the query is allowed to use.
+	NodeMemoryLimit int64
 }
 
 func (d ExecutionDependencies) Inject(ctx context.Context) context.Context {
@@ -71,6 +86,25 @@ func GetExecutionDependencies(ctx context.Context) ExecutionDependencies {
 	return ctx.Value(executionDependenciesKey).(ExecutionDependencies)
 }
 
+// GetSpillDir returns the directory that transformations should use
+// for spill files. It returns an empty string, which disables spilling,
+// if there are no execution dependencies.
+func GetSpillDir(ctx context.Context) string {
+	if !HaveExecutionDependencies(ctx) {
+		return ""
+	}
+	return GetExecutionDependencies(ctx).SpillDir
+}
+
+// GetNodeMemoryLimit returns the memory limit for each node in the plan.
+// It returns zero when the nodes are not limited.
+func GetNodeMemoryLimit(ctx context.Context) int64 {
+	if !HaveExecutionDependencies(ctx) {
+		return 0
+	}
+	return GetExecutionDependencies(ctx).NodeMemoryLimit
+}
+
 // Create some execution dependencies. Any arg may be nil, this will choose
 // some suitable defaults.
 func NewExecutionDependencies(allocator memory.Allocator, now *time.Time, logger *zap.Logger) ExecutionDependencies {
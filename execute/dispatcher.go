@@ -19,9 +19,17 @@ type Dispatcher interface {
 // The throughput is the maximum number of messages to process for this scheduling.
 type ScheduleFunc func(ctx context.Context, throughput int)
 
+// priorityDispatcher is a Dispatcher that can run work
+// with a higher priority before other work.
+type priorityDispatcher interface {
+	Dispatcher
+	// SchedulePriority schedules fn with the given priority.
+	SchedulePriority(fn ScheduleFunc, priority int)
+}
+
 // poolDispatcher implements Dispatcher using a pool of goroutines.
 type poolDispatcher struct {
-	work   *ring
+	work   workQueue
 	ready  chan struct{}
 	workMu sync.Mutex
 
@@ -40,7 +48,6 @@ type poolDispatcher struct {
 func newPoolDispatcher(throughput int, logger *zap.Logger) *poolDispatcher {
 	return &poolDispatcher{
 		throughput: throughput,
-		work:       newRing(100),
 		ready:      make(chan struct{}, 1),
 		closing:    make(chan struct{}),
 		errC:       make(chan error, 1),
@@ -49,12 +56,16 @@ func newPoolDispatcher(throughput int, logger *zap.Logger) *poolDispatcher {
 }
 
 func (d *poolDispatcher) Schedule(fn ScheduleFunc) {
+	d.SchedulePriority(fn, 0)
+}
+
+func (d *poolDispatcher) SchedulePriority(fn ScheduleFunc, priority int) {
 	d.workMu.Lock()
 	defer d.workMu.Unlock()
 
 	// Schedule the work and then report to the channel that there
 	// is available work to unblock the worker scheduler thread.
-	d.work.Append(fn)
+	d.work.Append(fn, priority)
 	select {
 	case d.ready <- struct{}{}:
 		// The ready channel should have a buffer of 1.
@@ -142,11 +153,8 @@ func (d *poolDispatcher) run(ctx context.Context) {
 // the dispatcher is closed, or there is no more work in the queue.
 func (d *poolDispatcher) doWork(ctx context.Context) {
 	for {
-		var fn ScheduleFunc
 		d.workMu.Lock()
-		if next := d.work.Next(); next != nil {
-			fn = next.(ScheduleFunc)
-		}
+		fn := d.work.Next()
 		d.workMu.Unlock()
 
 		if fn == nil {
@@ -168,3 +176,43 @@ func (d *poolDispatcher) doWork(ctx context.Context) {
 		}
 	}
 }
+
+// workQueue holds scheduled work ordered by priority.
+// Work with a higher priority is returned first and work with
+// the same priority is returned in the order it was appended.
+type workQueue struct {
+	// levels is sorted by descending priority.
+	levels []workLevel
+}
+
+type workLevel struct {
+	priority int
+	work     *ring
+}
+
+func (q *workQueue) Append(fn ScheduleFunc, priority int) {
+	i := 0
+	for ; i < len(q.levels); i++ {
+		if q.levels[i].priority <= priority {
+			break
+		}
+	}
+	if i == len(q.levels) || q.levels[i].priority != priority {
+		q.levels = append(q.levels, workLevel{})
+		copy(q.levels[i+1:], q.levels[i:])
+		q.levels[i] = workLevel{
+			priority: priority,
+			work:     newRing(100),
+		}
+	}
+	q.levels[i].work.Append(fn)
+}
+
+func (q *workQueue) Next() ScheduleFunc {
+	for _, l := range q.levels {
+		if next := l.work.Next(); next != nil {
+			return next.(ScheduleFunc)
+		}
+	}
+	return nil
+}
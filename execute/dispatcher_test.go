@@ -81,3 +81,41 @@ func TestDispatcher_ScheduleMany(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func TestDispatcher_SchedulePriority(t *testing.T) {
+	d := newPoolDispatcher(10, zaptest.NewLogger(t))
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for _, priority := range []int{0, 2, 1, 2, 0} {
+		priority := priority
+		wg.Add(1)
+		d.SchedulePriority(func(ctx context.Context, throughput int) {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+		}, priority)
+	}
+
+	// Start a single worker after the work has been scheduled
+	// so the work is run in priority order.
+	d.Start(1, context.Background())
+	wg.Wait()
+	if err := d.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{2, 2, 1, 0, 0}
+	if len(order) != len(want) {
+		t.Fatalf("unexpected order: %v", order)
+	}
+	for i := range want {
+		if want[i] != order[i] {
+			t.Fatalf("unexpected order -want/+got:\n\t- %v\n\t+ %v", want, order)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -61,6 +62,14 @@ type executionState struct {
 	cancel func()
 	alloc  memory.Allocator
 
+	// srcCtx is the context used by the sources. It is the same as ctx
+	// unless the query returns partial results, in which case it is also
+	// cancelled when the query is cancelled without aborting the query.
+	srcCtx context.Context
+
+	truncatedMu sync.Mutex
+	truncated   map[plan.NodeID]bool
+
 	resources flux.ResourceManagement
 
 	results map[string]flux.Result
@@ -69,6 +78,12 @@ type executionState struct {
 
 	transports []AsyncTransport
 
+	// nodeStats holds the statistics for each plan node.
+	// The statistics for the sources are kept in sourceStats
+	// when node statistics are enabled.
+	nodeStats   []*nodeStatistics
+	sourceStats []*nodeStatistics
+
 	dispatcher *poolDispatcher
 	logger     *zap.Logger
 }
@@ -85,19 +100,28 @@ func (e *executor) Execute(ctx context.Context, p *plan.Spec, a memory.Allocator
 func (e *executor) createExecutionState(ctx context.Context, p *plan.Spec, a memory.Allocator) (*executionState, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	es := &executionState{
-		p:         p,
-		ctx:       ctx,
-		cancel:    cancel,
-		alloc:     a,
-		resources: p.Resources,
-		results:   make(map[string]flux.Result),
-		// TODO(nathanielc): Have the planner specify the dispatcher throughput
-		dispatcher: newPoolDispatcher(10, e.logger),
+		p:          p,
+		ctx:        ctx,
+		cancel:     cancel,
+		alloc:      a,
+		resources:  p.Resources,
+		results:    make(map[string]flux.Result),
+		dispatcher: newPoolDispatcher(dispatcherThroughput(p), e.logger),
 		logger:     e.logger,
 	}
+	es.srcCtx = ctx
+	if pctx, ok := partialResultsContext(ctx); ok {
+		es.srcCtx = newSourceContext(ctx, pctx)
+		es.truncated = make(map[plan.NodeID]bool)
+	}
 	v := &createExecutionNodeVisitor{
-		es:    es,
-		nodes: make(map[plan.Node][]Node),
+		es:          es,
+		nodes:       make(map[plan.Node][]Node),
+		stats:       make(map[plan.Node]*nodeStatistics),
+		memoryLimit: GetNodeMemoryLimit(ctx),
+	}
+	if NodeStatisticsEnabled(ctx) {
+		v.outCounted = make(map[Node]bool)
 	}
 
 	if err := p.BottomUpWalk(v.Visit); err != nil {
@@ -123,6 +147,12 @@ func (e *executor) createExecutionState(ctx context.Context, p *plan.Spec, a mem
 type createExecutionNodeVisitor struct {
 	es    *executionState
 	nodes map[plan.Node][]Node
+
+	stats       map[plan.Node]*nodeStatistics
+	memoryLimit int64
+
+	// outCounted is only set when node statistics are enabled.
+	outCounted map[Node]bool
 }
 
 func skipYields(pn plan.Node) plan.Node {
@@ -187,6 +217,10 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 	//
 	// 3. Merge instantiation. There is a single copy of the node, but multiple copies of the
 	//    predecessors. These copies merge into the node.
+	//
+	// 4. Partition instantiation. There are multiple copies of the node, but
+	//    only a single copy of the predecessor. Every copy of the node reads
+	//    from the single copy of the predecessor.
 
 	copies := 1
 	if attr := plan.GetOutputAttribute(ppn, plan.ParallelRunKey); attr != nil {
@@ -200,11 +234,26 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 		predCopies = attr.(plan.ParallelMergeAttribute).Factor
 	}
 
+	stats := newNodeStatistics(node, copies, v.memoryLimit)
+	v.stats[node] = stats
+	v.es.nodeStats = append(v.es.nodeStats, stats)
+	alloc := &nodeAllocator{Allocator: v.es.alloc, stats: stats}
+	counting := v.outCounted != nil
+
+	// Sources use their own context so they can be stopped
+	// without aborting the query.
+	ctx := v.es.ctx
+	if len(node.Predecessors()) == 0 {
+		ctx = v.es.srcCtx
+	}
+
 	// Build execution context for each copy.
 	ec := make([]executionContext, copies)
 	for i := 0; i < copies; i++ {
 		ec[i] = executionContext{
 			es:            v.es,
+			ctx:           ctx,
+			alloc:         alloc,
 			parents:       make([]DatasetID, len(node.Predecessors())*predCopies),
 			streamContext: streamContext,
 			parallelOpts:  ParallelOpts{Group: i, Factor: copies},
@@ -212,7 +261,7 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 
 		for pi, pred := range nonYieldPredecessors(node) {
 			for j := 0; j < predCopies; j++ {
-				ec[i].parents[pi*predCopies+j] = datasetIDFromNodeID(pred.ID(), i+j)
+				ec[i].parents[pi*predCopies+j] = datasetIDFromNodeID(pred.ID(), v.predecessorCopy(pred, i+j))
 			}
 		}
 	}
@@ -237,6 +286,9 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 
 			source.SetLabel(string(node.ID()))
 			v.es.sources = append(v.es.sources, source)
+			if counting {
+				v.es.sourceStats = append(v.es.sourceStats, stats)
+			}
 			v.nodes[node][i] = source
 		}
 	} else {
@@ -280,12 +332,20 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 				// We link forward from all copies for the node to achieve the
 				// fan-in.
 				//   i == 0 AND ( iterating j )
+				//
+				// In case (4) above, copies is > 1 and the predecessor only
+				// has a single copy. We link forward from that copy to every
+				// copy of the node to achieve the fan-out.
+				//   ( iterating i ) AND j == 0
 				for j := 0; j < predCopies; j++ {
 					// Either i == 0 && j == 0: we are either iterating i, or we are iterating j.
-					executionNode := v.nodes[p][i+j]
-					transport := newConsecutiveTransport(v.es.ctx, v.es.dispatcher, tr, node, v.es.logger, v.es.alloc)
+					executionNode := v.nodes[p][v.predecessorCopy(p, i+j)]
+					transport := newConsecutiveTransport(v.es.ctx, v.es.dispatcher, tr, node, v.es.p.Dispatcher.Priorities[node.ID()], v.es.logger, alloc)
+					if counting {
+						transport.stats = v.edgeStatistics(stats, p, executionNode)
+					}
 					v.es.transports = append(v.es.transports, transport)
-					executionNode.AddTransformation(transport)
+					v.addTransformation(p, executionNode, transport)
 				}
 			}
 		}
@@ -316,6 +376,28 @@ func (v *createExecutionNodeVisitor) Visit(node plan.Node) error {
 	return nil
 }
 
+// predecessorCopy returns the copy of the predecessor node that
+// feeds the given instance. A predecessor that is not run in parallel
+// feeds every instance from its only copy.
+func (v *createExecutionNodeVisitor) predecessorCopy(pred plan.Node, instance int) int {
+	if len(v.nodes[pred]) == 1 {
+		return 0
+	}
+	return instance
+}
+
+// edgeStatistics creates the statistics for the edge from the execution
+// node of pred to a node with the given statistics. The first edge that
+// leaves an execution node also counts the output of pred.
+func (v *createExecutionNodeVisitor) edgeStatistics(in *nodeStatistics, pred plan.Node, n Node) *edgeStatistics {
+	s := &edgeStatistics{in: in}
+	if !v.outCounted[n] {
+		v.outCounted[n] = true
+		s.out = v.stats[pred]
+	}
+	return s
+}
+
 // generateResult will attach a result to the query for the specified node.
 func (v *createExecutionNodeVisitor) generateResult(resultName string, node plan.Node, idx int) error {
 	// if the result name is already present in the result set, that's an error.
@@ -327,10 +409,29 @@ func (v *createExecutionNodeVisitor) generateResult(resultName string, node plan
 	}
 	r := newResult(resultName)
 	v.es.results[resultName] = r
-	v.nodes[skipYields(node)][idx].AddTransformation(r)
+	pred := skipYields(node)
+	n := v.nodes[pred][idx]
+	if v.outCounted != nil {
+		r.stats = v.edgeStatistics(nil, pred, n)
+	}
+	if v.es.truncated != nil {
+		r.sources = sourceIDs(pred)
+	}
+	v.addTransformation(pred, n, r)
 	return nil
 }
 
+// addTransformation adds t to the execution node n of pred.
+// When the query returns partial results, transformations that
+// read from a source are wrapped so that stopping the source
+// does not abort the query.
+func (v *createExecutionNodeVisitor) addTransformation(pred plan.Node, n Node, t Transformation) {
+	if v.es.truncated != nil && len(pred.Predecessors()) == 0 {
+		t = newPartialTransformation(t, pred.ID(), v.es)
+	}
+	n.AddTransformation(t)
+}
+
 // getResultName will offer a "best guess" name for a given node's result.
 //
 // For nodes that have side-effects, the result will be based on the node ID.
@@ -483,10 +584,24 @@ func (es *executionState) chooseDefaultResources(ctx context.Context, p *plan.Sp
 		es.resources.MemoryBytesQuota = math.MaxInt64
 	}
 
-	// Update concurrency quota
+	// Update concurrency quota. The planner may ask for more workers
+	// than are required, but never fewer.
 	if es.resources.ConcurrencyQuota == 0 {
-		es.resources.ConcurrencyQuota = computeQueryConcurrencyQuota(ctx, p)
+		quota := computeQueryConcurrencyQuota(ctx, p)
+		if p.Dispatcher.Workers > quota {
+			quota = p.Dispatcher.Workers
+		}
+		es.resources.ConcurrencyQuota = quota
+	}
+}
+
+// dispatcherThroughput returns the throughput chosen by the planner
+// or the default throughput if the planner did not choose one.
+func dispatcherThroughput(p *plan.Spec) int {
+	if p.Dispatcher.Throughput > 0 {
+		return p.Dispatcher.Throughput
 	}
+	return plan.DefaultDispatcherThroughput
 }
 
 func (es *executionState) abort(err error) {
@@ -510,10 +625,14 @@ func (es *executionState) do() {
 	}
 
 	stats.Metadata = make(metadata.Metadata)
-	for _, src := range es.sources {
+	for i, src := range es.sources {
+		var srcStats *nodeStatistics
+		if len(es.sourceStats) > 0 {
+			srcStats = es.sourceStats[i]
+		}
 		wg.Add(1)
-		go func(src Source) {
-			ctx := es.ctx
+		go func(src Source, srcStats *nodeStatistics) {
+			ctx := es.srcCtx
 			opName := reflect.TypeOf(src).String()
 
 			// If operator profiling is enabled for this execution, begin profiling
@@ -534,6 +653,9 @@ func (es *executionState) do() {
 			defer es.recover()
 			src.Run(ctx)
 			profileSpan.Finish()
+			if srcStats != nil {
+				srcStats.addWallTime(time.Duration(profile.Sum))
+			}
 
 			updateStats(func(stats *flux.Statistics) {
 				stats.Profiles = append(stats.Profiles, profile)
@@ -541,7 +663,7 @@ func (es *executionState) do() {
 					stats.Metadata.AddAll(mdn.Metadata())
 				}
 			})
-		}(src)
+		}(src, srcStats)
 	}
 
 	wg.Add(1)
@@ -582,6 +704,23 @@ func (es *executionState) do() {
 		// by the sources.
 		stats.Profiles = append(stats.Profiles, profiles...)
 
+		for _, s := range es.nodeStats {
+			stats.Nodes = append(stats.Nodes, s.snapshot())
+		}
+
+		if es.truncated != nil {
+			names := make([]string, 0, len(es.results))
+			for name := range es.results {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if es.isPartial(es.results[name].(*result)) {
+					stats.Metadata.Add(PartialResultsMetadataKey, name)
+				}
+			}
+		}
+
 		es.statsCh <- stats
 	}()
 }
@@ -594,6 +733,8 @@ type ParallelOpts struct {
 // Need a unique stream context per execution context
 type executionContext struct {
 	es            *executionState
+	ctx           context.Context
+	alloc         memory.Allocator
 	parents       []DatasetID
 	streamContext streamContext
 	parallelOpts  ParallelOpts
@@ -604,7 +745,7 @@ func resolveTime(qt flux.Time, now time.Time) Time {
 }
 
 func (ec executionContext) Context() context.Context {
-	return ec.es.ctx
+	return ec.ctx
 }
 
 func (ec executionContext) ResolveTime(qt flux.Time) Time {
@@ -616,7 +757,7 @@ func (ec executionContext) StreamContext() StreamContext {
 }
 
 func (ec executionContext) Allocator() memory.Allocator {
-	return ec.es.alloc
+	return ec.alloc
 }
 
 func (ec executionContext) Parents() []DatasetID {
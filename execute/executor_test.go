@@ -802,3 +802,220 @@ func TestExecutor_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestExecutor_NodeStatistics(t *testing.T) {
+	input := make([]*executetest.Table, 0, 3)
+	for _, t0 := range []string{"a", "b", "c"} {
+		tbl := &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "t0", Type: flux.TString},
+			},
+		}
+		for i := 0; i < 10; i++ {
+			tbl.Data = append(tbl.Data, []interface{}{execute.Time(i), float64(i), t0})
+		}
+		input = append(input, tbl)
+	}
+
+	testcases := []struct {
+		name string
+		spec *plantest.PlanSpec
+		want []flux.NodeStatistics
+	}{
+		{
+			name: "partition",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalNode("from", executetest.NewFromProcedureSpec(input)),
+					plantest.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 3}),
+					plantest.CreatePhysicalNode("merge", &universe.PartitionMergeProcedureSpec{Factor: 3}),
+					plantest.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+				},
+			},
+			want: []flux.NodeStatistics{
+				{
+					NodeID:         "from",
+					NodeType:       executetest.FromTestKind,
+					RowsOut:        30,
+					TablesOut:      3,
+					ParallelFactor: 1,
+				},
+				{
+					// Every copy of the partition reads all of the input.
+					NodeID:         "partition",
+					NodeType:       universe.PartitionKind,
+					RowsIn:         90,
+					RowsOut:        30,
+					TablesIn:       9,
+					TablesOut:      3,
+					ParallelFactor: 3,
+				},
+				{
+					NodeID:         "merge",
+					NodeType:       universe.ParallelMergeKind,
+					RowsIn:         30,
+					RowsOut:        30,
+					TablesIn:       3,
+					TablesOut:      3,
+					ParallelFactor: 1,
+				},
+			},
+		},
+		{
+			name: "allocated",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("allocating-from-test", &executetest.AllocatingFromProcedureSpec{ByteCount: 65}),
+					plan.CreatePhysicalNode("yield", &universe.YieldProcedureSpec{Name: "_result"}),
+				},
+				Edges: [][2]int{
+					{0, 1},
+				},
+			},
+			want: []flux.NodeStatistics{
+				{
+					NodeID:         "allocating-from-test",
+					NodeType:       executetest.AllocatingFromTestKind,
+					BytesAllocated: 65,
+					MaxAllocated:   65,
+					ParallelFactor: 1,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.Resources = flux.ResourceManagement{
+				ConcurrencyQuota: 3,
+				MemoryBytesQuota: math.MaxInt64,
+			}
+			tc.spec.Now = time.Now()
+			ps := plantest.CreatePlanSpec(tc.spec)
+			if err := ps.TopDownWalk(plan.SetTriggerSpec); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, deps := dependency.Inject(context.Background(), executetest.NewTestExecuteDependencies())
+			defer deps.Finish()
+			ctx = execute.WithNodeStatistics(ctx)
+
+			exe := execute.NewExecutor(zaptest.NewLogger(t))
+			results, statsCh, err := exe.Execute(ctx, ps, executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				if err := r.Tables().Do(func(tbl flux.Table) error {
+					tbl.Done()
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stats := <-statsCh
+			for i := range stats.Nodes {
+				if stats.Nodes[i].WallTime <= 0 && stats.Nodes[i].RowsIn > 0 {
+					t.Errorf("expected wall time for node %s", stats.Nodes[i].NodeID)
+				}
+				stats.Nodes[i].WallTime = 0
+			}
+			if !cmp.Equal(tc.want, stats.Nodes) {
+				t.Errorf("unexpected node statistics -want/+got:\n%s", cmp.Diff(tc.want, stats.Nodes))
+			}
+		})
+	}
+}
+
+func TestExecutor_NodeMemoryLimit(t *testing.T) {
+	testcases := []struct {
+		name    string
+		limit   int64
+		want    []flux.NodeStatistics
+		wantErr string
+	}{
+		{
+			name:  "within limit",
+			limit: 128,
+			want: []flux.NodeStatistics{{
+				NodeID:         "allocating-from-test",
+				NodeType:       executetest.AllocatingFromTestKind,
+				BytesAllocated: 65,
+				MaxAllocated:   65,
+				ParallelFactor: 1,
+			}},
+		},
+		{
+			name:    "limit exceeded",
+			limit:   64,
+			wantErr: `node "allocating-from-test" exceeded its memory limit: memory allocation limit reached: limit 64 bytes, allocated: 0, wanted: 65`,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("allocating-from-test", &executetest.AllocatingFromProcedureSpec{ByteCount: 65}),
+					plan.CreatePhysicalNode("yield", &universe.YieldProcedureSpec{Name: "_result"}),
+				},
+				Edges: [][2]int{
+					{0, 1},
+				},
+				Resources: flux.ResourceManagement{
+					ConcurrencyQuota: 1,
+					MemoryBytesQuota: math.MaxInt64,
+				},
+				Now: time.Now(),
+			})
+
+			ctx, deps := dependency.Inject(context.Background(), executetest.NewTestExecuteDependencies())
+			defer deps.Finish()
+			execDeps := execute.DefaultExecutionDependencies()
+			execDeps.NodeMemoryLimit = tc.limit
+			ctx = execDeps.Inject(ctx)
+
+			exe := execute.NewExecutor(zaptest.NewLogger(t))
+			results, statsCh, err := exe.Execute(ctx, ps, executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = results["_result"].Tables().Do(func(tbl flux.Table) error {
+				tbl.Done()
+				return nil
+			})
+			stats := <-statsCh
+
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if got := err.Error(); got != tc.wantErr {
+					t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", tc.wantErr, got)
+				}
+				if code := errors.Code(err); code != codes.ResourceExhausted {
+					t.Errorf("unexpected error code: %v", code)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			// Memory is reported for every node even
+			// when the other statistics are not collected.
+			if !cmp.Equal(tc.want, stats.Nodes) {
+				t.Errorf("unexpected node statistics -want/+got:\n%s", cmp.Diff(tc.want, stats.Nodes))
+			}
+		})
+	}
+}
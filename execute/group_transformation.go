@@ -49,6 +49,10 @@ func (g *groupTransformation) ProcessMessage(m Message) error {
 		return g.t.Process(m.TableChunk(), g.d, g.d.mem)
 	case FlushKeyMsg:
 		return nil
+	case UpdateWatermarkMsg:
+		return g.d.UpdateWatermark(m.WatermarkTime())
+	case UpdateProcessingTimeMsg:
+		return g.d.UpdateProcessingTime(m.ProcessingTime())
 	case ProcessMsg:
 		panic("unreachable")
 	}
@@ -66,6 +66,10 @@ func (n *narrowStateTransformation[T]) ProcessMessage(m Message) error {
 			}
 		}
 		return nil
+	case UpdateWatermarkMsg:
+		return n.d.UpdateWatermark(m.WatermarkTime())
+	case UpdateProcessingTimeMsg:
+		return n.d.UpdateProcessingTime(m.ProcessingTime())
 	case ProcessMsg:
 		panic("unreachable")
 	}
@@ -0,0 +1,190 @@
+package execute
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+)
+
+// WithNodeStatistics returns a context that enables the collection
+// of statistics for each node in the plan. The statistics are reported
+// through the Nodes field of the flux.Statistics for the query.
+//
+// Collecting node statistics adds overhead to the query and results
+// are buffered before they are sent to the client so the number of rows
+// can be counted. It is meant to be used when explaining a query.
+func WithNodeStatistics(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nodeStatisticsKey, true)
+}
+
+// NodeStatisticsEnabled reports whether node statistics
+// should be collected for the query.
+func NodeStatisticsEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(nodeStatisticsKey).(bool)
+	return enabled
+}
+
+// nodeStatistics collects the statistics for a single plan node.
+// The statistics are shared by every copy of the node and
+// every transport that connects to it so they are updated atomically.
+//
+// The memory used by a node is always accounted for. The other
+// statistics are only collected when node statistics are enabled.
+type nodeStatistics struct {
+	// Variables accessed with atomic operations should be at
+	// the beginning of the struct to ensure byte alignment is correct.
+	rowsIn    int64
+	rowsOut   int64
+	tablesIn  int64
+	tablesOut int64
+	wallTime  int64
+
+	id       plan.NodeID
+	nodeType string
+	factor   int
+
+	// mem accounts for the memory allocated by the node.
+	// It does not allocate any memory itself.
+	mem *memory.ResourceAllocator
+}
+
+func newNodeStatistics(node plan.Node, factor int, limit int64) *nodeStatistics {
+	s := &nodeStatistics{
+		id:       node.ID(),
+		nodeType: string(node.Kind()),
+		factor:   factor,
+		mem:      &memory.ResourceAllocator{},
+	}
+	if limit > 0 {
+		s.mem.Limit = &limit
+	}
+	return s
+}
+
+func (s *nodeStatistics) addWallTime(d time.Duration) {
+	atomic.AddInt64(&s.wallTime, int64(d))
+}
+
+// snapshot returns the statistics collected for the node.
+func (s *nodeStatistics) snapshot() flux.NodeStatistics {
+	return flux.NodeStatistics{
+		NodeID:         string(s.id),
+		NodeType:       s.nodeType,
+		RowsIn:         atomic.LoadInt64(&s.rowsIn),
+		RowsOut:        atomic.LoadInt64(&s.rowsOut),
+		TablesIn:       atomic.LoadInt64(&s.tablesIn),
+		TablesOut:      atomic.LoadInt64(&s.tablesOut),
+		BytesAllocated: s.mem.TotalAllocated(),
+		MaxAllocated:   s.mem.MaxAllocated(),
+		WallTime:       time.Duration(atomic.LoadInt64(&s.wallTime)),
+		ParallelFactor: s.factor,
+	}
+}
+
+// edgeStatistics counts the data sent across an edge in the execution graph.
+// The data is counted as input for the downstream node when in is set and
+// as output for the upstream node when out is set. Only one edge leaving a
+// node counts its output so data sent to multiple successors is not
+// counted twice.
+type edgeStatistics struct {
+	in, out *nodeStatistics
+	keys    *GroupLookup
+}
+
+func (s *edgeStatistics) addTable() {
+	if s.in != nil {
+		atomic.AddInt64(&s.in.tablesIn, 1)
+	}
+	if s.out != nil {
+		atomic.AddInt64(&s.out.tablesOut, 1)
+	}
+}
+
+func (s *edgeStatistics) addRows(n int) {
+	if s.in != nil {
+		atomic.AddInt64(&s.in.rowsIn, int64(n))
+	}
+	if s.out != nil {
+		atomic.AddInt64(&s.out.rowsOut, int64(n))
+	}
+}
+
+// addChunk counts the rows in the chunk and counts a table
+// the first time a chunk for its group key is seen.
+func (s *edgeStatistics) addChunk(chunk table.Chunk) {
+	if s.keys == nil {
+		s.keys = NewGroupLookup()
+	}
+	if _, ok := s.keys.Lookup(chunk.Key()); !ok {
+		s.keys.Set(chunk.Key(), true)
+		s.addTable()
+	}
+	s.addRows(chunk.Len())
+}
+
+// nodeAllocator attributes the memory allocated through the query
+// allocator to the node that allocated it. If the node has a memory
+// limit, allocations that would exceed it fail with an error that
+// names the node.
+type nodeAllocator struct {
+	memory.Allocator
+	stats *nodeStatistics
+}
+
+func (a *nodeAllocator) Allocate(size int) []byte {
+	if size > 0 {
+		if err := a.account(size); err != nil {
+			panic(err)
+		}
+	}
+	return a.Allocator.Allocate(size)
+}
+
+func (a *nodeAllocator) Reallocate(size int, b []byte) []byte {
+	if err := a.account(size - cap(b)); err != nil {
+		panic(err)
+	}
+	return a.Allocator.Reallocate(size, b)
+}
+
+func (a *nodeAllocator) Free(b []byte) {
+	a.Allocator.Free(b)
+	_ = a.stats.mem.Account(-len(b))
+}
+
+func (a *nodeAllocator) Account(size int) error {
+	if err := a.account(size); err != nil {
+		return err
+	}
+	if err := a.Allocator.Account(size); err != nil {
+		_ = a.stats.mem.Account(-size)
+		return err
+	}
+	return nil
+}
+
+// CanAllocate reports whether size more bytes can be allocated
+// without exceeding the limit of the node or of the query.
+func (a *nodeAllocator) CanAllocate(size int) bool {
+	if !a.stats.mem.CanAllocate(size) {
+		return false
+	}
+	if ca, ok := a.Allocator.(interface{ CanAllocate(size int) bool }); ok {
+		return ca.CanAllocate(size)
+	}
+	return true
+}
+
+func (a *nodeAllocator) account(size int) error {
+	if err := a.stats.mem.Account(size); err != nil {
+		return errors.Wrapf(err, codes.ResourceExhausted, "node %q exceeded its memory limit", a.stats.id)
+	}
+	return nil
+}
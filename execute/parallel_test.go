@@ -562,3 +562,95 @@ func TestParallel_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestParallel_Partition(t *testing.T) {
+	cols := []flux.ColMeta{
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TFloat},
+		{Label: "t0", Type: flux.TString},
+	}
+	input := func() []*executetest.Table {
+		tables := make([]*executetest.Table, 0, 3)
+		for _, t0 := range []string{"a", "b", "c"} {
+			tbl := &executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: cols,
+			}
+			for i := 0; i < 10; i++ {
+				tbl.Data = append(tbl.Data, []interface{}{execute.Time(i), float64(i), t0})
+			}
+			tables = append(tables, tbl)
+		}
+		return tables
+	}
+
+	for _, mode := range []plan.PartitionMode{plan.PartitionByGroupKey, plan.PartitionByTime} {
+		mode := mode
+		t.Run(mode.String(), func(t *testing.T) {
+			partition := plantest.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{
+				Factor: 3,
+				Mode:   mode,
+			})
+			partition.SetBounds(&plan.Bounds{Start: 0, Stop: 10})
+
+			ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalNode("from", executetest.NewFromProcedureSpec(input())),
+					partition,
+					plantest.CreatePhysicalNode("merge", &universe.PartitionMergeProcedureSpec{
+						Factor: 3,
+						Mode:   mode,
+					}),
+					plantest.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+				},
+				Resources: flux.ResourceManagement{
+					ConcurrencyQuota: 3,
+					MemoryBytesQuota: math.MaxInt64,
+				},
+				Now: time.Now(),
+			})
+			if err := ps.TopDownWalk(plan.SetTriggerSpec); err != nil {
+				t.Fatal(err)
+			}
+			if err := plan.ValidatePhysicalPlan(ps); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, deps := dependency.Inject(context.Background(), executetest.NewTestExecuteDependencies())
+			defer deps.Finish()
+
+			exe := execute.NewExecutor(zaptest.NewLogger(t))
+			results, _, err := exe.Execute(ctx, ps, executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []*executetest.Table
+			for _, r := range results {
+				if err := r.Tables().Do(func(tbl flux.Table) error {
+					cb, err := executetest.ConvertTable(tbl)
+					if err != nil {
+						return err
+					}
+					got = append(got, cb)
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Every table is produced once with its rows in order.
+			want := input()
+			executetest.NormalizeTables(got)
+			executetest.NormalizeTables(want)
+			if !cmp.Equal(want, got) {
+				t.Error("unexpected results -want/+got", cmp.Diff(want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package execute
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/flux/plan"
+)
+
+// PartialResultsMetadataKey is the metadata key in the query statistics
+// that holds the names of the results that may be missing data because
+// the sources were stopped before they read all of their data.
+const PartialResultsMetadataKey = "flux/partial-results"
+
+// WithPartialResults returns a context that makes the executor
+// stop the sources of a query gracefully when ctx is cancelled or its
+// deadline is reached.
+//
+// The executor does not abort the query when this happens. The sources
+// are cancelled, the transformations process and flush the data that was
+// read before the sources were stopped, and every result that may be
+// missing data is listed in the query statistics metadata under
+// PartialResultsMetadataKey.
+//
+// The returned context is not cancelled when ctx is cancelled.
+// Queries that use it are only aborted when they fail.
+func WithPartialResults(ctx context.Context) context.Context {
+	return context.WithValue(withoutCancel{ctx}, partialResultsKey, ctx)
+}
+
+// PartialResultsEnabled reports whether the sources of the query
+// should be stopped gracefully when the query is cancelled.
+func PartialResultsEnabled(ctx context.Context) bool {
+	_, ok := partialResultsContext(ctx)
+	return ok
+}
+
+func partialResultsContext(ctx context.Context) (context.Context, bool) {
+	pctx, ok := ctx.Value(partialResultsKey).(context.Context)
+	return pctx, ok
+}
+
+// withoutCancel is a context that keeps the values of its
+// parent but is never cancelled and has no deadline.
+type withoutCancel struct {
+	parent context.Context
+}
+
+func (withoutCancel) Deadline() (deadline time.Time, ok bool) { return }
+func (withoutCancel) Done() <-chan struct{}                   { return nil }
+func (withoutCancel) Err() error                              { return nil }
+func (c withoutCancel) Value(key interface{}) interface{}     { return c.parent.Value(key) }
+
+// newSourceContext creates the context for the sources of a query
+// that returns partial results. The context has the values of ctx and
+// is cancelled when ctx is cancelled, when the original context passed
+// to WithPartialResults is cancelled, or when its deadline is reached.
+func newSourceContext(ctx, pctx context.Context) context.Context {
+	var (
+		srcCtx context.Context
+		cancel context.CancelFunc
+	)
+	if deadline, ok := pctx.Deadline(); ok {
+		srcCtx, cancel = context.WithDeadline(ctx, deadline)
+	} else {
+		srcCtx, cancel = context.WithCancel(ctx)
+	}
+	go func() {
+		select {
+		case <-pctx.Done():
+			cancel()
+		case <-srcCtx.Done():
+		}
+	}()
+	return srcCtx
+}
+
+// partialTransformation is attached to a source when the query returns
+// partial results. It hides the error sent by a source that was stopped
+// because the query was cancelled so the downstream transformations flush
+// the data they have instead of aborting.
+type partialTransformation struct {
+	Transformation
+	transport Transport
+
+	source plan.NodeID
+	es     *executionState
+}
+
+func newPartialTransformation(t Transformation, source plan.NodeID, es *executionState) *partialTransformation {
+	return &partialTransformation{
+		Transformation: t,
+		transport:      WrapTransformationInTransport(t, es.alloc),
+		source:         source,
+		es:             es,
+	}
+}
+
+func (t *partialTransformation) ProcessMessage(m Message) error {
+	if m.Type() == FinishType {
+		if err := m.(FinishMsg).Error(); err != nil && t.finishErr(err) == nil {
+			m = &finishMsg{
+				srcMessage: srcMessage(m.SrcDatasetID()),
+			}
+		}
+	}
+	return t.transport.ProcessMessage(m)
+}
+
+func (t *partialTransformation) Finish(id DatasetID, err error) {
+	t.Transformation.Finish(id, t.finishErr(err))
+}
+
+// finishErr returns the error that the source should finish with.
+// A source that finishes after it has been stopped is marked as truncated
+// and any error it reports is dropped unless the query itself was aborted.
+func (t *partialTransformation) finishErr(err error) error {
+	if t.es.srcCtx.Err() == nil || t.es.ctx.Err() != nil {
+		return err
+	}
+	t.es.markTruncated(t.source)
+	return nil
+}
+
+// markTruncated records that the source stopped before reading all of its data.
+func (es *executionState) markTruncated(source plan.NodeID) {
+	es.truncatedMu.Lock()
+	defer es.truncatedMu.Unlock()
+	es.truncated[source] = true
+}
+
+// isPartial reports whether any of the sources of the result was truncated.
+func (es *executionState) isPartial(r *result) bool {
+	es.truncatedMu.Lock()
+	defer es.truncatedMu.Unlock()
+	for _, id := range r.sources {
+		if es.truncated[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceIDs returns the IDs of the sources that feed data into node.
+func sourceIDs(node plan.Node) []plan.NodeID {
+	var ids []plan.NodeID
+	visited := make(map[plan.Node]bool)
+	var walk func(n plan.Node)
+	walk = func(n plan.Node) {
+		if visited[n] {
+			return
+		}
+		visited[n] = true
+		if len(n.Predecessors()) == 0 {
+			ids = append(ids, n.ID())
+			return
+		}
+		for _, pred := range n.Predecessors() {
+			walk(pred)
+		}
+	}
+	walk(node)
+	return ids
+}
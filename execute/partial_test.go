@@ -0,0 +1,205 @@
+package execute_test
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/dependency"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+	"github.com/influxdata/flux/stdlib/universe"
+	"go.uber.org/zap/zaptest"
+)
+
+const blockingFromKind = "blocking-from-test"
+
+func init() {
+	execute.RegisterSource(blockingFromKind, createBlockingFromSource)
+}
+
+// blockingFromProcedureSpec is a procedure spec AND an execution node.
+// It sends its data and then, if block is set, waits until it is cancelled
+// before finishing with the error from the context.
+type blockingFromProcedureSpec struct {
+	execute.ExecutionNode
+	data  []*executetest.Table
+	block bool
+
+	id execute.DatasetID
+	ts []execute.Transformation
+}
+
+func (s *blockingFromProcedureSpec) Kind() plan.ProcedureKind {
+	return blockingFromKind
+}
+
+func (s *blockingFromProcedureSpec) Copy() plan.ProcedureSpec {
+	return s
+}
+
+func (s *blockingFromProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	return plan.Cost{}, plan.Statistics{}
+}
+
+func (s *blockingFromProcedureSpec) AddTransformation(t execute.Transformation) {
+	s.ts = append(s.ts, t)
+}
+
+func (s *blockingFromProcedureSpec) Run(ctx context.Context) {
+	for _, t := range s.ts {
+		for _, tbl := range s.data {
+			if err := t.Process(s.id, tbl); err != nil {
+				t.Finish(s.id, err)
+				return
+			}
+		}
+	}
+
+	var err error
+	if s.block {
+		<-ctx.Done()
+		err = ctx.Err()
+	}
+	for _, t := range s.ts {
+		t.Finish(s.id, err)
+	}
+}
+
+func createBlockingFromSource(spec plan.ProcedureSpec, id execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	s := spec.(*blockingFromProcedureSpec)
+	s.id = id
+	return s, nil
+}
+
+func TestExecutor_PartialResults(t *testing.T) {
+	input := func() []*executetest.Table {
+		return []*executetest.Table{{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{"a", 1.0},
+				{"a", 2.0},
+				{"a", 3.0},
+			},
+		}}
+	}
+	want := []*executetest.Table{{
+		KeyCols: []string{"t0"},
+		ColMeta: []flux.ColMeta{
+			{Label: "t0", Type: flux.TString},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{"a", 6.0},
+		},
+	}}
+
+	testcases := []struct {
+		name         string
+		partial      bool
+		block        bool
+		want         []*executetest.Table
+		wantErr      string
+		wantMetadata []interface{}
+	}{
+		{
+			name:         "partial",
+			partial:      true,
+			block:        true,
+			want:         want,
+			wantMetadata: []interface{}{"_result"},
+		},
+		{
+			name:    "complete",
+			partial: true,
+			want:    want,
+		},
+		{
+			name:    "disabled",
+			block:   true,
+			wantErr: context.DeadlineExceeded.Error(),
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", &blockingFromProcedureSpec{
+						data:  input(),
+						block: tc.block,
+					}),
+					plan.CreatePhysicalNode("sum", &universe.SumProcedureSpec{
+						SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+					}),
+					plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+				Resources: flux.ResourceManagement{
+					ConcurrencyQuota: 1,
+					MemoryBytesQuota: math.MaxInt64,
+				},
+				Now: time.Now(),
+			})
+			if err := ps.TopDownWalk(plan.SetTriggerSpec); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, deps := dependency.Inject(context.Background(), executetest.NewTestExecuteDependencies())
+			defer deps.Finish()
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			if tc.partial {
+				ctx = execute.WithPartialResults(ctx)
+			}
+
+			exe := execute.NewExecutor(zaptest.NewLogger(t))
+			results, statsCh, err := exe.Execute(ctx, ps, executetest.UnlimitedAllocator)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []*executetest.Table
+			err = results["_result"].Tables().Do(func(tbl flux.Table) error {
+				cb, err := executetest.ConvertTable(tbl)
+				if err != nil {
+					return err
+				}
+				got = append(got, cb)
+				return nil
+			})
+			stats := <-statsCh
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("unexpected error -want/+got:\n\t- %s\n\t+ %v", tc.wantErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			executetest.NormalizeTables(got)
+			executetest.NormalizeTables(tc.want)
+			if !cmp.Equal(tc.want, got) {
+				t.Errorf("unexpected results -want/+got:\n%s", cmp.Diff(tc.want, got))
+			}
+			if got := stats.Metadata[execute.PartialResultsMetadataKey]; !cmp.Equal(tc.wantMetadata, got) {
+				t.Errorf("unexpected partial results metadata -want/+got:\n%s", cmp.Diff(tc.wantMetadata, got))
+			}
+		})
+	}
+}
@@ -119,6 +119,14 @@ func (o *OperatorProfiler) getTableBuilder(stats flux.Statistics, alloc memory.A
 			Label: "MeanDuration",
 			Type:  flux.TFloat,
 		},
+		{
+			Label: "MaxAllocated",
+			Type:  flux.TInt,
+		},
+		{
+			Label: "TotalAllocated",
+			Type:  flux.TInt,
+		},
 	}
 	for _, col := range colMeta {
 		if _, err := b.AddCol(col); err != nil {
@@ -126,6 +134,13 @@ func (o *OperatorProfiler) getTableBuilder(stats flux.Statistics, alloc memory.A
 		}
 	}
 
+	// The memory is accounted for each node in the plan
+	// and the profiles are labeled with the node ID.
+	nodes := make(map[string]flux.NodeStatistics, len(stats.Nodes))
+	for _, node := range stats.Nodes {
+		nodes[node.NodeID] = node
+	}
+
 	for _, profile := range stats.Profiles {
 		b.AppendString(0, "profiler/operator")
 		b.AppendString(1, profile.NodeType)
@@ -135,6 +150,8 @@ func (o *OperatorProfiler) getTableBuilder(stats flux.Statistics, alloc memory.A
 		b.AppendInt(5, profile.Max)
 		b.AppendInt(6, profile.Sum)
 		b.AppendFloat(7, profile.Mean)
+		b.AppendInt(8, nodes[profile.Label].MaxAllocated)
+		b.AppendInt(9, nodes[profile.Label].BytesAllocated)
 	}
 	return b, nil
 }
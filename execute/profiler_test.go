@@ -42,27 +42,32 @@ func TestOperatorProfiler_GetResult(t *testing.T) {
 	// Build the "want" table.
 	var wantStr bytes.Buffer
 	wantStr.WriteString(`
-#datatype,string,long,string,string,string,long,long,long,long,double
-#group,false,false,true,false,false,false,false,false,false,false
-#default,_profiler,,,,,,,,,
-,result,table,_measurement,Type,Label,Count,MinDuration,MaxDuration,DurationSum,MeanDuration
+#datatype,string,long,string,string,string,long,long,long,long,double,long,long
+#group,false,false,true,false,false,false,false,false,false,false,false,false
+#default,_profiler,,,,,,,,,,,
+,result,table,_measurement,Type,Label,Count,MinDuration,MaxDuration,DurationSum,MeanDuration,MaxAllocated,TotalAllocated
 `)
-	fmt.Fprintf(&wantStr, ",,0,profiler/operator,%s,%s,%d,%d,%d,%d,%f\n",
-		"type0", "lab0", 4, 1000, 1606, 5212, 1303.0,
+	fmt.Fprintf(&wantStr, ",,0,profiler/operator,%s,%s,%d,%d,%d,%d,%f,%d,%d\n",
+		"type0", "lab0", 4, 1000, 1606, 5212, 1303.0, 512, 2048,
 	)
-	fmt.Fprintf(&wantStr, ",,0,profiler/operator,%s,%s,%d,%d,%d,%d,%f\n",
-		"type1", "lab0", 4, 1101, 1707, 5616, 1404.0,
+	fmt.Fprintf(&wantStr, ",,0,profiler/operator,%s,%s,%d,%d,%d,%d,%f,%d,%d\n",
+		"type1", "lab0", 4, 1101, 1707, 5616, 1404.0, 512, 2048,
 	)
-	fmt.Fprintf(&wantStr, ",,0,profiler/operator,%s,%s,%d,%d,%d,%d,%f\n",
-		"type0", "lab1", 4, 1808, 2414, 8444, 2111.0,
+	fmt.Fprintf(&wantStr, ",,0,profiler/operator,%s,%s,%d,%d,%d,%d,%f,%d,%d\n",
+		"type0", "lab1", 4, 1808, 2414, 8444, 2111.0, 0, 0,
 	)
-	fmt.Fprintf(&wantStr, ",,0,profiler/operator,%s,%s,%d,%d,%d,%d,%f\n",
-		"type1", "lab1", 4, 1909, 2515, 8848, 2212.0,
+	fmt.Fprintf(&wantStr, ",,0,profiler/operator,%s,%s,%d,%d,%d,%d,%f,%d,%d\n",
+		"type1", "lab1", 4, 1909, 2515, 8848, 2212.0, 0, 0,
 	)
 	count := 16
 
 	stats := flux.Statistics{
 		Profiles: make([]flux.TransportProfile, 0, 4),
+		Nodes: []flux.NodeStatistics{{
+			NodeID:         "lab0",
+			BytesAllocated: 2048,
+			MaxAllocated:   512,
+		}},
 	}
 	for i := 0; i < 2; i++ {
 		for j := 0; j < 2; j++ {
@@ -4,6 +4,8 @@ import (
 	"sync"
 
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/plan"
 )
 
 // result implements both the Transformation and Result interfaces,
@@ -17,6 +19,14 @@ type result struct {
 
 	abortErr chan error
 	aborted  chan struct{}
+
+	// stats counts the data sent to the result.
+	// It is only set when node statistics are enabled.
+	stats *edgeStatistics
+
+	// sources holds the IDs of the sources that feed the result.
+	// It is only set when the query returns partial results.
+	sources []plan.NodeID
 }
 
 type resultMessage struct {
@@ -43,6 +53,20 @@ func (s *result) RetractTable(DatasetID, flux.GroupKey) error {
 }
 
 func (s *result) Process(id DatasetID, tbl flux.Table) error {
+	if s.stats != nil {
+		// Buffer the table so the rows are counted before
+		// the statistics for the query are reported.
+		buffered, err := table.Copy(tbl)
+		if err != nil {
+			return err
+		}
+		s.stats.addTable()
+		for i, n := 0, buffered.BufferN(); i < n; i++ {
+			s.stats.addRows(buffered.Buffer(i).Len())
+		}
+		tbl = buffered
+	}
+
 	select {
 	case s.tables <- resultMessage{
 		table: tbl,
@@ -0,0 +1,127 @@
+package execute
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/values"
+)
+
+// WithStreaming returns a context that runs a query in streaming mode.
+//
+// In streaming mode, sources that support it read data continuously
+// instead of reading a bounded set of data. They advance the watermark
+// as they read data so windows and aggregates emit their tables
+// when the watermark passes the stop time of the table instead of
+// when the source finishes. The results of the query only end
+// when the query is cancelled.
+//
+// Streaming queries stop their sources gracefully when ctx is cancelled
+// so the tables that were read before the query was cancelled are still
+// emitted. See WithPartialResults.
+func WithStreaming(ctx context.Context) context.Context {
+	return context.WithValue(WithPartialResults(ctx), streamingKey, true)
+}
+
+// StreamingEnabled reports whether the query runs in streaming mode.
+func StreamingEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(streamingKey).(bool)
+	return enabled
+}
+
+// Watermark tracks the times of the rows read by a streaming source
+// and computes the watermark the source sends downstream.
+//
+// The watermark is the latest time that has been read minus the allowed
+// lateness. Rows that are older than the watermark may be dropped by the
+// transformations that receive them.
+type Watermark struct {
+	// TimeColumn is the column that holds the time of each row.
+	// It defaults to DefaultTimeColLabel.
+	TimeColumn string
+
+	// AllowedLateness is how far the rows from a source may be out of order.
+	AllowedLateness Duration
+
+	latest  Time
+	mark    Time
+	hasData bool
+	sent    bool
+}
+
+// Observe reads the times from the table and returns a copy of the
+// table that can be sent downstream.
+func (w *Watermark) Observe(tbl flux.Table) (flux.Table, error) {
+	buffered, err := table.Copy(tbl)
+	if err != nil {
+		return nil, err
+	}
+
+	label := w.TimeColumn
+	if label == "" {
+		label = DefaultTimeColLabel
+	}
+	idx := ColIdx(label, buffered.Cols())
+	if idx < 0 || buffered.Cols()[idx].Type != flux.TTime {
+		return buffered, nil
+	}
+	for i, n := 0, buffered.BufferN(); i < n; i++ {
+		vs := buffered.Buffer(i).Times(idx)
+		for j, l := 0, vs.Len(); j < l; j++ {
+			if vs.IsValid(j) {
+				w.ObserveTime(Time(vs.Value(j)))
+			}
+		}
+	}
+	return buffered, nil
+}
+
+// ObserveTime records that a row with the given time was read.
+func (w *Watermark) ObserveTime(t Time) {
+	if !w.hasData || t > w.latest {
+		w.latest = t
+		w.hasData = true
+	}
+}
+
+// Current returns the current watermark and whether any data has been read.
+func (w *Watermark) Current() (Time, bool) {
+	if !w.hasData {
+		return 0, false
+	}
+	return w.latest.Add(w.AllowedLateness.Mul(-1)), true
+}
+
+// Update sends the watermark and the current processing time to the
+// transformations if the watermark has advanced since the last update.
+func (w *Watermark) Update(id DatasetID, ts []Transformation) error {
+	mark, ok := w.Current()
+	if !ok || (w.sent && mark <= w.mark) {
+		return nil
+	}
+	w.mark, w.sent = mark, true
+
+	now := values.ConvertTime(time.Now())
+	for _, t := range ts {
+		if err := t.UpdateWatermark(id, mark); err != nil {
+			return err
+		}
+		if err := t.UpdateProcessingTime(id, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closedByWatermark reports whether no more data for the group key can
+// arrive once the watermark has reached mark. Only group keys with a stop
+// column are closed by the watermark.
+func closedByWatermark(key flux.GroupKey, mark Time) bool {
+	idx := ColIdx(DefaultStopColLabel, key.Cols())
+	if idx < 0 || key.Cols()[idx].Type != flux.TTime {
+		return false
+	}
+	return mark >= key.ValueTime(idx)
+}
@@ -0,0 +1,248 @@
+package execute_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/dependency"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
+	"go.uber.org/zap/zaptest"
+)
+
+const streamingFromKind = "streaming-from-test"
+
+func init() {
+	execute.RegisterSource(streamingFromKind, createStreamingFromSource)
+}
+
+// streamingFromProcedureSpec is a procedure spec AND an execution node.
+// It sends each table, advances the watermark after each one
+// and then waits until it is cancelled.
+type streamingFromProcedureSpec struct {
+	execute.ExecutionNode
+	data []*executetest.Table
+
+	id        execute.DatasetID
+	ts        []execute.Transformation
+	watermark execute.Watermark
+}
+
+func (s *streamingFromProcedureSpec) Kind() plan.ProcedureKind {
+	return streamingFromKind
+}
+
+func (s *streamingFromProcedureSpec) Copy() plan.ProcedureSpec {
+	return s
+}
+
+func (s *streamingFromProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	return plan.Cost{}, plan.Statistics{}
+}
+
+func (s *streamingFromProcedureSpec) AddTransformation(t execute.Transformation) {
+	s.ts = append(s.ts, t)
+}
+
+func (s *streamingFromProcedureSpec) Run(ctx context.Context) {
+	err := s.run(ctx)
+	for _, t := range s.ts {
+		t.Finish(s.id, err)
+	}
+}
+
+func (s *streamingFromProcedureSpec) run(ctx context.Context) error {
+	for _, data := range s.data {
+		tbl, err := s.watermark.Observe(data)
+		if err != nil {
+			return err
+		}
+		for _, t := range s.ts {
+			if err := t.Process(s.id, tbl); err != nil {
+				return err
+			}
+		}
+		if err := s.watermark.Update(s.id, s.ts); err != nil {
+			return err
+		}
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func createStreamingFromSource(spec plan.ProcedureSpec, id execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	s := spec.(*streamingFromProcedureSpec)
+	s.id = id
+	return s, nil
+}
+
+func TestWatermark(t *testing.T) {
+	w := execute.Watermark{AllowedLateness: values.ConvertDurationNsecs(5 * time.Second)}
+	if _, ok := w.Current(); ok {
+		t.Fatal("expected no watermark before data is read")
+	}
+
+	tbl, err := w.Observe(&executetest.Table{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{seconds(20), 1.0},
+			{nil, 2.0},
+			{seconds(10), 3.0},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The returned table holds the same data.
+	got, err := executetest.ConvertTable(tbl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 3, len(got.Data); want != got {
+		t.Fatalf("unexpected number of rows -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	if got, ok := w.Current(); !ok {
+		t.Fatal("expected a watermark")
+	} else if want := seconds(15); want != got {
+		t.Fatalf("unexpected watermark -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+
+	// Earlier times do not move the watermark back.
+	w.ObserveTime(seconds(1))
+	if got, _ := w.Current(); got != seconds(15) {
+		t.Fatalf("unexpected watermark: %v", got)
+	}
+}
+
+func TestExecutor_Streaming(t *testing.T) {
+	input := func(times ...int64) *executetest.Table {
+		tbl := &executetest.Table{
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+			},
+		}
+		for _, sec := range times {
+			tbl.Data = append(tbl.Data, []interface{}{seconds(sec), 1.0})
+		}
+		return tbl
+	}
+
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("from", &streamingFromProcedureSpec{
+				data: []*executetest.Table{
+					input(1, 2, 3),
+					// This row closes the window [0s, 10s).
+					input(12),
+					// This row is late and is dropped.
+					input(4, 25),
+				},
+			}),
+			plan.CreatePhysicalNode("window", &universe.WindowProcedureSpec{
+				Window: plan.WindowSpec{
+					Every:  flux.ConvertDuration(10 * time.Second),
+					Period: flux.ConvertDuration(10 * time.Second),
+				},
+				TimeColumn:  execute.DefaultTimeColLabel,
+				StartColumn: execute.DefaultStartColLabel,
+				StopColumn:  execute.DefaultStopColLabel,
+			}),
+			plan.CreatePhysicalNode("sum", &universe.SumProcedureSpec{
+				SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+			}),
+			plan.CreatePhysicalNode("yield", executetest.NewYieldProcedureSpec("_result")),
+		},
+		Edges: [][2]int{
+			{0, 1},
+			{1, 2},
+			{2, 3},
+		},
+		Resources: flux.ResourceManagement{
+			ConcurrencyQuota: 1,
+			MemoryBytesQuota: math.MaxInt64,
+		},
+		Now: time.Now(),
+	})
+	if err := ps.TopDownWalk(plan.SetTriggerSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, deps := dependency.Inject(context.Background(), executetest.NewTestExecuteDependencies())
+	defer deps.Finish()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	exe := execute.NewExecutor(zaptest.NewLogger(t))
+	results, statsCh, err := exe.Execute(execute.WithStreaming(ctx), ps, executetest.UnlimitedAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The source never finishes on its own so the test fails
+	// with a timeout if the closed windows are not emitted.
+	timer := time.AfterFunc(10*time.Second, cancel)
+	defer timer.Stop()
+
+	var got []*executetest.Table
+	if err := results["_result"].Tables().Do(func(tbl flux.Table) error {
+		cb, err := executetest.ConvertTable(tbl)
+		if err != nil {
+			return err
+		}
+		got = append(got, cb)
+		if len(got) == 2 {
+			if !timer.Stop() {
+				t.Error("closed windows were not emitted before the query was cancelled")
+			}
+			cancel()
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-statsCh
+
+	window := func(start, stop int64, sum float64) *executetest.Table {
+		return &executetest.Table{
+			KeyCols: []string{"_start", "_stop"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_start", Type: flux.TTime},
+				{Label: "_stop", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{seconds(start), seconds(stop), sum},
+			},
+		}
+	}
+	want := []*executetest.Table{
+		window(0, 10, 3),
+		window(10, 20, 1),
+		// The last window is emitted when the query is cancelled.
+		window(20, 30, 1),
+	}
+	executetest.NormalizeTables(got)
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, got) {
+		t.Errorf("unexpected results -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
+
+// seconds returns the time that is n seconds after the epoch.
+func seconds(n int64) execute.Time {
+	return execute.Time(n * int64(time.Second))
+}
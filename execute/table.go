@@ -12,6 +12,7 @@ import (
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/internal/execute/table"
+	"github.com/influxdata/flux/internal/spill"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/semantic"
@@ -1265,6 +1266,25 @@ func (b *ColListTableBuilder) Release() {
 	b.nrows = 0
 }
 
+// tableWith returns the table that has been built
+// with the columns allocated by mem.
+func (b *ColListTableBuilder) tableWith(mem memory.Allocator) (flux.Table, error) {
+	alloc := b.alloc.Allocator
+	b.alloc.Allocator = mem
+	defer func() { b.alloc.Allocator = alloc }()
+	return b.Table()
+}
+
+// reset removes all rows and releases the memory held by
+// the columns while preserving the column meta data.
+func (b *ColListTableBuilder) reset() {
+	for _, c := range b.cols {
+		c.Release()
+		c.clearNils()
+	}
+	b.nrows = 0
+}
+
 func (b *ColListTableBuilder) Sort(cols []string, desc bool) {
 	colIdxs := make([]int, 0, len(cols))
 	for _, label := range cols {
@@ -1441,6 +1461,7 @@ type columnBuilder interface {
 	Len() int
 	IsNil(i int) bool
 	SetNil(i int, isNil bool)
+	clearNils()
 	Equal(i, j int) bool
 	Less(i, j int) bool
 	Swap(i, j int)
@@ -1460,6 +1481,12 @@ func (c *columnBuilderBase) IsNil(i int) bool {
 	return c.nils[i]
 }
 
+func (c *columnBuilderBase) clearNils() {
+	for i := range c.nils {
+		delete(c.nils, i)
+	}
+}
+
 func (c *columnBuilderBase) SetNil(i int, isNil bool) {
 	if isNil {
 		c.nils[i] = isNil
@@ -2017,6 +2044,7 @@ type TableBuilderCache interface {
 type tableBuilderCache struct {
 	tables *GroupLookup
 	alloc  memory.Allocator
+	dir    string
 
 	triggerSpec plan.TriggerSpec
 }
@@ -2031,26 +2059,48 @@ func NewTableBuilderCache(a memory.Allocator) *tableBuilderCache {
 type tableState struct {
 	builder TableBuilder
 	trigger Trigger
+	// spilled holds the rows that were written to disk
+	// by SpillTableBuilders. These rows come before
+	// the rows held by the builder.
+	spilled *spill.Buffer
 }
 
 func (d *tableBuilderCache) SetTriggerSpec(ts plan.TriggerSpec) {
 	d.triggerSpec = ts
 }
 
+// SetSpillDir sets the directory that SpillTableBuilders
+// writes temporary files to. Spilling is disabled when dir is empty.
+func (d *tableBuilderCache) SetSpillDir(dir string) {
+	d.dir = dir
+}
+
 func (d *tableBuilderCache) Table(key flux.GroupKey) (flux.Table, error) {
 	b, ok := d.lookupState(key)
 	if !ok {
 		return nil, fmt.Errorf("table not found with key %v", key)
 	}
-	return b.builder.Table()
+	if b.spilled == nil {
+		return b.builder.Table()
+	}
+
+	// Spill the rows that are still in the builder so all of
+	// the rows are read back from disk one buffer at a time.
+	// The returned table takes ownership of the spilled rows.
+	if err := d.spill(key, b); err != nil {
+		return nil, err
+	}
+	buf := b.spilled
+	b.spilled = nil
+	return buf.Table(b.builder.Cols()), nil
 }
 
-func (d *tableBuilderCache) lookupState(key flux.GroupKey) (tableState, bool) {
+func (d *tableBuilderCache) lookupState(key flux.GroupKey) (*tableState, bool) {
 	v, ok := d.tables.Lookup(key)
 	if !ok {
-		return tableState{}, false
+		return nil, false
 	}
-	return v.(tableState), true
+	return v.(*tableState), true
 }
 
 // TableBuilder will return the builder for the specified table.
@@ -2060,7 +2110,7 @@ func (d *tableBuilderCache) TableBuilder(key flux.GroupKey) (TableBuilder, bool)
 	if !ok {
 		builder := NewColListTableBuilder(key, d.alloc)
 		t := NewTriggerFromSpec(d.triggerSpec)
-		b = tableState{
+		b = &tableState{
 			builder: builder,
 			trigger: t,
 		}
@@ -2071,7 +2121,7 @@ func (d *tableBuilderCache) TableBuilder(key flux.GroupKey) (TableBuilder, bool)
 
 func (d *tableBuilderCache) ForEachBuilder(f func(flux.GroupKey, TableBuilder) error) error {
 	return d.tables.Range(func(key flux.GroupKey, value interface{}) error {
-		return f(key, value.(tableState).builder)
+		return f(key, value.(*tableState).builder)
 	})
 }
 
@@ -2079,13 +2129,15 @@ func (d *tableBuilderCache) DiscardTable(key flux.GroupKey) {
 	b, ok := d.lookupState(key)
 	if ok {
 		b.builder.ClearData()
+		b.release()
 	}
 }
 
 func (d *tableBuilderCache) ExpireTable(key flux.GroupKey) {
 	b, ok := d.tables.Delete(key)
 	if ok {
-		b.(tableState).builder.Release()
+		b.(*tableState).builder.Release()
+		b.(*tableState).release()
 	}
 }
 
@@ -2097,14 +2149,68 @@ func (d *tableBuilderCache) ForEach(f func(flux.GroupKey) error) error {
 
 func (d *tableBuilderCache) ForEachWithContext(f func(flux.GroupKey, Trigger, TableContext) error) error {
 	return d.tables.Range(func(key flux.GroupKey, value interface{}) error {
-		b := value.(tableState)
+		b := value.(*tableState)
+		count := b.builder.NRows()
+		if b.spilled != nil {
+			count += b.spilled.Len()
+		}
 		return f(key, b.trigger, TableContext{
 			Key:   key,
-			Count: b.builder.NRows(),
+			Count: count,
 		})
 	})
 }
 
+// spill writes the rows held by the builder to disk
+// and removes them from the builder.
+func (d *tableBuilderCache) spill(key flux.GroupKey, b *tableState) error {
+	builder, ok := b.builder.(*ColListTableBuilder)
+	if !ok || builder.NRows() == 0 {
+		return nil
+	}
+	// The query allocator is likely out of memory so the rows
+	// are copied with an allocator that is not limited. The copy
+	// only lives until it has been written to disk.
+	tbl, err := builder.tableWith(memory.DefaultAllocator)
+	if err != nil {
+		return err
+	}
+	if b.spilled == nil {
+		b.spilled = spill.NewBuffer(key, d.dir, d.alloc)
+	}
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		b.spilled.Append(cr)
+		return nil
+	}); err != nil {
+		return err
+	}
+	builder.reset()
+	return b.spilled.Spill()
+}
+
+func (b *tableState) release() {
+	if b.spilled != nil {
+		b.spilled.Release()
+		b.spilled = nil
+	}
+}
+
+// SpillTableBuilders writes the rows held by the table builders
+// in the cache to disk if the allocator used by the cache cannot
+// provide size more bytes. The spilled rows are read back when
+// the table is produced.
+//
+// This does nothing if the cache does not support spilling.
+func SpillTableBuilders(cache TableBuilderCache, size int) error {
+	d, ok := cache.(*tableBuilderCache)
+	if !ok || !spill.ShouldSpill(d.dir, d.alloc, size) {
+		return nil
+	}
+	return d.tables.Range(func(key flux.GroupKey, value interface{}) error {
+		return d.spill(key, value.(*tableState))
+	})
+}
+
 type emptyTable struct {
 	key  flux.GroupKey
 	cols []flux.ColMeta
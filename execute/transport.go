@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/apache/arrow/go/v7/arrow/memory"
 	"github.com/influxdata/flux"
@@ -48,6 +49,7 @@ var _ Transformation = (*consecutiveTransport)(nil)
 type consecutiveTransport struct {
 	ctx        context.Context
 	dispatcher Dispatcher
+	priority   int
 	logger     *zap.Logger
 
 	t        Transport
@@ -55,6 +57,10 @@ type consecutiveTransport struct {
 	stack    []interpreter.StackEntry
 	profile  flux.TransportProfile
 
+	// stats counts the data received by this transport.
+	// It is only set when node statistics are enabled.
+	stats *edgeStatistics
+
 	finished chan struct{}
 	errMu    sync.Mutex
 	errValue error
@@ -67,10 +73,11 @@ type consecutiveTransport struct {
 	span         opentracing.Span
 }
 
-func newConsecutiveTransport(ctx context.Context, dispatcher Dispatcher, t Transformation, n plan.Node, logger *zap.Logger, mem memory.Allocator) *consecutiveTransport {
+func newConsecutiveTransport(ctx context.Context, dispatcher Dispatcher, t Transformation, n plan.Node, priority int, logger *zap.Logger, mem memory.Allocator) *consecutiveTransport {
 	return &consecutiveTransport{
 		ctx:        ctx,
 		dispatcher: dispatcher,
+		priority:   priority,
 		logger:     logger,
 		t:          WrapTransformationInTransport(t, mem),
 		// TODO(nathanielc): Have planner specify message queue initial buffer size.
@@ -215,6 +222,10 @@ const (
 // schedule indicates that there is work available to schedule.
 func (t *consecutiveTransport) schedule() {
 	if t.tryTransition(idle, running) {
+		if d, ok := t.dispatcher.(priorityDispatcher); ok {
+			d.SchedulePriority(t.processMessages, t.priority)
+			return
+		}
 		t.dispatcher.Schedule(t.processMessages)
 	}
 }
@@ -294,6 +305,19 @@ func (t *consecutiveTransport) processMessage(m Message) (finished bool, err err
 	span := t.profile.StartSpan()
 	defer span.Finish()
 
+	if t.stats != nil {
+		switch m.Type() {
+		case ProcessType:
+			t.stats.addTable()
+		case ProcessChunkType:
+			t.stats.addChunk(m.(ProcessChunkMsg).TableChunk())
+		}
+		start := time.Now()
+		defer func() {
+			t.stats.in.addWallTime(time.Since(start))
+		}()
+	}
+
 	if err := t.t.ProcessMessage(m); err != nil {
 		return false, err
 	}
@@ -569,6 +593,9 @@ func (t *consecutiveTransportTable) Do(f func(flux.ColReader) error) error {
 			}
 			logger.Info("Invalid column reader received from predecessor", fields...)
 		}
+		if t.transport.stats != nil {
+			t.transport.stats.addRows(cr.Len())
+		}
 		return f(cr)
 	})
 }
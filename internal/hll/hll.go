@@ -0,0 +1,137 @@
+// Package hll implements the HyperLogLog sketch which estimates
+// the number of distinct values in a set using a fixed amount of
+// memory. Sketches built from different parts of a set can be
+// merged into a sketch of the whole set.
+package hll
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/cespare/xxhash/v2"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+)
+
+const (
+	// MinPrecision and MaxPrecision are the bounds of the precision
+	// of a sketch. A sketch has 2^precision registers of one byte.
+	MinPrecision = 4
+	MaxPrecision = 18
+
+	// DefaultPrecision has a standard error of about 0.8%
+	// and uses 16 KiB of memory.
+	DefaultPrecision = 14
+)
+
+// Sketch is a HyperLogLog sketch.
+type Sketch struct {
+	p         uint8
+	registers []byte
+	mem       memory.Allocator
+}
+
+// New creates an empty sketch with the given precision.
+// The registers are allocated from mem and are freed by Release.
+func New(precision int, mem memory.Allocator) (*Sketch, error) {
+	if precision < MinPrecision || precision > MaxPrecision {
+		return nil, errors.Newf(codes.Invalid, "precision must be between %d and %d, got %d", MinPrecision, MaxPrecision, precision)
+	}
+	registers := mem.Allocate(1 << precision)
+	for i := range registers {
+		registers[i] = 0
+	}
+	return &Sketch{
+		p:         uint8(precision),
+		registers: registers,
+		mem:       mem,
+	}, nil
+}
+
+// Precision returns the precision of the sketch.
+func (s *Sketch) Precision() int {
+	return int(s.p)
+}
+
+// Add adds a 64-bit hash of a value to the sketch.
+// The hash must be uniformly distributed.
+func (s *Sketch) Add(hash uint64) {
+	// The first p bits select the register and the register keeps
+	// the highest position of the first set bit in the remaining bits.
+	i := hash >> (64 - s.p)
+	w := hash<<s.p | 1<<(s.p-1)
+	if rho := uint8(bits.LeadingZeros64(w)) + 1; rho > s.registers[i] {
+		s.registers[i] = rho
+	}
+}
+
+// AddBytes hashes the bytes and adds the hash to the sketch.
+func (s *Sketch) AddBytes(b []byte) {
+	s.Add(xxhash.Sum64(b))
+}
+
+// AddString hashes the string and adds the hash to the sketch.
+func (s *Sketch) AddString(v string) {
+	s.Add(xxhash.Sum64String(v))
+}
+
+// Merge adds the values in the other sketch to this sketch.
+// Both sketches must have the same precision.
+func (s *Sketch) Merge(other *Sketch) error {
+	if s.p != other.p {
+		return errors.Newf(codes.Internal, "cannot merge sketches with precision %d and %d", s.p, other.p)
+	}
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// Count returns the estimated number of distinct values
+// that were added to the sketch.
+func (s *Sketch) Count() uint64 {
+	m := float64(len(s.registers))
+	var sum float64
+	var zeros int
+	for _, r := range s.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha(len(s.registers)) * m * m / sum
+	// Use linear counting for small cardinalities where
+	// the raw estimate has a large bias. Large cardinalities
+	// do not need a correction with 64-bit hashes.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Release frees the registers of the sketch.
+func (s *Sketch) Release() {
+	if s.registers != nil {
+		s.mem.Free(s.registers)
+		s.registers = nil
+	}
+}
+
+// alpha is the constant that corrects the bias
+// of the estimate for m registers.
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
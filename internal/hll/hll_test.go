@@ -0,0 +1,83 @@
+package hll_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux/internal/hll"
+)
+
+func TestSketch_Count(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 100000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+			defer mem.AssertSize(t, 0)
+
+			s, err := hll.New(hll.DefaultPrecision, mem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Release()
+
+			// Every value is added twice.
+			for i := 0; i < 2*n; i++ {
+				s.AddString(strconv.Itoa(i % n))
+			}
+
+			// The standard error is 1.04 / sqrt(m).
+			stderr := 1.04 / math.Sqrt(float64(uint64(1)<<hll.DefaultPrecision))
+			if got, want := float64(s.Count()), float64(n); math.Abs(got-want) > 3*stderr*want+0.5 {
+				t.Fatalf("unexpected count -want/+got:\n\t- %v\n\t+ %v", want, got)
+			}
+		})
+	}
+}
+
+func TestSketch_Merge(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	newSketch := func(from, to int) *hll.Sketch {
+		s, err := hll.New(10, mem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := from; i < to; i++ {
+			s.AddString(strconv.Itoa(i))
+		}
+		return s
+	}
+
+	// Merging sketches of overlapping sets is the same
+	// as building a sketch of their union.
+	a, b, union := newSketch(0, 6000), newSketch(4000, 10000), newSketch(0, 10000)
+	defer a.Release()
+	defer b.Release()
+	defer union.Release()
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := union.Count(), a.Count(); want != got {
+		t.Fatalf("unexpected merged count -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	other, err := hll.New(12, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Release()
+	if err := a.Merge(other); err == nil {
+		t.Fatal("expected an error when merging sketches with different precisions")
+	}
+}
+
+func TestNew_InvalidPrecision(t *testing.T) {
+	for _, p := range []int{hll.MinPrecision - 1, hll.MaxPrecision + 1} {
+		if _, err := hll.New(p, memory.DefaultAllocator); err == nil {
+			t.Errorf("expected an error for precision %d", p)
+		}
+	}
+}
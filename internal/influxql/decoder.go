@@ -47,6 +47,10 @@ func (ri *resultIterator) Next() flux.Result {
 }
 
 func (ri *resultIterator) Release() {
+	// Retain a statement error so it is still reported by Err.
+	if ri.resp.Err == "" && len(ri.resp.Results) > 0 {
+		ri.resp.Err = ri.resp.Results[0].Err
+	}
 	ri.resp.Results = nil
 }
 
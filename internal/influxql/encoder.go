@@ -0,0 +1,389 @@
+package influxql
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/iocounter"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+// ResultEncoderConfig are options that can be specified on the MultiResultEncoder.
+type ResultEncoderConfig struct {
+	// ChunkSize is the maximum number of rows written in a single series.
+	// When it is greater than zero, the response is split into chunks
+	// and each chunk is written as its own JSON object on a separate line.
+	// This mirrors the chunked responses from the InfluxDB 1.x /query endpoint.
+	ChunkSize int
+}
+
+// MultiResultEncoder encodes results in the format of an InfluxDB 1.x /query response.
+//
+// Each result is written as a statement. The statement ID is the result name
+// when it is an integer and the position of the result otherwise. Each non-empty
+// table is written as a series. The _measurement column of the group key becomes
+// the series name and the remaining group key columns become the series tags,
+// with the exception of _start and _stop. The _time column is written as the time
+// column and the other columns are written as the series columns. When _field is
+// part of the group key, the _value column is named after the field.
+//
+// Errors from query execution are written into the response. Errors from
+// encoding are returned.
+type MultiResultEncoder struct {
+	c ResultEncoderConfig
+}
+
+// NewMultiResultEncoder creates a new MultiResultEncoder.
+func NewMultiResultEncoder(c ResultEncoderConfig) *MultiResultEncoder {
+	return &MultiResultEncoder{c: c}
+}
+
+type influxqlEncoderError struct {
+	err error
+}
+
+func (e *influxqlEncoderError) Error() string {
+	return e.err.Error()
+}
+
+func (e *influxqlEncoderError) IsEncoderError() bool {
+	return true
+}
+
+func (e *influxqlEncoderError) Unwrap() error {
+	return e.err
+}
+
+func wrapEncodingError(err error) error {
+	if err == nil {
+		return err
+	}
+	return &influxqlEncoderError{err: err}
+}
+
+func isEncoderError(err error) bool {
+	encErr, ok := err.(flux.EncoderError)
+	return ok && encErr.IsEncoderError()
+}
+
+type flusher interface {
+	Flush()
+}
+
+func (e *MultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	wc := &iocounter.Writer{Writer: w}
+	rw := &responseWriter{
+		enc:       json.NewEncoder(wc),
+		w:         w,
+		chunkSize: e.c.ChunkSize,
+	}
+
+	for i := 0; results.More(); i++ {
+		result := results.Next()
+		if err := rw.writeResult(statementID(result.Name(), i), result); err != nil {
+			return wc.Count(), err
+		}
+	}
+	results.Release()
+
+	err := rw.close(results.Err())
+	return wc.Count(), err
+}
+
+// statementID determines the statement ID from the result name.
+func statementID(name string, i int) int {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id
+	}
+	return i
+}
+
+// responseWriter accumulates the results of a response
+// and writes them either all at once or in chunks.
+type responseWriter struct {
+	enc       *json.Encoder
+	w         io.Writer
+	chunkSize int
+
+	// resp holds the results when the response is not chunked.
+	resp Response
+	// pending holds the last series chunk of the current statement.
+	// It is written once it is known whether more chunks follow.
+	pending *Series
+}
+
+func (rw *responseWriter) writeResult(id int, result flux.Result) error {
+	res := Result{StatementID: id}
+	err := result.Tables().Do(func(tbl flux.Table) error {
+		return rw.writeTable(&res, tbl)
+	})
+	if err != nil {
+		if isEncoderError(err) {
+			return err
+		}
+		// The error happened during query execution
+		// so it is reported as a statement error.
+		res.Series = nil
+		res.Err = err.Error()
+	}
+
+	if rw.chunkSize <= 0 {
+		rw.resp.Results = append(rw.resp.Results, res)
+		return nil
+	}
+
+	// Write the last chunk of the statement. A statement that
+	// did not produce any series is still written so the
+	// client sees its statement ID.
+	if rw.pending != nil {
+		if err := rw.writeChunk(id, rw.pending, false); err != nil {
+			return err
+		}
+		rw.pending = nil
+		if res.Err == "" {
+			return nil
+		}
+	}
+	if err := rw.writeResponse(&Response{Results: []Result{res}}); err != nil {
+		return err
+	}
+	// Flush the writer after each result.
+	if f, ok := rw.w.(flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func (rw *responseWriter) writeTable(res *Result, tbl flux.Table) error {
+	sb, err := newSeriesBuilder(tbl)
+	if err != nil {
+		return err
+	}
+	series := sb.newSeries()
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		for i, n := 0, cr.Len(); i < n; i++ {
+			if rw.chunkSize > 0 && len(series.Values) == rw.chunkSize {
+				// The series continues in the next chunk.
+				series.Partial = true
+				if err := rw.addSeries(res, series); err != nil {
+					return err
+				}
+				series = sb.newSeries()
+			}
+			series.Values = append(series.Values, sb.row(cr, i))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(series.Values) == 0 {
+		return nil
+	}
+	return rw.addSeries(res, series)
+}
+
+// addSeries adds a series to the result or queues it as the next chunk.
+func (rw *responseWriter) addSeries(res *Result, series *Series) error {
+	if rw.chunkSize <= 0 {
+		res.Series = append(res.Series, series)
+		return nil
+	}
+	if rw.pending != nil {
+		if err := rw.writeChunk(res.StatementID, rw.pending, true); err != nil {
+			return err
+		}
+	}
+	rw.pending = series
+	return nil
+}
+
+// writeChunk writes a single series chunk. The partial flag
+// marks whether more chunks follow in the same statement.
+func (rw *responseWriter) writeChunk(id int, series *Series, partial bool) error {
+	return rw.writeResponse(&Response{
+		Results: []Result{{
+			StatementID: id,
+			Series:      []*Series{series},
+			Partial:     partial,
+		}},
+	})
+}
+
+func (rw *responseWriter) writeResponse(resp *Response) error {
+	return wrapEncodingError(rw.enc.Encode(resp))
+}
+
+// close writes the remainder of the response along with
+// any error that was returned by the result iterator.
+func (rw *responseWriter) close(err error) error {
+	if rw.chunkSize <= 0 {
+		if err != nil {
+			rw.resp.Err = err.Error()
+		}
+		return rw.writeResponse(&rw.resp)
+	}
+	if err != nil {
+		return rw.writeResponse(&Response{Err: err.Error()})
+	}
+	return nil
+}
+
+// seriesBuilder constructs series from the rows of a table.
+type seriesBuilder struct {
+	name    string
+	tags    map[string]string
+	columns []string
+	// indices contains the table column index for each series column.
+	indices []int
+	types   []flux.ColType
+}
+
+func newSeriesBuilder(tbl flux.Table) (*seriesBuilder, error) {
+	sb := &seriesBuilder{}
+
+	// Determine the series name and tags from the group key.
+	var field string
+	key := tbl.Key()
+	for j, c := range key.Cols() {
+		if key.IsNull(j) {
+			continue
+		}
+		switch c.Label {
+		case "_measurement":
+			if c.Type == flux.TString {
+				sb.name = key.ValueString(j)
+				continue
+			}
+		case "_field":
+			if c.Type == flux.TString {
+				field = key.ValueString(j)
+				continue
+			}
+		case execute.DefaultStartColLabel, execute.DefaultStopColLabel:
+			continue
+		}
+		if sb.tags == nil {
+			sb.tags = make(map[string]string)
+		}
+		sb.tags[c.Label] = tagValue(key.Value(j))
+	}
+
+	cols := tbl.Cols()
+	if idx := execute.ColIdx(execute.DefaultTimeColLabel, cols); idx >= 0 {
+		sb.add("time", idx, cols[idx].Type)
+	}
+	for j, c := range cols {
+		if c.Label == execute.DefaultTimeColLabel || key.HasCol(c.Label) {
+			continue
+		}
+		label := c.Label
+		if label == execute.DefaultValueColLabel && field != "" {
+			label = field
+		}
+		switch c.Type {
+		case flux.TBool, flux.TInt, flux.TUInt, flux.TFloat, flux.TString, flux.TTime:
+		default:
+			return nil, wrapEncodingError(errors.Newf(codes.Invalid, "unknown column type %s", c.Type))
+		}
+		sb.add(label, j, c.Type)
+	}
+
+	// A field that was not used to name the value column is kept as a tag.
+	if field != "" && execute.ColIdx(execute.DefaultValueColLabel, cols) < 0 {
+		if sb.tags == nil {
+			sb.tags = make(map[string]string)
+		}
+		sb.tags["_field"] = field
+	}
+	return sb, nil
+}
+
+func (sb *seriesBuilder) add(label string, idx int, typ flux.ColType) {
+	sb.columns = append(sb.columns, label)
+	sb.indices = append(sb.indices, idx)
+	sb.types = append(sb.types, typ)
+}
+
+func (sb *seriesBuilder) newSeries() *Series {
+	return &Series{
+		Name:    sb.name,
+		Tags:    sb.tags,
+		Columns: sb.columns,
+	}
+}
+
+func (sb *seriesBuilder) row(cr flux.ColReader, i int) []interface{} {
+	row := make([]interface{}, len(sb.indices))
+	for k, j := range sb.indices {
+		row[k] = valueForRow(cr, i, j, sb.types[k])
+	}
+	return row
+}
+
+// valueForRow returns the JSON representation of a value.
+// Times are formatted as RFC3339 strings and floats that
+// cannot be represented as JSON numbers are returned as null.
+func valueForRow(cr flux.ColReader, i, j int, typ flux.ColType) interface{} {
+	switch typ {
+	case flux.TBool:
+		if vs := cr.Bools(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TInt:
+		if vs := cr.Ints(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TUInt:
+		if vs := cr.UInts(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TFloat:
+		if vs := cr.Floats(j); vs.IsValid(i) {
+			if v := vs.Value(i); !math.IsNaN(v) && !math.IsInf(v, 0) {
+				return v
+			}
+		}
+	case flux.TString:
+		if vs := cr.Strings(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TTime:
+		if vs := cr.Times(j); vs.IsValid(i) {
+			return formatTime(vs.Value(i))
+		}
+	}
+	return nil
+}
+
+// tagValue formats a group key value as a tag value.
+func tagValue(v values.Value) string {
+	switch v.Type().Nature() {
+	case semantic.String:
+		return v.Str()
+	case semantic.Time:
+		return formatTime(int64(v.Time()))
+	case semantic.Int:
+		return strconv.FormatInt(v.Int(), 10)
+	case semantic.UInt:
+		return strconv.FormatUint(v.UInt(), 10)
+	case semantic.Float:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case semantic.Bool:
+		return strconv.FormatBool(v.Bool())
+	default:
+		return ""
+	}
+}
+
+func formatTime(ns int64) string {
+	return time.Unix(0, ns).UTC().Format(time.RFC3339Nano)
+}
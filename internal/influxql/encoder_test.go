@@ -0,0 +1,268 @@
+package influxql_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/diff"
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/influxql"
+	"github.com/influxdata/flux/values"
+)
+
+func encoderTestResults() []flux.Result {
+	t0 := values.ConvertTime(time.Date(2018, 4, 17, 0, 0, 0, 0, time.UTC))
+	t1 := values.ConvertTime(time.Date(2018, 4, 17, 0, 0, 1, 500, time.UTC))
+	return []flux.Result{
+		&executetest.Result{
+			Nm: "_result",
+			Tbls: []*executetest.Table{
+				{
+					KeyCols: []string{"_start", "_stop", "_measurement", "host", "_field"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_stop", Type: flux.TTime},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+						{Label: "_field", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{t0, t1, t0, "cpu", "A", "usage_user", 42.0},
+						{t0, t1, t1, "cpu", "A", "usage_user", math.NaN()},
+						{t0, t1, t1, "cpu", "A", "usage_user", nil},
+					},
+				},
+				{
+					KeyCols:   []string{"_measurement", "host", "_field"},
+					KeyValues: []interface{}{"cpu", "B", "usage_user"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+						{Label: "_field", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+				},
+				{
+					KeyCols: []string{"_measurement", "id"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "id", Type: flux.TInt},
+						{Label: "n", Type: flux.TInt},
+						{Label: "u", Type: flux.TUInt},
+						{Label: "ok", Type: flux.TBool},
+						{Label: "s", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{t0, "mem", int64(1), int64(-3), uint64(7), true, "x"},
+					},
+				},
+			},
+		},
+		&executetest.Result{
+			Nm: "1",
+			Tbls: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{1.5},
+				},
+			}},
+		},
+	}
+}
+
+func TestMultiResultEncoder(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  influxql.ResultEncoderConfig
+		results []flux.Result
+		encoded string
+		err     error
+	}{
+		{
+			name:    "simple",
+			results: encoderTestResults(),
+			encoded: `{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"A"},"columns":["time","usage_user"],"values":[["2018-04-17T00:00:00Z",42],["2018-04-17T00:00:01.0000005Z",null],["2018-04-17T00:00:01.0000005Z",null]]},{"name":"mem","tags":{"id":"1"},"columns":["time","n","u","ok","s"],"values":[["2018-04-17T00:00:00Z",-3,7,true,"x"]]}]},{"statement_id":1,"series":[{"columns":["_value"],"values":[[1.5]]}]}]}
+`,
+		},
+		{
+			name:    "chunked",
+			config:  influxql.ResultEncoderConfig{ChunkSize: 2},
+			results: encoderTestResults(),
+			encoded: `{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"A"},"columns":["time","usage_user"],"values":[["2018-04-17T00:00:00Z",42],["2018-04-17T00:00:01.0000005Z",null]],"partial":true}],"partial":true}]}
+{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"A"},"columns":["time","usage_user"],"values":[["2018-04-17T00:00:01.0000005Z",null]]}],"partial":true}]}
+{"results":[{"statement_id":0,"series":[{"name":"mem","tags":{"id":"1"},"columns":["time","n","u","ok","s"],"values":[["2018-04-17T00:00:00Z",-3,7,true,"x"]]}]}]}
+{"results":[{"statement_id":1,"series":[{"columns":["_value"],"values":[[1.5]]}]}]}
+`,
+		},
+		{
+			name: "empty statement",
+			results: []flux.Result{&executetest.Result{
+				Nm: "_result",
+			}},
+			encoded: `{"results":[{"statement_id":0}]}
+`,
+		},
+		{
+			name:    "empty statement chunked",
+			config:  influxql.ResultEncoderConfig{ChunkSize: 2},
+			results: []flux.Result{&executetest.Result{Nm: "_result"}},
+			encoded: `{"results":[{"statement_id":0}]}
+`,
+		},
+		{
+			name: "statement error",
+			results: func() []flux.Result {
+				results := encoderTestResults()
+				results[1].(*executetest.Result).Err = errors.New("test error")
+				return results
+			}(),
+			encoded: `{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"A"},"columns":["time","usage_user"],"values":[["2018-04-17T00:00:00Z",42],["2018-04-17T00:00:01.0000005Z",null],["2018-04-17T00:00:01.0000005Z",null]]},{"name":"mem","tags":{"id":"1"},"columns":["time","n","u","ok","s"],"values":[["2018-04-17T00:00:00Z",-3,7,true,"x"]]}]},{"statement_id":1,"error":"test error"}]}
+`,
+		},
+		{
+			name: "returns encoding errors",
+			results: []flux.Result{&executetest.Result{
+				Nm: "_result",
+				Tbls: []*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInvalid},
+					},
+				}},
+			}},
+			err: errors.New("unknown column type invalid"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			encoder := influxql.NewMultiResultEncoder(tc.config)
+			var got bytes.Buffer
+			n, err := encoder.Encode(&got, flux.NewSliceResultIterator(tc.results))
+			if err != nil && tc.err != nil {
+				if err.Error() != tc.err.Error() {
+					t.Errorf("unexpected error want: %s\n got: %s\n", tc.err.Error(), err.Error())
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error want: none\n got: %s\n", err.Error())
+			} else if tc.err != nil {
+				t.Errorf("unexpected error want: %s\n got: none", tc.err.Error())
+			}
+
+			if g, w := got.String(), tc.encoded; g != w {
+				t.Errorf("unexpected encoding -want/+got:\n%s", diff.LineDiff(w, g))
+			}
+			if g, w := n, int64(got.Len()); g != w {
+				t.Errorf("unexpected encoding count want: %d got: %d", w, g)
+			}
+		})
+	}
+}
+
+func TestEncoder_RoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		resp string
+	}{
+		{
+			name: "series",
+			resp: `{
+	"results": [
+		{
+			"statement_id": 0,
+			"series": [
+				{
+					"name": "cpu",
+					"tags": {"host": "server01", "region": "west"},
+					"columns": ["time", "usage_user"],
+					"values": [
+						["1970-01-01T00:00:00Z", 0.5],
+						["1970-01-01T00:00:10Z", 1.25]
+					]
+				},
+				{
+					"name": "cpu",
+					"tags": {"host": "server02", "region": "west"},
+					"columns": ["time", "usage_user"],
+					"values": [
+						["1970-01-01T00:00:00.000000001Z", 3]
+					]
+				}
+			]
+		},
+		{
+			"statement_id": 1,
+			"series": [
+				{
+					"name": "syslog",
+					"columns": ["time", "message"],
+					"values": [
+						["2018-04-17T00:00:00Z", "started"]
+					]
+				}
+			]
+		}
+	]
+}`,
+		},
+		{
+			name: "error",
+			resp: `{
+	"results": [
+		{
+			"statement_id": 0,
+			"series": [
+				{
+					"name": "mem",
+					"columns": ["time", "active"],
+					"values": [
+						["1970-01-01T00:00:00Z", true]
+					]
+				}
+			]
+		}
+	],
+	"error": "database not found: telegraf"
+}`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dec := influxql.NewResultDecoder(executetest.UnlimitedAllocator)
+			results, err := dec.Decode(io.NopCloser(strings.NewReader(tc.resp)))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var buf bytes.Buffer
+			enc := influxql.NewMultiResultEncoder(influxql.ResultEncoderConfig{})
+			if _, err := enc.Encode(&buf, results); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var want, got influxql.Response
+			if err := json.Unmarshal([]byte(tc.resp), &want); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(want, got) {
+				t.Fatalf("unexpected response -want/+got:\n%s", cmp.Diff(want, got))
+			}
+		})
+	}
+}
@@ -17,7 +17,8 @@ import (
 // The `_value` column contains tokens.
 // The `_time` column contains the timestamps for when each `_value` has been read.
 // Strings in `_value` are obtained from the io.Reader passed to the Decode function.
-// ResultDecoder outputs one table once the reader reaches EOF.
+// ResultDecoder outputs one table once the reader reaches EOF unless
+// the decoder is configured to flush its tables.
 type ResultDecoder struct {
 	reader *bufio.Reader
 	config *ResultDecoderConfig
@@ -37,48 +38,78 @@ type TimeProvider interface {
 type ResultDecoderConfig struct {
 	Separator    byte
 	TimeProvider TimeProvider
+
+	// Flush makes the decoder output a table each time it has read
+	// all of the input that is available without blocking.
+	// This is used to decode a stream that does not end.
+	Flush bool
 }
 
 func (rd *ResultDecoder) Do(f func(flux.Table) error) error {
+	for {
+		tbl, eof, err := rd.readTable()
+		if err != nil {
+			return err
+		}
+		// An empty table is only sent when it is the only table.
+		if !rd.config.Flush || !eof || !tbl.Empty() {
+			if err := f(tbl); err != nil {
+				return err
+			}
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+
+// readTable reads the tokens from the reader into a table until the reader
+// reaches EOF or, if the decoder flushes its tables, until there is
+// no more buffered input.
+func (rd *ResultDecoder) readTable() (flux.Table, bool, error) {
 	timeCol := flux.ColMeta{Label: "_time", Type: flux.TTime}
 	valueCol := flux.ColMeta{Label: "_value", Type: flux.TString}
 	key := execute.NewGroupKey(nil, nil)
 	builder := execute.NewColListTableBuilder(key, &memory.ResourceAllocator{})
 	timeIdx, err := builder.AddCol(timeCol)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 	valueIdx, err := builder.AddCol(valueCol)
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 
 	var eof bool
 	for !eof {
 		s, err := rd.reader.ReadString(rd.config.Separator)
 		if err != nil && err == io.EOF {
+			eof = true
 			break
 		} else if err != nil {
-			return err
+			return nil, false, err
 		}
 
 		v := strings.Trim(s, string(rd.config.Separator))
 		ts := rd.config.TimeProvider.CurrentTime()
 		err = builder.AppendTime(timeIdx, ts)
 		if err != nil {
-			return err
+			return nil, false, err
 		}
 		err = builder.AppendString(valueIdx, v)
 		if err != nil {
-			return err
+			return nil, false, err
+		}
+		if rd.config.Flush && rd.reader.Buffered() == 0 {
+			break
 		}
 	}
 
 	tbl, err := builder.Table()
 	if err != nil {
-		return err
+		return nil, false, err
 	}
-	return f(tbl)
+	return tbl, eof, nil
 }
 
 func (*ResultDecoder) Name() string {
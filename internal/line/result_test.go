@@ -2,6 +2,7 @@ package line_test
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -121,3 +122,69 @@ empty line above
 		})
 	}
 }
+
+// chunkReader returns one chunk of its input for each call to Read.
+type chunkReader struct {
+	chunks []string
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func TestResultDecoder_Flush(t *testing.T) {
+	decoder := line.NewResultDecoder(&line.ResultDecoderConfig{
+		Separator:    '\n',
+		TimeProvider: &mock.AscendingTimeProvider{},
+		Flush:        true,
+	})
+
+	r, err := decoder.Decode(&chunkReader{
+		chunks: []string{"a\nb\n", "c\n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []*executetest.Table
+	if err := r.Tables().Do(func(table flux.Table) error {
+		ct, err := executetest.ConvertTable(table)
+		if err != nil {
+			return err
+		}
+		got = append(got, ct)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cols := []flux.ColMeta{
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TString},
+	}
+	want := []*executetest.Table{
+		{
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{execute.Time(0), "a"},
+				{execute.Time(1), "b"},
+			},
+		},
+		{
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{execute.Time(2), "c"},
+			},
+		},
+	}
+	executetest.NormalizeTables(got)
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, got) {
+		t.Errorf("unexpected tables -want/+got\n%s", cmp.Diff(want, got))
+	}
+}
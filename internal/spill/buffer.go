@@ -0,0 +1,232 @@
+package spill
+
+import (
+	"io"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+)
+
+// Buffer holds an ordered sequence of table buffers with the same
+// group key. Buffers are kept in memory until Spill is called,
+// at which point the buffers held in memory are written to a
+// temporary file and released.
+//
+// The buffers are always read back in the order they were appended.
+type Buffer struct {
+	key   flux.GroupKey
+	dir   string
+	mem   memory.Allocator
+	files []*File
+	bufs  []flux.ColReader
+	n     int
+}
+
+// NewBuffer constructs a Buffer that spills to files in dir
+// and allocates the buffers it reads back with mem.
+func NewBuffer(key flux.GroupKey, dir string, mem memory.Allocator) *Buffer {
+	return &Buffer{
+		key: key,
+		dir: dir,
+		mem: mem,
+	}
+}
+
+// Key returns the group key of the buffer.
+func (b *Buffer) Key() flux.GroupKey { return b.key }
+
+// Len returns the number of rows in the buffer.
+func (b *Buffer) Len() int { return b.n }
+
+// Spilled reports whether any data has been written to disk.
+func (b *Buffer) Spilled() bool { return len(b.files) > 0 }
+
+// Append adds the column reader to the end of the buffer.
+// The buffer retains a reference to the column reader.
+func (b *Buffer) Append(cr flux.ColReader) {
+	cr.Retain()
+	b.bufs = append(b.bufs, cr)
+	b.n += cr.Len()
+}
+
+// Spill writes all of the buffers held in memory to disk
+// and releases them. Consecutive buffers with the same schema
+// are written to the same file.
+func (b *Buffer) Spill() error {
+	var w *Writer
+	for len(b.bufs) > 0 {
+		cr := b.bufs[0]
+		if w != nil && !colsEqual(w.file.cols, cr.Cols()) {
+			f, err := w.Close()
+			if err != nil {
+				return err
+			}
+			b.files = append(b.files, f)
+			w = nil
+		}
+		if w == nil {
+			nw, err := Create(b.dir, b.key, cr.Cols())
+			if err != nil {
+				return err
+			}
+			w = nw
+		}
+		if err := w.Write(cr); err != nil {
+			w.Abort()
+			return err
+		}
+		cr.Release()
+		b.bufs = b.bufs[1:]
+	}
+	b.bufs = nil
+
+	if w != nil {
+		f, err := w.Close()
+		if err != nil {
+			return err
+		}
+		b.files = append(b.files, f)
+	}
+	return nil
+}
+
+// Do calls f with each buffer in the order they were appended.
+// Buffers that were spilled are read back one at a time.
+// The buffer passed to f is released after f returns so f must
+// retain it if it needs to keep a reference.
+//
+// Do consumes the contents of the Buffer.
+func (b *Buffer) Do(f func(cr flux.ColReader) error) error {
+	defer b.Release()
+	for len(b.files) > 0 {
+		file := b.files[0]
+		if err := b.readFile(file, f); err != nil {
+			return err
+		}
+		_ = file.Remove()
+		b.files = b.files[1:]
+	}
+	for len(b.bufs) > 0 {
+		cr := b.bufs[0]
+		b.bufs = b.bufs[1:]
+		err := f(cr)
+		cr.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Buffer) readFile(file *File, f func(cr flux.ColReader) error) error {
+	r, err := file.Open(b.mem)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	for {
+		cr, err := r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		err = f(cr)
+		cr.Release()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Release removes any spilled files and releases
+// the buffers held in memory.
+func (b *Buffer) Release() {
+	for _, f := range b.files {
+		_ = f.Remove()
+	}
+	b.files = nil
+	for _, cr := range b.bufs {
+		cr.Release()
+	}
+	b.bufs = nil
+	b.n = 0
+}
+
+// Table returns a table that reads the contents of the buffer.
+// Every buffer is converted to the given columns with columns that
+// are missing from a buffer filled with null values.
+//
+// The table takes ownership of the contents of the Buffer.
+func (b *Buffer) Table(cols []flux.ColMeta) flux.Table {
+	return &bufferTable{
+		buf:   b,
+		cols:  cols,
+		empty: b.n == 0,
+	}
+}
+
+// bufferTable is a flux.Table that reads its buffers from a Buffer.
+type bufferTable struct {
+	used  int32
+	buf   *Buffer
+	cols  []flux.ColMeta
+	empty bool
+}
+
+func (t *bufferTable) Key() flux.GroupKey   { return t.buf.key }
+func (t *bufferTable) Cols() []flux.ColMeta { return t.cols }
+func (t *bufferTable) Empty() bool          { return t.empty }
+
+func (t *bufferTable) Do(f func(flux.ColReader) error) error {
+	if !atomic.CompareAndSwapInt32(&t.used, 0, 1) {
+		return errors.New(codes.Internal, "table already read")
+	}
+	return t.buf.Do(func(cr flux.ColReader) error {
+		if colsEqual(cr.Cols(), t.cols) {
+			return f(cr)
+		}
+		buf := normalize(cr, t.cols, t.buf.mem)
+		defer buf.Release()
+		return f(buf)
+	})
+}
+
+func (t *bufferTable) Done() {
+	if atomic.CompareAndSwapInt32(&t.used, 0, 1) {
+		t.buf.Release()
+	}
+}
+
+// normalize constructs a buffer with the given columns from cr.
+// Columns that are not present in cr are filled with null values.
+func normalize(cr flux.ColReader, cols []flux.ColMeta, mem memory.Allocator) *arrow.TableBuffer {
+	buf := &arrow.TableBuffer{
+		GroupKey: cr.Key(),
+		Columns:  cols,
+		Values:   make([]array.Array, len(cols)),
+	}
+	for j, col := range cols {
+		idx := -1
+		for i, c := range cr.Cols() {
+			if c.Label == col.Label {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			buf.Values[j] = arrow.Nulls(col.Type, cr.Len(), mem)
+			continue
+		}
+		buf.Values[j] = table.Values(cr, idx)
+		buf.Values[j].Retain()
+	}
+	return buf
+}
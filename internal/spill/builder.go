@@ -0,0 +1,119 @@
+package spill
+
+import (
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+)
+
+// Builder is a table.Builder that holds the buffers of a table
+// in a Buffer so they can be spilled to disk.
+//
+// Similar to table.BufferedBuilder, the table that is built contains
+// the union of the columns of every buffer and columns that are missing
+// from a buffer are filled with null values.
+type Builder struct {
+	key  flux.GroupKey
+	dir  string
+	mem  memory.Allocator
+	cols []flux.ColMeta
+	buf  *Buffer
+}
+
+// NewBuilder constructs a new Builder that spills to files in dir.
+func NewBuilder(key flux.GroupKey, dir string, mem memory.Allocator) *Builder {
+	return &Builder{
+		key: key,
+		dir: dir,
+		mem: mem,
+		buf: NewBuffer(key, dir, mem),
+	}
+}
+
+// GetBuilder is a convenience method for retrieving a
+// Builder from the BuilderCache.
+func GetBuilder(key flux.GroupKey, cache *table.BuilderCache) (builder *Builder, created bool) {
+	created = cache.Get(key, &builder)
+	return builder, created
+}
+
+// SpillCache spills the buffers of every Builder in the cache to disk.
+// Builders of other types are ignored.
+func SpillCache(cache *table.BuilderCache) error {
+	return cache.ForEach(func(key flux.GroupKey, builder table.Builder) error {
+		if b, ok := builder.(*Builder); ok {
+			return b.Spill()
+		}
+		return nil
+	})
+}
+
+// AppendCols adds the columns to the schema of the table.
+// It returns an error if a column already exists with a different type.
+func (b *Builder) AppendCols(cols []flux.ColMeta) error {
+	if b.cols == nil {
+		b.cols = make([]flux.ColMeta, len(cols))
+		copy(b.cols, cols)
+		return nil
+	}
+
+	for _, c := range cols {
+		idx := -1
+		for i, ec := range b.cols {
+			if ec.Label == c.Label {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			b.cols = append(b.cols, c)
+			continue
+		}
+
+		// Verify the column type is the same.
+		if ec := b.cols[idx]; ec.Type != c.Type {
+			return errors.Newf(codes.FailedPrecondition, "schema collision detected: column \"%s\" is both of type %s and %s", c.Label, c.Type, ec.Type)
+		}
+	}
+	return nil
+}
+
+// AppendTable appends all of the buffers inside of the table.
+func (b *Builder) AppendTable(tbl flux.Table) error {
+	if err := b.AppendCols(tbl.Cols()); err != nil {
+		return err
+	}
+	return tbl.Do(func(cr flux.ColReader) error {
+		b.buf.Append(cr)
+		return nil
+	})
+}
+
+// AppendBuffer appends a buffer to the table.
+func (b *Builder) AppendBuffer(cr flux.ColReader) error {
+	if err := b.AppendCols(cr.Cols()); err != nil {
+		return err
+	}
+	b.buf.Append(cr)
+	return nil
+}
+
+// Spill writes the buffers held in memory to disk.
+func (b *Builder) Spill() error {
+	return b.buf.Spill()
+}
+
+// Table constructs a table that reads the buffers from memory
+// or from disk. The builder is reset.
+func (b *Builder) Table() (flux.Table, error) {
+	tbl := b.buf.Table(b.cols)
+	b.buf = NewBuffer(b.key, b.dir, b.mem)
+	return tbl, nil
+}
+
+// Release releases the buffers and removes any spilled files.
+func (b *Builder) Release() {
+	b.buf.Release()
+}
@@ -0,0 +1,180 @@
+package spill_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/execute/table/static"
+	"github.com/influxdata/flux/internal/spill"
+	"github.com/influxdata/flux/memory"
+)
+
+func TestBuilder_AppendTable(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		in      static.TableGroup
+		want    static.Table
+		wantErr string
+	}{
+		{
+			name: "OneTable",
+			in: static.TableGroup{
+				static.StringKey("_measurement", "m0"),
+				static.StringKey("_field", "f0"),
+				static.Table{
+					static.Times("_time", "2020-01-01T00:00:00Z", 10, 20, 30, 40),
+					static.Ints("_value", 5, 3, nil, 9, 2),
+				},
+			},
+			want: static.Table{
+				static.StringKey("_measurement", "m0"),
+				static.StringKey("_field", "f0"),
+				static.Times("_time", "2020-01-01T00:00:00Z", 10, 20, 30, 40),
+				static.Ints("_value", 5, 3, nil, 9, 2),
+			},
+		},
+		{
+			name: "TwoTables",
+			in: static.TableGroup{
+				static.StringKey("_measurement", "m0"),
+				static.Table{
+					static.Times("_time", "2020-01-01T00:00:00Z", 10, 20, 30),
+					static.Strings("_value", "a", "b", "c", "d"),
+				},
+				static.Table{
+					static.Times("_time", "2020-01-01T00:00:40Z", 10, 20),
+					static.Strings("_value", "e", "f", "g"),
+				},
+			},
+			want: static.Table{
+				static.StringKey("_measurement", "m0"),
+				static.Times("_time", "2020-01-01T00:00:00Z", 10, 20, 30, 40, 50, 60),
+				static.Strings("_value", "a", "b", "c", "d", "e", "f", "g"),
+			},
+		},
+		{
+			name: "FillNulls",
+			in: static.TableGroup{
+				static.StringKey("_measurement", "m0"),
+				static.Table{
+					static.Times("_time", "2020-01-01T00:00:00Z", 10, 20),
+					static.Floats("f0", 3, 8, 2),
+					static.Ints("f1", 5, 9, 2),
+				},
+				static.Table{
+					static.Times("_time", "2020-01-01T00:00:00Z", 10, 20),
+					static.Floats("f0", 18, 2, 7),
+				},
+			},
+			want: static.Table{
+				static.StringKey("_measurement", "m0"),
+				static.Times("_time", "2020-01-01T00:00:00Z", 10, 20, "2020-01-01T00:00:00Z", 10, 20),
+				static.Floats("f0", 3, 8, 2, 18, 2, 7),
+				static.Ints("f1", 5, 9, 2, nil, nil, nil),
+			},
+		},
+		{
+			name: "ConflictingSchema",
+			in: static.TableGroup{
+				static.Table{
+					static.Times("_time", "2020-01-01T00:00:00Z", 10, 20),
+					static.Floats("_value", 3, 8, 2),
+				},
+				static.Table{
+					static.Times("_time", "2020-01-01T00:00:00Z", 10, 20),
+					static.Ints("_value", 5, 9, 2),
+				},
+			},
+			wantErr: `schema collision detected: column "_value" is both of type int and float`,
+		},
+	} {
+		for _, spilled := range []bool{false, true} {
+			name := tt.name
+			if spilled {
+				name += "/Spilled"
+			}
+			t.Run(name, func(t *testing.T) {
+				dir := t.TempDir()
+				mem := arrowmem.NewCheckedAllocator(memory.DefaultAllocator)
+				defer mem.AssertSize(t, 0)
+
+				var b *spill.Builder
+				if err := tt.in.Do(func(tbl flux.Table) error {
+					if b == nil {
+						b = spill.NewBuilder(tbl.Key(), dir, mem)
+					}
+					if err := b.AppendTable(tbl); err != nil {
+						return err
+					}
+					if spilled {
+						return b.Spill()
+					}
+					return nil
+				}); err != nil {
+					if b != nil {
+						b.Release()
+					}
+					if want, got := tt.wantErr, err.Error(); want != got {
+						t.Errorf("unexpected error -want/+got:\n\t- %s\n\t+ %s", want, got)
+					}
+					return
+				}
+
+				if tt.wantErr != "" {
+					t.Fatal("expected error")
+				}
+
+				out, err := b.Table()
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				want, got := tt.want, table.Iterator{out}
+
+				if diff := table.Diff(want, got); diff != "" {
+					t.Fatalf("unexpected diff -want/+got:\n%s", diff)
+				}
+
+				// Every spill file should have been removed
+				// once the table was read.
+				files, err := filepath.Glob(filepath.Join(dir, "*"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(files) != 0 {
+					t.Errorf("spill files were not removed: %v", files)
+				}
+			})
+		}
+	}
+}
+
+func TestShouldSpill(t *testing.T) {
+	limit := int64(1024)
+	mem := &memory.ResourceAllocator{Limit: &limit}
+	dir := t.TempDir()
+	if spill.ShouldSpill(dir, mem, 512) {
+		t.Error("unexpected spill when memory is available")
+	}
+	if !spill.ShouldSpill(dir, mem, 2048) {
+		t.Error("expected spill when memory is not available")
+	}
+	if spill.ShouldSpill("", mem, 2048) {
+		t.Error("unexpected spill without a spill directory")
+	}
+	if spill.ShouldSpill(dir, memory.DefaultAllocator, 1<<40) {
+		t.Error("unexpected spill with an allocator that does not limit memory")
+	}
+}
+
+func TestCreate_DirDoesNotExist(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	if _, err := spill.Create(dir, nil, []flux.ColMeta{{Label: "_value", Type: flux.TInt}}); err == nil {
+		t.Fatal("expected error")
+	} else if _, statErr := os.Stat(dir); !os.IsNotExist(statErr) {
+		t.Errorf("unexpected directory: %v", statErr)
+	}
+}
@@ -0,0 +1,313 @@
+// Package spill writes table buffers to temporary files so that
+// transformations which buffer large amounts of data can release
+// memory when the query allocator runs out of it, and read the
+// data back when it is needed again.
+package spill
+
+import (
+	"io"
+	"os"
+
+	stdarrow "github.com/apache/arrow/go/v7/arrow"
+	arrowarray "github.com/apache/arrow/go/v7/arrow/array"
+	"github.com/apache/arrow/go/v7/arrow/ipc"
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+)
+
+// filePattern is the pattern used to name the temporary files.
+const filePattern = "flux-spill-*.arrow"
+
+// ShouldSpill reports whether buffered data should be written to files
+// in dir before buffering size more bytes. This is the case when the
+// allocator is unable to provide size more bytes.
+//
+// Spilling is disabled when dir is empty. Allocators that do not
+// limit memory never need to spill.
+func ShouldSpill(dir string, mem memory.Allocator, size int) bool {
+	if dir == "" {
+		return false
+	}
+	a, ok := mem.(interface{ CanAllocate(size int) bool })
+	return ok && !a.CanAllocate(size)
+}
+
+// Size returns the approximate number of bytes held by the buffer.
+func Size(cr flux.ColReader) int {
+	n := 0
+	for j := range cr.Cols() {
+		data := table.Values(cr, j).Data()
+		if data == nil {
+			continue
+		}
+		for _, buf := range data.Buffers() {
+			if buf != nil {
+				n += buf.Len()
+			}
+		}
+	}
+	return n
+}
+
+// File is a temporary file that holds a sequence of table buffers
+// with the same group key and schema.
+type File struct {
+	path string
+	key  flux.GroupKey
+	cols []flux.ColMeta
+	n    int
+}
+
+// Key returns the group key of the buffers in the file.
+func (f *File) Key() flux.GroupKey { return f.key }
+
+// Cols returns the columns of the buffers in the file.
+func (f *File) Cols() []flux.ColMeta { return f.cols }
+
+// Len returns the number of rows in the file.
+func (f *File) Len() int { return f.n }
+
+// Open opens the file for reading.
+// Buffers that are read will be allocated with mem.
+func (f *File) Open(mem memory.Allocator) (*Reader, error) {
+	fp, err := os.Open(f.path)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "could not open spill file")
+	}
+	rr, err := ipc.NewReader(fp, ipc.WithAllocator(mem))
+	if err != nil {
+		_ = fp.Close()
+		return nil, errors.Wrap(err, codes.Internal, "could not read spill file")
+	}
+	return &Reader{f: f, fp: fp, rr: rr}, nil
+}
+
+// Remove deletes the file from disk.
+func (f *File) Remove() error {
+	return os.Remove(f.path)
+}
+
+// Writer writes table buffers to a new File.
+type Writer struct {
+	fp     *os.File
+	w      *ipc.Writer
+	schema *stdarrow.Schema
+	file   *File
+}
+
+// Create creates a new temporary file in dir for buffers
+// with the given group key and columns.
+// If dir is empty, the default directory for temporary files is used.
+func Create(dir string, key flux.GroupKey, cols []flux.ColMeta) (*Writer, error) {
+	schema, err := newSchema(cols)
+	if err != nil {
+		return nil, err
+	}
+	fp, err := os.CreateTemp(dir, filePattern)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Internal, "could not create spill file")
+	}
+	return &Writer{
+		fp: fp,
+		// The writer only allocates scratch space while a record
+		// is written. It does not use the query allocator because
+		// that is likely to be out of memory when spilling.
+		w:      ipc.NewWriter(fp, ipc.WithSchema(schema), ipc.WithAllocator(memory.NewGoAllocator())),
+		schema: schema,
+		file: &File{
+			path: fp.Name(),
+			key:  key,
+			cols: cols,
+		},
+	}, nil
+}
+
+// Write appends the buffer to the file.
+// The buffer must have the same columns as the file.
+// Large buffers are split into records of at most
+// table.BufferSize rows so they can be read back
+// with a bounded amount of memory.
+func (w *Writer) Write(cr flux.ColReader) error {
+	if !colsEqual(cr.Cols(), w.file.cols) {
+		return errors.New(codes.Internal, "spill buffer schema does not match the file")
+	}
+
+	n := cr.Len()
+	cols := make([]stdarrow.Array, len(w.file.cols))
+	defer func() {
+		for _, col := range cols {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+	for j := range cols {
+		cols[j] = toArrowArray(table.Values(cr, j), n)
+	}
+
+	for i := 0; i < n; i += table.BufferSize {
+		end := i + table.BufferSize
+		if end > n {
+			end = n
+		}
+		if err := w.writeRecord(cols, int64(i), int64(end)); err != nil {
+			return err
+		}
+	}
+	w.file.n += n
+	return nil
+}
+
+func (w *Writer) writeRecord(cols []stdarrow.Array, i, j int64) error {
+	slices := make([]stdarrow.Array, len(cols))
+	for k, col := range cols {
+		slices[k] = arrowarray.NewSlice(col, i, j)
+	}
+	rec := arrowarray.NewRecord(w.schema, slices, j-i)
+	for _, s := range slices {
+		s.Release()
+	}
+	defer rec.Release()
+	if err := w.w.Write(rec); err != nil {
+		return errors.Wrap(err, codes.Internal, "could not write spill file")
+	}
+	return nil
+}
+
+// Close finishes writing and returns the File that was written.
+// If writing the file failed, the file is removed.
+func (w *Writer) Close() (*File, error) {
+	err := w.w.Close()
+	if cerr := w.fp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = w.file.Remove()
+		return nil, errors.Wrap(err, codes.Internal, "could not write spill file")
+	}
+	return w.file, nil
+}
+
+// Abort stops writing and removes the file.
+func (w *Writer) Abort() {
+	_ = w.w.Close()
+	_ = w.fp.Close()
+	_ = w.file.Remove()
+}
+
+// Reader reads the buffers that were written to a File.
+type Reader struct {
+	f  *File
+	fp *os.File
+	rr *ipc.Reader
+}
+
+// Read returns the next buffer in the file.
+// The caller is responsible for releasing the buffer.
+// It returns io.EOF when there are no more buffers.
+func (r *Reader) Read() (flux.ColReader, error) {
+	if !r.rr.Next() {
+		if err := r.rr.Err(); err != nil && err != io.EOF {
+			return nil, errors.Wrap(err, codes.Internal, "could not read spill file")
+		}
+		return nil, io.EOF
+	}
+	rec := r.rr.Record()
+	buffer := &arrow.TableBuffer{
+		GroupKey: r.f.key,
+		Columns:  r.f.cols,
+		Values:   make([]array.Array, len(r.f.cols)),
+	}
+	for j := range buffer.Values {
+		buffer.Values[j] = fromArrowArray(rec.Column(j))
+	}
+	return buffer, nil
+}
+
+// Close closes the file. It does not remove it.
+func (r *Reader) Close() error {
+	r.rr.Release()
+	return r.fp.Close()
+}
+
+func newSchema(cols []flux.ColMeta) (*stdarrow.Schema, error) {
+	fields := make([]stdarrow.Field, len(cols))
+	for j, col := range cols {
+		var typ stdarrow.DataType
+		switch col.Type {
+		case flux.TInt, flux.TTime:
+			typ = stdarrow.PrimitiveTypes.Int64
+		case flux.TUInt:
+			typ = stdarrow.PrimitiveTypes.Uint64
+		case flux.TFloat:
+			typ = stdarrow.PrimitiveTypes.Float64
+		case flux.TString:
+			// Strings are stored as binary data by flux.
+			typ = stdarrow.BinaryTypes.Binary
+		case flux.TBool:
+			typ = stdarrow.FixedWidthTypes.Boolean
+		default:
+			return nil, errors.Newf(codes.Internal, "cannot spill column %q of type %s", col.Label, col.Type)
+		}
+		fields[j] = stdarrow.Field{Name: col.Label, Type: typ, Nullable: true}
+	}
+	return stdarrow.NewSchema(fields, nil), nil
+}
+
+// toArrowArray returns an arrow array with the same contents as arr.
+// The returned array must be released.
+func toArrowArray(arr array.Array, n int) stdarrow.Array {
+	if s, ok := arr.(*array.String); ok && s.IsConstant() {
+		// Constant strings do not have any arrow data
+		// so they need to be materialized. The materialized
+		// array only lives until it is written so it is not
+		// allocated from the query allocator which is likely
+		// to be out of memory when spilling.
+		b := arrowarray.NewBinaryBuilder(memory.NewGoAllocator(), stdarrow.BinaryTypes.Binary)
+		defer b.Release()
+		b.Reserve(n)
+		v := s.Value(0)
+		for i := 0; i < n; i++ {
+			b.AppendString(v)
+		}
+		return b.NewArray()
+	}
+	data := arr.Data()
+	if data.DataType().ID() == stdarrow.STRING {
+		// Strings may also be utf8 arrays which have the same
+		// layout as binary arrays.
+		bin := arrowarray.NewData(stdarrow.BinaryTypes.Binary, data.Len(), data.Buffers(), nil, data.NullN(), data.Offset())
+		defer bin.Release()
+		return arrowarray.MakeFromData(bin)
+	}
+	return arrowarray.MakeFromData(data)
+}
+
+// fromArrowArray converts an array read from a spill file
+// into the array type that flux uses for it.
+func fromArrowArray(arr stdarrow.Array) array.Array {
+	if arr.DataType().ID() == stdarrow.BINARY {
+		data := arrowarray.NewBinaryData(arr.Data())
+		defer data.Release()
+		return array.NewStringFromBinaryArray(data)
+	}
+	arr.Retain()
+	return arr
+}
+
+func colsEqual(a, b []flux.ColMeta) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -422,7 +422,7 @@ func TestEval(t *testing.T) {
 		{
 			name:    "invalid function parameter",
 			query:   `from(bucket: "telegraf") |> window(every: 0s)`,
-			wantErr: `error calling function "window" @\d+:\d+-\d+:\d+: window function requires at least one of "every" or "period" to be set and non-zero`,
+			wantErr: `error calling function "window" @\d+:\d+-\d+:\d+: window function requires one of "every", "period", "gap" or "count" to be set and non-zero`,
 		},
 		{
 			// tests that we don't nest error messages when
@@ -430,7 +430,7 @@ func TestEval(t *testing.T) {
 			// function.
 			name:    "nested function error",
 			query:   `from(bucket: "telegraf") |> window(every: 0s) |> mean()`,
-			wantErr: `error calling function "window" @\d+:\d+-\d+:\d+: window function requires at least one of "every" or "period" to be set and non-zero`,
+			wantErr: `error calling function "window" @\d+:\d+-\d+:\d+: window function requires one of "every", "period", "gap" or "count" to be set and non-zero`,
 		},
 	}
 
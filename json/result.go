@@ -0,0 +1,270 @@
+// Package json contains result encoders that write results as JSON.
+//
+// Every row of a table is written as a single object:
+//
+//	{"result":"_result","table":0,"group":["host"],"record":{"_time":"2018-04-17T00:00:00Z","host":"A","_value":42}}
+//
+// The objects are either written as the elements of one JSON array
+// or as newline delimited JSON (NDJSON) with one object on each line.
+// Empty tables do not have any rows and are not written.
+package json
+
+import (
+	stdjson "encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/iocounter"
+)
+
+// ResultEncoderConfig are options that can be specified on the MultiResultEncoder.
+type ResultEncoderConfig struct {
+	// NDJSON writes each row on its own line instead
+	// of writing all of the rows in a single array.
+	NDJSON bool
+}
+
+// MultiResultEncoder encodes multiple results as JSON.
+//
+// If an error is encountered when iterating and the error is an encoder error,
+// the error will be returned. Otherwise, the error is assumed to have arisen
+// from query execution and is written as an object with an error property
+// if any rows have already been written.
+type MultiResultEncoder struct {
+	c ResultEncoderConfig
+}
+
+// NewMultiResultEncoder creates a new MultiResultEncoder.
+func NewMultiResultEncoder(c ResultEncoderConfig) *MultiResultEncoder {
+	return &MultiResultEncoder{c: c}
+}
+
+type jsonEncoderError struct {
+	err error
+}
+
+func (e *jsonEncoderError) Error() string {
+	return e.err.Error()
+}
+
+func (e *jsonEncoderError) IsEncoderError() bool {
+	return true
+}
+
+func (e *jsonEncoderError) Unwrap() error {
+	return e.err
+}
+
+func wrapEncodingError(err error) error {
+	if err == nil {
+		return err
+	}
+	return &jsonEncoderError{err: err}
+}
+
+func isEncoderError(err error) bool {
+	encErr, ok := err.(flux.EncoderError)
+	return ok && encErr.IsEncoderError()
+}
+
+type flusher interface {
+	Flush()
+}
+
+func (e *MultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	wc := &iocounter.Writer{Writer: w}
+	rw := &rowWriter{w: wc, ndjson: e.c.NDJSON}
+
+	for results.More() {
+		result := results.Next()
+		if err := rw.writeResult(result); err != nil {
+			// If we have an error that's from encoding or if we have not
+			// yet written any data to the writer, return the error.
+			if isEncoderError(err) || wc.Count() == 0 {
+				return wc.Count(), err
+			}
+			// Otherwise, the error happened during query execution and we
+			// are stuck encoding it.
+			err := rw.writeError(err)
+			return wc.Count(), err
+		}
+		// Flush the writer after each result.
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+	}
+	results.Release()
+
+	if err := results.Err(); err != nil {
+		if wc.Count() == 0 {
+			return 0, err
+		}
+		err := rw.writeError(err)
+		return wc.Count(), err
+	}
+	err := rw.close()
+	return wc.Count(), err
+}
+
+// rowWriter writes rows as JSON objects.
+type rowWriter struct {
+	w      io.Writer
+	ndjson bool
+	buf    []byte
+	// started is set when the opening bracket
+	// of the array has been written.
+	started bool
+}
+
+func (rw *rowWriter) writeResult(result flux.Result) error {
+	name, err := stdjson.Marshal(result.Name())
+	if err != nil {
+		return wrapEncodingError(err)
+	}
+	tableID := 0
+	return result.Tables().Do(func(tbl flux.Table) error {
+		// The prefix of the objects is the same for every row in the table.
+		prefix := append([]byte(`{"result":`), name...)
+		prefix = append(prefix, `,"table":`...)
+		prefix = strconv.AppendInt(prefix, int64(tableID), 10)
+		prefix = append(prefix, `,"group":[`...)
+		for j, c := range tbl.Key().Cols() {
+			if j > 0 {
+				prefix = append(prefix, ',')
+			}
+			prefix = appendString(prefix, c.Label)
+		}
+		prefix = append(prefix, `],"record":{`...)
+		tableID++
+
+		cols := tbl.Cols()
+		labels := make([][]byte, len(cols))
+		for j, c := range cols {
+			if c.Type == flux.TInvalid {
+				return wrapEncodingError(errors.Newf(codes.Invalid, "unknown column type %s", c.Type))
+			}
+			labels[j] = append(appendString(nil, c.Label), ':')
+		}
+
+		return tbl.Do(func(cr flux.ColReader) error {
+			for i, n := 0, cr.Len(); i < n; i++ {
+				rw.buf = append(rw.buf[:0], prefix...)
+				for j, c := range cols {
+					if j > 0 {
+						rw.buf = append(rw.buf, ',')
+					}
+					rw.buf = append(rw.buf, labels[j]...)
+					rw.buf = appendValue(rw.buf, cr, i, j, c.Type)
+				}
+				rw.buf = append(rw.buf, "}}"...)
+				if err := rw.writeRow(rw.buf); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// writeRow writes a single object with the separator that precedes it.
+func (rw *rowWriter) writeRow(row []byte) error {
+	var sep string
+	switch {
+	case rw.ndjson:
+	case !rw.started:
+		sep = "[\n"
+		rw.started = true
+	default:
+		sep = ",\n"
+	}
+	if _, err := io.WriteString(rw.w, sep); err != nil {
+		return wrapEncodingError(err)
+	}
+	if _, err := rw.w.Write(row); err != nil {
+		return wrapEncodingError(err)
+	}
+	if rw.ndjson {
+		if _, err := io.WriteString(rw.w, "\n"); err != nil {
+			return wrapEncodingError(err)
+		}
+	}
+	return nil
+}
+
+// writeError writes an error as the last object.
+func (rw *rowWriter) writeError(err error) error {
+	row := append([]byte(`{"error":`), appendString(nil, err.Error())...)
+	row = append(row, '}')
+	if err := rw.writeRow(row); err != nil {
+		return err
+	}
+	return rw.close()
+}
+
+// close ends the array of rows.
+func (rw *rowWriter) close() error {
+	if rw.ndjson {
+		return nil
+	}
+	end := "\n]\n"
+	if !rw.started {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(rw.w, end)
+	return wrapEncodingError(err)
+}
+
+// appendValue appends the JSON representation of a value to buf.
+// Times are written in RFC3339 format and floats that cannot be
+// represented by JSON numbers are written as strings.
+func appendValue(buf []byte, cr flux.ColReader, i, j int, typ flux.ColType) []byte {
+	switch typ {
+	case flux.TBool:
+		if vs := cr.Bools(j); vs.IsValid(i) {
+			return strconv.AppendBool(buf, vs.Value(i))
+		}
+	case flux.TInt:
+		if vs := cr.Ints(j); vs.IsValid(i) {
+			return strconv.AppendInt(buf, vs.Value(i), 10)
+		}
+	case flux.TUInt:
+		if vs := cr.UInts(j); vs.IsValid(i) {
+			return strconv.AppendUint(buf, vs.Value(i), 10)
+		}
+	case flux.TFloat:
+		if vs := cr.Floats(j); vs.IsValid(i) {
+			v := vs.Value(i)
+			switch {
+			case math.IsNaN(v):
+				return append(buf, `"NaN"`...)
+			case math.IsInf(v, 1):
+				return append(buf, `"+Inf"`...)
+			case math.IsInf(v, -1):
+				return append(buf, `"-Inf"`...)
+			}
+			return strconv.AppendFloat(buf, v, 'g', -1, 64)
+		}
+	case flux.TString:
+		if vs := cr.Strings(j); vs.IsValid(i) {
+			return appendString(buf, vs.Value(i))
+		}
+	case flux.TTime:
+		if vs := cr.Times(j); vs.IsValid(i) {
+			buf = append(buf, '"')
+			buf = time.Unix(0, vs.Value(i)).UTC().AppendFormat(buf, time.RFC3339Nano)
+			return append(buf, '"')
+		}
+	}
+	return append(buf, "null"...)
+}
+
+// appendString appends s as a JSON string.
+func appendString(buf []byte, s string) []byte {
+	b, _ := stdjson.Marshal(s)
+	return append(buf, b...)
+}
@@ -0,0 +1,164 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/diff"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/json"
+	"github.com/influxdata/flux/values"
+)
+
+func testResults() []flux.Result {
+	return []flux.Result{
+		&executetest.Result{
+			Nm: "_result",
+			Tbls: []*executetest.Table{
+				{
+					KeyCols: []string{"host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "n", Type: flux.TInt},
+						{Label: "ok", Type: flux.TBool},
+					},
+					Data: [][]interface{}{
+						{values.ConvertTime(time.Date(2018, 4, 17, 0, 0, 0, 0, time.UTC)), "A", 42.0, int64(1), true},
+						{values.ConvertTime(time.Date(2018, 4, 17, 0, 0, 1, 500, time.UTC)), "A", math.NaN(), nil, nil},
+					},
+				},
+				{
+					KeyCols:   []string{"host"},
+					KeyValues: []interface{}{"B"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "host", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+					},
+				},
+				{
+					KeyCols: []string{"host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "host", Type: flux.TString},
+						{Label: "u", Type: flux.TUInt},
+					},
+					Data: [][]interface{}{
+						{"C\"", uint64(7)},
+					},
+				},
+			},
+		},
+		&executetest.Result{
+			Nm: "other",
+			Tbls: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{1.5},
+				},
+			}},
+		},
+	}
+}
+
+func TestMultiResultEncoder(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  json.ResultEncoderConfig
+		results []flux.Result
+		encoded string
+		err     error
+	}{
+		{
+			name:    "json",
+			results: testResults(),
+			encoded: `[
+{"result":"_result","table":0,"group":["host"],"record":{"_time":"2018-04-17T00:00:00Z","host":"A","_value":42,"n":1,"ok":true}},
+{"result":"_result","table":0,"group":["host"],"record":{"_time":"2018-04-17T00:00:01.0000005Z","host":"A","_value":"NaN","n":null,"ok":null}},
+{"result":"_result","table":2,"group":["host"],"record":{"host":"C\"","u":7}},
+{"result":"other","table":0,"group":[],"record":{"_value":1.5}}
+]
+`,
+		},
+		{
+			name:    "ndjson",
+			config:  json.ResultEncoderConfig{NDJSON: true},
+			results: testResults(),
+			encoded: `{"result":"_result","table":0,"group":["host"],"record":{"_time":"2018-04-17T00:00:00Z","host":"A","_value":42,"n":1,"ok":true}}
+{"result":"_result","table":0,"group":["host"],"record":{"_time":"2018-04-17T00:00:01.0000005Z","host":"A","_value":"NaN","n":null,"ok":null}}
+{"result":"_result","table":2,"group":["host"],"record":{"host":"C\"","u":7}}
+{"result":"other","table":0,"group":[],"record":{"_value":1.5}}
+`,
+		},
+		{
+			name:    "no rows",
+			encoded: "[]\n",
+		},
+		{
+			name: "error after rows",
+			results: func() []flux.Result {
+				results := testResults()
+				results[1].(*executetest.Result).Err = errors.New("test error")
+				return results
+			}(),
+			encoded: `[
+{"result":"_result","table":0,"group":["host"],"record":{"_time":"2018-04-17T00:00:00Z","host":"A","_value":42,"n":1,"ok":true}},
+{"result":"_result","table":0,"group":["host"],"record":{"_time":"2018-04-17T00:00:01.0000005Z","host":"A","_value":"NaN","n":null,"ok":null}},
+{"result":"_result","table":2,"group":["host"],"record":{"host":"C\"","u":7}},
+{"error":"test error"}
+]
+`,
+		},
+		{
+			name: "error before rows",
+			results: []flux.Result{&executetest.Result{
+				Nm:  "_result",
+				Err: errors.New("test error"),
+			}},
+			err: errors.New("test error"),
+		},
+		{
+			name: "returns encoding errors",
+			results: []flux.Result{&executetest.Result{
+				Nm: "_result",
+				Tbls: []*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInvalid},
+					},
+				}},
+			}},
+			err: errors.New("unknown column type invalid"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			encoder := json.NewMultiResultEncoder(tc.config)
+			var got bytes.Buffer
+			n, err := encoder.Encode(&got, flux.NewSliceResultIterator(tc.results))
+			if err != nil && tc.err != nil {
+				if err.Error() != tc.err.Error() {
+					t.Errorf("unexpected error want: %s\n got: %s\n", tc.err.Error(), err.Error())
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error want: none\n got: %s\n", err.Error())
+			} else if tc.err != nil {
+				t.Errorf("unexpected error want: %s\n got: none", tc.err.Error())
+			}
+
+			if g, w := got.String(), tc.encoded; g != w {
+				t.Errorf("unexpected encoding -want/+got:\n%s", diff.LineDiff(w, g))
+			}
+			if g, w := n, int64(got.Len()); g != w {
+				t.Errorf("unexpected encoding count want: %d got: %d", w, g)
+			}
+		})
+	}
+}
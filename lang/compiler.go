@@ -51,7 +51,9 @@ func AddCompilerMappings(mappings flux.CompilerMappings) error {
 type CompileOption func(*compileOptions)
 
 type compileOptions struct {
-	extern flux.ASTHandle
+	extern         flux.ASTHandle
+	partialResults bool
+	streaming      bool
 
 	planOptions struct {
 		logical  []plan.LogicalOption
@@ -75,6 +77,25 @@ func WithExtern(extern flux.ASTHandle) CompileOption {
 	}
 }
 
+// WithPartialResults makes the program return the data read so far
+// when the context it is started with is cancelled or its deadline
+// is reached, instead of failing. See execute.WithPartialResults.
+func WithPartialResults() CompileOption {
+	return func(o *compileOptions) {
+		o.partialResults = true
+	}
+}
+
+// WithStreaming makes the program run in streaming mode.
+// Its results only end when the context it is started with is cancelled
+// and windows emit their tables as the watermark advances.
+// See execute.WithStreaming.
+func WithStreaming() CompileOption {
+	return func(o *compileOptions) {
+		o.streaming = true
+	}
+}
+
 func defaultOptions() *compileOptions {
 	o := new(compileOptions)
 	return o
@@ -136,6 +157,9 @@ func buildPlan(ctx context.Context, spec *operation.Spec, opts *compileOptions)
 	s, _ := opentracing.StartSpanFromContext(ctx, "plan")
 	defer s.Finish()
 	pb := plan.PlannerBuilder{}
+	if opts.streaming {
+		ctx = execute.WithStreaming(ctx)
+	}
 
 	planOptions := opts.planOptions
 
@@ -264,6 +288,14 @@ func (p *Program) SetLogger(logger *zap.Logger) {
 }
 
 func (p *Program) Start(ctx context.Context, alloc memory.Allocator) (flux.Query, error) {
+	// Cancelling the context stops the sources instead of the query
+	// when partial results are enabled. Cancel and Done still stop the query.
+	if p.opts != nil && p.opts.partialResults && !execute.PartialResultsEnabled(ctx) {
+		ctx = execute.WithPartialResults(ctx)
+	}
+	if p.opts != nil && p.opts.streaming && !execute.StreamingEnabled(ctx) {
+		ctx = execute.WithStreaming(ctx)
+	}
 	ctx, cancel := context.WithCancel(ctx)
 
 	// This span gets closed by the query when it is done.
@@ -345,6 +377,39 @@ func (p *Program) readStatistics(q *query, statsCh <-chan flux.Statistics) {
 	}
 }
 
+// ExplainAnalyze executes the program and returns its physical plan
+// annotated with the statistics collected for each node.
+// The results of the program are read and discarded.
+func (p *Program) ExplainAnalyze(ctx context.Context, alloc memory.Allocator) (*plan.Explanation, error) {
+	return explainAnalyze(ctx, p, p, alloc)
+}
+
+func explainAnalyze(ctx context.Context, prog flux.Program, p *Program, alloc memory.Allocator) (*plan.Explanation, error) {
+	q, err := prog.Start(execute.WithNodeStatistics(ctx), alloc)
+	if err != nil {
+		return nil, err
+	}
+
+	results := flux.NewResultIteratorFromQuery(q)
+	defer results.Release()
+	for results.More() {
+		if err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			tbl.Done()
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	results.Release()
+	if err := results.Err(); err != nil {
+		return nil, err
+	}
+	return &plan.Explanation{
+		Spec:  p.PlanSpec,
+		Nodes: results.Statistics().Nodes,
+	}, nil
+}
+
 // AstProgram wraps a Program with an AST that will be evaluated upon Start.
 // As such, the PlanSpec is populated after Start and evaluation errors are returned by Start.
 type AstProgram struct {
@@ -518,6 +583,12 @@ func (p *AstProgram) Start(ctx context.Context, alloc memory.Allocator) (flux.Qu
 	}, nil
 }
 
+// ExplainAnalyze evaluates and executes the program and returns its
+// physical plan annotated with the statistics collected for each node.
+func (p *AstProgram) ExplainAnalyze(ctx context.Context, alloc memory.Allocator) (*plan.Explanation, error) {
+	return explainAnalyze(ctx, p, p.Program, alloc)
+}
+
 func (p *AstProgram) updateProfilers(ctx context.Context, scope values.Scope) error {
 	if execute.HaveExecutionDependencies(ctx) {
 		deps := execute.GetExecutionDependencies(ctx)
@@ -539,9 +610,7 @@ func (p *AstProgram) updateOpts(scope values.Scope) error {
 	if lo != nil {
 		p.opts.planOptions.logical = append(p.opts.planOptions.logical, lo)
 	}
-	if po != nil {
-		p.opts.planOptions.physical = append(p.opts.planOptions.physical, po)
-	}
+	p.opts.planOptions.physical = append(p.opts.planOptions.physical, po...)
 	return nil
 }
 
@@ -572,7 +641,7 @@ func getPackageFromScope(pkgName string, scope values.Scope) (values.Package, bo
 	return foundPkg, found
 }
 
-func getPlanOptions(plannerPkg values.Package) (plan.LogicalOption, plan.PhysicalOption, error) {
+func getPlanOptions(plannerPkg values.Package) (plan.LogicalOption, []plan.PhysicalOption, error) {
 	if plannerPkg.Type().Nature() != semantic.Object {
 		// No import for planner, this is useless.
 		return nil, nil, nil
@@ -586,7 +655,38 @@ func getPlanOptions(plannerPkg values.Package) (plan.LogicalOption, plan.Physica
 	if err != nil {
 		return nil, nil, err
 	}
-	return plan.RemoveLogicalRules(ls...), plan.RemovePhysicalRules(ps...), nil
+	parallelism, err := getParallelism(plannerPkg.Object())
+	if err != nil {
+		return nil, nil, err
+	}
+	po := []plan.PhysicalOption{plan.RemovePhysicalRules(ps...)}
+	if parallelism.Factor > 0 {
+		// A factor of zero keeps the parallelism the planner was created with.
+		po = append(po, plan.WithParallelism(parallelism))
+	}
+	return plan.RemoveLogicalRules(ls...), po, nil
+}
+
+// getParallelism reads the parallelism options from the planner package.
+func getParallelism(pkg values.Object) (plan.Parallelism, error) {
+	var p plan.Parallelism
+	if v, ok := pkg.Get("parallelism"); ok {
+		if v.Type().Nature() != semantic.Int {
+			return p, errors.Newf(codes.Invalid, "planner.parallelism must be an int but found %s", v.Type())
+		}
+		p.Factor = int(v.Int())
+	}
+	if v, ok := pkg.Get("parallelPartitionBy"); ok {
+		if v.Type().Nature() != semantic.String {
+			return p, errors.Newf(codes.Invalid, "planner.parallelPartitionBy must be a string but found %s", v.Type())
+		}
+		mode, err := plan.ParsePartitionMode(v.Str())
+		if err != nil {
+			return p, err
+		}
+		p.Mode = mode
+	}
+	return p, nil
 }
 
 func getOptionValues(pkg values.Object, optionName string) ([]string, error) {
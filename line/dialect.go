@@ -0,0 +1,42 @@
+package line
+
+import (
+	"net/http"
+
+	"github.com/influxdata/flux"
+)
+
+const DialectType = "line"
+
+// AddDialectMappings adds the line protocol specific dialect mappings.
+func AddDialectMappings(mappings flux.DialectMappings) error {
+	return mappings.Add(DialectType, func() flux.Dialect {
+		return &Dialect{
+			ResultEncoderConfig: DefaultEncoderConfig(),
+		}
+	})
+}
+
+// Dialect describes the output format of queries as line protocol.
+type Dialect struct {
+	ResultEncoderConfig
+}
+
+func (d Dialect) SetHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Transfer-Encoding", "chunked")
+}
+
+func (d Dialect) Encoder() flux.MultiResultEncoder {
+	return NewMultiResultEncoder(d.ResultEncoderConfig)
+}
+
+func (d Dialect) DialectType() flux.DialectType {
+	return DialectType
+}
+
+func DefaultDialect() *Dialect {
+	return &Dialect{
+		ResultEncoderConfig: DefaultEncoderConfig(),
+	}
+}
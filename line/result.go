@@ -0,0 +1,299 @@
+// Package line contains a result encoder that writes results as InfluxDB line protocol.
+//
+// Every row of a table is written as a single point. The measurement is read from
+// the _measurement column and the tags are the other string columns of the group key.
+// When a table has _field and _value columns, each row is a point with a single field
+// named by _field. Otherwise the table is assumed to be wide, as produced by pivot(),
+// and every column outside of the group key other than _time is a field.
+package line
+
+import (
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/iocounter"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+const (
+	measurementColLabel = "_measurement"
+	fieldColLabel       = "_field"
+)
+
+// ResultEncoderConfig are options that can be specified on the ResultEncoder.
+type ResultEncoderConfig struct {
+	// Precision is the precision of the timestamps that are written.
+	// It must be one of time.Nanosecond, time.Microsecond, time.Millisecond
+	// or time.Second. The zero value writes timestamps in nanoseconds.
+	Precision time.Duration
+}
+
+// DefaultEncoderConfig creates a new ResultEncoderConfig with default options.
+func DefaultEncoderConfig() ResultEncoderConfig {
+	return ResultEncoderConfig{Precision: time.Nanosecond}
+}
+
+// ResultEncoder encodes a result as line protocol.
+type ResultEncoder struct {
+	c ResultEncoderConfig
+}
+
+// NewResultEncoder creates a new encoder with the provided configuration.
+func NewResultEncoder(c ResultEncoderConfig) *ResultEncoder {
+	return &ResultEncoder{c: c}
+}
+
+// NewMultiResultEncoder creates a new encoder that writes
+// the points of multiple results one after the other.
+func NewMultiResultEncoder(c ResultEncoderConfig) flux.MultiResultEncoder {
+	return &flux.DelimitedMultiResultEncoder{
+		Encoder: NewResultEncoder(c),
+	}
+}
+
+type lineEncoderError struct {
+	err error
+}
+
+func (e *lineEncoderError) Error() string {
+	return e.err.Error()
+}
+
+func (e *lineEncoderError) IsEncoderError() bool {
+	return true
+}
+
+func (e *lineEncoderError) Unwrap() error {
+	return e.err
+}
+
+func wrapEncodingError(err error) error {
+	if err == nil {
+		return err
+	}
+	return &lineEncoderError{err: err}
+}
+
+// precision converts a duration to a line protocol precision.
+func precision(d time.Duration) (lineprotocol.Precision, error) {
+	switch d {
+	case 0, time.Nanosecond:
+		return lineprotocol.Nanosecond, nil
+	case time.Microsecond:
+		return lineprotocol.Microsecond, nil
+	case time.Millisecond:
+		return lineprotocol.Millisecond, nil
+	case time.Second:
+		return lineprotocol.Second, nil
+	default:
+		return 0, errors.Newf(codes.Invalid, "invalid precision %v, must be one of 1ns, 1us, 1ms or 1s", d)
+	}
+}
+
+// Encode writes the points of the result to the writer.
+// Rows with a null time and rows without any fields that can be
+// written, such as null, NaN or infinite values, are skipped.
+func (e *ResultEncoder) Encode(w io.Writer, result flux.Result) (int64, error) {
+	p, err := precision(e.c.Precision)
+	if err != nil {
+		return 0, wrapEncodingError(err)
+	}
+	wc := &iocounter.Writer{Writer: w}
+	var enc lineprotocol.Encoder
+	enc.SetPrecision(p)
+
+	err = result.Tables().Do(func(tbl flux.Table) error {
+		pw, err := newPointWriter(tbl)
+		if err != nil {
+			return wrapEncodingError(err)
+		}
+		return tbl.Do(func(cr flux.ColReader) error {
+			enc.Reset()
+			for i, n := 0, cr.Len(); i < n; i++ {
+				if err := pw.encodePoint(&enc, cr, i); err != nil {
+					return wrapEncodingError(err)
+				}
+			}
+			if _, err := wc.Write(enc.Bytes()); err != nil {
+				return wrapEncodingError(err)
+			}
+			return nil
+		})
+	})
+	return wc.Count(), err
+}
+
+// EncodeError returns the error unchanged because
+// line protocol has no way to represent an error.
+func (e *ResultEncoder) EncodeError(w io.Writer, err error) error {
+	return err
+}
+
+// pointWriter knows which columns of a table
+// hold the parts of each point.
+type pointWriter struct {
+	measurement int
+	time        int
+	// field is the index of the _field column
+	// when the field names are read from it.
+	field int
+	// tags and fields are the indices of the tag
+	// and field columns. The tags are sorted by label.
+	tags   []int
+	fields []int
+	cols   []flux.ColMeta
+}
+
+func newPointWriter(tbl flux.Table) (*pointWriter, error) {
+	cols := tbl.Cols()
+	pw := &pointWriter{
+		measurement: execute.ColIdx(measurementColLabel, cols),
+		time:        execute.ColIdx(execute.DefaultTimeColLabel, cols),
+		field:       -1,
+		cols:        cols,
+	}
+	if pw.measurement < 0 {
+		return nil, errors.Newf(codes.Invalid, "no column with label %s exists", measurementColLabel)
+	} else if cols[pw.measurement].Type != flux.TString {
+		return nil, errors.Newf(codes.Invalid, "column %s of type %s is not of type %s", measurementColLabel, cols[pw.measurement].Type, flux.TString)
+	}
+	if pw.time >= 0 && cols[pw.time].Type != flux.TTime {
+		return nil, errors.Newf(codes.Invalid, "column %s of type %s is not of type %s", execute.DefaultTimeColLabel, cols[pw.time].Type, flux.TTime)
+	}
+
+	// A table with _field and _value columns has one field for each row.
+	if idx := execute.ColIdx(fieldColLabel, cols); idx >= 0 && cols[idx].Type == flux.TString {
+		if value := execute.ColIdx(execute.DefaultValueColLabel, cols); value >= 0 {
+			pw.field = idx
+			pw.fields = []int{value}
+		}
+	}
+
+	key := tbl.Key()
+	for j, c := range cols {
+		switch {
+		case j == pw.measurement || j == pw.time || j == pw.field:
+		case key.HasCol(c.Label):
+			if c.Type == flux.TString {
+				pw.tags = append(pw.tags, j)
+			}
+		case pw.field < 0:
+			pw.fields = append(pw.fields, j)
+		}
+	}
+	// Tags must be written in lexical order.
+	sort.Slice(pw.tags, func(i, j int) bool {
+		return cols[pw.tags[i]].Label < cols[pw.tags[j]].Label
+	})
+	for _, j := range pw.fields {
+		switch cols[j].Type {
+		case flux.TBool, flux.TInt, flux.TUInt, flux.TFloat, flux.TString, flux.TTime:
+		default:
+			return nil, errors.Newf(codes.Invalid, "unsupported field type %s for column %s", cols[j].Type, cols[j].Label)
+		}
+	}
+	return pw, nil
+}
+
+// encodePoint encodes the point at row i.
+func (pw *pointWriter) encodePoint(enc *lineprotocol.Encoder, cr flux.ColReader, i int) error {
+	var ts time.Time
+	if pw.time >= 0 {
+		vs := cr.Times(pw.time)
+		if vs.IsNull(i) {
+			// Skip rows with a null timestamp.
+			return nil
+		}
+		ts = time.Unix(0, vs.Value(i)).UTC()
+	}
+
+	measurement := cr.Strings(pw.measurement)
+	if measurement.IsNull(i) {
+		return errors.Newf(codes.Invalid, "null value in column %s", measurementColLabel)
+	}
+	// Collect the fields before starting the line so
+	// points without any fields can be skipped.
+	type field struct {
+		key   string
+		value lineprotocol.Value
+	}
+	fields := make([]field, 0, len(pw.fields))
+	for _, j := range pw.fields {
+		v, ok := fieldValue(cr, i, j, pw.cols[j].Type)
+		if !ok {
+			continue
+		}
+		key := pw.cols[j].Label
+		if pw.field >= 0 {
+			names := cr.Strings(pw.field)
+			if names.IsNull(i) {
+				continue
+			}
+			key = names.Value(i)
+		}
+		fields = append(fields, field{key: key, value: v})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	enc.StartLine(measurement.Value(i))
+	for _, j := range pw.tags {
+		vs := cr.Strings(j)
+		// Skip tags without a value.
+		if vs.IsNull(i) || vs.Value(i) == "" {
+			continue
+		}
+		enc.AddTag(pw.cols[j].Label, vs.Value(i))
+	}
+	for _, f := range fields {
+		enc.AddField(f.key, f.value)
+	}
+	enc.EndLine(ts)
+	return enc.Err()
+}
+
+// fieldValue returns the line protocol value in row i of column j.
+// It reports false for values that cannot be written.
+func fieldValue(cr flux.ColReader, i, j int, typ flux.ColType) (lineprotocol.Value, bool) {
+	var v interface{}
+	switch typ {
+	case flux.TBool:
+		if vs := cr.Bools(j); vs.IsValid(i) {
+			v = vs.Value(i)
+		}
+	case flux.TInt:
+		if vs := cr.Ints(j); vs.IsValid(i) {
+			v = vs.Value(i)
+		}
+	case flux.TUInt:
+		if vs := cr.UInts(j); vs.IsValid(i) {
+			v = vs.Value(i)
+		}
+	case flux.TFloat:
+		if vs := cr.Floats(j); vs.IsValid(i) {
+			if f := vs.Value(i); !math.IsNaN(f) && !math.IsInf(f, 0) {
+				v = f
+			}
+		}
+	case flux.TString:
+		if vs := cr.Strings(j); vs.IsValid(i) {
+			v = vs.Value(i)
+		}
+	case flux.TTime:
+		// Times are written as integer nanoseconds like influxdb.to() does.
+		if vs := cr.Times(j); vs.IsValid(i) {
+			v = vs.Value(i)
+		}
+	}
+	if v == nil {
+		return lineprotocol.Value{}, false
+	}
+	return lineprotocol.NewValue(v)
+}
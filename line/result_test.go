@@ -0,0 +1,193 @@
+package line_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/diff"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/line"
+	"github.com/influxdata/flux/values"
+)
+
+func TestMultiResultEncoder(t *testing.T) {
+	t0 := values.ConvertTime(time.Date(2018, 4, 17, 0, 0, 0, 0, time.UTC))
+	t1 := values.ConvertTime(time.Date(2018, 4, 17, 0, 0, 1, 500, time.UTC))
+
+	narrow := func() *executetest.Table {
+		return &executetest.Table{
+			KeyCols: []string{"_start", "_stop", "_measurement", "region", "host", "_field"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_start", Type: flux.TTime},
+				{Label: "_stop", Type: flux.TTime},
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_measurement", Type: flux.TString},
+				{Label: "region", Type: flux.TString},
+				{Label: "host", Type: flux.TString},
+				{Label: "_field", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{t0, t1, t0, "cpu", "west", "A", "usage_user", 42.0},
+				{t0, t1, t1, "cpu", "west", "A", "usage_user", 1.5},
+				{t0, t1, t1, "cpu", "west", "A", "usage_user", math.NaN()},
+				{t0, t1, nil, "cpu", "west", "A", "usage_user", 2.0},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		config  line.ResultEncoderConfig
+		results []flux.Result
+		encoded string
+		err     error
+	}{
+		{
+			name: "narrow",
+			results: []flux.Result{&executetest.Result{
+				Nm:   "_result",
+				Tbls: []*executetest.Table{narrow()},
+			}},
+			encoded: `cpu,host=A,region=west usage_user=42 1523923200000000000
+cpu,host=A,region=west usage_user=1.5 1523923201000000500
+`,
+		},
+		{
+			name:   "precision",
+			config: line.ResultEncoderConfig{Precision: time.Second},
+			results: []flux.Result{&executetest.Result{
+				Nm:   "_result",
+				Tbls: []*executetest.Table{narrow()},
+			}},
+			encoded: `cpu,host=A,region=west usage_user=42 1523923200
+cpu,host=A,region=west usage_user=1.5 1523923201
+`,
+		},
+		{
+			name: "wide",
+			results: []flux.Result{&executetest.Result{
+				Nm: "_result",
+				Tbls: []*executetest.Table{{
+					KeyCols: []string{"_measurement", "host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+						{Label: "n", Type: flux.TInt},
+						{Label: "u", Type: flux.TUInt},
+						{Label: "ok", Type: flux.TBool},
+						{Label: "msg", Type: flux.TString},
+						{Label: "at", Type: flux.TTime},
+					},
+					Data: [][]interface{}{
+						{t0, "sys", "a b", int64(-3), uint64(7), true, `say "hi"`, t1},
+						{t1, "sys", "a b", nil, uint64(8), nil, nil, nil},
+						{t1, "sys", "a b", nil, nil, nil, nil, nil},
+					},
+				}},
+			}},
+			encoded: `sys,host=a\ b n=-3i,u=7u,ok=true,msg="say \"hi\"",at=1523923201000000500i 1523923200000000000
+sys,host=a\ b u=8u 1523923201000000500
+`,
+		},
+		{
+			name: "no time column",
+			results: []flux.Result{
+				&executetest.Result{
+					Nm: "_result",
+					Tbls: []*executetest.Table{{
+						ColMeta: []flux.ColMeta{
+							{Label: "_measurement", Type: flux.TString},
+							{Label: "_value", Type: flux.TInt},
+						},
+						Data: [][]interface{}{
+							{"count", int64(1)},
+						},
+					}},
+				},
+				&executetest.Result{
+					Nm: "other",
+					Tbls: []*executetest.Table{{
+						ColMeta: []flux.ColMeta{
+							{Label: "_measurement", Type: flux.TString},
+							{Label: "_value", Type: flux.TInt},
+						},
+						Data: [][]interface{}{
+							{"count", int64(2)},
+						},
+					}},
+				},
+			},
+			encoded: `count _value=1i
+count _value=2i
+`,
+		},
+		{
+			name: "returns query errors",
+			results: []flux.Result{
+				&executetest.Result{
+					Nm:   "_result",
+					Tbls: []*executetest.Table{narrow()},
+				},
+				&executetest.Result{
+					Nm:  "other",
+					Err: errors.New("test error"),
+				},
+			},
+			encoded: `cpu,host=A,region=west usage_user=42 1523923200000000000
+cpu,host=A,region=west usage_user=1.5 1523923201000000500
+`,
+			err: errors.New("test error"),
+		},
+		{
+			name: "missing measurement",
+			results: []flux.Result{&executetest.Result{
+				Nm: "_result",
+				Tbls: []*executetest.Table{{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+					},
+				}},
+			}},
+			err: errors.New("no column with label _measurement exists"),
+		},
+		{
+			name:   "invalid precision",
+			config: line.ResultEncoderConfig{Precision: time.Minute},
+			results: []flux.Result{&executetest.Result{
+				Nm:   "_result",
+				Tbls: []*executetest.Table{narrow()},
+			}},
+			err: errors.New("invalid precision 1m0s, must be one of 1ns, 1us, 1ms or 1s"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			encoder := line.NewMultiResultEncoder(tc.config)
+			var got bytes.Buffer
+			n, err := encoder.Encode(&got, flux.NewSliceResultIterator(tc.results))
+			if err != nil && tc.err != nil {
+				if err.Error() != tc.err.Error() {
+					t.Errorf("unexpected error want: %s\n got: %s\n", tc.err.Error(), err.Error())
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error want: none\n got: %s\n", err.Error())
+			} else if tc.err != nil {
+				t.Errorf("unexpected error want: %s\n got: none", tc.err.Error())
+			}
+
+			if g, w := got.String(), tc.encoded; g != w {
+				t.Errorf("unexpected encoding -want/+got:\n%s", diff.LineDiff(w, g))
+			}
+			if g, w := n, int64(got.Len()); g != w {
+				t.Errorf("unexpected encoding count want: %d got: %d", w, g)
+			}
+		})
+	}
+}
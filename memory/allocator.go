@@ -136,6 +136,26 @@ func (a *ResourceAllocator) Account(size int) error {
 	return a.count(size)
 }
 
+// CanAllocate reports whether size more bytes can be allocated
+// without exceeding the limit. When the limit would be exceeded,
+// it checks whether the Manager could provide the additional memory
+// without requesting it. Managers that cannot report the memory they
+// have available are assumed to be unable to provide it.
+// It does not account for or reserve any memory.
+func (a *ResourceAllocator) CanAllocate(size int) bool {
+	if a == nil || a.Limit == nil || size <= 0 {
+		return true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	want := atomic.LoadInt64(&a.bytesAllocated) + int64(size)
+	if want <= *a.Limit {
+		return true
+	}
+	m, ok := a.Manager.(interface{ AvailableMemory() int64 })
+	return ok && want-*a.Limit <= m.AvailableMemory()
+}
+
 // Allocated returns the amount of currently allocated memory.
 func (a *ResourceAllocator) Allocated() int64 {
 	return atomic.LoadInt64(&a.bytesAllocated)
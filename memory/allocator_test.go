@@ -183,6 +183,58 @@ func TestAllocator_Limit(t *testing.T) {
 	}
 }
 
+func TestAllocator_CanAllocate(t *testing.T) {
+	maxLimit := int64(64)
+	allocator := &memory.ResourceAllocator{Limit: &maxLimit}
+	if err := allocator.Account(32); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !allocator.CanAllocate(32) {
+		t.Fatal("expected to be able to allocate 32 bytes")
+	}
+	if allocator.CanAllocate(33) {
+		t.Fatal("expected to not be able to allocate 33 bytes")
+	}
+
+	// The counts should not change.
+	if want, got := int64(32), allocator.Allocated(); want != got {
+		t.Fatalf("unexpected allocated count -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+	if want, got := int64(32), allocator.MaxAllocated(); want != got {
+		t.Fatalf("unexpected max allocated count -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// An allocator without a limit can always allocate.
+	unlimited := &memory.ResourceAllocator{}
+	if !unlimited.CanAllocate(1 << 30) {
+		t.Fatal("expected to be able to allocate without a limit")
+	}
+}
+
+func TestAllocator_CanAllocate_Manager(t *testing.T) {
+	pool := memory.NewPool(memory.PoolConfig{Capacity: 64})
+	m := pool.NewManager(0)
+	defer m.Release()
+
+	limit := int64(16)
+	allocator := &memory.ResourceAllocator{Limit: &limit, Manager: m}
+	if !allocator.CanAllocate(80) {
+		t.Fatal("expected to be able to allocate memory that the pool can grant")
+	}
+	if allocator.CanAllocate(81) {
+		t.Fatal("expected to not be able to allocate more than the pool can grant")
+	}
+
+	// Checking must not take memory from the pool.
+	if want, got := int64(16), limit; want != got {
+		t.Fatalf("unexpected limit -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+	if want, got := int64(0), pool.Stats().Granted; want != got {
+		t.Fatalf("unexpected granted memory -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+}
+
 func TestAllocator_Free(t *testing.T) {
 	allocator := memory.NewResourceAllocator(nil)
 	if err := allocator.Account(64); err != nil {
@@ -0,0 +1,294 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+)
+
+// PoolConfig configures a Pool.
+type PoolConfig struct {
+	// Capacity is the total number of bytes that the pool
+	// will grant to all of its managers at the same time.
+	Capacity int64
+
+	// Timeout is the maximum amount of time that a request
+	// for memory will wait for memory to become available.
+	// A request that cannot be granted immediately is rejected
+	// if this is zero.
+	Timeout time.Duration
+
+	// Increment is the minimum number of bytes that the pool grants
+	// for a single request. Requests are rounded up to a multiple of it
+	// when there is enough memory available so that allocators
+	// do not have to go back to the pool for every allocation.
+	Increment int64
+}
+
+// Pool shares a fixed memory budget between the queries of a process.
+//
+// Each query requests memory through its own PoolManager.
+// A request that fits in the remaining budget is granted immediately
+// unless other requests are already waiting. Otherwise, the request
+// waits until memory is released by another query or until the timeout
+// is reached. Waiting requests are granted in priority order and,
+// for requests with the same priority, the query that holds the least
+// memory is granted first so the budget is shared fairly.
+type Pool struct {
+	config PoolConfig
+
+	mu       sync.Mutex
+	granted  int64
+	managers int
+	waiting  []*poolRequest
+	seq      int64
+	rejected int64
+}
+
+// NewPool creates a Pool with the given configuration.
+func NewPool(config PoolConfig) *Pool {
+	return &Pool{config: config}
+}
+
+// PoolStats is a snapshot of the state of a Pool.
+type PoolStats struct {
+	// Capacity is the total number of bytes in the pool.
+	Capacity int64
+	// Granted is the number of bytes that are currently
+	// held by the managers of the pool.
+	Granted int64
+	// Waiting is the number of requests that are
+	// waiting for memory to become available.
+	Waiting int
+	// Rejected is the total number of requests that
+	// were rejected by the pool.
+	Rejected int64
+	// Managers is the number of managers that have
+	// not been released.
+	Managers int
+}
+
+// Stats returns the current statistics for the pool.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{
+		Capacity: p.config.Capacity,
+		Granted:  p.granted,
+		Waiting:  len(p.waiting),
+		Rejected: p.rejected,
+		Managers: p.managers,
+	}
+}
+
+// NewManager creates a Manager that requests memory from the pool.
+// Requests from managers with a higher priority are granted before
+// the requests from managers with a lower priority.
+//
+// The manager must be released when the query is done so the
+// memory it holds is returned to the pool.
+func (p *Pool) NewManager(priority int) *PoolManager {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.managers++
+	return &PoolManager{
+		pool:     p,
+		priority: priority,
+	}
+}
+
+// poolRequest is a request for memory that is waiting in the pool.
+type poolRequest struct {
+	m    *PoolManager
+	want int64
+	seq  int64
+
+	// ready is closed when the request has been granted.
+	ready chan struct{}
+	got   int64
+}
+
+// PoolManager is a Manager for a single query that
+// requests memory from a shared Pool.
+type PoolManager struct {
+	pool     *Pool
+	priority int
+
+	// held and released are guarded by the pool mutex.
+	held     int64
+	released bool
+}
+
+var _ Manager = (*PoolManager)(nil)
+
+// RequestMemory reserves at least want bytes from the pool.
+// It blocks until the memory is available or the pool timeout is reached.
+func (m *PoolManager) RequestMemory(want int64) (got int64, err error) {
+	p := m.pool
+	p.mu.Lock()
+	if m.released {
+		p.mu.Unlock()
+		return 0, errors.New(codes.Internal, "memory requested from a released manager")
+	}
+	if want > p.config.Capacity {
+		p.rejected++
+		p.mu.Unlock()
+		return 0, errors.Newf(codes.ResourceExhausted, "requested %d bytes which exceeds the memory pool capacity of %d bytes", want, p.config.Capacity)
+	}
+
+	if len(p.waiting) == 0 && p.granted+want <= p.config.Capacity {
+		got = p.grant(m, want)
+		p.mu.Unlock()
+		return got, nil
+	}
+	if p.config.Timeout <= 0 {
+		p.rejected++
+		p.mu.Unlock()
+		return 0, p.exhausted(want)
+	}
+
+	p.seq++
+	r := &poolRequest{
+		m:     m,
+		want:  want,
+		seq:   p.seq,
+		ready: make(chan struct{}),
+	}
+	p.waiting = append(p.waiting, r)
+	p.dispatch()
+	p.mu.Unlock()
+
+	timer := time.NewTimer(p.config.Timeout)
+	defer timer.Stop()
+	select {
+	case <-r.ready:
+		return r.got, nil
+	case <-timer.C:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-r.ready:
+		// The request was granted while we were waiting for the lock.
+		return r.got, nil
+	default:
+	}
+	p.remove(r)
+	p.rejected++
+	// The removed request may have been blocking smaller requests.
+	p.dispatch()
+	return 0, p.exhausted(want)
+}
+
+// AvailableMemory returns the number of bytes that a request
+// from the manager would be granted without waiting.
+// It does not reserve any memory.
+func (m *PoolManager) AvailableMemory() int64 {
+	p := m.pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m.released || len(p.waiting) > 0 {
+		return 0
+	}
+	return p.config.Capacity - p.granted
+}
+
+// FreeMemory returns memory held by the manager to the pool.
+func (m *PoolManager) FreeMemory(bytes int64) {
+	p := m.pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if bytes > m.held {
+		bytes = m.held
+	}
+	m.held -= bytes
+	p.granted -= bytes
+	p.dispatch()
+}
+
+// Release returns all of the memory held by the manager to the pool.
+// The manager cannot be used after it has been released.
+func (m *PoolManager) Release() {
+	p := m.pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m.released {
+		return
+	}
+	m.released = true
+	p.managers--
+	p.granted -= m.held
+	m.held = 0
+	p.dispatch()
+}
+
+// Held returns the number of bytes the manager holds from the pool.
+func (m *PoolManager) Held() int64 {
+	m.pool.mu.Lock()
+	defer m.pool.mu.Unlock()
+	return m.held
+}
+
+// grant reserves the memory for a request. The request is rounded up
+// to the pool increment if there is enough memory available.
+// The caller must hold the pool mutex.
+func (p *Pool) grant(m *PoolManager, want int64) int64 {
+	got := want
+	if inc := p.config.Increment; inc > 0 {
+		if rounded := (want + inc - 1) / inc * inc; p.granted+rounded <= p.config.Capacity {
+			got = rounded
+		}
+	}
+	m.held += got
+	p.granted += got
+	return got
+}
+
+// dispatch grants the waiting requests in order until
+// a request does not fit in the remaining memory.
+// The caller must hold the pool mutex.
+func (p *Pool) dispatch() {
+	if len(p.waiting) == 0 {
+		return
+	}
+	sort.Slice(p.waiting, func(i, j int) bool {
+		ri, rj := p.waiting[i], p.waiting[j]
+		if ri.m.priority != rj.m.priority {
+			return ri.m.priority > rj.m.priority
+		}
+		if ri.m.held != rj.m.held {
+			return ri.m.held < rj.m.held
+		}
+		return ri.seq < rj.seq
+	})
+
+	n := 0
+	for _, r := range p.waiting {
+		if p.granted+r.want > p.config.Capacity {
+			break
+		}
+		r.got = p.grant(r.m, r.want)
+		close(r.ready)
+		n++
+	}
+	p.waiting = p.waiting[n:]
+}
+
+// remove removes a request from the waiting requests.
+// The caller must hold the pool mutex.
+func (p *Pool) remove(r *poolRequest) {
+	for i, w := range p.waiting {
+		if w == r {
+			p.waiting = append(p.waiting[:i], p.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *Pool) exhausted(want int64) error {
+	return errors.Newf(codes.ResourceExhausted, "memory pool exhausted: capacity %d bytes, granted: %d, wanted: %d", p.config.Capacity, p.granted, want)
+}
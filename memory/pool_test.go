@@ -0,0 +1,198 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+)
+
+func TestPool_RequestMemory(t *testing.T) {
+	pool := memory.NewPool(memory.PoolConfig{
+		Capacity:  125,
+		Increment: 16,
+	})
+	m := pool.NewManager(0)
+	defer m.Release()
+
+	// Requests are rounded up to the increment.
+	got, err := m.RequestMemory(20)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := int64(32); want != got {
+		t.Fatalf("unexpected grant -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// Unless there isn't enough memory left in the pool.
+	if got, err = m.RequestMemory(90); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := int64(90); want != got {
+		t.Fatalf("unexpected grant -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// The pool has no timeout so this is rejected immediately.
+	if _, err := m.RequestMemory(40); err == nil {
+		t.Fatal("expected error")
+	} else if want, got := codes.ResourceExhausted, errors.Code(err); want != got {
+		t.Fatalf("unexpected error code -want/+got\n\t- %v\n\t+ %v", want, got)
+	}
+
+	// More than the capacity can never be granted.
+	if _, err := m.RequestMemory(256); err == nil {
+		t.Fatal("expected error")
+	}
+
+	m.FreeMemory(32)
+	want := memory.PoolStats{
+		Capacity: 125,
+		Granted:  90,
+		Rejected: 2,
+		Managers: 1,
+	}
+	if got := pool.Stats(); want != got {
+		t.Fatalf("unexpected stats -want/+got\n\t- %+v\n\t+ %+v", want, got)
+	}
+}
+
+func TestPool_Wait(t *testing.T) {
+	pool := memory.NewPool(memory.PoolConfig{
+		Capacity: 64,
+		Timeout:  time.Minute,
+	})
+	m1, m2 := pool.NewManager(0), pool.NewManager(0)
+	defer m2.Release()
+
+	if _, err := m1.RequestMemory(64); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m2.RequestMemory(32)
+		done <- err
+	}()
+
+	waitForStats(t, pool, func(s memory.PoolStats) bool { return s.Waiting == 1 })
+	m1.Release()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want, got := int64(32), m2.Held(); want != got {
+		t.Fatalf("unexpected held memory -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+}
+
+func TestPool_Timeout(t *testing.T) {
+	pool := memory.NewPool(memory.PoolConfig{
+		Capacity: 64,
+		Timeout:  10 * time.Millisecond,
+	})
+	m1, m2 := pool.NewManager(0), pool.NewManager(0)
+	defer m1.Release()
+	defer m2.Release()
+
+	if _, err := m1.RequestMemory(64); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := m2.RequestMemory(1); err == nil {
+		t.Fatal("expected error")
+	} else if want, got := codes.ResourceExhausted, errors.Code(err); want != got {
+		t.Fatalf("unexpected error code -want/+got\n\t- %v\n\t+ %v", want, got)
+	}
+	if s := pool.Stats(); s.Waiting != 0 || s.Rejected != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestPool_GrantOrder(t *testing.T) {
+	pool := memory.NewPool(memory.PoolConfig{
+		Capacity: 100,
+		Timeout:  time.Minute,
+	})
+	owner := pool.NewManager(0)
+	if _, err := owner.RequestMemory(100); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The busy manager already holds memory from an earlier grant
+	// so it should be granted after the managers with the same priority
+	// that hold nothing.
+	busy := pool.NewManager(0)
+	fair := pool.NewManager(0)
+	high := pool.NewManager(1)
+	defer busy.Release()
+	defer fair.Release()
+	defer high.Release()
+
+	order := make(chan string, 4)
+	request := func(name string, m *memory.PoolManager, want int64) {
+		if _, err := m.RequestMemory(want); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		order <- name
+	}
+
+	// Give the busy manager memory that it holds while it waits.
+	owner.FreeMemory(10)
+	if _, err := busy.RequestMemory(10); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	go request("busy", busy, 30)
+	waitForStats(t, pool, func(s memory.PoolStats) bool { return s.Waiting == 1 })
+	go request("fair", fair, 30)
+	waitForStats(t, pool, func(s memory.PoolStats) bool { return s.Waiting == 2 })
+	go request("high", high, 30)
+	waitForStats(t, pool, func(s memory.PoolStats) bool { return s.Waiting == 3 })
+
+	// Free enough memory for one request at a time.
+	for _, want := range []string{"high", "fair", "busy"} {
+		owner.FreeMemory(30)
+		if got := <-order; want != got {
+			t.Fatalf("unexpected grant order -want/+got\n\t- %s\n\t+ %s", want, got)
+		}
+	}
+	owner.Release()
+}
+
+func TestPool_ResourceAllocator(t *testing.T) {
+	pool := memory.NewPool(memory.PoolConfig{
+		Capacity: 128,
+	})
+	m := pool.NewManager(0)
+	allocator := &memory.ResourceAllocator{
+		Limit:   func(v int64) *int64 { return &v }(0),
+		Manager: m,
+	}
+
+	if err := allocator.Account(96); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := allocator.Account(64); err == nil {
+		t.Fatal("expected error")
+	}
+	if want, got := int64(96), pool.Stats().Granted; want != got {
+		t.Fatalf("unexpected granted memory -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+
+	m.Release()
+	if want, got := int64(0), pool.Stats().Granted; want != got {
+		t.Fatalf("unexpected granted memory -want/+got\n\t- %d\n\t+ %d", want, got)
+	}
+}
+
+// waitForStats waits until the pool statistics satisfy fn.
+func waitForStats(t *testing.T, pool *memory.Pool, fn func(s memory.PoolStats) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !fn(pool.Stats()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for pool stats: %+v", pool.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,10 +1,47 @@
 package plan
 
+import (
+	"context"
+	"reflect"
+)
+
+// Statistics holds estimates about the data produced by a plan node.
+// The zero value means that nothing is known about the output.
 type Statistics struct {
-	Cardinality      int64
+	// Cardinality is the estimated number of rows.
+	Cardinality int64
+	// GroupCardinality is the estimated number of tables.
 	GroupCardinality int64
 }
 
+// IsKnown reports whether the statistics contain a row estimate.
+func (s Statistics) IsKnown() bool {
+	return s.Cardinality > 0
+}
+
+// RowsPerGroup returns the estimated number of rows in each table.
+func (s Statistics) RowsPerGroup() int64 {
+	if s.GroupCardinality <= 1 {
+		return s.Cardinality
+	}
+	return s.Cardinality / s.GroupCardinality
+}
+
+// SumStatistics combines the statistics of several inputs
+// as if their tables were concatenated. If any of the inputs
+// is unknown, the result is unknown.
+func SumStatistics(stats []Statistics) Statistics {
+	var out Statistics
+	for _, s := range stats {
+		if !s.IsKnown() {
+			return Statistics{}
+		}
+		out.Cardinality += s.Cardinality
+		out.GroupCardinality += s.GroupCardinality
+	}
+	return out
+}
+
 // Cost stores various dimensions of the cost of a query plan
 type Cost struct {
 	Disk int64
@@ -25,9 +62,161 @@ func Add(a Cost, b Cost) Cost {
 	}
 }
 
+// The weights used to reduce a Cost to a single number.
+// Memory, disk and network usage are weighted more heavily than
+// processing because they are more likely to stall or fail a query.
+const (
+	cpuWeight  = 1
+	gpuWeight  = 1
+	memWeight  = 4
+	diskWeight = 8
+	netWeight  = 16
+)
+
+// Total reduces the cost to a single number that can be used
+// to compare alternative plans.
+func (c Cost) Total() int64 {
+	return c.CPU*cpuWeight +
+		c.GPU*gpuWeight +
+		c.MEM*memWeight +
+		c.Disk*diskWeight +
+		c.NET*netWeight
+}
+
+// Less reports whether c is cheaper than other.
+func (c Cost) Less(other Cost) bool {
+	return c.Total() < other.Total()
+}
+
+// DefaultCost estimates the cost of a narrow transformation
+// that does a constant amount of work for each row and
+// passes its input through unchanged.
 type DefaultCost struct {
 }
 
 func (c DefaultCost) Cost(inStats []Statistics) (Cost, Statistics) {
-	return Cost{}, Statistics{}
+	out := SumStatistics(inStats)
+	return Cost{CPU: out.Cardinality}, out
+}
+
+// StatisticsEstimator is implemented by procedure specs that can
+// estimate the statistics of their output with access to the query
+// context. This is typically implemented by sources that can ask
+// the underlying storage about the data they are going to read.
+//
+// The estimate is requested at most once for each physical plan node.
+// Estimators that need to contact a database or a remote service
+// should only do so when StatisticsProbesEnabled reports true.
+type StatisticsEstimator interface {
+	// EstimateStatistics returns the estimated statistics of the output.
+	// It returns the zero value if no estimate could be made.
+	EstimateStatistics(ctx context.Context) Statistics
+}
+
+type statisticsProbesKey struct{}
+
+// ContextWithStatisticsProbes returns a context that allows statistics
+// estimators to query databases and remote services while planning.
+func ContextWithStatisticsProbes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, statisticsProbesKey{}, true)
+}
+
+// StatisticsProbesEnabled reports whether statistics estimators may
+// query databases and remote services while planning.
+func StatisticsProbesEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(statisticsProbesKey{}).(bool)
+	return enabled
+}
+
+// memoizedStatistics holds the statistics that were estimated
+// for the procedure spec of a physical plan node.
+type memoizedStatistics struct {
+	spec  ProcedureSpec
+	stats Statistics
+}
+
+// estimateStatistics returns the statistics estimated by the spec of node.
+// The estimate is stored on physical plan nodes so a source is only asked
+// once per plan, no matter how many times the cost of the plan is estimated.
+// It is estimated again when the spec of the node has been replaced.
+func estimateStatistics(ctx context.Context, node Node, estimator StatisticsEstimator) Statistics {
+	ppn, ok := node.(*PhysicalPlanNode)
+	if !ok {
+		return estimator.EstimateStatistics(ctx)
+	}
+	if m := ppn.statistics; m != nil && sameSpec(m.spec, ppn.Spec) {
+		return m.stats
+	}
+	stats := estimator.EstimateStatistics(ctx)
+	ppn.statistics = &memoizedStatistics{spec: ppn.Spec, stats: stats}
+	return stats
+}
+
+// sameSpec reports whether a and b are the same procedure spec.
+// Specs that cannot be compared are never the same.
+func sameSpec(a, b ProcedureSpec) bool {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || t == nil || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// Coster is implemented by procedure specs that can estimate their
+// own cost given the statistics of their inputs.
+// Every PhysicalProcedureSpec implements this interface.
+type Coster interface {
+	Cost(inStats []Statistics) (cost Cost, outStats Statistics)
+}
+
+// EstimateCost computes the cumulative cost of the sub-plan rooted at node,
+// together with the statistics of the data that node produces.
+// Nodes shared by several paths in the sub-plan are only counted once.
+func EstimateCost(ctx context.Context, node Node) (Cost, Statistics) {
+	e := costEstimator{
+		ctx:     ctx,
+		visited: make(map[Node]Statistics),
+	}
+	stats := e.estimate(node)
+	return e.total, stats
+}
+
+type costEstimator struct {
+	ctx     context.Context
+	visited map[Node]Statistics
+	total   Cost
+}
+
+func (e *costEstimator) estimate(node Node) Statistics {
+	if stats, ok := e.visited[node]; ok {
+		return stats
+	}
+
+	preds := node.Predecessors()
+	inStats := make([]Statistics, len(preds))
+	for i, pred := range preds {
+		inStats[i] = e.estimate(pred)
+	}
+
+	var (
+		cost  Cost
+		stats Statistics
+	)
+	spec := node.ProcedureSpec()
+	if c, ok := spec.(Coster); ok {
+		cost, stats = c.Cost(inStats)
+	} else {
+		cost, stats = DefaultCost{}.Cost(inStats)
+	}
+	if estimator, ok := spec.(StatisticsEstimator); ok {
+		if s := estimateStatistics(e.ctx, node, estimator); s.IsKnown() {
+			stats = s
+			if cost == (Cost{}) {
+				cost.CPU = s.Cardinality
+			}
+		}
+	}
+	e.total = Add(e.total, cost)
+	e.visited[node] = stats
+	return stats
 }
@@ -0,0 +1,185 @@
+package plan_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+)
+
+type statsEstimatorSpec struct {
+	plantest.MockProcedureSpec
+	stats plan.Statistics
+}
+
+func (s statsEstimatorSpec) EstimateStatistics(ctx context.Context) plan.Statistics {
+	return s.stats
+}
+
+func TestEstimateCost(t *testing.T) {
+	source := func(id string, rows int64) plan.Node {
+		return plan.CreatePhysicalNode(plan.NodeID(id), statsEstimatorSpec{
+			stats: plan.Statistics{Cardinality: rows, GroupCardinality: 1},
+		})
+	}
+
+	t.Run("linear", func(t *testing.T) {
+		spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+			Nodes: []plan.Node{
+				source("0", 10),
+				plantest.CreatePhysicalMockNode("1"),
+			},
+			Edges: [][2]int{{0, 1}},
+		})
+		var root plan.Node
+		for r := range spec.Roots {
+			root = r
+		}
+
+		cost, stats := plan.EstimateCost(context.Background(), root)
+		if want := (plan.Cost{CPU: 20}); !cmp.Equal(want, cost) {
+			t.Errorf("unexpected cost -want/+got:\n%s", cmp.Diff(want, cost))
+		}
+		if want := (plan.Statistics{Cardinality: 10, GroupCardinality: 1}); !cmp.Equal(want, stats) {
+			t.Errorf("unexpected statistics -want/+got:\n%s", cmp.Diff(want, stats))
+		}
+	})
+
+	t.Run("shared predecessor", func(t *testing.T) {
+		spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+			Nodes: []plan.Node{
+				source("0", 10),
+				plantest.CreatePhysicalMockNode("1"),
+				plantest.CreatePhysicalMockNode("2"),
+				plantest.CreatePhysicalMockNode("3"),
+			},
+			Edges: [][2]int{{0, 1}, {0, 2}, {1, 3}, {2, 3}},
+		})
+		var root plan.Node
+		for r := range spec.Roots {
+			root = r
+		}
+
+		// The source is only counted once even though it is
+		// reachable through two paths.
+		cost, stats := plan.EstimateCost(context.Background(), root)
+		if want := (plan.Cost{CPU: 50}); !cmp.Equal(want, cost) {
+			t.Errorf("unexpected cost -want/+got:\n%s", cmp.Diff(want, cost))
+		}
+		if want := (plan.Statistics{Cardinality: 20, GroupCardinality: 2}); !cmp.Equal(want, stats) {
+			t.Errorf("unexpected statistics -want/+got:\n%s", cmp.Diff(want, stats))
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+			Nodes: []plan.Node{
+				plantest.CreatePhysicalMockNode("0"),
+				plantest.CreatePhysicalMockNode("1"),
+			},
+			Edges: [][2]int{{0, 1}},
+		})
+		var root plan.Node
+		for r := range spec.Roots {
+			root = r
+		}
+
+		if _, stats := plan.EstimateCost(context.Background(), root); stats.IsKnown() {
+			t.Errorf("expected unknown statistics, got %v", stats)
+		}
+	})
+}
+
+// countingEstimatorSpec counts how many times its statistics are estimated.
+type countingEstimatorSpec struct {
+	plantest.MockProcedureSpec
+	calls  int
+	probes bool
+}
+
+func (s *countingEstimatorSpec) EstimateStatistics(ctx context.Context) plan.Statistics {
+	s.calls++
+	s.probes = plan.StatisticsProbesEnabled(ctx)
+	return plan.Statistics{Cardinality: 10, GroupCardinality: 1}
+}
+
+func (s *countingEstimatorSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func TestEstimateCost_MemoizesStatistics(t *testing.T) {
+	spec := &countingEstimatorSpec{}
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("0", spec),
+			plantest.CreatePhysicalMockNode("1"),
+		},
+		Edges: [][2]int{{0, 1}},
+	})
+	var root plan.Node
+	for r := range ps.Roots {
+		root = r
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		plan.EstimateCost(ctx, root)
+	}
+	plan.ChooseDispatcherSettings(ctx, ps)
+	if want, got := 1, spec.calls; want != got {
+		t.Fatalf("unexpected number of estimates want: %d got: %d", want, got)
+	}
+
+	// Replacing the spec of the node invalidates the estimate.
+	newSpec := &countingEstimatorSpec{}
+	if err := root.Predecessors()[0].ReplaceSpec(newSpec); err != nil {
+		t.Fatal(err)
+	}
+	plan.EstimateCost(ctx, root)
+	plan.EstimateCost(ctx, root)
+	if want, got := 1, newSpec.calls; want != got {
+		t.Fatalf("unexpected number of estimates after replacing the spec want: %d got: %d", want, got)
+	}
+}
+
+func TestStatisticsProbes(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []plan.PhysicalOption
+		want    bool
+	}{
+		{
+			name: "disabled by default",
+		},
+		{
+			name:    "enabled",
+			options: []plan.PhysicalOption{plan.EnableStatisticsProbes()},
+			want:    true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &countingEstimatorSpec{}
+			ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("0", spec),
+					plantest.CreatePhysicalMockNode("1"),
+				},
+				Edges: [][2]int{{0, 1}},
+			})
+			options := append(tc.options, plan.DisableValidation())
+			if _, err := plan.NewPhysicalPlanner(options...).Plan(context.Background(), ps); err != nil {
+				t.Fatal(err)
+			}
+			if spec.calls != 1 {
+				t.Fatalf("expected statistics to be estimated once, got %d", spec.calls)
+			}
+			if spec.probes != tc.want {
+				t.Fatalf("unexpected statistics probes want: %v got: %v", tc.want, spec.probes)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package plan
+
+// CommonSubexpressionEliminationRule is the name used to disable
+// common subexpression elimination with RemoveLogicalRules.
+const CommonSubexpressionEliminationRule = "CommonSubexpressionElimination"
+
+// eliminateCommonSubexpressions merges the nodes in the plan that perform
+// the same procedure on the same predecessors so the work is only done once
+// and the data is fanned out to the successors of both nodes.
+//
+// Nodes are visited from the sources so two pipelines that are written the
+// same way are merged node by node. Nodes with side effects are never merged.
+// It reports whether the plan was changed.
+func eliminateCommonSubexpressions(plan *Spec) (bool, error) {
+	var nodes []Node
+	if err := plan.BottomUpWalk(func(node Node) error {
+		nodes = append(nodes, node)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	type nodeKey struct {
+		kind  ProcedureKind
+		first Node
+		n     int
+	}
+	seen := make(map[nodeKey][]Node)
+	changed := false
+	for _, node := range nodes {
+		spec := node.ProcedureSpec()
+		if HasSideEffect(spec) {
+			continue
+		}
+
+		preds := node.Predecessors()
+		key := nodeKey{kind: node.Kind(), n: len(preds)}
+		if len(preds) > 0 {
+			key.first = preds[0]
+		}
+
+		var same Node
+		for _, other := range seen[key] {
+			if samePredecessors(node, other) && ProcedureSpecsEqual(other.ProcedureSpec(), spec) && canMerge(node, other) {
+				same = other
+				break
+			}
+		}
+		if same == nil {
+			seen[key] = append(seen[key], node)
+			continue
+		}
+		mergeNode(node, same)
+		changed = true
+	}
+	return changed, nil
+}
+
+// samePredecessors reports whether two nodes read from
+// the same predecessors in the same order.
+func samePredecessors(a, b Node) bool {
+	ap, bp := a.Predecessors(), b.Predecessors()
+	if len(ap) != len(bp) {
+		return false
+	}
+	for i := range ap {
+		if ap[i] != bp[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// canMerge reports whether node can be merged into other.
+// Roots are not merged because each root produces its own result.
+// A successor cannot read from the same node twice so nodes
+// that share a successor are not merged either.
+func canMerge(node, other Node) bool {
+	if len(node.Successors()) == 0 || len(other.Successors()) == 0 {
+		return false
+	}
+	for _, succ := range node.Successors() {
+		if IndexOfNode(other, succ.Predecessors()) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeNode removes node from the plan and
+// connects its successors to other instead.
+func mergeNode(node, other Node) {
+	for _, pred := range node.Predecessors() {
+		succs := make([]Node, 0, len(pred.Successors()))
+		for _, succ := range pred.Successors() {
+			if succ != node {
+				succs = append(succs, succ)
+			}
+		}
+		pred.ClearSuccessors()
+		pred.AddSuccessors(succs...)
+	}
+
+	for _, succ := range node.Successors() {
+		preds := succ.Predecessors()
+		for i := range preds {
+			if preds[i] == node {
+				preds[i] = other
+			}
+		}
+		other.AddSuccessors(succ)
+	}
+}
@@ -0,0 +1,146 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// DefaultDispatcherThroughput is the number of messages a transformation
+	// processes each time it is scheduled when the planner has no better choice.
+	DefaultDispatcherThroughput = 10
+
+	// smallQueryThroughput is the throughput used for small queries.
+	// Small queries only produce a handful of messages so processing all of
+	// them in one schedule avoids the overhead of rescheduling.
+	smallQueryThroughput = 100
+
+	// smallQueryCost is the estimated CPU cost under which
+	// a query is considered small.
+	smallQueryCost = 10000
+
+	// cpuIntensiveWorkers is the number of workers added
+	// for each procedure that is CPU intensive.
+	cpuIntensiveWorkers = 2
+
+	// maxCPUIntensiveWorkers limits the number of workers that
+	// are added for procedures that are CPU intensive.
+	maxCPUIntensiveWorkers = 8
+)
+
+// DispatcherSettings configures how the executor schedules work for a plan.
+// The zero value means the planner has not chosen any settings.
+type DispatcherSettings struct {
+	// Throughput is the maximum number of messages a transformation
+	// processes each time it is scheduled.
+	Throughput int
+	// Workers is the number of worker goroutines used to process messages.
+	// The executor may use more workers if the plan requires them.
+	Workers int
+	// Priorities holds the scheduling priority of each node.
+	// Work for nodes with a higher priority is run first.
+	Priorities map[NodeID]int
+}
+
+// IsZero reports whether no settings have been chosen.
+func (s DispatcherSettings) IsZero() bool {
+	return s.Throughput == 0 && s.Workers == 0 && len(s.Priorities) == 0
+}
+
+func (s DispatcherSettings) String() string {
+	return fmt.Sprintf("throughput=%d workers=%d", s.Throughput, s.Workers)
+}
+
+// CPUIntensive is implemented by procedure specs that do a significant
+// amount of processing for each row, such as evaluating a function.
+// Plans that contain these procedures are given more workers.
+type CPUIntensive interface {
+	CPUIntensive() bool
+}
+
+// ChooseDispatcherSettings picks the dispatcher settings for a physical plan.
+//
+// The number of workers is taken from the concurrency quota in the plan
+// resources when it is set. Otherwise, one worker is used for each result
+// and more workers are added for CPU intensive procedures unless the
+// estimated cost of the query is small. Small queries process more messages
+// each time they are scheduled. The cost is not estimated when none of the
+// sources can provide statistics.
+//
+// Nodes are prioritized by their distance from the sources so work that
+// moves data towards the results runs first and buffered data is released
+// sooner.
+func ChooseDispatcherSettings(ctx context.Context, p *Spec) DispatcherSettings {
+	s := DispatcherSettings{
+		Throughput: DefaultDispatcherThroughput,
+		Priorities: make(map[NodeID]int),
+	}
+
+	var (
+		cpuIntensive int
+		estimable    bool
+	)
+	_ = p.BottomUpWalk(func(node Node) error {
+		if len(node.Predecessors()) == 0 && hasSourceStatistics(node.ProcedureSpec()) {
+			estimable = true
+		}
+		priority := 0
+		for _, pred := range node.Predecessors() {
+			if pp := s.Priorities[pred.ID()] + 1; pp > priority {
+				priority = pp
+			}
+		}
+		s.Priorities[node.ID()] = priority
+
+		if c, ok := node.ProcedureSpec().(CPUIntensive); ok && c.CPUIntensive() {
+			cpuIntensive++
+		}
+		return nil
+	})
+
+	// The cost is only estimated when a source knows something about its
+	// data. Source statistics are memoized on the plan nodes, so this does
+	// not repeat the estimates that were made by the cost-based rules.
+	var small bool
+	if estimable {
+		e := costEstimator{
+			ctx:     ctx,
+			visited: make(map[Node]Statistics),
+		}
+		for root := range p.Roots {
+			e.estimate(root)
+		}
+		small = e.total.CPU > 0 && e.total.CPU <= smallQueryCost
+	}
+	if small {
+		s.Throughput = smallQueryThroughput
+	}
+
+	if p.Resources.ConcurrencyQuota > 0 {
+		s.Workers = p.Resources.ConcurrencyQuota
+		return s
+	}
+
+	s.Workers = len(p.Roots)
+	if !small {
+		extra := cpuIntensive * cpuIntensiveWorkers
+		if extra > maxCPUIntensiveWorkers {
+			extra = maxCPUIntensiveWorkers
+		}
+		s.Workers += extra
+	}
+	return s
+}
+
+// hasSourceStatistics reports whether a source spec may produce
+// statistics. Without them, every estimate in the plan is unknown.
+func hasSourceStatistics(spec ProcedureSpec) bool {
+	if _, ok := spec.(StatisticsEstimator); ok {
+		return true
+	}
+	if c, ok := spec.(Coster); ok {
+		_, stats := c.Cost(nil)
+		return stats.IsKnown()
+	}
+	return false
+}
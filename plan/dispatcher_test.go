@@ -0,0 +1,189 @@
+package plan_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+)
+
+type cpuIntensiveSpec struct {
+	plantest.MockProcedureSpec
+}
+
+func (s cpuIntensiveSpec) CPUIntensive() bool { return true }
+
+func TestChooseDispatcherSettings(t *testing.T) {
+	source := func(id string, rows int64) plan.Node {
+		return plan.CreatePhysicalNode(plan.NodeID(id), statsEstimatorSpec{
+			stats: plan.Statistics{Cardinality: rows, GroupCardinality: 1},
+		})
+	}
+	cpuIntensive := func(id string) plan.Node {
+		return plan.CreatePhysicalNode(plan.NodeID(id), cpuIntensiveSpec{})
+	}
+
+	testCases := []struct {
+		name string
+		spec *plantest.PlanSpec
+		want plan.DispatcherSettings
+	}{
+		{
+			name: "unknown size",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalMockNode("0"),
+					plantest.CreatePhysicalMockNode("1"),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			want: plan.DispatcherSettings{
+				Throughput: plan.DefaultDispatcherThroughput,
+				Workers:    1,
+				Priorities: map[plan.NodeID]int{"0": 0, "1": 1},
+			},
+		},
+		{
+			name: "small query",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					source("0", 100),
+					cpuIntensive("1"),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			want: plan.DispatcherSettings{
+				Throughput: 100,
+				Workers:    1,
+				Priorities: map[plan.NodeID]int{"0": 0, "1": 1},
+			},
+		},
+		{
+			name: "cpu intensive",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					source("0", 1000000),
+					cpuIntensive("1"),
+					cpuIntensive("2"),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}},
+			},
+			want: plan.DispatcherSettings{
+				Throughput: plan.DefaultDispatcherThroughput,
+				Workers:    5,
+				Priorities: map[plan.NodeID]int{"0": 0, "1": 1, "2": 2},
+			},
+		},
+		{
+			name: "cpu intensive limit",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalMockNode("0"),
+					cpuIntensive("1"),
+					cpuIntensive("2"),
+					cpuIntensive("3"),
+					cpuIntensive("4"),
+					cpuIntensive("5"),
+				},
+				Edges: [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}, {4, 5}},
+			},
+			want: plan.DispatcherSettings{
+				Throughput: plan.DefaultDispatcherThroughput,
+				Workers:    9,
+				Priorities: map[plan.NodeID]int{"0": 0, "1": 1, "2": 2, "3": 3, "4": 4, "5": 5},
+			},
+		},
+		{
+			name: "concurrency quota",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalMockNode("0"),
+					cpuIntensive("1"),
+				},
+				Edges:     [][2]int{{0, 1}},
+				Resources: flux.ResourceManagement{ConcurrencyQuota: 3},
+			},
+			want: plan.DispatcherSettings{
+				Throughput: plan.DefaultDispatcherThroughput,
+				Workers:    3,
+				Priorities: map[plan.NodeID]int{"0": 0, "1": 1},
+			},
+		},
+		{
+			name: "join",
+			spec: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalMockNode("0"),
+					plantest.CreatePhysicalMockNode("1"),
+					plantest.CreatePhysicalMockNode("2"),
+					plantest.CreatePhysicalMockNode("3"),
+				},
+				Edges: [][2]int{{0, 1}, {1, 3}, {2, 3}},
+			},
+			want: plan.DispatcherSettings{
+				Throughput: plan.DefaultDispatcherThroughput,
+				Workers:    1,
+				Priorities: map[plan.NodeID]int{"0": 0, "1": 1, "2": 0, "3": 2},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			spec := plantest.CreatePlanSpec(tc.spec)
+			got := plan.ChooseDispatcherSettings(context.Background(), spec)
+			if !cmp.Equal(tc.want, got) {
+				t.Errorf("unexpected dispatcher settings -want/+got:\n%s", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func TestChooseDispatcherSettings_SkipsEstimation(t *testing.T) {
+	var calls int
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plantest.CreatePhysicalMockNode("0"),
+			plan.CreatePhysicalNode("1", plantest.MockProcedureSpec{
+				CostFn: func(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+					calls++
+					return plan.Cost{CPU: 1}, plan.Statistics{}
+				},
+			}),
+		},
+		Edges: [][2]int{{0, 1}},
+	})
+	got := plan.ChooseDispatcherSettings(context.Background(), ps)
+	if want := plan.DefaultDispatcherThroughput; got.Throughput != want {
+		t.Errorf("unexpected throughput want: %d got: %d", want, got.Throughput)
+	}
+	if calls != 0 {
+		t.Errorf("cost was estimated %d times for a plan without source statistics", calls)
+	}
+}
+
+func TestChooseDispatcherSettings_CosterSource(t *testing.T) {
+	// A source that reports its statistics through Cost
+	// is estimated even though it is not a StatisticsEstimator.
+	ps := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plan.CreatePhysicalNode("0", plantest.MockProcedureSpec{
+				CostFn: func(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+					return plan.Cost{CPU: 10}, plan.Statistics{Cardinality: 10, GroupCardinality: 1}
+				},
+			}),
+		},
+	})
+	got := plan.ChooseDispatcherSettings(context.Background(), ps)
+	want := plan.DispatcherSettings{
+		Throughput: 100,
+		Workers:    1,
+		Priorities: map[plan.NodeID]int{"0": 0},
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("unexpected dispatcher settings -want/+got:\n%s", cmp.Diff(want, got))
+	}
+}
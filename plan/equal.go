@@ -0,0 +1,159 @@
+package plan
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+// EqualProcedureSpec is implemented by procedure specs that
+// know how to compare themselves with another procedure spec.
+type EqualProcedureSpec interface {
+	ProcedureSpec
+
+	// Equal reports whether the other procedure spec
+	// describes exactly the same operation.
+	Equal(other ProcedureSpec) bool
+}
+
+// ProcedureSpecsEqual reports whether two procedure specs describe the same
+// operation so that, given the same input, they produce the same output.
+//
+// If a implements EqualProcedureSpec, its Equal method is used.
+// Otherwise the specs are compared structurally. The structural comparison
+// ignores source locations so that two functions written the same way
+// in different parts of a script are equal. Scopes are only equal if they
+// are the same scope and function values are never equal.
+// A false result does not mean that the procedures are different.
+func ProcedureSpecsEqual(a, b ProcedureSpec) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	if eq, ok := a.(EqualProcedureSpec); ok {
+		return eq.Equal(b)
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+	return deepEqual(va, vb, make(map[visit]bool))
+}
+
+var (
+	locType   = reflect.TypeOf(semantic.Loc{})
+	scopeType = reflect.TypeOf((*values.Scope)(nil)).Elem()
+)
+
+// visit records a comparison that is in progress so cycles terminate.
+type visit struct {
+	a1, a2 unsafe.Pointer
+	typ    reflect.Type
+}
+
+// deepEqual is like reflect.DeepEqual with the exceptions
+// documented on ProcedureSpecsEqual.
+func deepEqual(v1, v2 reflect.Value, visited map[visit]bool) bool {
+	if !v1.IsValid() || !v2.IsValid() {
+		return v1.IsValid() == v2.IsValid()
+	}
+	if v1.Type() != v2.Type() {
+		return false
+	}
+	if v1.Type() == locType {
+		return true
+	}
+
+	switch v1.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v1.IsNil() || v2.IsNil() {
+			return v1.IsNil() == v2.IsNil()
+		}
+		p1, p2 := unsafe.Pointer(v1.Pointer()), unsafe.Pointer(v2.Pointer())
+		if p1 == p2 && (v1.Kind() != reflect.Slice || v1.Len() == v2.Len()) {
+			return true
+		}
+		k := visit{a1: p1, a2: p2, typ: v1.Type()}
+		if visited[k] {
+			return true
+		}
+		visited[k] = true
+	}
+
+	switch v1.Kind() {
+	case reflect.Ptr:
+		return deepEqual(v1.Elem(), v2.Elem(), visited)
+	case reflect.Interface:
+		if v1.IsNil() || v2.IsNil() {
+			return v1.IsNil() == v2.IsNil()
+		}
+		if v1.Type() == scopeType {
+			return sameScope(v1.Elem(), v2.Elem())
+		}
+		return deepEqual(v1.Elem(), v2.Elem(), visited)
+	case reflect.Array, reflect.Slice:
+		if v1.Len() != v2.Len() {
+			return false
+		}
+		for i := 0; i < v1.Len(); i++ {
+			if !deepEqual(v1.Index(i), v2.Index(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i, n := 0, v1.NumField(); i < n; i++ {
+			if !deepEqual(v1.Field(i), v2.Field(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if v1.Len() != v2.Len() {
+			return false
+		}
+		iter := v1.MapRange()
+		for iter.Next() {
+			e2 := v2.MapIndex(iter.Key())
+			if !e2.IsValid() || !deepEqual(iter.Value(), e2, visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func:
+		return v1.IsNil() && v2.IsNil()
+	case reflect.Chan, reflect.UnsafePointer:
+		return v1.Pointer() == v2.Pointer()
+	case reflect.Bool:
+		return v1.Bool() == v2.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v1.Int() == v2.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v1.Uint() == v2.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v1.Float() == v2.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return v1.Complex() == v2.Complex()
+	case reflect.String:
+		return v1.String() == v2.String()
+	default:
+		return false
+	}
+}
+
+// sameScope reports whether two scope values refer to the same scope.
+func sameScope(v1, v2 reflect.Value) bool {
+	if v1.Type() != v2.Type() {
+		return false
+	}
+	switch v1.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return v1.Pointer() == v2.Pointer()
+	default:
+		return false
+	}
+}
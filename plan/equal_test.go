@@ -0,0 +1,81 @@
+package plan_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
+)
+
+func TestProcedureSpecsEqual(t *testing.T) {
+	scope := values.NewScope()
+	fn := func(line int, value string, scope values.Scope) *universe.FilterProcedureSpec {
+		loc := semantic.Loc{Start: ast.Position{Line: line, Column: 1}}
+		return &universe.FilterProcedureSpec{
+			Fn: interpreter.ResolvedFunction{
+				Scope: scope,
+				Fn: &semantic.FunctionExpression{
+					Loc: loc,
+					Block: &semantic.Block{
+						Loc: loc,
+						Body: []semantic.Statement{
+							&semantic.ReturnStatement{
+								Loc:      loc,
+								Argument: &semantic.StringLiteral{Loc: loc, Value: value},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name string
+		a, b plan.ProcedureSpec
+		want bool
+	}{
+		{
+			name: "different locations",
+			a:    fn(1, "a", nil),
+			b:    fn(2, "a", nil),
+			want: true,
+		},
+		{
+			name: "different values",
+			a:    fn(1, "a", nil),
+			b:    fn(1, "b", nil),
+			want: false,
+		},
+		{
+			name: "different kinds",
+			a:    &universe.SumProcedureSpec{},
+			b:    &universe.MeanProcedureSpec{},
+			want: false,
+		},
+		{
+			name: "different scopes",
+			a:    fn(1, "a", values.NewScope()),
+			b:    fn(1, "a", values.NewScope()),
+			want: false,
+		},
+		{
+			name: "same scope",
+			a:    fn(1, "a", scope),
+			b:    fn(2, "a", scope),
+			want: true,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if got := plan.ProcedureSpecsEqual(tc.a, tc.b); tc.want != got {
+				t.Errorf("unexpected result -want/+got:\n\t- %v\n\t+ %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/influxdata/flux"
+)
+
+// Explanation is a physical plan together with the statistics
+// that were collected for each of its nodes while it was executed.
+type Explanation struct {
+	Spec  *Spec
+	Nodes []flux.NodeStatistics
+}
+
+// String returns the plan in the same format as Formatted with
+// the details and statistics for each node.
+func (e *Explanation) String() string {
+	return fmt.Sprintf("%v", Formatted(e.Spec, WithDetails(), WithNodeStatistics(e.Nodes)))
+}
+
+type explainedNode struct {
+	ID           NodeID               `json:"id"`
+	Kind         ProcedureKind        `json:"kind"`
+	Predecessors []NodeID             `json:"predecessors,omitempty"`
+	Details      []string             `json:"details,omitempty"`
+	Statistics   *flux.NodeStatistics `json:"statistics,omitempty"`
+}
+
+// MarshalJSON encodes the nodes of the plan from the sources to the
+// results with the details and statistics for each node.
+func (e *Explanation) MarshalJSON() ([]byte, error) {
+	stats := make(map[NodeID]flux.NodeStatistics, len(e.Nodes))
+	for _, s := range e.Nodes {
+		stats[NodeID(s.NodeID)] = s
+	}
+
+	var nodes []explainedNode
+	if err := e.Spec.BottomUpWalk(func(pn Node) error {
+		n := explainedNode{
+			ID:      pn.ID(),
+			Kind:    pn.Kind(),
+			Details: nodeDetails(e.Spec, pn),
+		}
+		for _, pred := range pn.Predecessors() {
+			n.Predecessors = append(n.Predecessors, pred.ID())
+		}
+		if s, ok := stats[pn.ID()]; ok {
+			n.Statistics = &s
+		}
+		nodes = append(nodes, n)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Nodes []explainedNode `json:"nodes"`
+	}{Nodes: nodes})
+}
@@ -0,0 +1,76 @@
+package plan_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/diff"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+)
+
+func TestExplanation(t *testing.T) {
+	spec := plantest.CreatePlanSpec(&plantest.PlanSpec{
+		Nodes: []plan.Node{
+			plantest.CreatePhysicalMockNode("0"),
+			plantest.CreatePhysicalMockNode("1"),
+		},
+		Edges: [][2]int{{0, 1}},
+	})
+	e := &plan.Explanation{
+		Spec: spec,
+		Nodes: []flux.NodeStatistics{
+			{
+				NodeID:         "0",
+				NodeType:       "mock",
+				RowsOut:        10,
+				TablesOut:      2,
+				BytesAllocated: 128,
+				MaxAllocated:   64,
+				WallTime:       2 * time.Millisecond,
+				ParallelFactor: 1,
+			},
+			{
+				NodeID:         "1",
+				NodeType:       "mock",
+				RowsIn:         10,
+				RowsOut:        2,
+				TablesIn:       2,
+				TablesOut:      2,
+				WallTime:       time.Millisecond,
+				ParallelFactor: 1,
+			},
+		},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		want := `digraph {
+  "0"
+  // rows: in=0 out=10, tables: in=0 out=2, allocated: 128 bytes (max 64 bytes), time: 2ms, parallel: 1
+  "1"
+  // rows: in=10 out=2, tables: in=2 out=2, allocated: 0 bytes (max 0 bytes), time: 1ms, parallel: 1
+
+  "0" -> "1"
+}
+`
+		if got := e.String(); want != got {
+			t.Errorf("unexpected explanation -want/+got:\n%s", diff.LineDiff(want, got))
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		want := `{"nodes":[` +
+			`{"id":"0","kind":"mock","statistics":{"node_id":"0","node_type":"mock","rows_in":0,"rows_out":10,"tables_in":0,"tables_out":2,"bytes_allocated":128,"max_allocated":64,"wall_time":2000000,"parallel_factor":1}},` +
+			`{"id":"1","kind":"mock","predecessors":["0"],"statistics":{"node_id":"1","node_type":"mock","rows_in":10,"rows_out":2,"tables_in":2,"tables_out":2,"bytes_allocated":0,"max_allocated":0,"wall_time":1000000,"parallel_factor":1}}` +
+			`]}`
+		got, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != string(got) {
+			t.Errorf("unexpected json -want/+got:\n%s", diff.LineDiff(want, string(got)))
+		}
+	})
+}
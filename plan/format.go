@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"runtime/debug"
 	"strings"
+
+	"github.com/influxdata/flux"
 )
 
 type FormatOption func(*formatter)
@@ -30,6 +32,17 @@ func WithDetails() FormatOption {
 	}
 }
 
+// WithNodeStatistics returns a FormatOption that annotates each node
+// in a formatted plan with the statistics collected while executing it.
+func WithNodeStatistics(stats []flux.NodeStatistics) FormatOption {
+	return func(f *formatter) {
+		f.stats = make(map[NodeID]flux.NodeStatistics, len(stats))
+		for _, s := range stats {
+			f.stats[NodeID(s.NodeID)] = s
+		}
+	}
+}
+
 // Detailer provides an optional interface that ProcedureSpecs can implement.
 // Implementors of this interface will have their details appear in the
 // formatted output for a plan if the WithDetails() option is set.
@@ -39,6 +52,7 @@ type Detailer interface {
 
 type formatter struct {
 	withDetails bool
+	stats       map[NodeID]flux.NodeStatistics
 	p           *Spec
 }
 
@@ -59,31 +73,20 @@ func (f formatter) Format(fs fmt.State, c rune) {
 	}()
 
 	_, _ = fmt.Fprintf(fs, "digraph {\n")
+	if !f.p.Dispatcher.IsZero() {
+		_, _ = fmt.Fprintf(fs, "  // dispatcher: %v\n", f.p.Dispatcher)
+	}
 	var edges []string
 	_ = f.p.BottomUpWalk(func(pn Node) error {
 		_, _ = fmt.Fprintf(fs, "  %v\n", formatAsDOT(pn.ID()))
 		if f.withDetails {
-			details := ""
-			if d, ok := pn.ProcedureSpec().(Detailer); ok {
-				details += d.PlanDetails() + "\n"
-			}
-
-			if ppn, ok := pn.(*PhysicalPlanNode); ok {
-				for _, attr := range ppn.outputAttrs() {
-					if d, ok := attr.(Detailer); ok {
-						details += d.PlanDetails() + "\n"
-					}
-				}
-			}
-
-			lines := strings.Split(strings.TrimSpace(details), "\n")
-
-			for _, line := range lines {
-				if len(line) > 0 {
-					_, _ = fmt.Fprintf(fs, "  // %s\n", line)
-				}
+			for _, line := range nodeDetails(f.p, pn) {
+				_, _ = fmt.Fprintf(fs, "  // %s\n", line)
 			}
 		}
+		if s, ok := f.stats[pn.ID()]; ok {
+			_, _ = fmt.Fprintf(fs, "  // %s\n", formatNodeStatistics(s))
+		}
 		for _, pred := range pn.Predecessors() {
 			edges = append(edges, fmt.Sprintf("  %v -> %v", formatAsDOT(pred.ID()), formatAsDOT(pn.ID())))
 		}
@@ -96,3 +99,35 @@ func (f formatter) Format(fs fmt.State, c rune) {
 	}
 	_, _ = fmt.Fprintf(fs, "}\n")
 }
+
+// nodeDetails returns the lines of details for a node in the plan.
+func nodeDetails(p *Spec, pn Node) []string {
+	details := ""
+	if d, ok := pn.ProcedureSpec().(Detailer); ok {
+		details += d.PlanDetails() + "\n"
+	}
+	if priority, ok := p.Dispatcher.Priorities[pn.ID()]; ok {
+		details += fmt.Sprintf("priority: %d\n", priority)
+	}
+
+	if ppn, ok := pn.(*PhysicalPlanNode); ok {
+		for _, attr := range ppn.outputAttrs() {
+			if d, ok := attr.(Detailer); ok {
+				details += d.PlanDetails() + "\n"
+			}
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(details), "\n") {
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func formatNodeStatistics(s flux.NodeStatistics) string {
+	return fmt.Sprintf("rows: in=%d out=%d, tables: in=%d out=%d, allocated: %d bytes (max %d bytes), time: %v, parallel: %d",
+		s.RowsIn, s.RowsOut, s.TablesIn, s.TablesOut, s.BytesAllocated, s.MaxAllocated, s.WallTime, s.ParallelFactor)
+}
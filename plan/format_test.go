@@ -27,9 +27,10 @@ func TestFormatted(t *testing.T) {
 	}
 
 	type testcase struct {
-		name string
-		plan *plantest.PlanSpec
-		want string
+		name       string
+		plan       *plantest.PlanSpec
+		dispatcher plan.DispatcherSettings
+		want       string
 	}
 
 	tcs := []testcase{
@@ -90,6 +91,33 @@ func TestFormatted(t *testing.T) {
 
   "source" -> "merge"
 }
+`,
+		},
+		{
+			name: "dispatcher settings",
+			plan: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalNode("source", spec.MockProcedureSpec{}),
+					plantest.CreatePhysicalNode("map", spec.MockProcedureSpec{}),
+				},
+				Edges: [][2]int{
+					{0, 1},
+				},
+			},
+			dispatcher: plan.DispatcherSettings{
+				Throughput: 10,
+				Workers:    3,
+				Priorities: map[plan.NodeID]int{"source": 0, "map": 1},
+			},
+			want: `digraph {
+  // dispatcher: throughput=10 workers=3
+  "source"
+  // priority: 0
+  "map"
+  // priority: 1
+
+  "source" -> "map"
+}
 `,
 		},
 	}
@@ -98,6 +126,7 @@ func TestFormatted(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			ps := plantest.CreatePlanSpec(tc.plan)
+			ps.Dispatcher = tc.dispatcher
 			got := fmt.Sprintf("%v", plan.Formatted(ps, plan.WithDetails()))
 			if tc.want != got {
 				t.Fatalf("unexpected output: -want/+got:\n%v", diff.LineDiff(tc.want, got))
@@ -114,6 +114,16 @@ func (p *heuristicPlanner) matchRules(ctx context.Context, spec *Spec, node Node
 // Plan may change its argument and/or return a new instance of Spec, so the correct way to call Plan is:
 // plan, err = plan.Plan(plan)
 func (p *heuristicPlanner) Plan(ctx context.Context, inputPlan *Spec) (*Spec, error) {
+	if _, err := p.plan(ctx, inputPlan); err != nil {
+		return nil, err
+	}
+	return inputPlan, nil
+}
+
+// plan runs the fixed-point algorithm described in Plan and
+// reports whether any rule changed the plan.
+func (p *heuristicPlanner) plan(ctx context.Context, inputPlan *Spec) (bool, error) {
+	everChanged := false
 	for anyChanged := true; anyChanged; {
 		visited := make(map[Node]struct{})
 		visitedSuccessors := make(map[Node]int)
@@ -141,9 +151,10 @@ func (p *heuristicPlanner) Plan(ctx context.Context, inputPlan *Spec) (*Spec, er
 			if !alreadyVisited && visitable {
 				newNode, changed, err := p.matchRules(ctx, inputPlan, node)
 				if err != nil {
-					return nil, err
+					return false, err
 				}
 				anyChanged = anyChanged || changed
+				everChanged = everChanged || changed
 
 				// append to stack in reverse order so lower-indexed children
 				// are visited first.
@@ -157,7 +168,7 @@ func (p *heuristicPlanner) Plan(ctx context.Context, inputPlan *Spec) (*Spec, er
 		}
 	}
 
-	return inputPlan, nil
+	return everChanged, nil
 }
 
 // updateSuccessors looks at all the successors of oldNode
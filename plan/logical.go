@@ -27,7 +27,8 @@ type LogicalPlanner interface {
 // been registered.
 func NewLogicalPlanner(options ...LogicalOption) LogicalPlanner {
 	thePlanner := &logicalPlanner{
-		heuristicPlanner: newHeuristicPlanner(),
+		heuristicPlanner:              newHeuristicPlanner(),
+		eliminateCommonSubexpressions: true,
 	}
 
 	rules := make([]Rule, len(ruleNameToLogicalRule))
@@ -61,15 +62,26 @@ func (opt logicalOption) apply(lp *logicalPlanner) {
 
 type logicalPlanner struct {
 	*heuristicPlanner
-	disableIntegrityChecks bool
+	disableIntegrityChecks        bool
+	eliminateCommonSubexpressions bool
 }
 
 // OnlyLogicalRules produces a logical plan option that forces only a set of particular rules to be
-// applied.
+// applied. Common subexpression elimination is disabled unless it is enabled again
+// with EnableCommonSubexpressionElimination.
 func OnlyLogicalRules(rules ...Rule) LogicalOption {
 	return logicalOption(func(lp *logicalPlanner) {
 		lp.clearRules()
 		lp.addRules(rules...)
+		lp.eliminateCommonSubexpressions = false
+	})
+}
+
+// EnableCommonSubexpressionElimination makes the logical planner merge the nodes
+// that perform the same procedure on the same input. It is enabled by default.
+func EnableCommonSubexpressionElimination() LogicalOption {
+	return logicalOption(func(lp *logicalPlanner) {
+		lp.eliminateCommonSubexpressions = true
 	})
 }
 
@@ -79,9 +91,17 @@ func AddLogicalRules(rules ...Rule) LogicalOption {
 	})
 }
 
+// RemoveLogicalRules disables the rules with the given names.
+// CommonSubexpressionEliminationRule may be used to disable
+// common subexpression elimination.
 func RemoveLogicalRules(rules ...string) LogicalOption {
 	return logicalOption(func(lp *logicalPlanner) {
 		lp.removeRules(rules...)
+		for _, name := range rules {
+			if name == CommonSubexpressionEliminationRule {
+				lp.eliminateCommonSubexpressions = false
+			}
+		}
 	})
 }
 
@@ -98,12 +118,26 @@ func (l *logicalPlanner) CreateInitialPlan(spec *operation.Spec) (*Spec, error)
 }
 
 // Plan transforms the given naive plan by applying rules.
+// Once the rules reach a fixed point, duplicate subexpressions are merged
+// and the rules are applied again if any nodes were merged.
 func (l *logicalPlanner) Plan(ctx context.Context, logicalPlan *Spec) (*Spec, error) {
 	newLogicalPlan, err := l.heuristicPlanner.Plan(ctx, logicalPlan)
 	if err != nil {
 		return nil, err
 	}
 
+	if l.eliminateCommonSubexpressions {
+		changed, err := eliminateCommonSubexpressions(newLogicalPlan)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			if newLogicalPlan, err = l.heuristicPlanner.Plan(ctx, newLogicalPlan); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// check integrity after planning is complete
 	if !l.disableIntegrityChecks {
 		err := newLogicalPlan.CheckIntegrity()
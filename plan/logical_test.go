@@ -22,6 +22,7 @@ import (
 	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
 	"github.com/influxdata/flux/stdlib/kafka"
 	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
 	"github.com/influxdata/flux/values/valuestest"
 )
 
@@ -717,3 +718,180 @@ from(bucket: "telegraf")
 		t.Fatal("unexpected pass")
 	}
 }
+
+func TestLogicalPlanner_CommonSubexpressionElimination(t *testing.T) {
+	scope := values.NewScope()
+	filter := func(line int, measurement string) *universe.FilterProcedureSpec {
+		loc := semantic.Loc{Start: ast.Position{Line: line, Column: 1}}
+		return &universe.FilterProcedureSpec{
+			Fn: interpreter.ResolvedFunction{
+				Scope: scope,
+				Fn: &semantic.FunctionExpression{
+					Loc: loc,
+					Parameters: &semantic.FunctionParameters{
+						Loc:  loc,
+						List: []*semantic.FunctionParameter{{Loc: loc, Key: &semantic.Identifier{Loc: loc, Name: semantic.NewSymbol("r")}}},
+					},
+					Block: &semantic.Block{
+						Loc: loc,
+						Body: []semantic.Statement{
+							&semantic.ReturnStatement{
+								Loc: loc,
+								Argument: &semantic.BinaryExpression{
+									Loc:      loc,
+									Operator: ast.EqualOperator,
+									Left:     &semantic.MemberExpression{Loc: loc, Object: &semantic.IdentifierExpression{Loc: loc, Name: semantic.NewSymbol("r")}, Property: semantic.NewSymbol("_measurement")},
+									Right:    &semantic.StringLiteral{Loc: loc, Value: measurement},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	from := func() *influxdb.FromProcedureSpec {
+		return &influxdb.FromProcedureSpec{Bucket: influxdb.NameOrID{Name: "telegraf"}}
+	}
+	rangeSpec := func() *universe.RangeProcedureSpec {
+		return &universe.RangeProcedureSpec{
+			Bounds: flux.Bounds{
+				Start: flux.Time{IsRelative: true, Relative: -time.Hour},
+				Stop:  flux.Now,
+			},
+			TimeColumn:  "_time",
+			StartColumn: "_start",
+			StopColumn:  "_stop",
+		}
+	}
+
+	testcases := []struct {
+		name    string
+		options []plan.LogicalOption
+		before  plantest.PlanSpec
+		after   plantest.PlanSpec
+	}{
+		{
+			name: "identical pipelines",
+			before: plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from0", from()),
+					plan.CreateLogicalNode("range1", rangeSpec()),
+					plan.CreateLogicalNode("filter2", filter(2, "cpu")),
+					plan.CreateLogicalNode("sum3", &universe.SumProcedureSpec{}),
+					plan.CreateLogicalNode("yield4", &universe.YieldProcedureSpec{Name: "sum"}),
+					plan.CreateLogicalNode("from5", from()),
+					plan.CreateLogicalNode("range6", rangeSpec()),
+					plan.CreateLogicalNode("filter7", filter(3, "cpu")),
+					plan.CreateLogicalNode("mean8", &universe.MeanProcedureSpec{}),
+					plan.CreateLogicalNode("yield9", &universe.YieldProcedureSpec{Name: "mean"}),
+				},
+				Edges: [][2]int{
+					{0, 1}, {1, 2}, {2, 3}, {3, 4},
+					{5, 6}, {6, 7}, {7, 8}, {8, 9},
+				},
+			},
+			after: plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from0", from()),
+					plan.CreateLogicalNode("range1", rangeSpec()),
+					plan.CreateLogicalNode("filter2", filter(2, "cpu")),
+					plan.CreateLogicalNode("sum3", &universe.SumProcedureSpec{}),
+					plan.CreateLogicalNode("yield4", &universe.YieldProcedureSpec{Name: "sum"}),
+					plan.CreateLogicalNode("mean8", &universe.MeanProcedureSpec{}),
+					plan.CreateLogicalNode("yield9", &universe.YieldProcedureSpec{Name: "mean"}),
+				},
+				Edges: [][2]int{
+					{0, 1}, {1, 2}, {2, 3}, {3, 4},
+					{2, 5}, {5, 6},
+				},
+			},
+		},
+		{
+			name: "pipelines that diverge",
+			before: plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from0", from()),
+					plan.CreateLogicalNode("range1", rangeSpec()),
+					plan.CreateLogicalNode("filter2", filter(2, "cpu")),
+					plan.CreateLogicalNode("yield3", &universe.YieldProcedureSpec{Name: "cpu"}),
+					plan.CreateLogicalNode("from4", from()),
+					plan.CreateLogicalNode("range5", rangeSpec()),
+					plan.CreateLogicalNode("filter6", filter(3, "mem")),
+					plan.CreateLogicalNode("yield7", &universe.YieldProcedureSpec{Name: "mem"}),
+				},
+				Edges: [][2]int{
+					{0, 1}, {1, 2}, {2, 3},
+					{4, 5}, {5, 6}, {6, 7},
+				},
+			},
+			after: plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from0", from()),
+					plan.CreateLogicalNode("range1", rangeSpec()),
+					plan.CreateLogicalNode("filter2", filter(2, "cpu")),
+					plan.CreateLogicalNode("yield3", &universe.YieldProcedureSpec{Name: "cpu"}),
+					plan.CreateLogicalNode("filter6", filter(3, "mem")),
+					plan.CreateLogicalNode("yield7", &universe.YieldProcedureSpec{Name: "mem"}),
+				},
+				Edges: [][2]int{
+					{0, 1}, {1, 2}, {2, 3},
+					{1, 4}, {4, 5},
+				},
+			},
+		},
+		{
+			name: "same successor",
+			before: plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from0", from()),
+					plan.CreateLogicalNode("from1", from()),
+					plan.CreateLogicalNode("union2", &universe.UnionProcedureSpec{}),
+					plan.CreateLogicalNode("yield3", &universe.YieldProcedureSpec{Name: "result"}),
+				},
+				Edges: [][2]int{
+					{0, 2}, {1, 2}, {2, 3},
+				},
+			},
+		},
+		{
+			name:    "disabled",
+			options: []plan.LogicalOption{plan.RemoveLogicalRules(plan.CommonSubexpressionEliminationRule)},
+			before: plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreateLogicalNode("from0", from()),
+					plan.CreateLogicalNode("yield1", &universe.YieldProcedureSpec{Name: "a"}),
+					plan.CreateLogicalNode("from2", from()),
+					plan.CreateLogicalNode("yield3", &universe.YieldProcedureSpec{Name: "b"}),
+				},
+				Edges: [][2]int{
+					{0, 1}, {2, 3},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			after := tc.after
+			if after.Nodes == nil {
+				after = *tc.before.Copy()
+			}
+			options := append([]plan.LogicalOption{
+				plan.OnlyLogicalRules(),
+				plan.EnableCommonSubexpressionElimination(),
+			}, tc.options...)
+
+			logicalPlanner := plan.NewLogicalPlanner(options...)
+			got, err := logicalPlanner.Plan(context.Background(), plantest.CreatePlanSpec(&tc.before))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := plantest.CreatePlanSpec(&after)
+			if err := plantest.CompareLogicalPlans(want, got); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
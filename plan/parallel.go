@@ -1,7 +1,11 @@
 package plan
 
 import (
+	"context"
 	"fmt"
+
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
 )
 
 // Physical attributes used in specifying parallelization.
@@ -68,3 +72,73 @@ func (a ParallelMergeAttribute) PlanDetails() string {
 func (a ParallelMergeAttribute) String() string {
 	return fmt.Sprintf("%v{Factor: %d}", ParallelMergeKey, a.Factor)
 }
+
+// PartitionMode determines how the data produced by a source
+// is divided between the copies of a parallel plan.
+type PartitionMode int
+
+const (
+	// PartitionByGroupKey sends each table to a single partition
+	// chosen from a hash of its group key.
+	PartitionByGroupKey PartitionMode = iota
+
+	// PartitionByTime divides the rows of each table between the
+	// partitions so that each partition covers an equal part of the
+	// query time range. Tables are partitioned by group key when the
+	// time range is not known.
+	PartitionByTime
+)
+
+func (m PartitionMode) String() string {
+	switch m {
+	case PartitionByGroupKey:
+		return "group"
+	case PartitionByTime:
+		return "time"
+	default:
+		return fmt.Sprintf("PartitionMode(%d)", int(m))
+	}
+}
+
+// ParsePartitionMode returns the partition mode with the given name.
+func ParsePartitionMode(s string) (PartitionMode, error) {
+	switch s {
+	case "", "group":
+		return PartitionByGroupKey, nil
+	case "time":
+		return PartitionByTime, nil
+	default:
+		return 0, errors.Newf(codes.Invalid, "unknown partition mode %q", s)
+	}
+}
+
+// Parallelism configures the rules that parallelize a physical plan.
+type Parallelism struct {
+	// Factor is the number of partitions the data from a source
+	// is divided into. Parallelization is disabled when the
+	// factor is less than 2.
+	Factor int
+	// Mode determines how the data is partitioned.
+	Mode PartitionMode
+}
+
+// Enabled reports whether plans should be parallelized.
+func (p Parallelism) Enabled() bool {
+	return p.Factor > 1
+}
+
+type parallelismKey struct{}
+
+// ContextWithParallelism returns a context that carries the parallelism
+// used by the parallelization rules.
+func ContextWithParallelism(ctx context.Context, p Parallelism) context.Context {
+	return context.WithValue(ctx, parallelismKey{}, p)
+}
+
+// ParallelismFromContext returns the parallelism stored in the context.
+// The zero value, which disables parallelization, is returned when
+// the context does not carry a parallelism.
+func ParallelismFromContext(ctx context.Context) Parallelism {
+	p, _ := ctx.Value(parallelismKey{}).(Parallelism)
+	return p
+}
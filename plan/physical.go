@@ -21,9 +21,10 @@ type PhysicalPlanner interface {
 // The new plan will be configured to apply any physical rules that have been registered.
 func NewPhysicalPlanner(options ...PhysicalOption) PhysicalPlanner {
 	pp := &physicalPlanner{
-		heuristicPlannerPhysical: newHeuristicPlanner(),
-		heuristicPlannerParallel: newHeuristicPlanner(),
-		defaultMemoryLimit:       math.MaxInt64,
+		heuristicPlannerPhysical:  newHeuristicPlanner(),
+		heuristicPlannerCostBased: newHeuristicPlanner(),
+		heuristicPlannerParallel:  newHeuristicPlanner(),
+		defaultMemoryLimit:        math.MaxInt64,
 	}
 
 	rulesPhysical := make([]Rule, len(ruleNameToPhysicalRule))
@@ -33,6 +34,11 @@ func NewPhysicalPlanner(options ...PhysicalOption) PhysicalPlanner {
 		i++
 	}
 
+	rulesCostBased := make([]Rule, 0, len(ruleNameToCostBasedRule))
+	for _, v := range ruleNameToCostBasedRule {
+		rulesCostBased = append(rulesCostBased, v)
+	}
+
 	rulesParallel := make([]Rule, len(ruleNameToParallelizeRules))
 	i = 0
 	for _, v := range ruleNameToParallelizeRules {
@@ -44,6 +50,8 @@ func NewPhysicalPlanner(options ...PhysicalOption) PhysicalPlanner {
 
 	pp.heuristicPlannerPhysical.addRules(physicalConverterRule{})
 
+	pp.heuristicPlannerCostBased.addRules(rulesCostBased...)
+
 	pp.heuristicPlannerParallel.addRules(rulesParallel...)
 
 	// Options may add or remove rules, so process them after we've
@@ -56,11 +64,29 @@ func NewPhysicalPlanner(options ...PhysicalOption) PhysicalPlanner {
 }
 
 func (pp *physicalPlanner) Plan(ctx context.Context, spec *Spec) (*Spec, error) {
+	if pp.parallelism.Enabled() {
+		ctx = ContextWithParallelism(ctx, pp.parallelism)
+	}
+	if pp.statisticsProbes {
+		ctx = ContextWithStatisticsProbes(ctx)
+	}
+
 	intermediateSpec, err := pp.heuristicPlannerPhysical.Plan(ctx, spec)
 	if err != nil {
 		return nil, err
 	}
 
+	// Choose between alternative implementations using the estimated cost.
+	// A cost-based rule may introduce new nodes (such as the sorts required by
+	// a merge join), so the physical rules are applied again when it does.
+	if changed, err := pp.heuristicPlannerCostBased.plan(ctx, intermediateSpec); err != nil {
+		return nil, err
+	} else if changed {
+		if intermediateSpec, err = pp.heuristicPlannerPhysical.Plan(ctx, intermediateSpec); err != nil {
+			return nil, err
+		}
+	}
+
 	transformedSpec, err := pp.heuristicPlannerParallel.Plan(ctx, intermediateSpec)
 	if err != nil {
 		return nil, err
@@ -76,6 +102,9 @@ func (pp *physicalPlanner) Plan(ctx context.Context, spec *Spec) (*Spec, error)
 		return nil, err
 	}
 
+	// Choose how the executor should schedule work for this plan.
+	transformedSpec.Dispatcher = ChooseDispatcherSettings(ctx, transformedSpec)
+
 	// Ensure that the plan is valid
 	if !pp.disableValidation {
 		err := transformedSpec.CheckIntegrity()
@@ -125,10 +154,13 @@ func ValidatePhysicalPlan(plan *Spec) error {
 }
 
 type physicalPlanner struct {
-	heuristicPlannerPhysical *heuristicPlanner
-	heuristicPlannerParallel *heuristicPlanner
-	defaultMemoryLimit       int64
-	disableValidation        bool
+	heuristicPlannerPhysical  *heuristicPlanner
+	heuristicPlannerCostBased *heuristicPlanner
+	heuristicPlannerParallel  *heuristicPlanner
+	defaultMemoryLimit        int64
+	disableValidation         bool
+	parallelism               Parallelism
+	statisticsProbes          bool
 }
 
 // PhysicalOption is an option to configure the behavior of the physical plan.
@@ -150,10 +182,29 @@ func WithDefaultMemoryLimit(memBytes int64) PhysicalOption {
 	})
 }
 
+// WithParallelism sets the parallelism used by the parallelization rules.
+// Sources are divided into p.Factor partitions so the stateless
+// transformations that follow them run in parallel.
+func WithParallelism(p Parallelism) PhysicalOption {
+	return physicalOption(func(pp *physicalPlanner) {
+		pp.parallelism = p
+	})
+}
+
+// EnableStatisticsProbes allows sources to query databases and remote
+// services for statistics about their data while the plan is chosen.
+// Without it, only statistics that can be estimated locally are used.
+func EnableStatisticsProbes() PhysicalOption {
+	return physicalOption(func(pp *physicalPlanner) {
+		pp.statisticsProbes = true
+	})
+}
+
 // OnlyPhysicalRules produces a physical plan option that forces only a particular set of rules to be applied.
 func OnlyPhysicalRules(rules ...Rule) PhysicalOption {
 	return physicalOption(func(pp *physicalPlanner) {
 		pp.heuristicPlannerPhysical.clearRules()
+		pp.heuristicPlannerCostBased.clearRules()
 		pp.heuristicPlannerParallel.clearRules()
 		// Always add physicalConverterRule. It doesn't change the plan but only convert nodes to physical.
 		// This is required for some pieces to work on the physical plan (e.g. SetTriggerSpec).
@@ -168,9 +219,18 @@ func AddParallelRules(rules ...Rule) PhysicalOption {
 	})
 }
 
+// AddCostBasedRules adds rules that are applied once the physical rules
+// have reached a fixed point.
+func AddCostBasedRules(rules ...Rule) PhysicalOption {
+	return physicalOption(func(pp *physicalPlanner) {
+		pp.heuristicPlannerCostBased.addRules(rules...)
+	})
+}
+
 func RemovePhysicalRules(rules ...string) PhysicalOption {
 	return physicalOption(func(pp *physicalPlanner) {
 		pp.heuristicPlannerPhysical.removeRules(rules...)
+		pp.heuristicPlannerCostBased.removeRules(rules...)
 		pp.heuristicPlannerParallel.removeRules(rules...)
 	})
 }
@@ -225,7 +285,7 @@ func (physicalConverterRule) Rewrite(ctx context.Context, pn Node) (Node, bool,
 type PhysicalProcedureSpec interface {
 	Kind() ProcedureKind
 	Copy() ProcedureSpec
-	Cost(inStats []Statistics) (cost Cost, outStats Statistics)
+	Coster
 }
 
 // PhysicalPlanNode represents a physical operation in a plan.
@@ -239,6 +299,9 @@ type PhysicalPlanNode struct {
 	// The trigger spec defines how and when a transformation
 	// sends its tables to downstream operators
 	TriggerSpec TriggerSpec
+
+	// statistics memoizes the estimate of a StatisticsEstimator spec.
+	statistics *memoizedStatistics
 }
 
 // ID returns a human-readable id for this plan node.
@@ -73,6 +73,7 @@ func HasSideEffect(spec ProcedureSpec) bool {
 var ruleNameToLogicalRule = make(map[string]Rule)
 var ruleNameToPhysicalRule = make(map[string]Rule)
 var ruleNameToParallelizeRules = make(map[string]Rule)
+var ruleNameToCostBasedRule = make(map[string]Rule)
 
 // RegisterLogicalRules registers the rule created by createFn with the logical plan.
 func RegisterLogicalRules(rules ...Rule) {
@@ -89,6 +90,14 @@ func RegisterParallelizeRules(rules ...Rule) {
 	registerRule(ruleNameToParallelizeRules, rules...)
 }
 
+// RegisterCostBasedRules registers rules that choose between alternative
+// physical implementations by comparing their estimated cost.
+// These rules are applied after the physical rules have reached a fixed point
+// so the statistics they consult describe the final shape of the sources.
+func RegisterCostBasedRules(rules ...Rule) {
+	registerRule(ruleNameToCostBasedRule, rules...)
+}
+
 func registerRule(ruleMap map[string]Rule, rules ...Rule) {
 	for _, rule := range rules {
 		name := rule.Name()
@@ -103,4 +112,5 @@ func ClearRegisteredRules() {
 	ruleNameToLogicalRule = make(map[string]Rule)
 	ruleNameToPhysicalRule = make(map[string]Rule)
 	ruleNameToParallelizeRules = make(map[string]Rule)
+	ruleNameToCostBasedRule = make(map[string]Rule)
 }
@@ -65,6 +65,10 @@ type Spec struct {
 	Roots     map[Node]struct{}
 	Resources flux.ResourceManagement
 	Now       time.Time
+
+	// Dispatcher holds the settings the executor uses
+	// to schedule work. They are chosen by the physical planner.
+	Dispatcher DispatcherSettings
 }
 
 // NewPlanSpec initializes a new query plan
@@ -59,6 +59,11 @@ type Statistics struct {
 	// Profiles holds the profiles for each transport (source/transformation) in this query.
 	Profiles []TransportProfile `json:"profiles"`
 
+	// Nodes holds the statistics for each node in the query plan.
+	// The memory used by each node is always reported. The rows, tables
+	// and wall time are only collected when the query is explained.
+	Nodes []NodeStatistics `json:"nodes,omitempty"`
+
 	// RuntimeErrors contains error messages that happened during the execution of the query.
 	RuntimeErrors []string `json:"runtime_errors"`
 
@@ -77,6 +82,12 @@ func (s Statistics) Add(other Statistics) Statistics {
 	profiles := make([]TransportProfile, 0, len(s.Profiles)+len(other.Profiles))
 	profiles = append(profiles, s.Profiles...)
 	profiles = append(profiles, other.Profiles...)
+	var nodes []NodeStatistics
+	if len(s.Nodes)+len(other.Nodes) > 0 {
+		nodes = make([]NodeStatistics, 0, len(s.Nodes)+len(other.Nodes))
+		nodes = append(nodes, s.Nodes...)
+		nodes = append(nodes, other.Nodes...)
+	}
 	return Statistics{
 		TotalDuration:   s.TotalDuration + other.TotalDuration,
 		CompileDuration: s.CompileDuration + other.CompileDuration,
@@ -88,6 +99,7 @@ func (s Statistics) Add(other Statistics) Statistics {
 		MaxAllocated:    s.MaxAllocated + other.MaxAllocated,
 		TotalAllocated:  s.TotalAllocated + other.TotalAllocated,
 		Profiles:        profiles,
+		Nodes:           nodes,
 		RuntimeErrors:   errs,
 		Metadata:        md,
 	}
@@ -105,10 +117,47 @@ func (s *Statistics) Merge(other Statistics) {
 	s.MaxAllocated += other.MaxAllocated
 	s.TotalAllocated += other.TotalAllocated
 	s.Profiles = append(s.Profiles, other.Profiles...)
+	s.Nodes = append(s.Nodes, other.Nodes...)
 	s.RuntimeErrors = append(s.RuntimeErrors, other.RuntimeErrors...)
 	s.Metadata.AddAll(other.Metadata)
 }
 
+// NodeStatistics holds the statistics for a single node in the query plan.
+// The statistics for a node include every parallel copy of that node.
+type NodeStatistics struct {
+	// NodeID is the ID of the plan node.
+	NodeID string `json:"node_id"`
+
+	// NodeType is the kind of procedure for the plan node.
+	NodeType string `json:"node_type"`
+
+	// RowsIn is the number of rows the node received.
+	RowsIn int64 `json:"rows_in"`
+
+	// RowsOut is the number of rows the node produced.
+	RowsOut int64 `json:"rows_out"`
+
+	// TablesIn is the number of tables the node received.
+	TablesIn int64 `json:"tables_in"`
+
+	// TablesOut is the number of tables the node produced.
+	TablesOut int64 `json:"tables_out"`
+
+	// BytesAllocated is the total number of bytes the node allocated.
+	// The number includes memory that was freed and then used again.
+	BytesAllocated int64 `json:"bytes_allocated"`
+
+	// MaxAllocated is the maximum number of bytes
+	// the node had allocated at any one time.
+	MaxAllocated int64 `json:"max_allocated"`
+
+	// WallTime is the time spent processing data in the node.
+	WallTime time.Duration `json:"wall_time"`
+
+	// ParallelFactor is the number of copies of the node that were run.
+	ParallelFactor int `json:"parallel_factor"`
+}
+
 // TransportProfile holds the profile for transport statistics.
 type TransportProfile struct {
 	// NodeType holds the node type which is a string representation
@@ -0,0 +1,433 @@
+package anomalydetection
+
+import (
+	"math"
+	"sort"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/values"
+)
+
+const pkgPath = "contrib/anaisdg/anomalydetection"
+
+const (
+	RollingMADKind       = "rollingMADKind"
+	RollingZScoreKind    = "rollingZScoreKind"
+	SeasonalBaselineKind = "seasonalBaselineKind"
+)
+
+const (
+	scoreColLabel = "score"
+	levelColLabel = "level"
+
+	anomalyLevel = "anomaly"
+	normalLevel  = "normal"
+
+	defaultThreshold = 3.0
+	defaultPeriods   = 3
+
+	// madScale makes the median absolute deviation a consistent
+	// estimator of the standard deviation of normally distributed data.
+	madScale = 1.4826
+)
+
+// detectors maps the name of each detection function to its kind.
+var detectors = map[string]plan.ProcedureKind{
+	"rollingMAD":       RollingMADKind,
+	"rollingZScore":    RollingZScoreKind,
+	"seasonalBaseline": SeasonalBaselineKind,
+}
+
+// DetectOpSpec is the operation spec of rollingMAD, rollingZScore
+// and seasonalBaseline. The method selects the function.
+type DetectOpSpec struct {
+	Method     string        `json:"method"`
+	Window     int64         `json:"window"`
+	Period     flux.Duration `json:"period"`
+	Periods    int64         `json:"periods"`
+	Threshold  float64       `json:"threshold"`
+	Column     string        `json:"column"`
+	TimeColumn string        `json:"timeColumn"`
+}
+
+func init() {
+	for method, kind := range detectors {
+		method := method
+		signature := runtime.MustLookupBuiltinType(pkgPath, method)
+		runtime.RegisterPackageValue(pkgPath, method, flux.MustValue(flux.FunctionValue(method, func(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+			return createDetectOpSpec(method, args, a)
+		}, signature)))
+		plan.RegisterProcedureSpec(kind, newDetectProcedure, flux.OperationKind(kind))
+		execute.RegisterTransformation(kind, createDetectTransformation)
+	}
+}
+
+func createDetectOpSpec(method string, args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := &DetectOpSpec{
+		Method: method,
+	}
+
+	switch method {
+	case "rollingMAD", "rollingZScore":
+		window, err := args.GetRequiredInt("window")
+		if err != nil {
+			return nil, err
+		}
+		if window < 2 {
+			return nil, errors.Newf(codes.Invalid, "window must be at least 2, got %d", window)
+		}
+		spec.Window = window
+	case "seasonalBaseline":
+		period, err := args.GetRequiredDuration("period")
+		if err != nil {
+			return nil, err
+		}
+		if period.IsNegative() || period.IsZero() {
+			return nil, errors.New(codes.Invalid, "period must be positive")
+		}
+		spec.Period = period
+
+		if periods, ok, err := args.GetInt("periods"); err != nil {
+			return nil, err
+		} else if ok {
+			if periods < 2 {
+				return nil, errors.Newf(codes.Invalid, "periods must be at least 2, got %d", periods)
+			}
+			spec.Periods = periods
+		} else {
+			spec.Periods = defaultPeriods
+		}
+		if col, ok, err := args.GetString("timeColumn"); err != nil {
+			return nil, err
+		} else if ok {
+			spec.TimeColumn = col
+		} else {
+			spec.TimeColumn = execute.DefaultTimeColLabel
+		}
+	}
+
+	if threshold, ok, err := args.GetFloat("threshold"); err != nil {
+		return nil, err
+	} else if ok {
+		if threshold <= 0 {
+			return nil, errors.Newf(codes.Invalid, "threshold must be positive, got %v", threshold)
+		}
+		spec.Threshold = threshold
+	} else {
+		spec.Threshold = defaultThreshold
+	}
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+	return spec, nil
+}
+
+func (s *DetectOpSpec) Kind() flux.OperationKind {
+	return flux.OperationKind(detectors[s.Method])
+}
+
+type DetectProcedureSpec struct {
+	plan.DefaultCost
+	Method     string
+	Window     int64
+	Period     flux.Duration
+	Periods    int64
+	Threshold  float64
+	Column     string
+	TimeColumn string
+}
+
+func newDetectProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*DetectOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &DetectProcedureSpec{
+		Method:     spec.Method,
+		Window:     spec.Window,
+		Period:     spec.Period,
+		Periods:    spec.Periods,
+		Threshold:  spec.Threshold,
+		Column:     spec.Column,
+		TimeColumn: spec.TimeColumn,
+	}, nil
+}
+
+func (s *DetectProcedureSpec) Kind() plan.ProcedureKind {
+	return detectors[s.Method]
+}
+
+func (s *DetectProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(DetectProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *DetectProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createDetectTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*DetectProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewDetectTransformation(d, cache, s)
+	return t, d, nil
+}
+
+type detectTransformation struct {
+	execute.ExecutionNode
+	d     execute.Dataset
+	cache execute.TableBuilderCache
+
+	spec DetectProcedureSpec
+}
+
+func NewDetectTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *DetectProcedureSpec) *detectTransformation {
+	return &detectTransformation{
+		d:     d,
+		cache: cache,
+		spec:  *spec,
+	}
+}
+
+func (t *detectTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return t.d.RetractTable(key)
+}
+
+func (t *detectTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return t.d.UpdateWatermark(mark)
+}
+
+func (t *detectTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return t.d.UpdateProcessingTime(pt)
+}
+
+func (t *detectTransformation) Finish(id execute.DatasetID, err error) {
+	t.d.Finish(err)
+}
+
+func (t *detectTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	builder, created := t.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "%s found duplicate table with key: %v", t.spec.Method, tbl.Key())
+	}
+	cols := tbl.Cols()
+	colIdx := execute.ColIdx(t.spec.Column, cols)
+	if colIdx < 0 {
+		return errors.Newf(codes.FailedPrecondition, "cannot find column %s", t.spec.Column)
+	}
+	typ := cols[colIdx].Type
+	if typ != flux.TInt && typ != flux.TUInt && typ != flux.TFloat {
+		return errors.Newf(codes.FailedPrecondition, "%s can work only on numerical types, got %s", t.spec.Method, typ)
+	}
+	timeIdx := -1
+	if t.spec.TimeColumn != "" {
+		timeIdx = execute.ColIdx(t.spec.TimeColumn, cols)
+		if timeIdx < 0 {
+			return errors.Newf(codes.FailedPrecondition, "cannot find time column %s", t.spec.TimeColumn)
+		}
+		if typ := cols[timeIdx].Type; typ != flux.TTime {
+			return errors.Newf(codes.FailedPrecondition, "time column %s must be of type time, got %s", t.spec.TimeColumn, typ)
+		}
+	}
+	for _, label := range []string{scoreColLabel, levelColLabel} {
+		if execute.ColIdx(label, cols) >= 0 {
+			return errors.Newf(codes.FailedPrecondition, "column %q already exists", label)
+		}
+	}
+
+	if err := execute.AddTableCols(tbl, builder); err != nil {
+		return err
+	}
+	var (
+		vs    []float64
+		ts    []values.Time
+		valid []bool
+	)
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		if err := execute.AppendCols(cr, builder); err != nil {
+			return err
+		}
+		for i := 0; i < cr.Len(); i++ {
+			var v float64
+			var ok bool
+			switch typ {
+			case flux.TInt:
+				col := cr.Ints(colIdx)
+				v, ok = float64(col.Value(i)), col.IsValid(i)
+			case flux.TUInt:
+				col := cr.UInts(colIdx)
+				v, ok = float64(col.Value(i)), col.IsValid(i)
+			case flux.TFloat:
+				col := cr.Floats(colIdx)
+				v, ok = col.Value(i), col.IsValid(i) && !math.IsNaN(col.Value(i))
+			}
+			if timeIdx >= 0 {
+				times := cr.Times(timeIdx)
+				ok = ok && times.IsValid(i)
+				ts = append(ts, values.Time(times.Value(i)))
+			}
+			vs = append(vs, v)
+			valid = append(valid, ok)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var scores []float64
+	var scored []bool
+	switch detectors[t.spec.Method] {
+	case RollingMADKind:
+		scores, scored = rollingScores(vs, valid, int(t.spec.Window), madScore)
+	case RollingZScoreKind:
+		scores, scored = rollingScores(vs, valid, int(t.spec.Window), zScore)
+	case SeasonalBaselineKind:
+		scores, scored = seasonalScores(vs, ts, valid, t.spec.Period, int(t.spec.Periods))
+	default:
+		return errors.Newf(codes.Internal, "unknown anomaly detection method %q", t.spec.Method)
+	}
+
+	// The new columns are filled with nulls for the existing rows
+	// so only the rows that have a score are set.
+	scoreIdx, err := builder.AddCol(flux.ColMeta{Label: scoreColLabel, Type: flux.TFloat})
+	if err != nil {
+		return err
+	}
+	levelIdx, err := builder.AddCol(flux.ColMeta{Label: levelColLabel, Type: flux.TString})
+	if err != nil {
+		return err
+	}
+	for i, score := range scores {
+		if !scored[i] {
+			continue
+		}
+		level := normalLevel
+		if math.Abs(score) >= t.spec.Threshold {
+			level = anomalyLevel
+		}
+		if err := builder.SetValue(i, scoreIdx, values.NewFloat(score)); err != nil {
+			return err
+		}
+		if err := builder.SetValue(i, levelIdx, values.NewString(level)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollingScores scores each valid value against the window of valid
+// values that precede it. Values without a full window are not scored.
+func rollingScores(vs []float64, valid []bool, window int, score func(v float64, baseline []float64) float64) ([]float64, []bool) {
+	scores := make([]float64, len(vs))
+	scored := make([]bool, len(vs))
+	baseline := make([]float64, 0, window)
+	for i, v := range vs {
+		if !valid[i] {
+			continue
+		}
+		if len(baseline) == window {
+			scores[i], scored[i] = score(v, baseline), true
+			copy(baseline, baseline[1:])
+			baseline = baseline[:window-1]
+		}
+		baseline = append(baseline, v)
+	}
+	return scores, scored
+}
+
+// seasonalScores scores each valid value against the values at the same
+// time in the previous periods. Values with fewer than two of those
+// values are not scored.
+func seasonalScores(vs []float64, ts []values.Time, valid []bool, period flux.Duration, periods int) ([]float64, []bool) {
+	byTime := make(map[values.Time]float64, len(vs))
+	for i, v := range vs {
+		if valid[i] {
+			byTime[ts[i]] = v
+		}
+	}
+	scores := make([]float64, len(vs))
+	scored := make([]bool, len(vs))
+	baseline := make([]float64, 0, periods)
+	for i, v := range vs {
+		if !valid[i] {
+			continue
+		}
+		baseline = baseline[:0]
+		for k := 1; k <= periods; k++ {
+			if b, ok := byTime[ts[i].Add(period.Mul(-k))]; ok {
+				baseline = append(baseline, b)
+			}
+		}
+		if len(baseline) >= 2 {
+			scores[i], scored[i] = zScore(v, baseline), true
+		}
+	}
+	return scores, scored
+}
+
+// madScore is the distance of v from the median of the baseline
+// in units of the scaled median absolute deviation of the baseline.
+func madScore(v float64, baseline []float64) float64 {
+	sorted := make([]float64, len(baseline))
+	copy(sorted, baseline)
+	center := median(sorted)
+	for i, b := range baseline {
+		sorted[i] = math.Abs(b - center)
+	}
+	return scaled(v-center, madScale*median(sorted))
+}
+
+// zScore is the distance of v from the mean of the baseline
+// in units of the sample standard deviation of the baseline.
+func zScore(v float64, baseline []float64) float64 {
+	var mean float64
+	for _, b := range baseline {
+		mean += b
+	}
+	mean /= float64(len(baseline))
+	var ss float64
+	for _, b := range baseline {
+		ss += (b - mean) * (b - mean)
+	}
+	return scaled(v-mean, math.Sqrt(ss/float64(len(baseline)-1)))
+}
+
+// scaled divides the deviation by the spread. When the baseline
+// has no spread, any deviation from it is infinitely far.
+func scaled(deviation, spread float64) float64 {
+	if spread == 0 {
+		if deviation == 0 {
+			return 0
+		}
+		return math.Inf(int(math.Copysign(1, deviation)))
+	}
+	return deviation / spread
+}
+
+// median sorts the values and returns their median.
+func median(vs []float64) float64 {
+	sort.Float64s(vs)
+	n := len(vs)
+	if n%2 == 1 {
+		return vs[n/2]
+	}
+	return (vs[n/2-1] + vs[n/2]) / 2
+}
@@ -0,0 +1,302 @@
+package anomalydetection_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	_ "github.com/influxdata/flux/fluxinit/static" // We need to init flux for the tests to work.
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/contrib/anaisdg/anomalydetection"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestDetect_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "rollingMAD defaults",
+			Raw:  `import "contrib/anaisdg/anomalydetection" from(bucket:"mydb") |> range(start:-1h) |> anomalydetection.rollingMAD(window: 10)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mydb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop:        flux.Now,
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "rollingMADKind2",
+						Spec: &anomalydetection.DetectOpSpec{
+							Method:    "rollingMAD",
+							Window:    10,
+							Threshold: 3,
+							Column:    "_value",
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "rollingMADKind2"},
+				},
+			},
+		},
+		{
+			Name: "seasonalBaseline no defaults",
+			Raw:  `import "contrib/anaisdg/anomalydetection" from(bucket:"mydb") |> range(start:-1h) |> anomalydetection.seasonalBaseline(period: 1d, periods: 5, threshold: 2.5, column: "v", timeColumn: "t")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mydb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop:        flux.Now,
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "seasonalBaselineKind2",
+						Spec: &anomalydetection.DetectOpSpec{
+							Method:     "seasonalBaseline",
+							Period:     flux.ConvertDuration(24 * time.Hour),
+							Periods:    5,
+							Threshold:  2.5,
+							Column:     "v",
+							TimeColumn: "t",
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "seasonalBaselineKind2"},
+				},
+			},
+		},
+		{
+			Name:    "rollingZScore window too small",
+			Raw:     `import "contrib/anaisdg/anomalydetection" from(bucket:"mydb") |> range(start:-1h) |> anomalydetection.rollingZScore(window: 1)`,
+			WantErr: true,
+		},
+		{
+			Name:    "seasonalBaseline negative threshold",
+			Raw:     `import "contrib/anaisdg/anomalydetection" from(bucket:"mydb") |> range(start:-1h) |> anomalydetection.seasonalBaseline(period: 1d, threshold: -1.0)`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestDetect_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *anomalydetection.DetectProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "rollingMAD",
+			spec: &anomalydetection.DetectProcedureSpec{
+				Method:    "rollingMAD",
+				Window:    3,
+				Threshold: 3,
+				Column:    "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.0, "a"},
+					{execute.Time(2), 2.0, "a"},
+					{execute.Time(3), 3.0, "a"},
+					{execute.Time(4), 2.0, "a"},
+					{execute.Time(5), 10.0, "a"},
+					{execute.Time(6), nil, "a"},
+					{execute.Time(7), 2.0, "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "level", Type: flux.TString},
+					{Label: "score", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), 1.0, nil, nil, "a"},
+					{execute.Time(2), 2.0, nil, nil, "a"},
+					{execute.Time(3), 3.0, nil, nil, "a"},
+					{execute.Time(4), 2.0, "normal", 0.0, "a"},
+					{execute.Time(5), 10.0, "anomaly", math.Inf(1), "a"},
+					{execute.Time(6), nil, nil, nil, "a"},
+					{execute.Time(7), 2.0, "normal", -1 / 1.4826, "a"},
+				},
+			}},
+		},
+		{
+			name: "rollingZScore",
+			spec: &anomalydetection.DetectProcedureSpec{
+				Method:    "rollingZScore",
+				Window:    2,
+				Threshold: 2,
+				Column:    "v",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "v", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{int64(1)},
+					{int64(3)},
+					{int64(2)},
+					{int64(8)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "level", Type: flux.TString},
+					{Label: "score", Type: flux.TFloat},
+					{Label: "v", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{nil, nil, int64(1)},
+					{nil, nil, int64(3)},
+					{"normal", 0.0, int64(2)},
+					{"anomaly", 5.5 / math.Sqrt(0.5), int64(8)},
+				},
+			}},
+		},
+		{
+			name: "seasonalBaseline",
+			spec: &anomalydetection.DetectProcedureSpec{
+				Method:     "seasonalBaseline",
+				Period:     flux.ConvertDuration(10 * time.Second),
+				Periods:    2,
+				Threshold:  3,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), uint64(1)},
+					{execute.Time(5 * time.Second), uint64(100)},
+					{execute.Time(10 * time.Second), uint64(3)},
+					{execute.Time(20 * time.Second), uint64(5)},
+					{execute.Time(30 * time.Second), uint64(20)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+					{Label: "level", Type: flux.TString},
+					{Label: "score", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), uint64(1), nil, nil},
+					{execute.Time(5 * time.Second), uint64(100), nil, nil},
+					{execute.Time(10 * time.Second), uint64(3), nil, nil},
+					{execute.Time(20 * time.Second), uint64(5), "normal", 3 / math.Sqrt(2)},
+					{execute.Time(30 * time.Second), uint64(20), "anomaly", 16 / math.Sqrt(2)},
+				},
+			}},
+		},
+		{
+			name: "score column exists",
+			spec: &anomalydetection.DetectProcedureSpec{
+				Method:    "rollingZScore",
+				Window:    2,
+				Threshold: 3,
+				Column:    "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "score", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{1.0, 1.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `column "score" already exists`),
+		},
+		{
+			name: "string column",
+			spec: &anomalydetection.DetectProcedureSpec{
+				Method:    "rollingMAD",
+				Window:    2,
+				Threshold: 3,
+				Column:    "_value",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{"a"},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "rollingMAD can work only on numerical types, got string"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return anomalydetection.NewDetectTransformation(d, c, tc.spec)
+				},
+			)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package csv
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"io"
 	"strings"
@@ -107,6 +109,63 @@ func (s *FromCSVProcedureSpec) Copy() plan.ProcedureSpec {
 	return ns
 }
 
+// statisticsSampleSize is the number of bytes read from a csv file
+// to estimate the number of rows it contains.
+const statisticsSampleSize = 64 * 1024
+
+// EstimateStatistics implements plan.StatisticsEstimator.
+// Raw csv text is counted directly. For files, a sample from the
+// beginning of the file is counted and extrapolated to the file size.
+func (s *FromCSVProcedureSpec) EstimateStatistics(ctx context.Context) plan.Statistics {
+	if s.File == "" {
+		rows, groups := countCSVRows(strings.NewReader(s.CSV))
+		return plan.Statistics{Cardinality: rows, GroupCardinality: groups}
+	}
+
+	info, err := filesystem.Stat(ctx, s.File)
+	if err != nil || info.Size() == 0 {
+		return plan.Statistics{}
+	}
+	f, err := filesystem.OpenFile(ctx, s.File)
+	if err != nil {
+		return plan.Statistics{}
+	}
+	defer func() { _ = f.Close() }()
+
+	sample, err := io.ReadAll(io.LimitReader(f, statisticsSampleSize))
+	if err != nil || len(sample) == 0 {
+		return plan.Statistics{}
+	}
+	rows, groups := countCSVRows(bytes.NewReader(sample))
+	if size := info.Size(); size > int64(len(sample)) {
+		rows = rows * size / int64(len(sample))
+		groups = groups * size / int64(len(sample))
+	}
+	return plan.Statistics{Cardinality: rows, GroupCardinality: groups}
+}
+
+// countCSVRows counts the data rows and the number of header rows
+// in the csv data. Each header is assumed to begin a new table.
+func countCSVRows(r io.Reader) (rows, groups int64) {
+	scanner := bufio.NewScanner(r)
+	inTable := false
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(bytes.TrimSpace(line)) == 0:
+			inTable = false
+		case line[0] == '#':
+			// Annotations are not data.
+		case !inTable:
+			inTable = true
+			groups++
+		default:
+			rows++
+		}
+	}
+	return rows, groups
+}
+
 func createFromCSVSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
 	spec, ok := prSpec.(*FromCSVProcedureSpec)
 	if !ok {
@@ -0,0 +1,583 @@
+package parquet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	stdarrow "github.com/apache/arrow/go/v7/arrow"
+	arrowarray "github.com/apache/arrow/go/v7/arrow/array"
+	pq "github.com/apache/arrow/go/v7/parquet"
+	"github.com/apache/arrow/go/v7/parquet/file"
+	"github.com/apache/arrow/go/v7/parquet/pqarrow"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/dependencies/filesystem"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/execute/table"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+const (
+	pkgPath = "experimental/parquet"
+
+	FromParquetKind = "fromParquet"
+
+	// groupKeyMetadataKey is the key of the file metadata
+	// that stores the group key written by parquet.to().
+	groupKeyMetadataKey = "flux.groupKey"
+
+	// readBatchSize is the number of rows that are converted at a time.
+	readBatchSize = 64 * 1024
+)
+
+type FromParquetOpSpec struct {
+	File      string   `json:"file"`
+	Columns   []string `json:"columns"`
+	RowGroups []int    `json:"rowGroups"`
+	// GroupKey is nil when the group key stored in the file is used.
+	GroupKey []string `json:"groupKey"`
+}
+
+func init() {
+	fromSignature := runtime.MustLookupBuiltinType(pkgPath, "from")
+	runtime.RegisterPackageValue(pkgPath, "from", flux.MustValue(flux.FunctionValue(FromParquetKind, createFromParquetOpSpec, fromSignature)))
+	plan.RegisterProcedureSpec(FromParquetKind, newFromParquetProcedure, FromParquetKind)
+	execute.RegisterSource(FromParquetKind, createFromParquetSource)
+	plan.RegisterPhysicalRules(
+		ParquetFilterPushDownRule{},
+		ParquetRangePushDownRule{},
+		ParquetKeepPushDownRule{},
+	)
+}
+
+func createFromParquetOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(FromParquetOpSpec)
+
+	f, err := args.GetRequiredString("file")
+	if err != nil {
+		return nil, err
+	}
+	spec.File = f
+
+	if columns, ok, err := args.GetArray("columns", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Columns, err = interpreter.ToStringArray(columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rowGroups, ok, err := args.GetArray("rowGroups", semantic.Int); err != nil {
+		return nil, err
+	} else if ok {
+		spec.RowGroups = make([]int, rowGroups.Len())
+		rowGroups.Range(func(i int, v values.Value) {
+			spec.RowGroups[i] = int(v.Int())
+		})
+		for _, rg := range spec.RowGroups {
+			if rg < 0 {
+				return nil, errors.Newf(codes.Invalid, "row group index must not be negative, got %d", rg)
+			}
+		}
+	}
+
+	if groupKey, ok, err := args.GetArray("groupKey", semantic.String); err != nil {
+		return nil, err
+	} else if ok {
+		spec.GroupKey, err = interpreter.ToStringArray(groupKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return spec, nil
+}
+
+func (s *FromParquetOpSpec) Kind() flux.OperationKind {
+	return FromParquetKind
+}
+
+type FromParquetProcedureSpec struct {
+	plan.DefaultCost
+	File      string
+	Columns   []string
+	RowGroups []int
+	GroupKey  []string
+
+	// Predicates are used to skip row groups
+	// that cannot contain any matching rows.
+	// They are added by the push down rules.
+	Predicates []Predicate
+}
+
+func newFromParquetProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*FromParquetOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &FromParquetProcedureSpec{
+		File:      spec.File,
+		Columns:   spec.Columns,
+		RowGroups: spec.RowGroups,
+		GroupKey:  spec.GroupKey,
+	}, nil
+}
+
+func (s *FromParquetProcedureSpec) Kind() plan.ProcedureKind {
+	return FromParquetKind
+}
+
+func (s *FromParquetProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(FromParquetProcedureSpec)
+	*ns = *s
+	if s.Columns != nil {
+		ns.Columns = append(make([]string, 0, len(s.Columns)), s.Columns...)
+	}
+	if s.RowGroups != nil {
+		ns.RowGroups = append(make([]int, 0, len(s.RowGroups)), s.RowGroups...)
+	}
+	if s.GroupKey != nil {
+		ns.GroupKey = append(make([]string, 0, len(s.GroupKey)), s.GroupKey...)
+	}
+	if s.Predicates != nil {
+		ns.Predicates = append(make([]Predicate, 0, len(s.Predicates)), s.Predicates...)
+	}
+	return ns
+}
+
+func createFromParquetSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*FromParquetProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	return CreateSource(spec, dsid, a)
+}
+
+func CreateSource(spec *FromParquetProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	return execute.CreateSourceFromIterator(&parquetSource{
+		spec:  spec,
+		alloc: a.Allocator(),
+	}, dsid)
+}
+
+type parquetSource struct {
+	spec  *FromParquetProcedureSpec
+	alloc memory.Allocator
+}
+
+func (s *parquetSource) Do(ctx context.Context, f func(flux.Table) error) error {
+	rdr, err := openParquetFile(ctx, s.spec.File)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rdr.Close() }()
+
+	fr, err := pqarrow.NewFileReader(rdr, pqarrow.ArrowReadProperties{}, s.alloc)
+	if err != nil {
+		return errors.Wrap(err, codes.Invalid, "parquet.from() failed to read file")
+	}
+	schema, err := fr.Schema()
+	if err != nil {
+		return errors.Wrap(err, codes.Invalid, "parquet.from() failed to read schema")
+	}
+
+	groupKey := s.spec.GroupKey
+	if groupKey == nil {
+		if v := rdr.MetaData().KeyValueMetadata().FindValue(groupKeyMetadataKey); v != nil {
+			if err := json.Unmarshal([]byte(*v), &groupKey); err != nil {
+				return errors.Wrap(err, codes.Invalid, "parquet.from() found an invalid group key in the file")
+			}
+		}
+	}
+	isKey := make(map[string]bool, len(groupKey))
+	for _, label := range groupKey {
+		if len(schema.FieldIndices(label)) == 0 {
+			return errors.Newf(codes.Invalid, "parquet.from() found no column %s for the group key", label)
+		}
+		isKey[label] = true
+	}
+
+	// Group key columns are read even when they are not in the column list
+	// so the rows can be grouped.
+	var want map[string]bool
+	if s.spec.Columns != nil {
+		want = make(map[string]bool, len(s.spec.Columns)+len(groupKey))
+		for _, label := range s.spec.Columns {
+			want[label] = true
+		}
+		for _, label := range groupKey {
+			want[label] = true
+		}
+	}
+	r := &recordReader{
+		fr:  fr,
+		mem: s.alloc,
+	}
+	for _, field := range schema.Fields() {
+		if want != nil && !want[field.Name] {
+			continue
+		}
+		typ, err := fluxType(field.Type)
+		if err != nil {
+			return errors.Wrapf(err, codes.Inherit, "parquet.from() cannot read column %s", field.Name)
+		}
+		// Columns are read by the index of their leaf in the file schema.
+		r.indices = append(r.indices, rdr.MetaData().Schema.ColumnIndexByName(field.Name))
+		r.cols = append(r.cols, flux.ColMeta{Label: field.Name, Type: typ})
+	}
+
+	r.rowGroups, err = s.rowGroups(rdr, schema)
+	if err != nil {
+		return err
+	}
+
+	if len(isKey) == 0 {
+		return s.stream(ctx, r, f)
+	}
+	return s.group(ctx, r, isKey, f)
+}
+
+// stream produces a single table when there is no group key.
+// The row groups are read one at a time while the table is consumed.
+func (s *parquetSource) stream(ctx context.Context, r *recordReader, f func(flux.Table) error) error {
+	key := execute.NewGroupKey(nil, nil)
+	tbl, err := table.StreamWithContext(ctx, key, r.cols, func(ctx context.Context, w *table.StreamWriter) error {
+		return r.Read(ctx, func(vs []array.Array) error {
+			return w.Write(vs)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	// The stream reads from the file so it must
+	// be finished before the file is closed.
+	defer tbl.Done()
+	return f(tbl)
+}
+
+// group splits the rows into tables by the values of the group key columns.
+// Each record is split into runs of rows with the same key and the runs are
+// sliced from the record so the data is not copied. A key can appear in any
+// row group so the tables are only produced once the whole file is read.
+func (s *parquetSource) group(ctx context.Context, r *recordReader, isKey map[string]bool, f func(flux.Table) error) error {
+	var keyIdx []int
+	for j, c := range r.cols {
+		if isKey[c.Label] {
+			keyIdx = append(keyIdx, j)
+		}
+	}
+
+	builders := execute.NewGroupLookup()
+	var keys []flux.GroupKey
+	defer func() {
+		_ = builders.Range(func(key flux.GroupKey, value interface{}) error {
+			value.(*table.BufferedBuilder).Release()
+			return nil
+		})
+	}()
+	if err := r.Read(ctx, func(vs []array.Array) error {
+		defer func() {
+			for _, arr := range vs {
+				arr.Release()
+			}
+		}()
+		buf := &arrow.TableBuffer{
+			Columns: r.cols,
+			Values:  vs,
+		}
+		for start, n := 0, buf.Len(); start < n; {
+			end := start + 1
+			for end < n && sameKey(vs, keyIdx, start, end) {
+				end++
+			}
+			key := execute.GroupKeyForRowOn(start, buf, isKey)
+			var b *table.BufferedBuilder
+			if v, ok := builders.Lookup(key); ok {
+				b = v.(*table.BufferedBuilder)
+			} else {
+				b = table.NewBufferedBuilder(key, s.alloc)
+				builders.Set(key, b)
+				keys = append(keys, key)
+			}
+			run := &arrow.TableBuffer{
+				GroupKey: key,
+				Columns:  r.cols,
+				Values:   make([]array.Array, len(vs)),
+			}
+			for j, arr := range vs {
+				run.Values[j] = array.Slice(arr, start, end)
+			}
+			err := b.AppendBuffer(run)
+			run.Release()
+			if err != nil {
+				return err
+			}
+			start = end
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		v, _ := builders.Delete(key)
+		tbl, err := v.(*table.BufferedBuilder).Table()
+		if err != nil {
+			return err
+		}
+		if err := f(tbl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordReader reads the columns of the row groups
+// as batches of flux arrays.
+type recordReader struct {
+	fr        *pqarrow.FileReader
+	indices   []int
+	cols      []flux.ColMeta
+	rowGroups []int
+	mem       memory.Allocator
+}
+
+// Read calls fn with the columns of each batch of rows.
+// The arrays are owned by fn.
+func (r *recordReader) Read(ctx context.Context, fn func(vs []array.Array) error) error {
+	for _, rg := range r.rowGroups {
+		if err := r.readRowGroup(ctx, rg, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *recordReader) readRowGroup(ctx context.Context, rg int, fn func(vs []array.Array) error) error {
+	tbl, err := r.fr.RowGroup(rg).ReadTable(ctx, r.indices)
+	if err != nil {
+		return errors.Wrapf(err, codes.Invalid, "parquet.from() failed to read row group %d", rg)
+	}
+	defer tbl.Release()
+
+	tr := arrowarray.NewTableReader(tbl, readBatchSize)
+	defer tr.Release()
+	for tr.Next() {
+		rec := tr.Record()
+		vs := make([]array.Array, len(r.cols))
+		for j := range vs {
+			vs[j] = fluxArray(rec.Column(j), r.mem)
+		}
+		if err := fn(vs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowGroups returns the row groups to read. These are the
+// requested row groups that are not excluded by the predicates.
+func (s *parquetSource) rowGroups(rdr *file.Reader, schema *stdarrow.Schema) ([]int, error) {
+	n := rdr.NumRowGroups()
+	requested := s.spec.RowGroups
+	if requested == nil {
+		requested = make([]int, n)
+		for i := range requested {
+			requested[i] = i
+		}
+	}
+	rowGroups := make([]int, 0, len(requested))
+	for _, rg := range requested {
+		if rg >= n {
+			return nil, errors.Newf(codes.Invalid, "parquet.from() found no row group %d; the file has %d row groups", rg, n)
+		}
+		if skipRowGroup(rdr.MetaData().RowGroup(rg), rdr.MetaData().Schema, schema, s.spec.Predicates) {
+			continue
+		}
+		rowGroups = append(rowGroups, rg)
+	}
+	return rowGroups, nil
+}
+
+// openParquetFile opens the file with the filesystem service.
+// Files that do not support random access are read into memory.
+func openParquetFile(ctx context.Context, name string) (*file.Reader, error) {
+	f, err := filesystem.OpenFile(ctx, name)
+	if err != nil {
+		return nil, errors.Wrap(err, codes.Inherit, "parquet.from() failed to open file")
+	}
+	var r pq.ReaderAtSeeker
+	if rs, ok := f.(pq.ReaderAtSeeker); ok {
+		r = rs
+	} else {
+		data, err := io.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, codes.Inherit, "parquet.from() failed to read file")
+		}
+		f = nil
+		r = bytes.NewReader(data)
+	}
+	rdr, err := file.NewParquetReader(r)
+	if err != nil {
+		if f != nil {
+			_ = f.Close()
+		}
+		return nil, errors.Wrap(err, codes.Invalid, "parquet.from() failed to read file")
+	}
+	return rdr, nil
+}
+
+// fluxType returns the column type that is used for an arrow type.
+func fluxType(typ stdarrow.DataType) (flux.ColType, error) {
+	switch typ.ID() {
+	case stdarrow.INT8, stdarrow.INT16, stdarrow.INT32, stdarrow.INT64:
+		return flux.TInt, nil
+	case stdarrow.UINT8, stdarrow.UINT16, stdarrow.UINT32, stdarrow.UINT64:
+		return flux.TUInt, nil
+	case stdarrow.FLOAT32, stdarrow.FLOAT64:
+		return flux.TFloat, nil
+	case stdarrow.BOOL:
+		return flux.TBool, nil
+	case stdarrow.STRING, stdarrow.BINARY:
+		return flux.TString, nil
+	case stdarrow.TIMESTAMP, stdarrow.DATE32, stdarrow.DATE64:
+		return flux.TTime, nil
+	default:
+		return flux.TInvalid, errors.Newf(codes.Unimplemented, "unsupported type %s", typ)
+	}
+}
+
+// sameKey reports whether rows i and j have the same values in the key columns.
+func sameKey(vs []array.Array, keyIdx []int, i, j int) bool {
+	for _, k := range keyIdx {
+		arr := vs[k]
+		if arr.IsNull(i) || arr.IsNull(j) {
+			if arr.IsNull(i) != arr.IsNull(j) {
+				return false
+			}
+			continue
+		}
+		var eq bool
+		switch a := arr.(type) {
+		case *array.Int:
+			eq = a.Value(i) == a.Value(j)
+		case *array.Uint:
+			eq = a.Value(i) == a.Value(j)
+		case *array.Float:
+			eq = a.Value(i) == a.Value(j)
+		case *array.Boolean:
+			eq = a.Value(i) == a.Value(j)
+		case *array.String:
+			eq = a.Value(i) == a.Value(j)
+		}
+		if !eq {
+			return false
+		}
+	}
+	return true
+}
+
+// fluxArray converts an arrow array to the array that flux uses for its
+// column type. Arrays that already have the layout flux uses are shared
+// with the record and other arrays are converted.
+// The returned array must be released.
+func fluxArray(arr stdarrow.Array, mem memory.Allocator) array.Array {
+	switch a := arr.(type) {
+	case *arrowarray.Int64, *arrowarray.Uint64, *arrowarray.Float64, *arrowarray.Boolean:
+		arr.Retain()
+		return arr
+	case *arrowarray.String, *arrowarray.Binary:
+		// Strings have the same layout as binary arrays.
+		data := arr.Data()
+		bin := arrowarray.NewData(stdarrow.BinaryTypes.Binary, data.Len(), data.Buffers(), nil, data.NullN(), data.Offset())
+		defer bin.Release()
+		b := arrowarray.NewBinaryData(bin)
+		defer b.Release()
+		return array.NewStringFromBinaryArray(b)
+	case *arrowarray.Timestamp:
+		unit := a.DataType().(*stdarrow.TimestampType).Unit
+		if unit == stdarrow.Nanosecond {
+			// Flux stores times as nanoseconds in int64 arrays.
+			data := arr.Data()
+			ints := arrowarray.NewData(stdarrow.PrimitiveTypes.Int64, data.Len(), data.Buffers(), nil, data.NullN(), data.Offset())
+			defer ints.Release()
+			return arrowarray.NewInt64Data(ints)
+		}
+		m := int64(unit.Multiplier())
+		return convertInts(arr, mem, func(i int) int64 { return int64(a.Value(i)) * m })
+	case *arrowarray.Date32:
+		return convertInts(arr, mem, func(i int) int64 { return int64(a.Value(i)) * int64(24*time.Hour) })
+	case *arrowarray.Date64:
+		return convertInts(arr, mem, func(i int) int64 { return int64(a.Value(i)) * int64(time.Millisecond) })
+	case *arrowarray.Int8:
+		return convertInts(arr, mem, func(i int) int64 { return int64(a.Value(i)) })
+	case *arrowarray.Int16:
+		return convertInts(arr, mem, func(i int) int64 { return int64(a.Value(i)) })
+	case *arrowarray.Int32:
+		return convertInts(arr, mem, func(i int) int64 { return int64(a.Value(i)) })
+	case *arrowarray.Uint8:
+		return convertUints(arr, mem, func(i int) uint64 { return uint64(a.Value(i)) })
+	case *arrowarray.Uint16:
+		return convertUints(arr, mem, func(i int) uint64 { return uint64(a.Value(i)) })
+	case *arrowarray.Uint32:
+		return convertUints(arr, mem, func(i int) uint64 { return uint64(a.Value(i)) })
+	case *arrowarray.Float32:
+		b := array.NewFloatBuilder(mem)
+		defer b.Release()
+		b.Resize(arr.Len())
+		for i, n := 0, arr.Len(); i < n; i++ {
+			if arr.IsNull(i) {
+				b.AppendNull()
+				continue
+			}
+			b.Append(float64(a.Value(i)))
+		}
+		return b.NewArray()
+	default:
+		// The column types are checked by fluxType before
+		// anything is read.
+		panic(errors.Newf(codes.Internal, "unsupported array type %T", arr))
+	}
+}
+
+// convertInts copies an array into an int array using value to read each row.
+func convertInts(arr stdarrow.Array, mem memory.Allocator, value func(i int) int64) array.Array {
+	b := array.NewIntBuilder(mem)
+	defer b.Release()
+	b.Resize(arr.Len())
+	for i, n := 0, arr.Len(); i < n; i++ {
+		if arr.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		b.Append(value(i))
+	}
+	return b.NewArray()
+}
+
+// convertUints copies an array into a uint array using value to read each row.
+func convertUints(arr stdarrow.Array, mem memory.Allocator, value func(i int) uint64) array.Array {
+	b := array.NewUintBuilder(mem)
+	defer b.Release()
+	b.Resize(arr.Len())
+	for i, n := 0, arr.Len(); i < n; i++ {
+		if arr.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		b.Append(value(i))
+	}
+	return b.NewArray()
+}
@@ -0,0 +1,629 @@
+package parquet_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	stdarrow "github.com/apache/arrow/go/v7/arrow"
+	arrowarray "github.com/apache/arrow/go/v7/arrow/array"
+	arrowmemory "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/apache/arrow/go/v7/parquet/pqarrow"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/dependencies/filesystem"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	_ "github.com/influxdata/flux/fluxinit/static"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/mock"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+	"github.com/influxdata/flux/stdlib/experimental/parquet"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
+)
+
+func sec(n int) execute.Time {
+	return execute.Time(time.Duration(n) * time.Second)
+}
+
+// testData returns two tables grouped by t0.
+func testData() []*executetest.Table {
+	cols := []flux.ColMeta{
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TFloat},
+		{Label: "n", Type: flux.TInt},
+		{Label: "t0", Type: flux.TString},
+	}
+	return []*executetest.Table{
+		{
+			KeyCols: []string{"t0"},
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{sec(0), 1.0, int64(1), "a"},
+				{sec(1), 2.0, nil, "a"},
+			},
+		},
+		{
+			KeyCols: []string{"t0"},
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{sec(10), 3.0, int64(3), "b"},
+				{sec(11), 4.0, int64(4), "b"},
+			},
+		},
+	}
+}
+
+// testContext returns a context that can read and write files.
+func testContext() context.Context {
+	ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+	return filesystem.InjectWritable(ctx, filesystem.WritableSystemFS)
+}
+
+// writeTestFile writes the test data to a file with parquet.to().
+func writeTestFile(t *testing.T, ctx context.Context) string {
+	t.Helper()
+	fpath := filepath.Join(t.TempDir(), "data.parquet")
+	data := make([]flux.Table, 0, 2)
+	for _, tbl := range testData() {
+		data = append(data, tbl)
+	}
+	executetest.ProcessTestHelper(
+		t,
+		data,
+		testData(),
+		nil,
+		func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+			return parquet.NewToParquetTransformation(ctx, d, c, executetest.UnlimitedAllocator, &parquet.ToParquetProcedureSpec{
+				File:        fpath,
+				Compression: "snappy",
+			})
+		},
+	)
+	return fpath
+}
+
+func TestFromParquet_Run(t *testing.T) {
+	ctx := testContext()
+	fpath := writeTestFile(t, ctx)
+
+	testCases := []struct {
+		name    string
+		spec    *parquet.FromParquetProcedureSpec
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "stored group key",
+			spec: &parquet.FromParquetProcedureSpec{File: fpath},
+			want: testData(),
+		},
+		{
+			name: "no group key",
+			spec: &parquet.FromParquetProcedureSpec{
+				File:     fpath,
+				GroupKey: []string{},
+			},
+			want: []*executetest.Table{{
+				ColMeta: testData()[0].ColMeta,
+				Data: [][]interface{}{
+					{sec(0), 1.0, int64(1), "a"},
+					{sec(1), 2.0, nil, "a"},
+					{sec(10), 3.0, int64(3), "b"},
+					{sec(11), 4.0, int64(4), "b"},
+				},
+			}},
+		},
+		{
+			name: "group key with nulls",
+			spec: &parquet.FromParquetProcedureSpec{
+				File:     fpath,
+				Columns:  []string{"_value"},
+				GroupKey: []string{"n"},
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"n"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "n", Type: flux.TInt},
+					},
+					Data: [][]interface{}{{1.0, int64(1)}},
+				},
+				{
+					KeyCols:   []string{"n"},
+					KeyValues: []interface{}{nil},
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "n", Type: flux.TInt},
+					},
+					Data: [][]interface{}{{2.0, nil}},
+				},
+				{
+					KeyCols: []string{"n"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "n", Type: flux.TInt},
+					},
+					Data: [][]interface{}{{3.0, int64(3)}},
+				},
+				{
+					KeyCols: []string{"n"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "n", Type: flux.TInt},
+					},
+					Data: [][]interface{}{{4.0, int64(4)}},
+				},
+			},
+		},
+		{
+			name: "columns",
+			spec: &parquet.FromParquetProcedureSpec{
+				File:    fpath,
+				Columns: []string{"_value", "missing"},
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{1.0, "a"},
+						{2.0, "a"},
+					},
+				},
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{3.0, "b"},
+						{4.0, "b"},
+					},
+				},
+			},
+		},
+		{
+			name: "row groups",
+			spec: &parquet.FromParquetProcedureSpec{
+				File:      fpath,
+				RowGroups: []int{1},
+			},
+			want: testData()[1:],
+		},
+		{
+			name: "string predicate",
+			spec: &parquet.FromParquetProcedureSpec{
+				File: fpath,
+				Predicates: []parquet.Predicate{
+					{Column: "t0", Op: ast.EqualOperator, Value: values.NewString("a")},
+				},
+			},
+			want: testData()[:1],
+		},
+		{
+			name: "time predicate",
+			spec: &parquet.FromParquetProcedureSpec{
+				File: fpath,
+				Predicates: []parquet.Predicate{
+					{Column: "_time", Op: ast.GreaterThanEqualOperator, Value: values.NewTime(values.Time(sec(5)))},
+				},
+			},
+			want: testData()[1:],
+		},
+		{
+			name: "float predicate with int value",
+			spec: &parquet.FromParquetProcedureSpec{
+				File: fpath,
+				Predicates: []parquet.Predicate{
+					{Column: "_value", Op: ast.LessThanOperator, Value: values.NewInt(2)},
+				},
+			},
+			want: testData()[:1],
+		},
+		{
+			name: "missing row group",
+			spec: &parquet.FromParquetProcedureSpec{
+				File:      fpath,
+				RowGroups: []int{2},
+			},
+			wantErr: errors.New(codes.Invalid, "parquet.from() found no row group 2; the file has 2 row groups"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.RunSourceHelper(t,
+				ctx,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID) execute.Source {
+					a := mock.AdministrationWithContext(ctx)
+					s, err := parquet.CreateSource(tc.spec, id, a)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return s
+				},
+			)
+		})
+	}
+}
+
+// allocatorAdministration is an administration
+// with an allocator that can be checked for leaks.
+type allocatorAdministration struct {
+	*mock.Administration
+	mem *memory.ResourceAllocator
+}
+
+func (a *allocatorAdministration) Allocator() memory.Allocator {
+	return a.mem
+}
+
+func TestFromParquet_ArrowTypes(t *testing.T) {
+	schema := stdarrow.NewSchema([]stdarrow.Field{
+		{Name: "_time", Type: &stdarrow.TimestampType{Unit: stdarrow.Millisecond}, Nullable: true},
+		{Name: "day", Type: stdarrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "i", Type: stdarrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "u", Type: stdarrow.PrimitiveTypes.Uint8, Nullable: true},
+		{Name: "f", Type: stdarrow.PrimitiveTypes.Float32, Nullable: true},
+		{Name: "s", Type: stdarrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := arrowarray.NewRecordBuilder(arrowmemory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*arrowarray.TimestampBuilder).AppendValues([]stdarrow.Timestamp{1000, 2000, 3000}, nil)
+	b.Field(1).(*arrowarray.Date32Builder).AppendValues([]stdarrow.Date32{0, 1, 2}, nil)
+	b.Field(2).(*arrowarray.Int32Builder).AppendValues([]int32{1, 0, 3}, []bool{true, false, true})
+	b.Field(3).(*arrowarray.Uint8Builder).AppendValues([]uint8{4, 5, 6}, nil)
+	b.Field(4).(*arrowarray.Float32Builder).AppendValues([]float32{0.5, 1.5, 2.5}, nil)
+	b.Field(5).(*arrowarray.StringBuilder).AppendValues([]string{"a", "b", ""}, []bool{true, true, false})
+	rec := b.NewRecord()
+	defer rec.Release()
+	tbl := arrowarray.NewTableFromRecords(schema, []stdarrow.Record{rec})
+	defer tbl.Release()
+
+	fpath := filepath.Join(t.TempDir(), "types.parquet")
+	fp, err := os.Create(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two rows per row group so the table is streamed from two row groups.
+	// The file is closed by the writer.
+	if err := pqarrow.WriteTable(tbl, fp, 2, nil, pqarrow.DefaultWriterProps()); err != nil {
+		t.Fatal(err)
+	}
+
+	day := execute.Time(24 * time.Hour)
+	want := []*executetest.Table{{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "day", Type: flux.TTime},
+			{Label: "i", Type: flux.TInt},
+			{Label: "u", Type: flux.TUInt},
+			{Label: "f", Type: flux.TFloat},
+			{Label: "s", Type: flux.TString},
+		},
+		Data: [][]interface{}{
+			{sec(1), execute.Time(0), int64(1), uint64(4), 0.5, "a"},
+			{sec(2), day, nil, uint64(5), 1.5, "b"},
+			{sec(3), 2 * day, int64(3), uint64(6), 2.5, nil},
+		},
+	}}
+
+	ctx := testContext()
+	mem := &memory.ResourceAllocator{}
+	executetest.RunSourceHelper(t,
+		ctx,
+		want,
+		nil,
+		func(id execute.DatasetID) execute.Source {
+			a := &allocatorAdministration{
+				Administration: mock.AdministrationWithContext(ctx),
+				mem:            mem,
+			}
+			s, err := parquet.CreateSource(&parquet.FromParquetProcedureSpec{File: fpath}, id, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	)
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected all memory to be released, got %d bytes", got)
+	}
+}
+
+func TestToParquet_Process(t *testing.T) {
+	ctx := testContext()
+	data := testData()
+	data[1].ColMeta = append([]flux.ColMeta(nil), data[1].ColMeta...)
+	data[1].ColMeta[1].Type = flux.TInt
+	data[1].Data = [][]interface{}{
+		{sec(10), int64(3), int64(3), "b"},
+	}
+	executetest.ProcessTestHelper(
+		t,
+		[]flux.Table{data[0], data[1]},
+		nil,
+		errors.New(codes.FailedPrecondition, "parquet.to() found column _value with type int but the first table has type float"),
+		func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+			return parquet.NewToParquetTransformation(ctx, d, c, executetest.UnlimitedAllocator, &parquet.ToParquetProcedureSpec{
+				File:        filepath.Join(t.TempDir(), "data.parquet"),
+				Compression: "none",
+			})
+		},
+	)
+}
+
+func TestToParquet_Schema(t *testing.T) {
+	ctx := testContext()
+
+	// The second table has no n column. It is written as null.
+	fpath := filepath.Join(t.TempDir(), "data.parquet")
+	missingCol := func() []*executetest.Table {
+		data := testData()
+		data[1].ColMeta = []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+			{Label: "t0", Type: flux.TString},
+		}
+		data[1].Data = [][]interface{}{
+			{sec(10), 3.0, "b"},
+		}
+		return data
+	}
+	data := missingCol()
+	executetest.ProcessTestHelper(
+		t,
+		[]flux.Table{data[0], data[1]},
+		missingCol(),
+		nil,
+		func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+			return parquet.NewToParquetTransformation(ctx, d, c, executetest.UnlimitedAllocator, &parquet.ToParquetProcedureSpec{
+				File:        fpath,
+				Compression: "none",
+			})
+		},
+	)
+	want := testData()
+	want[1].Data = [][]interface{}{
+		{sec(10), 3.0, nil, "b"},
+	}
+	executetest.RunSourceHelper(t,
+		ctx,
+		want,
+		nil,
+		func(id execute.DatasetID) execute.Source {
+			a := mock.AdministrationWithContext(ctx)
+			s, err := parquet.CreateSource(&parquet.FromParquetProcedureSpec{File: fpath}, id, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	)
+
+	// A column that is not in the first table cannot be added to the file.
+	data = testData()
+	data[1].ColMeta = append(data[1].ColMeta, flux.ColMeta{Label: "extra", Type: flux.TInt})
+	data[1].Data = [][]interface{}{
+		{sec(10), 3.0, int64(3), "b", int64(5)},
+	}
+	executetest.ProcessTestHelper(
+		t,
+		[]flux.Table{data[0], data[1]},
+		nil,
+		errors.New(codes.FailedPrecondition, "parquet.to() found column extra that is not in the first table"),
+		func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+			return parquet.NewToParquetTransformation(ctx, d, c, executetest.UnlimitedAllocator, &parquet.ToParquetProcedureSpec{
+				File:        filepath.Join(t.TempDir(), "data.parquet"),
+				Compression: "none",
+			})
+		},
+	)
+}
+
+func TestToParquet_Error(t *testing.T) {
+	// A failed query leaves the existing file unchanged.
+	ctx := testContext()
+	fpath := writeTestFile(t, ctx)
+	data := testData()
+	data[1].ColMeta = append(data[1].ColMeta, flux.ColMeta{Label: "extra", Type: flux.TInt})
+	data[1].Data = [][]interface{}{
+		{sec(10), 3.0, int64(3), "b", int64(5)},
+	}
+	executetest.ProcessTestHelper(
+		t,
+		[]flux.Table{data[0], data[1]},
+		nil,
+		errors.New(codes.FailedPrecondition, "parquet.to() found column extra that is not in the first table"),
+		func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+			return parquet.NewToParquetTransformation(ctx, d, c, executetest.UnlimitedAllocator, &parquet.ToParquetProcedureSpec{
+				File:        fpath,
+				Compression: "none",
+			})
+		},
+	)
+
+	entries, err := os.ReadDir(filepath.Dir(fpath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(fpath) {
+		names := make([]string, len(entries))
+		for i, e := range entries {
+			names[i] = e.Name()
+		}
+		t.Fatalf("expected only the written file to remain, got %v", names)
+	}
+	executetest.RunSourceHelper(t,
+		ctx,
+		testData(),
+		nil,
+		func(id execute.DatasetID) execute.Source {
+			a := mock.AdministrationWithContext(ctx)
+			s, err := parquet.CreateSource(&parquet.FromParquetProcedureSpec{File: fpath}, id, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	)
+}
+
+func TestToParquet_NotWritable(t *testing.T) {
+	// Files can only be written when the writable service is injected.
+	ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+	fpath := filepath.Join(t.TempDir(), "data.parquet")
+	data := testData()
+	executetest.ProcessTestHelper(
+		t,
+		[]flux.Table{data[0], data[1]},
+		nil,
+		errors.Wrap(errors.New(codes.Unimplemented, "writable filesystem service is uninitialized"), codes.Inherit, "parquet.to() failed to create file"),
+		func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+			return parquet.NewToParquetTransformation(ctx, d, c, executetest.UnlimitedAllocator, &parquet.ToParquetProcedureSpec{
+				File:        fpath,
+				Compression: "none",
+			})
+		},
+	)
+	if _, err := os.Stat(fpath); !os.IsNotExist(err) {
+		t.Fatalf("expected the file not to be created, got %v", err)
+	}
+}
+
+// parquetFrom returns a parquet source spec with the columns and predicates.
+func parquetFrom(columns []string, preds ...parquet.Predicate) *parquet.FromParquetProcedureSpec {
+	return &parquet.FromParquetProcedureSpec{
+		File:       "data.parquet",
+		Columns:    columns,
+		Predicates: preds,
+	}
+}
+
+func TestParquetPushDownRules(t *testing.T) {
+	rangeSpec := &universe.RangeProcedureSpec{
+		Bounds: flux.Bounds{
+			Start: flux.Time{Absolute: time.Unix(10, 0)},
+			Stop:  flux.Time{Absolute: time.Unix(20, 0)},
+		},
+		TimeColumn:  "_time",
+		StartColumn: "_start",
+		StopColumn:  "_stop",
+	}
+	keep := &universe.SchemaMutationProcedureSpec{
+		Mutations: []universe.SchemaMutation{
+			&universe.KeepOpSpec{Columns: []string{"_value", "host"}},
+		},
+	}
+	count := &universe.CountProcedureSpec{}
+
+	tests := []plantest.RuleTestCase{
+		{
+			Name:  "range",
+			Rules: []plan.Rule{parquet.ParquetRangePushDownRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", parquetFrom(nil)),
+					plan.CreatePhysicalNode("range", rangeSpec),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", parquetFrom(nil,
+						parquet.Predicate{Column: "_time", Op: ast.GreaterThanEqualOperator, Value: values.NewTime(values.ConvertTime(time.Unix(10, 0)))},
+						parquet.Predicate{Column: "_time", Op: ast.LessThanOperator, Value: values.NewTime(values.ConvertTime(time.Unix(20, 0)))},
+					)),
+					plan.CreatePhysicalNode("range", rangeSpec),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:  "keep",
+			Rules: []plan.Rule{parquet.ParquetKeepPushDownRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", parquetFrom([]string{"_time", "_value"})),
+					plan.CreatePhysicalNode("keep", keep),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", parquetFrom([]string{"_value"})),
+					plan.CreatePhysicalNode("keep", keep),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:  "shared source",
+			Rules: []plan.Rule{parquet.ParquetRangePushDownRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", parquetFrom(nil)),
+					plan.CreatePhysicalNode("range", rangeSpec),
+					plan.CreatePhysicalNode("count", count),
+				},
+				Edges: [][2]int{{0, 1}, {0, 2}},
+			},
+			NoChange:       true,
+			SkipValidation: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			plantest.PhysicalRuleTestHelper(t, &tc)
+		})
+	}
+}
+
+func TestParquetFilterPushDownRule(t *testing.T) {
+	filter := &universe.FilterProcedureSpec{
+		Fn: interpreter.ResolvedFunction{
+			Fn: executetest.FunctionExpression(t, `(r) => r.host == "a" and 1.5 < r._value and r._value != 3.0`),
+		},
+	}
+	tc := plantest.RuleTestCase{
+		Name:  "filter",
+		Rules: []plan.Rule{parquet.ParquetFilterPushDownRule{}},
+		Before: &plantest.PlanSpec{
+			Nodes: []plan.Node{
+				plan.CreatePhysicalNode("from", parquetFrom(nil)),
+				plan.CreatePhysicalNode("filter", filter),
+			},
+			Edges: [][2]int{{0, 1}},
+		},
+		After: &plantest.PlanSpec{
+			Nodes: []plan.Node{
+				plan.CreatePhysicalNode("from", parquetFrom(nil,
+					parquet.Predicate{Column: "host", Op: ast.EqualOperator, Value: values.NewString("a")},
+					parquet.Predicate{Column: "_value", Op: ast.GreaterThanOperator, Value: values.NewFloat(1.5)},
+				)),
+				plan.CreatePhysicalNode("filter", filter),
+			},
+			Edges: [][2]int{{0, 1}},
+		},
+		SkipValidation: true,
+	}
+	plantest.PhysicalRuleTestHelper(t, &tc)
+}
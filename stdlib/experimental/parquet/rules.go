@@ -0,0 +1,398 @@
+package parquet
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow/go/v7/arrow"
+	"github.com/apache/arrow/go/v7/parquet/metadata"
+	"github.com/apache/arrow/go/v7/parquet/schema"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
+)
+
+// Predicate is a comparison of a column with a constant value.
+// Row groups whose column statistics show that no row
+// satisfies a predicate are not read.
+type Predicate struct {
+	Column string
+	Op     ast.OperatorKind
+	Value  values.Value
+}
+
+func (p Predicate) equal(o Predicate) bool {
+	return p.Column == o.Column && p.Op == o.Op && p.Value.Equal(o.Value)
+}
+
+// addPredicates adds the predicates that the spec does not have yet
+// and reports whether any were added.
+func (s *FromParquetProcedureSpec) addPredicates(preds []Predicate) bool {
+	changed := false
+	for _, p := range preds {
+		found := false
+		for _, q := range s.Predicates {
+			if p.equal(q) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.Predicates = append(s.Predicates, p)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// ParquetFilterPushDownRule adds the comparisons of a filter that directly
+// follows parquet.from() to the predicates of the source.
+// The filter is kept since the predicates only skip row groups.
+type ParquetFilterPushDownRule struct{}
+
+func (ParquetFilterPushDownRule) Name() string {
+	return "ParquetFilterPushDownRule"
+}
+
+func (ParquetFilterPushDownRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessor(universe.FilterKind, plan.SingleSuccessor(FromParquetKind))
+}
+
+func (ParquetFilterPushDownRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	fromSpec := node.Predecessors()[0].ProcedureSpec().(*FromParquetProcedureSpec)
+	filterSpec := node.ProcedureSpec().(*universe.FilterProcedureSpec)
+	preds := filterPredicates(filterSpec.Fn)
+	return node, fromSpec.addPredicates(preds), nil
+}
+
+// ParquetRangePushDownRule adds the bounds of a range that directly
+// follows parquet.from() to the predicates of the source.
+// The range is kept since the predicates only skip row groups.
+type ParquetRangePushDownRule struct{}
+
+func (ParquetRangePushDownRule) Name() string {
+	return "ParquetRangePushDownRule"
+}
+
+func (ParquetRangePushDownRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessor(universe.RangeKind, plan.SingleSuccessor(FromParquetKind))
+}
+
+func (ParquetRangePushDownRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	fromSpec := node.Predecessors()[0].ProcedureSpec().(*FromParquetProcedureSpec)
+	rangeSpec := node.ProcedureSpec().(*universe.RangeProcedureSpec)
+	bounds := plan.FromFluxBounds(rangeSpec.Bounds)
+	preds := []Predicate{
+		{Column: rangeSpec.TimeColumn, Op: ast.GreaterThanEqualOperator, Value: values.NewTime(bounds.Start)},
+		{Column: rangeSpec.TimeColumn, Op: ast.LessThanOperator, Value: values.NewTime(bounds.Stop)},
+	}
+	return node, fromSpec.addPredicates(preds), nil
+}
+
+// ParquetKeepPushDownRule limits the columns that parquet.from() reads
+// to the columns of a keep that directly follows it.
+// The keep is kept since the source also reads the group key columns.
+type ParquetKeepPushDownRule struct{}
+
+func (ParquetKeepPushDownRule) Name() string {
+	return "ParquetKeepPushDownRule"
+}
+
+func (ParquetKeepPushDownRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessor(universe.SchemaMutationKind, plan.SingleSuccessor(FromParquetKind))
+}
+
+func (ParquetKeepPushDownRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	fromSpec := node.Predecessors()[0].ProcedureSpec().(*FromParquetProcedureSpec)
+	mutationSpec := node.ProcedureSpec().(*universe.SchemaMutationProcedureSpec)
+	if len(mutationSpec.Mutations) != 1 {
+		return node, false, nil
+	}
+	keep, ok := mutationSpec.Mutations[0].(*universe.KeepOpSpec)
+	if !ok || keep.Predicate.Fn != nil || len(keep.Columns) == 0 {
+		return node, false, nil
+	}
+
+	if fromSpec.Columns == nil {
+		fromSpec.Columns = append(make([]string, 0, len(keep.Columns)), keep.Columns...)
+		return node, true, nil
+	}
+	kept := make(map[string]bool, len(keep.Columns))
+	for _, label := range keep.Columns {
+		kept[label] = true
+	}
+	columns := make([]string, 0, len(fromSpec.Columns))
+	for _, label := range fromSpec.Columns {
+		if kept[label] {
+			columns = append(columns, label)
+		}
+	}
+	if len(columns) == len(fromSpec.Columns) {
+		return node, false, nil
+	}
+	fromSpec.Columns = columns
+	return node, true, nil
+}
+
+// filterPredicates returns the comparisons of a column with a constant
+// in a filter function. Comparisons are only used if the function body
+// is a comparison or a conjunction of expressions that contains them.
+func filterPredicates(fn interpreter.ResolvedFunction) []Predicate {
+	if fn.Fn == nil || fn.Fn.Parameters == nil || len(fn.Fn.Parameters.List) != 1 {
+		return nil
+	}
+	body, ok := fn.Fn.GetFunctionBodyExpression()
+	if !ok {
+		return nil
+	}
+	param := fn.Fn.Parameters.List[0].Key.Name.Name()
+
+	var preds []Predicate
+	var walk func(e semantic.Expression)
+	walk = func(e semantic.Expression) {
+		switch e := e.(type) {
+		case *semantic.LogicalExpression:
+			if e.Operator == ast.AndOperator {
+				walk(e.Left)
+				walk(e.Right)
+			}
+		case *semantic.BinaryExpression:
+			if p, ok := comparison(e, param, fn.Scope); ok {
+				preds = append(preds, p)
+			}
+		}
+	}
+	walk(body)
+	return preds
+}
+
+// comparison returns the predicate for a comparison of a record column
+// with a literal or a variable, with the column on either side.
+func comparison(e *semantic.BinaryExpression, param string, scope values.Scope) (Predicate, bool) {
+	op := e.Operator
+	switch op {
+	case ast.EqualOperator, ast.LessThanOperator, ast.LessThanEqualOperator,
+		ast.GreaterThanOperator, ast.GreaterThanEqualOperator:
+	default:
+		return Predicate{}, false
+	}
+	left, right := e.Left, e.Right
+	column, ok := recordColumn(left, param)
+	if !ok {
+		if column, ok = recordColumn(right, param); !ok {
+			return Predicate{}, false
+		}
+		left, right = right, left
+		op = flipOperator(op)
+	}
+	v, ok := constantValue(right, scope)
+	if !ok {
+		return Predicate{}, false
+	}
+	return Predicate{Column: column, Op: op, Value: v}, true
+}
+
+func flipOperator(op ast.OperatorKind) ast.OperatorKind {
+	switch op {
+	case ast.LessThanOperator:
+		return ast.GreaterThanOperator
+	case ast.LessThanEqualOperator:
+		return ast.GreaterThanEqualOperator
+	case ast.GreaterThanOperator:
+		return ast.LessThanOperator
+	case ast.GreaterThanEqualOperator:
+		return ast.LessThanEqualOperator
+	default:
+		return op
+	}
+}
+
+// recordColumn returns the column of a member expression like r.host.
+func recordColumn(e semantic.Expression, param string) (string, bool) {
+	m, ok := e.(*semantic.MemberExpression)
+	if !ok {
+		return "", false
+	}
+	obj, ok := m.Object.(*semantic.IdentifierExpression)
+	if !ok || obj.Name.Name() != param {
+		return "", false
+	}
+	return m.Property.Name(), true
+}
+
+// constantValue returns the value of a literal or of a variable
+// that holds a basic value.
+func constantValue(e semantic.Expression, scope values.Scope) (values.Value, bool) {
+	switch e := e.(type) {
+	case *semantic.IntegerLiteral:
+		return values.NewInt(e.Value), true
+	case *semantic.UnsignedIntegerLiteral:
+		return values.NewUInt(e.Value), true
+	case *semantic.FloatLiteral:
+		return values.NewFloat(e.Value), true
+	case *semantic.StringLiteral:
+		return values.NewString(e.Value), true
+	case *semantic.DateTimeLiteral:
+		return values.NewTime(values.ConvertTime(e.Value)), true
+	case *semantic.IdentifierExpression:
+		if scope == nil {
+			return nil, false
+		}
+		v, ok := scope.Lookup(e.Name.Name())
+		if !ok || v.IsNull() {
+			return nil, false
+		}
+		switch v.Type().Nature() {
+		case semantic.Int, semantic.UInt, semantic.Float, semantic.String, semantic.Time:
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// skipRowGroup reports whether the statistics of a row group
+// show that no row satisfies one of the predicates.
+func skipRowGroup(rg *metadata.RowGroupMetaData, pqSchema *schema.Schema, arrowSchema *arrow.Schema, preds []Predicate) bool {
+	for _, p := range preds {
+		fields := arrowSchema.FieldIndices(p.Column)
+		idx := pqSchema.ColumnIndexByName(p.Column)
+		if len(fields) != 1 || idx < 0 {
+			continue
+		}
+		min, max, ok := columnBounds(rg, idx, arrowSchema.Field(fields[0]).Type)
+		if !ok {
+			continue
+		}
+		lo, ok := compare(p.Value, min)
+		if !ok {
+			continue
+		}
+		hi, _ := compare(p.Value, max)
+		switch p.Op {
+		case ast.EqualOperator:
+			if lo < 0 || hi > 0 {
+				return true
+			}
+		case ast.LessThanOperator:
+			if lo <= 0 {
+				return true
+			}
+		case ast.LessThanEqualOperator:
+			if lo < 0 {
+				return true
+			}
+		case ast.GreaterThanOperator:
+			if hi >= 0 {
+				return true
+			}
+		case ast.GreaterThanEqualOperator:
+			if hi > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// columnBounds returns the smallest and largest values of a column
+// in a row group as values of the column type.
+func columnBounds(rg *metadata.RowGroupMetaData, idx int, typ arrow.DataType) (min, max values.Value, ok bool) {
+	cc, err := rg.ColumnChunk(idx)
+	if err != nil {
+		return nil, nil, false
+	}
+	if set, err := cc.StatsSet(); err != nil || !set {
+		return nil, nil, false
+	}
+	stats, err := cc.Statistics()
+	if err != nil || stats == nil || !stats.HasMinMax() {
+		return nil, nil, false
+	}
+
+	var lo, hi int64
+	switch s := stats.(type) {
+	case *metadata.Int32Statistics:
+		lo, hi = int64(s.Min()), int64(s.Max())
+		if typ.ID() == arrow.UINT32 || typ.ID() == arrow.UINT16 || typ.ID() == arrow.UINT8 {
+			lo, hi = int64(uint32(s.Min())), int64(uint32(s.Max()))
+		}
+	case *metadata.Int64Statistics:
+		lo, hi = s.Min(), s.Max()
+	case *metadata.Float32Statistics:
+		return values.NewFloat(float64(s.Min())), values.NewFloat(float64(s.Max())), true
+	case *metadata.Float64Statistics:
+		return values.NewFloat(s.Min()), values.NewFloat(s.Max()), true
+	case *metadata.ByteArrayStatistics:
+		if typ.ID() != arrow.STRING {
+			return nil, nil, false
+		}
+		return values.NewString(string(s.Min())), values.NewString(string(s.Max())), true
+	default:
+		return nil, nil, false
+	}
+
+	switch t := typ.(type) {
+	case *arrow.TimestampType:
+		m := int64(t.Unit.Multiplier())
+		return values.NewTime(values.Time(lo * m)), values.NewTime(values.Time(hi * m)), true
+	case *arrow.Date32Type:
+		m := int64(arrow.Second.Multiplier()) * 86400
+		return values.NewTime(values.Time(lo * m)), values.NewTime(values.Time(hi * m)), true
+	case *arrow.Date64Type:
+		m := int64(arrow.Millisecond.Multiplier())
+		return values.NewTime(values.Time(lo * m)), values.NewTime(values.Time(hi * m)), true
+	}
+	switch typ.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64:
+		return values.NewInt(lo), values.NewInt(hi), true
+	case arrow.UINT8, arrow.UINT16, arrow.UINT32, arrow.UINT64:
+		return values.NewUInt(uint64(lo)), values.NewUInt(uint64(hi)), true
+	default:
+		return nil, nil, false
+	}
+}
+
+// maxExactFloat is the largest integer that every smaller integer
+// can be converted to a float exactly.
+const maxExactFloat = 1 << 53
+
+// compare compares two values of the same type. Integers are also compared
+// with floats if they can be converted exactly. It reports false if the
+// values cannot be compared.
+func compare(x, y values.Value) (int, bool) {
+	xn, yn := x.Type().Nature(), y.Type().Nature()
+	if xn == semantic.Int && yn == semantic.Float && x.Int() >= -maxExactFloat && x.Int() <= maxExactFloat {
+		return compareOrdered(float64(x.Int()), y.Float()), true
+	}
+	if xn != yn {
+		return 0, false
+	}
+	switch xn {
+	case semantic.Int:
+		return compareOrdered(x.Int(), y.Int()), true
+	case semantic.UInt:
+		return compareOrdered(x.UInt(), y.UInt()), true
+	case semantic.Float:
+		return compareOrdered(x.Float(), y.Float()), true
+	case semantic.String:
+		return strings.Compare(x.Str(), y.Str()), true
+	case semantic.Time:
+		return compareOrdered(x.Time(), y.Time()), true
+	default:
+		return 0, false
+	}
+}
+
+func compareOrdered[T int64 | uint64 | float64 | values.Time](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,396 @@
+package parquet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+
+	"github.com/apache/arrow/go/v7/arrow"
+	"github.com/apache/arrow/go/v7/arrow/array"
+	pq "github.com/apache/arrow/go/v7/parquet"
+	"github.com/apache/arrow/go/v7/parquet/compress"
+	"github.com/apache/arrow/go/v7/parquet/pqarrow"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/dependencies/filesystem"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const (
+	ToParquetKind = "toParquet"
+
+	defaultCompression = "snappy"
+)
+
+// compressions maps the names of the supported compressions to their codecs.
+var compressions = map[string]compress.Compression{
+	"none":   compress.Codecs.Uncompressed,
+	"snappy": compress.Codecs.Snappy,
+	"gzip":   compress.Codecs.Gzip,
+	"brotli": compress.Codecs.Brotli,
+	"zstd":   compress.Codecs.Zstd,
+}
+
+type ToParquetOpSpec struct {
+	File        string `json:"file"`
+	Compression string `json:"compression"`
+}
+
+func init() {
+	toSignature := runtime.MustLookupBuiltinType(pkgPath, "to")
+	runtime.RegisterPackageValue(pkgPath, "to", flux.MustValue(flux.FunctionValueWithSideEffect(ToParquetKind, createToParquetOpSpec, toSignature)))
+	plan.RegisterProcedureSpecWithSideEffect(ToParquetKind, newToParquetProcedure, ToParquetKind)
+	execute.RegisterTransformation(ToParquetKind, createToParquetTransformation)
+}
+
+func createToParquetOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := new(ToParquetOpSpec)
+
+	f, err := args.GetRequiredString("file")
+	if err != nil {
+		return nil, err
+	}
+	spec.File = f
+
+	if c, ok, err := args.GetString("compression"); err != nil {
+		return nil, err
+	} else if ok {
+		if _, ok := compressions[c]; !ok {
+			return nil, errors.Newf(codes.Invalid, "unsupported compression %q", c)
+		}
+		spec.Compression = c
+	} else {
+		spec.Compression = defaultCompression
+	}
+	return spec, nil
+}
+
+func (s *ToParquetOpSpec) Kind() flux.OperationKind {
+	return ToParquetKind
+}
+
+type ToParquetProcedureSpec struct {
+	plan.DefaultCost
+	File        string
+	Compression string
+}
+
+func newToParquetProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ToParquetOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &ToParquetProcedureSpec{
+		File:        spec.File,
+		Compression: spec.Compression,
+	}, nil
+}
+
+func (s *ToParquetProcedureSpec) Kind() plan.ProcedureKind {
+	return ToParquetKind
+}
+
+func (s *ToParquetProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(ToParquetProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+func createToParquetTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ToParquetProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewToParquetTransformation(a.Context(), d, cache, a.Allocator(), s)
+	return t, d, nil
+}
+
+type toParquetTransformation struct {
+	execute.ExecutionNode
+	d     execute.Dataset
+	cache execute.TableBuilderCache
+	ctx   context.Context
+	mem   memory.Allocator
+	spec  *ToParquetProcedureSpec
+
+	// The writer and the schema of the file are created from
+	// the first table. Tables are written as they arrive so
+	// later tables cannot change the schema.
+	fw     *pqarrow.FileWriter
+	schema *arrow.Schema
+	cols   []flux.ColMeta
+
+	// Tables are written to a temporary file in the same directory
+	// that replaces the file only when every table has been written.
+	fs   filesystem.WritableService
+	w    io.WriteCloser
+	temp string
+}
+
+func NewToParquetTransformation(ctx context.Context, d execute.Dataset, cache execute.TableBuilderCache, mem memory.Allocator, spec *ToParquetProcedureSpec) *toParquetTransformation {
+	return &toParquetTransformation{
+		d:     d,
+		cache: cache,
+		ctx:   ctx,
+		mem:   mem,
+		spec:  spec,
+	}
+}
+
+func (t *toParquetTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	builder, created := t.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "parquet.to() found duplicate table with key: %v", tbl.Key())
+	}
+	if err := execute.AddTableCols(tbl, builder); err != nil {
+		return err
+	}
+	if t.fw == nil {
+		if err := t.createFile(tbl); err != nil {
+			return err
+		}
+	}
+
+	// colMap maps the columns of the file to the columns of the table.
+	colMap := make([]int, len(t.cols))
+	for i := range colMap {
+		colMap[i] = -1
+	}
+	for j, c := range tbl.Cols() {
+		i := execute.ColIdx(c.Label, t.cols)
+		if i < 0 {
+			return errors.Newf(codes.FailedPrecondition, "parquet.to() found column %s that is not in the first table", c.Label)
+		}
+		if t.cols[i].Type != c.Type {
+			return errors.Newf(codes.FailedPrecondition, "parquet.to() found column %s with type %s but the first table has type %s", c.Label, c.Type, t.cols[i].Type)
+		}
+		colMap[i] = j
+	}
+
+	builders := make([]array.Builder, len(t.cols))
+	for i, f := range t.schema.Fields() {
+		builders[i] = array.NewBuilder(t.mem, f.Type)
+	}
+	defer func() {
+		for _, b := range builders {
+			b.Release()
+		}
+	}()
+
+	nrows := 0
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		if err := execute.AppendCols(cr, builder); err != nil {
+			return err
+		}
+		for i, b := range builders {
+			appendColumn(b, cr, colMap[i], t.cols[i].Type)
+		}
+		nrows += cr.Len()
+		return nil
+	}); err != nil {
+		return err
+	}
+	if nrows == 0 {
+		return nil
+	}
+
+	arrs := make([]arrow.Array, len(builders))
+	for i, b := range builders {
+		arrs[i] = b.NewArray()
+	}
+	rec := array.NewRecord(t.schema, arrs, int64(nrows))
+	for _, arr := range arrs {
+		arr.Release()
+	}
+	defer rec.Release()
+	if err := t.fw.Write(rec); err != nil {
+		return errors.Wrap(err, codes.Internal, "parquet.to() failed to write table")
+	}
+	return nil
+}
+
+// createFile creates the file and its writer with the schema of the table.
+func (t *toParquetTransformation) createFile(tbl flux.Table) error {
+	t.cols = tbl.Cols()
+	fields := make([]arrow.Field, len(t.cols))
+	for i, c := range t.cols {
+		typ, err := arrowType(c.Type)
+		if err != nil {
+			return errors.Wrapf(err, codes.Inherit, "parquet.to() cannot write column %s", c.Label)
+		}
+		fields[i] = arrow.Field{Name: c.Label, Type: typ, Nullable: true}
+	}
+	key := make([]string, len(tbl.Key().Cols()))
+	for i, c := range tbl.Key().Cols() {
+		key[i] = c.Label
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	md := arrow.NewMetadata([]string{groupKeyMetadataKey}, []string{string(b)})
+	t.schema = arrow.NewSchema(fields, &md)
+
+	fs, err := filesystem.GetWritable(t.ctx)
+	if err != nil {
+		return errors.Wrap(err, codes.Inherit, "parquet.to() failed to create file")
+	}
+	dir, base := filepath.Split(t.spec.File)
+	temp := filepath.Join(dir, fmt.Sprintf(".%s.%x.tmp", base, rand.Uint64()))
+	w, err := fs.Create(temp)
+	if err != nil {
+		return errors.Wrap(err, codes.Inherit, "parquet.to() failed to create file")
+	}
+	t.fs, t.w, t.temp = fs, w, temp
+
+	props := pq.NewWriterProperties(
+		pq.WithCompression(compressions[t.spec.Compression]),
+		pq.WithAllocator(t.mem),
+	)
+	arrowProps := pqarrow.NewArrowWriterProperties(
+		pqarrow.WithStoreSchema(),
+		pqarrow.WithAllocator(t.mem),
+	)
+	t.fw, err = pqarrow.NewFileWriter(t.schema, w, props, arrowProps)
+	if err != nil {
+		return errors.Wrap(err, codes.Internal, "parquet.to() failed to create file")
+	}
+	return nil
+}
+
+// arrowType returns the arrow type that is used for a column type.
+func arrowType(typ flux.ColType) (arrow.DataType, error) {
+	switch typ {
+	case flux.TInt:
+		return arrow.PrimitiveTypes.Int64, nil
+	case flux.TUInt:
+		return arrow.PrimitiveTypes.Uint64, nil
+	case flux.TFloat:
+		return arrow.PrimitiveTypes.Float64, nil
+	case flux.TBool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case flux.TString:
+		return arrow.BinaryTypes.String, nil
+	case flux.TTime:
+		return &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}, nil
+	default:
+		return nil, errors.Newf(codes.Unimplemented, "unsupported type %s", typ)
+	}
+}
+
+// appendColumn appends column j of the reader to the builder
+// or nulls if the table does not have the column.
+func appendColumn(b array.Builder, cr flux.ColReader, j int, typ flux.ColType) {
+	n := cr.Len()
+	if j < 0 {
+		for i := 0; i < n; i++ {
+			b.AppendNull()
+		}
+		return
+	}
+	for i := 0; i < n; i++ {
+		switch typ {
+		case flux.TInt:
+			if vs := cr.Ints(j); vs.IsValid(i) {
+				b.(*array.Int64Builder).Append(vs.Value(i))
+				continue
+			}
+		case flux.TUInt:
+			if vs := cr.UInts(j); vs.IsValid(i) {
+				b.(*array.Uint64Builder).Append(vs.Value(i))
+				continue
+			}
+		case flux.TFloat:
+			if vs := cr.Floats(j); vs.IsValid(i) {
+				b.(*array.Float64Builder).Append(vs.Value(i))
+				continue
+			}
+		case flux.TBool:
+			if vs := cr.Bools(j); vs.IsValid(i) {
+				b.(*array.BooleanBuilder).Append(vs.Value(i))
+				continue
+			}
+		case flux.TString:
+			if vs := cr.Strings(j); vs.IsValid(i) {
+				b.(*array.StringBuilder).Append(vs.Value(i))
+				continue
+			}
+		case flux.TTime:
+			if vs := cr.Times(j); vs.IsValid(i) {
+				b.(*array.TimestampBuilder).Append(arrow.Timestamp(vs.Value(i)))
+				continue
+			}
+		}
+		b.AppendNull()
+	}
+}
+
+func (t *toParquetTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return t.d.RetractTable(key)
+}
+
+func (t *toParquetTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return t.d.UpdateWatermark(mark)
+}
+
+func (t *toParquetTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return t.d.UpdateProcessingTime(pt)
+}
+
+// Finish writes the footer of the file and replaces the file with it.
+// If the query failed, the footer is not written and the temporary
+// file is removed so the file is left unchanged.
+func (t *toParquetTransformation) Finish(id execute.DatasetID, err error) {
+	if t.w != nil {
+		if err != nil {
+			t.abort()
+		} else if err = t.commit(); err != nil {
+			t.abort()
+		}
+	}
+	t.d.Finish(err)
+}
+
+// commit writes the footer of the temporary file
+// and renames it to the file.
+func (t *toParquetTransformation) commit() error {
+	if t.fw == nil {
+		return errors.New(codes.Internal, "parquet.to() failed to create file")
+	}
+	err := t.fw.Close()
+	// The writer closes the temporary file.
+	t.w = nil
+	if err != nil {
+		return errors.Wrap(err, codes.Internal, "parquet.to() failed to close file")
+	}
+	if err := t.fs.Rename(t.temp, t.spec.File); err != nil {
+		return errors.Wrap(err, codes.Inherit, "parquet.to() failed to rename file")
+	}
+	t.temp = ""
+	return nil
+}
+
+// abort closes the temporary file without writing the footer
+// and removes it.
+func (t *toParquetTransformation) abort() {
+	if t.w != nil {
+		_ = t.w.Close()
+		t.w = nil
+	}
+	if t.temp != "" {
+		_ = t.fs.Remove(t.temp)
+		t.temp = ""
+	}
+}
@@ -0,0 +1,463 @@
+package polyline
+
+import (
+	"math"
+	"sort"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const (
+	LTTBKind = "lttb"
+	M4Kind   = "m4"
+)
+
+// DownsampleOpSpec is the operation spec of lttb and m4.
+// For lttb, N is the number of points to keep and
+// for m4, N is the number of pixels of the chart.
+type DownsampleOpSpec struct {
+	Method     string `json:"method"`
+	N          int64  `json:"n"`
+	ValColumn  string `json:"valcolumn"`
+	TimeColumn string `json:"timecolumn"`
+}
+
+func init() {
+	for _, method := range []string{LTTBKind, M4Kind} {
+		method := method
+		signature := runtime.MustLookupBuiltinType("experimental/polyline", method)
+		runtime.RegisterPackageValue("experimental/polyline", method, flux.MustValue(flux.FunctionValue(method, func(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+			return createDownsampleOpSpec(method, args, a)
+		}, signature)))
+		plan.RegisterProcedureSpec(plan.ProcedureKind(method), newDownsampleProcedure, flux.OperationKind(method))
+		execute.RegisterTransformation(plan.ProcedureKind(method), createDownsampleTransformation)
+	}
+}
+
+func createDownsampleOpSpec(method string, args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := &DownsampleOpSpec{
+		Method: method,
+	}
+	switch method {
+	case LTTBKind:
+		n, err := args.GetRequiredInt("n")
+		if err != nil {
+			return nil, err
+		}
+		// The first and last points are always kept
+		// so there must be room for at least one more.
+		if n < 3 {
+			return nil, errors.Newf(codes.Invalid, "n must be at least 3, got %d", n)
+		}
+		spec.N = n
+	case M4Kind:
+		pixels, err := args.GetRequiredInt("pixels")
+		if err != nil {
+			return nil, err
+		}
+		if pixels < 1 {
+			return nil, errors.Newf(codes.Invalid, "pixels must be at least 1, got %d", pixels)
+		}
+		spec.N = pixels
+	}
+	if col, ok, err := args.GetString("valColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.ValColumn = col
+	} else {
+		spec.ValColumn = execute.DefaultValueColLabel
+	}
+	if col, ok, err := args.GetString("timeColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.TimeColumn = col
+	} else {
+		spec.TimeColumn = execute.DefaultTimeColLabel
+	}
+	return spec, nil
+}
+
+func (s *DownsampleOpSpec) Kind() flux.OperationKind {
+	return flux.OperationKind(s.Method)
+}
+
+type DownsampleProcedureSpec struct {
+	plan.DefaultCost
+	Method     string
+	N          int64
+	ValColumn  string
+	TimeColumn string
+}
+
+func newDownsampleProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*DownsampleOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &DownsampleProcedureSpec{
+		Method:     spec.Method,
+		N:          spec.N,
+		ValColumn:  spec.ValColumn,
+		TimeColumn: spec.TimeColumn,
+	}, nil
+}
+
+func (s *DownsampleProcedureSpec) Kind() plan.ProcedureKind {
+	return plan.ProcedureKind(s.Method)
+}
+func (s *DownsampleProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(DownsampleProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+func (s *DownsampleProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createDownsampleTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*DownsampleProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewDownsampleTransformation(d, cache, a.Allocator(), s)
+	return t, d, nil
+}
+
+type DownsampleTransformation struct {
+	execute.ExecutionNode
+	d          execute.Dataset
+	cache      execute.TableBuilderCache
+	mem        memory.Allocator
+	method     string
+	n          int
+	valColumn  string
+	timeColumn string
+}
+
+func NewDownsampleTransformation(d execute.Dataset, cache execute.TableBuilderCache, mem memory.Allocator, spec *DownsampleProcedureSpec) *DownsampleTransformation {
+	return &DownsampleTransformation{
+		d:          d,
+		cache:      cache,
+		mem:        mem,
+		method:     spec.Method,
+		n:          int(spec.N),
+		valColumn:  spec.ValColumn,
+		timeColumn: spec.TimeColumn,
+	}
+}
+
+// point is a row of the table that may be kept.
+// The row number orders points with the same time.
+type point struct {
+	row  int
+	time int64
+	val  float64
+}
+
+// Process reads the points of the table in a single pass and writes
+// the points that are kept with the group key of the table.
+// Rows with a null time or value are dropped.
+func (dt *DownsampleTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	builder, created := dt.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "%s found duplicate table with key: %v", dt.method, tbl.Key())
+	}
+	cols := tbl.Cols()
+	timeIdx := execute.ColIdx(dt.timeColumn, cols)
+	if timeIdx < 0 {
+		return errors.Newf(codes.FailedPrecondition, "cannot find time column %s", dt.timeColumn)
+	}
+	if typ := cols[timeIdx].Type; typ != flux.TTime {
+		return errors.Newf(codes.FailedPrecondition, "time column %s must be of type time, got %s", dt.timeColumn, typ)
+	}
+	colIdx := execute.ColIdx(dt.valColumn, cols)
+	if colIdx < 0 {
+		return errors.Newf(codes.FailedPrecondition, "cannot find column %s", dt.valColumn)
+	}
+	typ := cols[colIdx].Type
+	if typ != flux.TInt &&
+		typ != flux.TUInt &&
+		typ != flux.TFloat {
+		return errors.Newf(codes.FailedPrecondition, "%s can work only on numerical types, got %s", dt.method, typ.String())
+	}
+
+	if err := execute.AddTableKeyCols(tbl.Key(), builder); err != nil {
+		return err
+	}
+	newTimeIdx, err := builder.AddCol(flux.ColMeta{
+		Label: dt.timeColumn,
+		Type:  flux.TTime,
+	})
+	if err != nil {
+		return err
+	}
+	newValueIdx, err := builder.AddCol(flux.ColMeta{
+		Label: dt.valColumn,
+		Type:  flux.TFloat,
+	})
+	if err != nil {
+		return err
+	}
+
+	var keep []point
+	switch dt.method {
+	case LTTBKind:
+		keep, err = dt.lttb(tbl, timeIdx, colIdx)
+	case M4Kind:
+		keep, err = dt.m4(tbl, timeIdx, colIdx)
+	default:
+		err = errors.Newf(codes.Internal, "unknown downsampling method %q", dt.method)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, p := range keep {
+		if err := builder.AppendTime(newTimeIdx, execute.Time(p.time)); err != nil {
+			return err
+		}
+		if err := builder.AppendFloat(newValueIdx, p.val); err != nil {
+			return err
+		}
+	}
+	return execute.AppendKeyValuesN(tbl.Key(), builder, len(keep))
+}
+
+// readPoints calls fn with each row of the table
+// that has both a time and a value.
+func readPoints(tbl flux.Table, timeIdx, colIdx int, fn func(p point) error) error {
+	row := 0
+	return tbl.Do(func(cr flux.ColReader) error {
+		times := cr.Times(timeIdx)
+		for i := 0; i < cr.Len(); i, row = i+1, row+1 {
+			if times.IsNull(i) {
+				continue
+			}
+			var v float64
+			switch vs := table.Values(cr, colIdx).(type) {
+			case *array.Int:
+				if vs.IsNull(i) {
+					continue
+				}
+				v = float64(vs.Value(i))
+			case *array.Uint:
+				if vs.IsNull(i) {
+					continue
+				}
+				v = float64(vs.Value(i))
+			case *array.Float:
+				if vs.IsNull(i) {
+					continue
+				}
+				v = vs.Value(i)
+			}
+			if err := fn(point{row: row, time: times.Value(i), val: v}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lttb returns the points that the Largest-Triangle-Three-Buckets
+// algorithm keeps to draw the series with n points. The points must be sorted by time.
+//
+// The buckets of lttb are ranges of points so every point of the table
+// is buffered before any are chosen. The buffer is allocated with the
+// allocator of the transformation so it counts towards the memory limit.
+//
+// The first and last points are kept and the others are split into n-2 buckets.
+// From each bucket, the point that forms the largest triangle with the point kept
+// from the previous bucket and the average of the next bucket is kept.
+func (dt *DownsampleTransformation) lttb(tbl flux.Table, timeIdx, colIdx int) ([]point, error) {
+	tb := array.NewIntBuilder(dt.mem)
+	defer tb.Release()
+	vb := array.NewFloatBuilder(dt.mem)
+	defer vb.Release()
+	var last int64
+	if err := readPoints(tbl, timeIdx, colIdx, func(p point) error {
+		if tb.Len() > 0 && p.time < last {
+			return errors.Newf(codes.FailedPrecondition, "%s requires the times in column %s to be sorted", dt.method, dt.timeColumn)
+		}
+		last = p.time
+		tb.Append(p.time)
+		vb.Append(p.val)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	ts := tb.NewIntArray()
+	defer ts.Release()
+	vs := vb.NewFloatArray()
+	defer vs.Release()
+
+	at := func(i int) point {
+		return point{row: i, time: ts.Value(i), val: vs.Value(i)}
+	}
+	n, l := dt.n, ts.Len()
+	if l <= n {
+		keep := make([]point, l)
+		for i := range keep {
+			keep[i] = at(i)
+		}
+		return keep, nil
+	}
+
+	// The times are relative to the first point
+	// so that they do not lose precision as floats.
+	x := func(i int) float64 {
+		return float64(ts.Value(i) - ts.Value(0))
+	}
+
+	keep := make([]point, 0, n)
+	keep = append(keep, at(0))
+	size := float64(l-2) / float64(n-2)
+	a := 0
+	for b := 0; b < n-2; b++ {
+		start := int(float64(b)*size) + 1
+		end := int(float64(b+1)*size) + 1
+
+		// The average of the next bucket, which is the last point
+		// when this is the last bucket.
+		nextEnd := int(float64(b+2)*size) + 1
+		if nextEnd > l {
+			nextEnd = l
+		}
+		var avgX, avgY float64
+		for j := end; j < nextEnd; j++ {
+			avgX += x(j)
+			avgY += vs.Value(j)
+		}
+		avgX /= float64(nextEnd - end)
+		avgY /= float64(nextEnd - end)
+
+		ax, ay := x(a), vs.Value(a)
+		maxArea, next := -1.0, start
+		for j := start; j < end; j++ {
+			area := math.Abs((ax-avgX)*(vs.Value(j)-ay) - (ax-x(j))*(avgY-ay))
+			if area > maxArea {
+				maxArea, next = area, j
+			}
+		}
+		keep = append(keep, at(next))
+		a = next
+	}
+	return append(keep, at(l-1)), nil
+}
+
+// m4Bucket holds the points that m4 keeps from one pixel.
+type m4Bucket struct {
+	ok                    bool
+	first, last, min, max point
+}
+
+// m4 returns the points that the M4 algorithm keeps to draw
+// the series on a chart that is the given number of pixels wide.
+//
+// The time range between the _start and _stop columns of the group key
+// is split into one bucket per pixel so the points can be read in a
+// single pass. Only the points that may be kept from each bucket are held.
+// From each bucket, the first, last, smallest and largest points are kept.
+// The points are sorted by time.
+func (dt *DownsampleTransformation) m4(tbl flux.Table, timeIdx, colIdx int) ([]point, error) {
+	lo, hi, err := timeBounds(tbl.Key())
+	if err != nil {
+		return nil, errors.Wrapf(err, codes.Inherit, "%s needs the time range of each table", dt.method)
+	}
+	pixels := dt.n
+	buckets := make([]m4Bucket, pixels)
+	span := float64(hi - lo)
+	if err := readPoints(tbl, timeIdx, colIdx, func(p point) error {
+		k := 0
+		if span > 0 {
+			k = int(float64(p.time-lo) / span * float64(pixels))
+		}
+		if k < 0 {
+			k = 0
+		} else if k >= pixels {
+			k = pixels - 1
+		}
+		b := &buckets[k]
+		if !b.ok {
+			*b = m4Bucket{ok: true, first: p, last: p, min: p, max: p}
+			return nil
+		}
+		if p.time < b.first.time {
+			b.first = p
+		}
+		if p.time >= b.last.time {
+			b.last = p
+		}
+		if p.val < b.min.val {
+			b.min = p
+		}
+		if p.val > b.max.val {
+			b.max = p
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	keep := make([]point, 0, 4*pixels)
+	for _, b := range buckets {
+		if !b.ok {
+			continue
+		}
+		ps := []point{b.first, b.min, b.max, b.last}
+		sort.Slice(ps, func(i, j int) bool {
+			if ps[i].time != ps[j].time {
+				return ps[i].time < ps[j].time
+			}
+			return ps[i].row < ps[j].row
+		})
+		for k, p := range ps {
+			if k == 0 || p.row != ps[k-1].row {
+				keep = append(keep, p)
+			}
+		}
+	}
+	return keep, nil
+}
+
+// timeBounds returns the _start and _stop values of the group key.
+func timeBounds(key flux.GroupKey) (lo, hi int64, err error) {
+	labels := []string{execute.DefaultStartColLabel, execute.DefaultStopColLabel}
+	var bounds [2]int64
+	for i, label := range labels {
+		j := execute.ColIdx(label, key.Cols())
+		if j < 0 || key.Cols()[j].Type != flux.TTime || key.IsNull(j) {
+			return 0, 0, errors.Newf(codes.FailedPrecondition, "group key column %s of type time is required, use range() to set it", label)
+		}
+		bounds[i] = int64(key.ValueTime(j))
+	}
+	return bounds[0], bounds[1], nil
+}
+
+func (dt *DownsampleTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return dt.d.RetractTable(key)
+}
+
+func (dt *DownsampleTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return dt.d.UpdateWatermark(mark)
+}
+func (dt *DownsampleTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return dt.d.UpdateProcessingTime(pt)
+}
+func (dt *DownsampleTransformation) Finish(id execute.DatasetID, err error) {
+	dt.d.Finish(err)
+}
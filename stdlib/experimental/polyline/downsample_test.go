@@ -0,0 +1,290 @@
+package polyline_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	_ "github.com/influxdata/flux/fluxinit/static"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/experimental/polyline"
+)
+
+func TestDownsample_Process(t *testing.T) {
+	sec := func(n int) execute.Time {
+		return execute.Time(time.Duration(n) * time.Second)
+	}
+	testCases := []struct {
+		name    string
+		spec    *polyline.DownsampleProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "lttb",
+			spec: &polyline.DownsampleProcedureSpec{
+				Method:     polyline.LTTBKind,
+				N:          4,
+				ValColumn:  "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{sec(0), int64(0), "a"},
+					{sec(1), int64(1), "a"},
+					{sec(2), int64(5), "a"},
+					{sec(3), int64(1), "a"},
+					{nil, int64(100), "a"},
+					{sec(4), int64(0), "a"},
+					{sec(5), int64(-4), "a"},
+					{sec(6), int64(0), "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "t0", Type: flux.TString},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{"a", sec(0), 0.0},
+					{"a", sec(2), 5.0},
+					{"a", sec(5), -4.0},
+					{"a", sec(6), 0.0},
+				},
+			}},
+		},
+		{
+			name: "lttb fewer points than n",
+			spec: &polyline.DownsampleProcedureSpec{
+				Method:     polyline.LTTBKind,
+				N:          3,
+				ValColumn:  "v",
+				TimeColumn: "t",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "t", Type: flux.TTime},
+					{Label: "v", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(0), 1.0},
+					{sec(1), nil},
+					{sec(2), 2.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "t", Type: flux.TTime},
+					{Label: "v", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(0), 1.0},
+					{sec(2), 2.0},
+				},
+			}},
+		},
+		{
+			name: "lttb decreasing times",
+			spec: &polyline.DownsampleProcedureSpec{
+				Method:     polyline.LTTBKind,
+				N:          3,
+				ValColumn:  "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(0), 1.0},
+					{sec(2), 2.0},
+					{sec(1), 3.0},
+					{sec(3), 4.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "lttb requires the times in column _time to be sorted"),
+		},
+		{
+			name: "m4",
+			spec: &polyline.DownsampleProcedureSpec{
+				Method:     polyline.M4Kind,
+				N:          2,
+				ValColumn:  "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(0), sec(8), sec(0), 2.0},
+					{sec(0), sec(8), sec(1), 5.0},
+					{sec(0), sec(8), sec(2), -1.0},
+					{sec(0), sec(8), sec(3), 3.0},
+					{sec(0), sec(8), sec(4), 0.0},
+					{sec(0), sec(8), sec(5), 0.0},
+					{sec(0), sec(8), sec(6), 9.0},
+					{sec(0), sec(8), sec(7), 1.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(0), sec(8), sec(0), 2.0},
+					{sec(0), sec(8), sec(1), 5.0},
+					{sec(0), sec(8), sec(2), -1.0},
+					{sec(0), sec(8), sec(3), 3.0},
+					{sec(0), sec(8), sec(4), 0.0},
+					{sec(0), sec(8), sec(6), 9.0},
+					{sec(0), sec(8), sec(7), 1.0},
+				},
+			}},
+		},
+		{
+			name: "m4 empty buckets",
+			spec: &polyline.DownsampleProcedureSpec{
+				Method:     polyline.M4Kind,
+				N:          4,
+				ValColumn:  "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{sec(0), sec(8), sec(0), int64(1)},
+					{sec(0), sec(8), sec(1), int64(2)},
+					{sec(0), sec(8), sec(6), nil},
+					{sec(0), sec(8), sec(7), int64(3)},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(0), sec(8), sec(0), 1.0},
+					{sec(0), sec(8), sec(1), 2.0},
+					{sec(0), sec(8), sec(7), 3.0},
+				},
+			}},
+		},
+		{
+			name: "m4 without time range",
+			spec: &polyline.DownsampleProcedureSpec{
+				Method:     polyline.M4Kind,
+				N:          2,
+				ValColumn:  "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(0), 2.0},
+				},
+			}},
+			wantErr: errors.Wrap(
+				errors.New(codes.FailedPrecondition, "group key column _start of type time is required, use range() to set it"),
+				codes.Inherit,
+				"m4 needs the time range of each table",
+			),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return polyline.NewDownsampleTransformation(d, c, executetest.UnlimitedAllocator, tc.spec)
+				},
+			)
+		})
+	}
+}
+
+func TestDownsample_LTTBAllocator(t *testing.T) {
+	mem := &memory.ResourceAllocator{}
+	spec := &polyline.DownsampleProcedureSpec{
+		Method:     polyline.LTTBKind,
+		N:          3,
+		ValColumn:  "_value",
+		TimeColumn: "_time",
+	}
+	data := []flux.Table{&executetest.Table{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{execute.Time(0), 1.0},
+			{execute.Time(1), 2.0},
+			{execute.Time(2), 3.0},
+		},
+	}}
+	want := []*executetest.Table{{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{execute.Time(0), 1.0},
+			{execute.Time(1), 2.0},
+			{execute.Time(2), 3.0},
+		},
+	}}
+	executetest.ProcessTestHelper(
+		t,
+		data,
+		want,
+		nil,
+		func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+			return polyline.NewDownsampleTransformation(d, c, mem, spec)
+		},
+	)
+	if mem.MaxAllocated() == 0 {
+		t.Error("expected the buffered points to be allocated with the allocator")
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("expected all memory to be released, got %d bytes allocated", got)
+	}
+}
@@ -5,6 +5,8 @@
 package influxdb
 
 import (
+	"context"
+
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/dependencies/influxdb"
@@ -194,6 +196,32 @@ func (s *FromRemoteProcedureSpec) Copy() plan.ProcedureSpec {
 	return ns
 }
 
+// EstimateStatistics implements plan.StatisticsEstimator by asking
+// the reader for an estimate when it implements influxdb.StatisticsReader.
+// The reader is only created when statistics probes are enabled in the planner.
+func (s *FromRemoteProcedureSpec) EstimateStatistics(ctx context.Context) plan.Statistics {
+	if s.Bounds.IsEmpty() || !plan.StatisticsProbesEnabled(ctx) {
+		return plan.Statistics{}
+	}
+	provider := influxdb.GetProvider(ctx)
+	reader, err := provider.ReaderFor(ctx, s.Config, s.Bounds, s.PredicateSet)
+	if err != nil {
+		return plan.Statistics{}
+	}
+	sr, ok := reader.(influxdb.StatisticsReader)
+	if !ok {
+		return plan.Statistics{}
+	}
+	stats, err := sr.ReadStatistics(ctx)
+	if err != nil {
+		return plan.Statistics{}
+	}
+	return plan.Statistics{
+		Cardinality:      stats.Rows,
+		GroupCardinality: stats.Series,
+	}
+}
+
 func (s *FromRemoteProcedureSpec) PostPhysicalValidate(id plan.NodeID) error {
 	if s.Bounds.IsEmpty() {
 		var bucket string
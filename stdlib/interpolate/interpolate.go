@@ -0,0 +1,338 @@
+package interpolate
+
+import (
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/values"
+)
+
+const (
+	LinearInterpolateKind      = "linearInterpolateKind"
+	PreviousInterpolateKind    = "previousInterpolateKind"
+	NearestInterpolateKind     = "nearestInterpolateKind"
+	CubicSplineInterpolateKind = "cubicSplineInterpolateKind"
+	AkimaInterpolateKind       = "akimaInterpolateKind"
+)
+
+// methods maps the name of each interpolation function to its kind.
+var methods = map[string]plan.ProcedureKind{
+	"linear":      LinearInterpolateKind,
+	"previous":    PreviousInterpolateKind,
+	"nearest":     NearestInterpolateKind,
+	"cubicSpline": CubicSplineInterpolateKind,
+	"akima":       AkimaInterpolateKind,
+}
+
+// methodKind returns the kind of an interpolation method.
+// Specs without a method use linear interpolation.
+func methodKind(method string) plan.ProcedureKind {
+	if method == "" {
+		return LinearInterpolateKind
+	}
+	return methods[method]
+}
+
+// LinearInterpolateOpSpec and LinearInterpolateProcedureSpec are the specs
+// of interpolate.linear(). They are kept so code that creates them directly
+// continues to work; without a method, the specs use linear interpolation.
+type (
+	LinearInterpolateOpSpec        = InterpolateOpSpec
+	LinearInterpolateProcedureSpec = InterpolateProcedureSpec
+)
+
+type InterpolateOpSpec struct {
+	Method string        `json:"method"`
+	Every  flux.Duration `json:"every"`
+	MaxGap flux.Duration `json:"maxGap"`
+}
+
+func init() {
+	for name, kind := range methods {
+		name := name
+		runtime.RegisterPackageValue("interpolate", name,
+			flux.MustValue(flux.FunctionValue(name,
+				func(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+					return createInterpolateOpSpec(name, args, a)
+				},
+				runtime.MustLookupBuiltinType("interpolate", name),
+			)),
+		)
+		plan.RegisterProcedureSpec(
+			kind,
+			newInterpolateProcedure,
+			flux.OperationKind(kind),
+		)
+		execute.RegisterTransformation(
+			kind,
+			createInterpolateTransformation,
+		)
+	}
+}
+
+func createInterpolateOpSpec(method string, args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	every, err := args.GetRequiredDuration("every")
+	if err != nil {
+		return nil, err
+	}
+	if every.IsNegative() || every.IsZero() {
+		return nil, errors.New(codes.Invalid, "every must be positive")
+	}
+
+	spec := &InterpolateOpSpec{
+		Method: method,
+		Every:  every,
+	}
+	if maxGap, ok, err := args.GetDuration("maxGap"); err != nil {
+		return nil, err
+	} else if ok {
+		if maxGap.IsNegative() {
+			return nil, errors.New(codes.Invalid, "maxGap must not be negative")
+		}
+		spec.MaxGap = maxGap
+	}
+	return spec, nil
+}
+
+func (s *InterpolateOpSpec) Kind() flux.OperationKind {
+	return flux.OperationKind(methodKind(s.Method))
+}
+
+type InterpolateProcedureSpec struct {
+	plan.DefaultCost
+	Method string        `json:"method"`
+	Every  flux.Duration `json:"every"`
+
+	// MaxGap is the longest gap between two points in which points are
+	// interpolated. There is no limit when it is zero.
+	MaxGap flux.Duration `json:"maxGap"`
+}
+
+func newInterpolateProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*InterpolateOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &InterpolateProcedureSpec{
+		Method: spec.Method,
+		Every:  spec.Every,
+		MaxGap: spec.MaxGap,
+	}, nil
+}
+
+func (s *InterpolateProcedureSpec) Kind() plan.ProcedureKind {
+	return methodKind(s.Method)
+}
+func (s *InterpolateProcedureSpec) Copy() plan.ProcedureSpec {
+	return &InterpolateProcedureSpec{
+		Method: s.Method,
+		Every:  s.Every,
+		MaxGap: s.MaxGap,
+	}
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *InterpolateProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createInterpolateTransformation(
+	id execute.DatasetID,
+	mode execute.AccumulationMode,
+	spec plan.ProcedureSpec,
+	a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*InterpolateProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewInterpolateTransformation(d, cache, s)
+	return t, d, nil
+}
+
+type interpolateTransformation struct {
+	execute.ExecutionNode
+	d      execute.Dataset
+	cache  execute.TableBuilderCache
+	spec   InterpolateProcedureSpec
+	window execute.Window
+}
+
+func NewInterpolateTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *InterpolateProcedureSpec) *interpolateTransformation {
+	t := &interpolateTransformation{
+		d:     d,
+		cache: cache,
+		spec:  *spec,
+		window: execute.Window{
+			Every:  spec.Every,
+			Period: spec.Every,
+		},
+	}
+	if t.spec.Method == "" {
+		t.spec.Method = "linear"
+	}
+	return t
+}
+
+func (t *interpolateTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return t.d.RetractTable(key)
+}
+
+func (t *interpolateTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	key, columns := tbl.Key(), tbl.Cols()
+
+	for _, c := range columns {
+		if key.HasCol(c.Label) {
+			continue
+		}
+		if c.Label == execute.DefaultTimeColLabel {
+			continue
+		}
+		if c.Label == execute.DefaultValueColLabel {
+			continue
+		}
+		return errors.Newf(codes.FailedPrecondition,
+			"interpolate.%s requires column %q to be in group key", t.spec.Method, c.Label,
+		)
+	}
+
+	b, created := t.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition,
+			"duplicate table with key: %v", tbl.Key(),
+		)
+	}
+
+	if err := execute.AddTableCols(tbl, b); err != nil {
+		return err
+	}
+
+	ti := execute.ColIdx("_time", columns)
+	if ti < 0 {
+		return errors.New(codes.FailedPrecondition,
+			"_time column does not exist",
+		)
+	}
+
+	vi := execute.ColIdx("_value", columns)
+	if vi < 0 {
+		return errors.New(codes.FailedPrecondition,
+			"_value column does not exist",
+		)
+	}
+
+	if ty := columns[vi].Type; ty != flux.TFloat {
+		return errors.Newf(codes.FailedPrecondition,
+			"cannot interpolate %v values; expected float values", ty,
+		)
+	}
+
+	// The splines depend on all of the points so
+	// the points are read before any are interpolated.
+	var xs []int64
+	var ys []float64
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		tc := cr.Times(ti)
+		vc := cr.Floats(vi)
+		for i := 0; i < cr.Len(); i++ {
+			if tc.IsNull(i) {
+				return errors.Newf(codes.FailedPrecondition,
+					"null _time found during %s interpolation", t.spec.Method,
+				)
+			}
+			if vc.IsNull(i) {
+				return errors.Newf(codes.FailedPrecondition,
+					"null _value found during %s interpolation", t.spec.Method,
+				)
+			}
+			xs = append(xs, tc.Value(i))
+			ys = append(ys, vc.Value(i))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	fn := appendFn(b, ti, vi)
+
+	// Points are only interpolated within runs of points
+	// where no gap is longer than maxGap.
+	for start := 0; start < len(xs); {
+		end := start + 1
+		for end < len(xs) && !t.exceedsMaxGap(xs[end-1], xs[end]) {
+			end++
+		}
+		if err := t.interpolateRun(key, b, fn, xs[start:end], ys[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// exceedsMaxGap reports whether no points should be
+// interpolated between the times x0 and x1.
+func (t *interpolateTransformation) exceedsMaxGap(x0, x1 int64) bool {
+	if t.spec.MaxGap.IsZero() {
+		return false
+	}
+	return execute.Time(x0).Add(t.spec.MaxGap) < execute.Time(x1)
+}
+
+// interpolateRun appends the points of a run and the interpolated
+// points at each multiple of every between them.
+func (t *interpolateTransformation) interpolateRun(key flux.GroupKey, b execute.TableBuilder, fn func(int64, float64) error, xs []int64, ys []float64) error {
+	f := newInterpolator(t.spec.Method, xs, ys)
+	for k := range xs {
+		if k > 0 {
+			xi := int64(t.window.GetEarliestBounds(values.Time(xs[k-1])).Stop)
+			for xi < xs[k] {
+				if err := fn(xi, f(k-1, xi)); err != nil {
+					return err
+				}
+				if err := execute.AppendKeyValues(key, b); err != nil {
+					return err
+				}
+				xi = int64(execute.Time(xi).Add(t.window.Every))
+			}
+		}
+		if err := fn(xs[k], ys[k]); err != nil {
+			return err
+		}
+		if err := execute.AppendKeyValues(key, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendFn(b execute.TableBuilder, timeIdx, valueIdx int) func(int64, float64) error {
+	return func(t int64, v float64) error {
+		if err := b.AppendTime(timeIdx, execute.Time(t)); err != nil {
+			return err
+		}
+		if err := b.AppendFloat(valueIdx, v); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+func (t *interpolateTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return t.d.UpdateWatermark(mark)
+}
+func (t *interpolateTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return t.d.UpdateProcessingTime(pt)
+}
+func (t *interpolateTransformation) Finish(id execute.DatasetID, err error) {
+	t.d.Finish(err)
+}
@@ -459,3 +459,168 @@ func TestLinearInterpolate(t *testing.T) {
 		})
 	}
 }
+
+func TestInterpolate_Methods(t *testing.T) {
+	table := func(rows ...[]interface{}) *executetest.Table {
+		return &executetest.Table{
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: rows,
+		}
+	}
+	testCases := []struct {
+		name string
+		spec *interpolate.InterpolateProcedureSpec
+		data *executetest.Table
+		want *executetest.Table
+	}{
+		{
+			name: "previous",
+			spec: &interpolate.InterpolateProcedureSpec{
+				Method: "previous",
+				Every:  flux.ConvertDuration(5 * time.Nanosecond),
+			},
+			data: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(20), 0.0},
+			),
+			want: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(5), 0.0},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(15), 10.0},
+				[]interface{}{execute.Time(20), 0.0},
+			),
+		},
+		{
+			name: "nearest",
+			spec: &interpolate.InterpolateProcedureSpec{
+				Method: "nearest",
+				Every:  flux.ConvertDuration(3 * time.Nanosecond),
+			},
+			data: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(8), 10.0},
+				[]interface{}{execute.Time(16), 0.0},
+				[]interface{}{execute.Time(20), 10.0},
+			),
+			want: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(3), 0.0},
+				[]interface{}{execute.Time(6), 10.0},
+				[]interface{}{execute.Time(8), 10.0},
+				[]interface{}{execute.Time(9), 10.0},
+				// Ties use the value of the previous point.
+				[]interface{}{execute.Time(12), 10.0},
+				[]interface{}{execute.Time(15), 0.0},
+				[]interface{}{execute.Time(16), 0.0},
+				[]interface{}{execute.Time(18), 0.0},
+				[]interface{}{execute.Time(20), 10.0},
+			),
+		},
+		{
+			name: "cubicSpline",
+			spec: &interpolate.InterpolateProcedureSpec{
+				Method: "cubicSpline",
+				Every:  flux.ConvertDuration(5 * time.Nanosecond),
+			},
+			data: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(20), 0.0},
+			),
+			want: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(5), 6.875},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(15), 6.875},
+				[]interface{}{execute.Time(20), 0.0},
+			),
+		},
+		{
+			name: "akima",
+			spec: &interpolate.InterpolateProcedureSpec{
+				Method: "akima",
+				Every:  flux.ConvertDuration(5 * time.Nanosecond),
+			},
+			data: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(20), 10.0},
+				[]interface{}{execute.Time(30), 0.0},
+			),
+			want: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(5), 6.25},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(15), 11.25},
+				[]interface{}{execute.Time(20), 10.0},
+				[]interface{}{execute.Time(25), 6.25},
+				[]interface{}{execute.Time(30), 0.0},
+			),
+		},
+		{
+			name: "max gap",
+			spec: &interpolate.InterpolateProcedureSpec{
+				Method: "linear",
+				Every:  flux.ConvertDuration(5 * time.Nanosecond),
+				MaxGap: flux.ConvertDuration(10 * time.Nanosecond),
+			},
+			data: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(40), 0.0},
+				[]interface{}{execute.Time(50), 10.0},
+			),
+			want: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(5), 5.0},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(40), 0.0},
+				[]interface{}{execute.Time(45), 5.0},
+				[]interface{}{execute.Time(50), 10.0},
+			),
+		},
+		{
+			// Each run between the long gaps only has two points
+			// so the spline is linear.
+			name: "max gap splits spline",
+			spec: &interpolate.InterpolateProcedureSpec{
+				Method: "cubicSpline",
+				Every:  flux.ConvertDuration(5 * time.Nanosecond),
+				MaxGap: flux.ConvertDuration(10 * time.Nanosecond),
+			},
+			data: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(40), 0.0},
+				[]interface{}{execute.Time(50), 10.0},
+			),
+			want: table(
+				[]interface{}{execute.Time(0), 0.0},
+				[]interface{}{execute.Time(5), 5.0},
+				[]interface{}{execute.Time(10), 10.0},
+				[]interface{}{execute.Time(40), 0.0},
+				[]interface{}{execute.Time(45), 5.0},
+				[]interface{}{execute.Time(50), 10.0},
+			),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				[]flux.Table{tc.data},
+				[]*executetest.Table{tc.want},
+				nil,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return interpolate.NewInterpolateTransformation(d, c, tc.spec)
+				},
+			)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package interpolate
+
+import "math"
+
+// interpolator returns the interpolated value at time x
+// which is between the points k and k+1.
+type interpolator func(k int, x int64) float64
+
+// newInterpolator creates the interpolator for the method
+// from the points of a run. The times must be increasing.
+func newInterpolator(method string, xs []int64, ys []float64) interpolator {
+	switch method {
+	case "previous":
+		return func(k int, x int64) float64 {
+			return ys[k]
+		}
+	case "nearest":
+		// Ties use the value of the previous point.
+		return func(k int, x int64) float64 {
+			if x-xs[k] <= xs[k+1]-x {
+				return ys[k]
+			}
+			return ys[k+1]
+		}
+	case "cubicSpline":
+		if len(xs) > 2 {
+			return newCubicSpline(xs, ys)
+		}
+	case "akima":
+		if len(xs) > 2 {
+			return newAkimaSpline(xs, ys)
+		}
+	}
+	// Linear interpolation is also used by the splines
+	// when there are only two points.
+	return func(k int, x int64) float64 {
+		m := (ys[k+1] - ys[k]) / float64(xs[k+1]-xs[k])
+		return ys[k] + m*float64(x-xs[k])
+	}
+}
+
+// newCubicSpline creates a natural cubic spline through the points.
+// The second derivative of the spline is zero at the first and last point.
+func newCubicSpline(xs []int64, ys []float64) interpolator {
+	n := len(xs)
+	h := make([]float64, n-1)
+	for i := range h {
+		h[i] = float64(xs[i+1] - xs[i])
+	}
+
+	// Solve the tridiagonal system for the second derivatives
+	// at the inner points with the Thomas algorithm.
+	m := make([]float64, n)
+	c := make([]float64, n)
+	d := make([]float64, n)
+	for i := 1; i < n-1; i++ {
+		a := h[i-1]
+		b := 2 * (h[i-1] + h[i])
+		r := 6 * ((ys[i+1]-ys[i])/h[i] - (ys[i]-ys[i-1])/h[i-1])
+		w := b - a*c[i-1]
+		c[i] = h[i] / w
+		d[i] = (r - a*d[i-1]) / w
+	}
+	for i := n - 2; i > 0; i-- {
+		m[i] = d[i] - c[i]*m[i+1]
+	}
+
+	return func(k int, x int64) float64 {
+		hk := h[k]
+		l, r := float64(x-xs[k]), float64(xs[k+1]-x)
+		return m[k]*r*r*r/(6*hk) + m[k+1]*l*l*l/(6*hk) +
+			(ys[k]/hk-m[k]*hk/6)*r + (ys[k+1]/hk-m[k+1]*hk/6)*l
+	}
+}
+
+// newAkimaSpline creates an Akima spline through the points.
+// The slope at each point is a weighted average of the slopes
+// of the neighbouring segments which avoids the overshoot of
+// a cubic spline near outliers.
+func newAkimaSpline(xs []int64, ys []float64) interpolator {
+	n := len(xs)
+	h := make([]float64, n-1)
+	for i := range h {
+		h[i] = float64(xs[i+1] - xs[i])
+	}
+
+	// The slopes of the segments are stored with an offset of two
+	// so the two extrapolated slopes at each end can be added.
+	s := make([]float64, n+3)
+	for i := 0; i < n-1; i++ {
+		s[i+2] = (ys[i+1] - ys[i]) / h[i]
+	}
+	s[1] = 2*s[2] - s[3]
+	s[0] = 2*s[1] - s[2]
+	s[n+1] = 2*s[n] - s[n-1]
+	s[n+2] = 2*s[n+1] - s[n]
+
+	t := make([]float64, n)
+	for i := range t {
+		w1 := math.Abs(s[i+3] - s[i+2])
+		w2 := math.Abs(s[i+1] - s[i])
+		if w1+w2 == 0 {
+			t[i] = (s[i+1] + s[i+2]) / 2
+		} else {
+			t[i] = (w1*s[i+1] + w2*s[i+2]) / (w1 + w2)
+		}
+	}
+
+	return func(k int, x int64) float64 {
+		hk := h[k]
+		u := float64(x-xs[k]) / hk
+		u2, u3 := u*u, u*u*u
+		return (2*u3-3*u2+1)*ys[k] + (u3-2*u2+u)*hk*t[k] +
+			(-2*u3+3*u2)*ys[k+1] + (u3-u2)*hk*t[k+1]
+	}
+}
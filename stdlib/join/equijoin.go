@@ -48,7 +48,12 @@ func (p *EquiJoinProcedureSpec) Copy() plan.ProcedureSpec {
 }
 
 func (p *EquiJoinProcedureSpec) Cost(inStats []plan.Statistics) (cost plan.Cost, outStats plan.Statistics) {
-	return plan.Cost{}, plan.Statistics{}
+	if len(inStats) != 2 || !inStats[0].IsKnown() || !inStats[1].IsKnown() {
+		return plan.Cost{}, plan.Statistics{}
+	}
+	return plan.Cost{
+		CPU: inStats[0].Cardinality + inStats[1].Cardinality,
+	}, joinStatistics(inStats)
 }
 
 func newEquiJoinProcedureSpec(spec *JoinProcedureSpec, cols []ColumnPair) *EquiJoinProcedureSpec {
@@ -0,0 +1,587 @@
+package join
+
+import (
+	"context"
+	"sync"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/spill"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+const HashJoinKind = "hashjoin"
+
+func init() {
+	plan.RegisterCostBasedRules(JoinStrategyRule{})
+	execute.RegisterTransformation(HashJoinKind, createHashJoinTransformation)
+}
+
+// HashJoinProcedureSpec describes an equijoin that buffers one of its
+// inputs (the build side) into a hash table and streams the other
+// input (the probe side) through it. Unlike a sort-merge join,
+// neither input needs to be sorted.
+type HashJoinProcedureSpec struct {
+	EquiJoinProcedureSpec
+
+	// BuildLeft is true if the left input is the build side.
+	BuildLeft bool
+}
+
+func (p *HashJoinProcedureSpec) Kind() plan.ProcedureKind {
+	return plan.ProcedureKind(HashJoinKind)
+}
+
+func (p *HashJoinProcedureSpec) Copy() plan.ProcedureSpec {
+	return &HashJoinProcedureSpec{
+		EquiJoinProcedureSpec: *p.EquiJoinProcedureSpec.Copy().(*EquiJoinProcedureSpec),
+		BuildLeft:             p.BuildLeft,
+	}
+}
+
+// Cost estimates a single pass over both inputs while the
+// build side is held in memory.
+func (p *HashJoinProcedureSpec) Cost(inStats []plan.Statistics) (cost plan.Cost, outStats plan.Statistics) {
+	if len(inStats) != 2 || !inStats[0].IsKnown() || !inStats[1].IsKnown() {
+		return plan.Cost{}, plan.Statistics{}
+	}
+	build := inStats[1]
+	if p.BuildLeft {
+		build = inStats[0]
+	}
+	return plan.Cost{
+		CPU: inStats[0].Cardinality + inStats[1].Cardinality,
+		MEM: build.Cardinality,
+	}, joinStatistics(inStats)
+}
+
+// JoinStrategyRule chooses the physical implementation of an equijoin.
+// If the statistics of both inputs are known, it compares the cost of
+// a hash join that buffers the smaller input with the cost of a
+// sort-merge join, including any sorts the merge join requires.
+// Otherwise, it falls back to the sort-merge join.
+type JoinStrategyRule struct{}
+
+func (JoinStrategyRule) Name() string {
+	return "joinStrategy"
+}
+
+func (JoinStrategyRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessor(EquiJoinKind, plan.AnyMultiSuccessor(), plan.AnyMultiSuccessor())
+}
+
+func (JoinStrategyRule) Rewrite(ctx context.Context, n plan.Node) (plan.Node, bool, error) {
+	spec, ok := n.ProcedureSpec().(*EquiJoinProcedureSpec)
+	if !ok {
+		return nil, false, errors.New(codes.Internal, "invalid spec type on join node")
+	}
+
+	predecessors := n.Predecessors()
+	_, lstats := plan.EstimateCost(ctx, predecessors[0])
+	_, rstats := plan.EstimateCost(ctx, predecessors[1])
+	if lstats.IsKnown() && rstats.IsKnown() {
+		inStats := []plan.Statistics{lstats, rstats}
+		hashSpec := &HashJoinProcedureSpec{
+			EquiJoinProcedureSpec: *spec,
+			BuildLeft:             lstats.Cardinality < rstats.Cardinality,
+		}
+		hashCost, _ := hashSpec.Cost(inStats)
+
+		smSpec := SortMergeJoinProcedureSpec(*spec)
+		mergeCost, _ := smSpec.Cost(inStats)
+		for i, side := range []bool{true, false} {
+			mergeCost = plan.Add(mergeCost, joinInputSortCost(predecessors[i], spec.On, side, inStats[i]))
+		}
+
+		if hashCost.Less(mergeCost) {
+			if err := n.ReplaceSpec(hashSpec); err != nil {
+				return nil, false, err
+			}
+			return n, true, nil
+		}
+	}
+	return SortMergeJoinPredicateRule{}.Rewrite(ctx, n)
+}
+
+// joinInputSortCost returns the cost of sorting one of the join inputs
+// by its join columns, or nothing if the input is already sorted.
+func joinInputSortCost(pred plan.Node, on []ColumnPair, isLeft bool, stats plan.Statistics) plan.Cost {
+	sortSpec := &universe.SortProcedureSpec{
+		Columns: getJoinKeyCols(on, isLeft),
+	}
+	if collation := plan.GetOutputAttribute(pred, plan.CollationKey); collation != nil {
+		if sortSpec.OutputAttributes()[plan.CollationKey].SatisfiedBy(collation) {
+			return plan.Cost{}
+		}
+	}
+	cost, _ := sortSpec.Cost([]plan.Statistics{stats})
+	return cost
+}
+
+// joinStatistics estimates the output of an equijoin by assuming
+// that every row on the larger side matches a single row on the other.
+func joinStatistics(inStats []plan.Statistics) plan.Statistics {
+	out := inStats[0]
+	if inStats[1].Cardinality > out.Cardinality {
+		out.Cardinality = inStats[1].Cardinality
+	}
+	if inStats[1].GroupCardinality > out.GroupCardinality {
+		out.GroupCardinality = inStats[1].GroupCardinality
+	}
+	return out
+}
+
+func createHashJoinTransformation(
+	id execute.DatasetID,
+	mode execute.AccumulationMode,
+	spec plan.ProcedureSpec,
+	a execute.Administration,
+) (execute.Transformation, execute.Dataset, error) {
+	t, err := NewHashJoinTransformation(
+		a.Context(),
+		id,
+		spec,
+		a.Parents()[0],
+		a.Parents()[1],
+		a.Allocator(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	tr := execute.NewTransformationFromTransport(t)
+	return tr, t.d, nil
+}
+
+// HashJoinTransformation performs a hash join on two table streams.
+// For each group key, rows from the build side are buffered and indexed
+// by their join key. Once the build side is complete for a group key,
+// rows from the probe side are joined as soon as they arrive.
+//
+// Rows that are buffered before the build side of a group key is
+// complete are spilled to disk when the allocator runs out of memory.
+type HashJoinTransformation struct {
+	ctx         context.Context
+	on          []ColumnPair
+	as          *JoinFn
+	left, right execute.DatasetID
+	buildLeft   bool
+	method      string
+	d           *execute.TransportDataset
+	mu          sync.Mutex
+	mem         memory.Allocator
+	dir         string
+
+	// leftSchema and rightSchema keep track of a union of all the schemas
+	// the join transformation has seen from each side. See the
+	// MergeJoinTransformation for details.
+	leftSchema, rightSchema []flux.ColMeta
+
+	leftFinished,
+	rightFinished bool
+}
+
+func NewHashJoinTransformation(
+	ctx context.Context,
+	id execute.DatasetID,
+	s plan.ProcedureSpec,
+	leftID execute.DatasetID,
+	rightID execute.DatasetID,
+	mem memory.Allocator,
+) (*HashJoinTransformation, error) {
+	spec, ok := s.(*HashJoinProcedureSpec)
+	if !ok {
+		return nil, errors.New(codes.Internal, "unsupported join spec - not a hashJoin")
+	}
+	return &HashJoinTransformation{
+		ctx:       ctx,
+		on:        spec.On,
+		as:        NewJoinFn(spec.As),
+		left:      leftID,
+		right:     rightID,
+		buildLeft: spec.BuildLeft,
+		method:    spec.Method,
+		d:         execute.NewTransportDataset(id, mem),
+		mem:       mem,
+		dir:       execute.GetSpillDir(ctx),
+	}, nil
+}
+
+func (t *HashJoinTransformation) Dataset() *execute.TransportDataset {
+	return t.d
+}
+
+func (t *HashJoinTransformation) ProcessMessage(m execute.Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer m.Ack()
+
+	switch m := m.(type) {
+	case execute.ProcessChunkMsg:
+		chunk := m.TableChunk()
+		state, _ := t.d.Lookup(chunk.Key())
+		s, err := t.processChunk(chunk, state, m.SrcDatasetID())
+		if err != nil {
+			return err
+		}
+		t.d.Set(chunk.Key(), s)
+	case execute.FlushKeyMsg:
+		state, ok := t.d.Lookup(m.Key())
+		if !ok {
+			return nil
+		}
+		s := state.(*hashJoinState)
+		if t.isBuild(m.SrcDatasetID()) {
+			s.build.done = true
+			if err := t.buildTable(s); err != nil {
+				return err
+			}
+		} else {
+			s.probe.done = true
+		}
+
+		if s.build.done && s.probe.done {
+			return t.flush(s)
+		}
+	case execute.FinishMsg:
+		if err := m.Error(); err != nil {
+			t.release()
+			t.d.Finish(err)
+			return nil
+		}
+
+		if id := m.SrcDatasetID(); id == t.left {
+			t.leftFinished = true
+		} else if id == t.right {
+			t.rightFinished = true
+		}
+
+		if t.leftFinished && t.rightFinished {
+			err := t.d.Range(func(key flux.GroupKey, value interface{}) error {
+				s, ok := value.(*hashJoinState)
+				if !ok {
+					return errors.New(codes.Internal, "received bad hashJoinState")
+				}
+				if err := t.buildTable(s); err != nil {
+					return err
+				}
+				return t.flush(s)
+			})
+			if err != nil {
+				t.release()
+			}
+			t.d.Finish(err)
+		}
+	}
+	return nil
+}
+
+func (t *HashJoinTransformation) isBuild(id execute.DatasetID) bool {
+	return (id == t.left) == t.buildLeft
+}
+
+func (t *HashJoinTransformation) processChunk(chunk table.Chunk, state interface{}, id execute.DatasetID) (*hashJoinState, error) {
+	s, ok := state.(*hashJoinState)
+	if state == nil {
+		s, ok = &hashJoinState{}, true
+	}
+	if !ok {
+		return nil, errors.New(codes.Internal, "invalid join state")
+	}
+
+	if chunk.Len() == 0 {
+		return s, nil
+	}
+
+	if id == t.left {
+		t.leftSchema = schemaUnion(t.leftSchema, chunk.Cols())
+	} else if id == t.right {
+		t.rightSchema = schemaUnion(t.rightSchema, chunk.Cols())
+	} else {
+		return s, errors.New(codes.Internal, "invalid chunk passed to join - dataset id is neither left nor right")
+	}
+
+	isLeft := id == t.left
+	side := s.side(t.isBuild(id))
+	side.schema = schemaUnion(side.schema, chunk.Cols())
+	if len(side.joinKeyCols) < 1 {
+		if err := side.setJoinKeyCols(getJoinKeyCols(t.on, isLeft), chunk); err != nil {
+			return s, errors.Newf(codes.Invalid, "cannot set join columns in %s table stream: %s", sideName(isLeft), err)
+		}
+	}
+
+	if t.isBuild(id) || !s.built {
+		// Buffer the chunk until the build side is complete. If there
+		// is not enough memory to read two more chunks like this one,
+		// the buffered chunks of every group key are written to disk.
+		buffer := chunk.Buffer()
+		if spill.ShouldSpill(t.dir, t.mem, 2*spill.Size(&buffer)) {
+			if err := t.spill(); err != nil {
+				return s, err
+			}
+		}
+		s.buffer(t.isBuild(id), chunk.Key(), t.dir, t.mem).Append(&buffer)
+		return s, nil
+	}
+	return s, t.probe(s, chunk)
+}
+
+// spill writes the buffered chunks of every group key to disk.
+func (t *HashJoinTransformation) spill() error {
+	return t.d.Range(func(key flux.GroupKey, value interface{}) error {
+		s, ok := value.(*hashJoinState)
+		if !ok {
+			return errors.New(codes.Internal, "received bad hashJoinState")
+		}
+		for _, buf := range []*spill.Buffer{s.buildBuf, s.probeBuf} {
+			if buf == nil {
+				continue
+			}
+			if err := buf.Spill(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// release releases the state of every group key
+// and removes any files that were spilled to disk.
+func (t *HashJoinTransformation) release() {
+	_ = t.d.Range(func(key flux.GroupKey, value interface{}) error {
+		if s, ok := value.(*hashJoinState); ok {
+			s.release()
+		}
+		return nil
+	})
+}
+
+// buildTable indexes all of the buffered build side rows by their join
+// key and then joins any probe side rows that arrived in the meantime.
+func (t *HashJoinTransformation) buildTable(s *hashJoinState) error {
+	if s.built {
+		return nil
+	}
+	s.built = true
+	s.table = make(map[string]*hashJoinBucket)
+	if s.buildBuf != nil {
+		buf := s.buildBuf
+		s.buildBuf = nil
+		if err := buf.Do(func(cr flux.ColReader) error {
+			// The partitioned rows are slices that retain
+			// their own reference to the data.
+			chunk := table.ChunkFromReader(cr)
+			for _, b := range partitionByJoinKey(s.build.joinKeyCols, chunk) {
+				k := b.key.str()
+				if existing, ok := s.table[k]; ok {
+					existing.rows = append(existing.rows, b.rows...)
+					continue
+				}
+				s.table[k] = b
+				s.order = append(s.order, b)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if s.probeBuf != nil {
+		buf := s.probeBuf
+		s.probeBuf = nil
+		return buf.Do(func(cr flux.ColReader) error {
+			return t.probe(s, table.ChunkFromReader(cr))
+		})
+	}
+	return nil
+}
+
+// probe joins the rows of a probe side chunk with the matching
+// rows in the hash table.
+func (t *HashJoinTransformation) probe(s *hashJoinState, chunk table.Chunk) error {
+	if err := t.prepare(s); err != nil {
+		return err
+	}
+	for _, b := range partitionByJoinKey(s.probe.joinKeyCols, chunk) {
+		p := joinProduct{key: b.key}
+		var build joinRows
+		if match, ok := s.table[b.key.str()]; ok {
+			match.matched = true
+			for _, c := range match.rows {
+				c.Retain()
+			}
+			build = match.rows
+		}
+		if t.buildLeft {
+			p.left, p.right = build, b.rows
+		} else {
+			p.left, p.right = b.rows, build
+		}
+		if err := t.evaluate(&p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush produces output for the build side rows that never matched
+// a row on the probe side and then releases the hash table.
+func (t *HashJoinTransformation) flush(s *hashJoinState) error {
+	if s.flushed {
+		return nil
+	}
+	s.flushed = true
+	defer s.release()
+
+	if err := t.prepare(s); err != nil {
+		return err
+	}
+	for _, b := range s.order {
+		if b.matched {
+			continue
+		}
+		p := joinProduct{key: b.key}
+		if t.buildLeft {
+			p.left = b.rows
+		} else {
+			p.right = b.rows
+		}
+		// The product takes ownership of the rows.
+		b.rows = nil
+		if err := t.evaluate(&p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *HashJoinTransformation) prepare(s *hashJoinState) error {
+	var lschema, rschema []flux.ColMeta
+	lside, rside := s.side(t.buildLeft), s.side(!t.buildLeft)
+	if len(lside.schema) > 0 {
+		lschema = lside.schema
+	} else {
+		lschema = t.leftSchema
+	}
+	if len(rside.schema) > 0 {
+		rschema = rside.schema
+	} else {
+		rschema = t.rightSchema
+	}
+	return t.as.Prepare(t.ctx, lschema, rschema)
+}
+
+func (t *HashJoinTransformation) evaluate(p *joinProduct) error {
+	joined, ok, err := p.evaluate(t.ctx, t.method, *t.as, t.mem)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		p.Release()
+		return nil
+	}
+	for _, chunk := range joined {
+		if err := t.d.Process(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type hashJoinState struct {
+	build, probe sideState
+
+	// buildBuf and probeBuf hold the chunks that arrived
+	// before the build side was complete.
+	buildBuf, probeBuf *spill.Buffer
+
+	table map[string]*hashJoinBucket
+	// order holds the buckets in the order their keys were first seen
+	// so output for unmatched rows is deterministic.
+	order   []*hashJoinBucket
+	built   bool
+	flushed bool
+}
+
+func (s *hashJoinState) side(build bool) *sideState {
+	if build {
+		return &s.build
+	}
+	return &s.probe
+}
+
+// buffer returns the buffer for one side of the join.
+func (s *hashJoinState) buffer(build bool, key flux.GroupKey, dir string, mem memory.Allocator) *spill.Buffer {
+	buf := &s.probeBuf
+	if build {
+		buf = &s.buildBuf
+	}
+	if *buf == nil {
+		*buf = spill.NewBuffer(key, dir, mem)
+	}
+	return *buf
+}
+
+func (s *hashJoinState) release() {
+	for _, buf := range []*spill.Buffer{s.buildBuf, s.probeBuf} {
+		if buf != nil {
+			buf.Release()
+		}
+	}
+	s.buildBuf, s.probeBuf = nil, nil
+	for _, b := range s.order {
+		b.rows.Release()
+	}
+	s.order = nil
+	s.table = nil
+}
+
+// hashJoinBucket holds the rows from one side of a join that share a join key.
+type hashJoinBucket struct {
+	key     joinKey
+	rows    joinRows
+	matched bool
+}
+
+// partitionByJoinKey splits a chunk into runs of consecutive rows that share
+// a join key and groups the runs by key in the order the keys first appear.
+// The returned rows are zero-copy slices of the chunk.
+func partitionByJoinKey(cols []flux.ColMeta, chunk table.Chunk) []*hashJoinBucket {
+	var (
+		buckets []*hashJoinBucket
+		index   = make(map[string]*hashJoinBucket)
+	)
+	for start := 0; start < chunk.Len(); {
+		key := joinKeyFromRow(cols, chunk, start)
+		end := start + 1
+		for ; end < chunk.Len(); end++ {
+			next := joinKeyFromRow(cols, chunk, end)
+			if !key.equal(next) {
+				break
+			}
+		}
+
+		rows := getChunkSlice(chunk, start, end)
+		k := key.str()
+		if b, ok := index[k]; ok {
+			b.rows = append(b.rows, rows)
+		} else {
+			b := &hashJoinBucket{key: key, rows: joinRows{rows}}
+			index[k] = b
+			buckets = append(buckets, b)
+		}
+		start = end
+	}
+	return buckets
+}
+
+func sideName(isLeft bool) string {
+	if isLeft {
+		return "left"
+	}
+	return "right"
+}
@@ -0,0 +1,274 @@
+package join_test
+
+import (
+	"context"
+	"testing"
+
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+	"github.com/influxdata/flux/stdlib/join"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
+)
+
+func statsNode(id string, rows int64) *plan.PhysicalPlanNode {
+	return plan.CreatePhysicalNode(plan.NodeID(id), plantest.MockProcedureSpec{
+		CostFn: func(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+			return plan.Cost{CPU: rows}, plan.Statistics{Cardinality: rows, GroupCardinality: 1}
+		},
+	})
+}
+
+func TestJoinStrategyRule(t *testing.T) {
+	on := []join.ColumnPair{{Left: "a", Right: "b"}}
+	equiJoin := func() *join.EquiJoinProcedureSpec {
+		return &join.EquiJoinProcedureSpec{On: on, Method: "inner"}
+	}
+
+	tests := []plantest.RuleTestCase{
+		{
+			Name:  "small right side",
+			Rules: []plan.Rule{join.JoinStrategyRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					statsNode("left", 1000000),
+					statsNode("right", 100),
+					plan.CreatePhysicalNode("join", equiJoin()),
+				},
+				Edges: [][2]int{{0, 2}, {1, 2}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					statsNode("left", 1000000),
+					statsNode("right", 100),
+					plan.CreatePhysicalNode("join", &join.HashJoinProcedureSpec{
+						EquiJoinProcedureSpec: *equiJoin(),
+					}),
+				},
+				Edges: [][2]int{{0, 2}, {1, 2}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:  "small left side",
+			Rules: []plan.Rule{join.JoinStrategyRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					statsNode("left", 100),
+					statsNode("right", 1000000),
+					plan.CreatePhysicalNode("join", equiJoin()),
+				},
+				Edges: [][2]int{{0, 2}, {1, 2}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					statsNode("left", 100),
+					statsNode("right", 1000000),
+					plan.CreatePhysicalNode("join", &join.HashJoinProcedureSpec{
+						EquiJoinProcedureSpec: *equiJoin(),
+						BuildLeft:             true,
+					}),
+				},
+				Edges: [][2]int{{0, 2}, {1, 2}},
+			},
+			SkipValidation: true,
+		},
+		{
+			Name:  "unknown statistics",
+			Rules: []plan.Rule{join.JoinStrategyRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalMockNode("left"),
+					plantest.CreatePhysicalMockNode("right"),
+					plan.CreatePhysicalNode("join", equiJoin()),
+				},
+				Edges: [][2]int{{0, 2}, {1, 2}},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plantest.CreatePhysicalMockNode("left"),
+					plan.CreatePhysicalNode("sort_join_lhs", &universe.SortProcedureSpec{
+						Columns: []string{"a"},
+					}),
+					plantest.CreatePhysicalMockNode("right"),
+					plan.CreatePhysicalNode("sort_join_rhs", &universe.SortProcedureSpec{
+						Columns: []string{"b"},
+					}),
+					plan.CreatePhysicalNode("join", (*join.SortMergeJoinProcedureSpec)(equiJoin())),
+				},
+				Edges: [][2]int{{0, 1}, {1, 4}, {2, 3}, {3, 4}},
+			},
+			SkipValidation: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			plantest.PhysicalRuleTestHelper(t, &tc,
+				cmpopts.IgnoreFields(plantest.MockProcedureSpec{}, "CostFn"),
+				cmpopts.IgnoreFields(join.EquiJoinProcedureSpec{}, "Left", "Right"),
+				cmpopts.IgnoreFields(join.SortMergeJoinProcedureSpec{}, "Left", "Right"),
+			)
+		})
+	}
+}
+
+func TestHashJoin(t *testing.T) {
+	keyCols := []flux.ColMeta{{Label: "group", Type: flux.TUInt}}
+	left := constructChunks(
+		keyCols,
+		[]flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+			{Label: "label", Type: flux.TString},
+			{Label: "group", Type: flux.TUInt},
+		},
+		[]map[string]interface{}{
+			{"_time": execute.Time(1), "_value": 1.2, "label": "a", "group": uint64(1)},
+			{"_time": execute.Time(1), "_value": 9.0, "label": "b", "group": uint64(1)},
+			{"_time": execute.Time(1), "_value": 4.6, "label": "d", "group": uint64(1)},
+			{"_time": execute.Time(2), "_value": 3.4, "label": "a", "group": uint64(1)},
+		},
+	)
+	right := constructChunks(
+		keyCols,
+		[]flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TInt},
+			{Label: "id", Type: flux.TString},
+			{Label: "group", Type: flux.TUInt},
+		},
+		[]map[string]interface{}{
+			{"_time": execute.Time(1), "_value": int64(1), "id": "d", "group": uint64(1)},
+			{"_time": execute.Time(1), "_value": int64(5), "id": "c", "group": uint64(1)},
+			{"_time": execute.Time(1), "_value": int64(9), "id": "a", "group": uint64(1)},
+		},
+	)
+	cols := []flux.ColMeta{
+		{Label: "group", Type: flux.TUInt},
+		{Label: "label", Type: flux.TString},
+		{Label: "lv", Type: flux.TFloat},
+		{Label: "rv", Type: flux.TInt},
+	}
+
+	testCases := []struct {
+		name       string
+		method     string
+		buildLeft  bool
+		wantTables []table.Chunk
+	}{
+		{
+			name:   "inner build right",
+			method: "inner",
+			wantTables: constructChunks(keyCols, cols, []map[string]interface{}{
+				{"group": uint64(1), "label": "a", "lv": 1.2, "rv": int64(9)},
+				{"group": uint64(1), "label": "a", "lv": 3.4, "rv": int64(9)},
+				{"group": uint64(1), "label": "d", "lv": 4.6, "rv": int64(1)},
+			}),
+		},
+		{
+			name:   "full build right",
+			method: "full",
+			wantTables: constructChunks(keyCols, cols, []map[string]interface{}{
+				{"group": uint64(1), "label": "a", "lv": 1.2, "rv": int64(9)},
+				{"group": uint64(1), "label": "a", "lv": 3.4, "rv": int64(9)},
+				{"group": uint64(1), "label": "b", "lv": 9.0, "rv": values.Null},
+				{"group": uint64(1), "label": "d", "lv": 4.6, "rv": int64(1)},
+				{"group": uint64(1), "label": "c", "lv": values.Null, "rv": int64(5)},
+			}),
+		},
+		{
+			name:      "left build left",
+			method:    "left",
+			buildLeft: true,
+			wantTables: constructChunks(keyCols, cols, []map[string]interface{}{
+				{"group": uint64(1), "label": "d", "lv": 4.6, "rv": int64(1)},
+				{"group": uint64(1), "label": "a", "lv": 1.2, "rv": int64(9)},
+				{"group": uint64(1), "label": "a", "lv": 3.4, "rv": int64(9)},
+				{"group": uint64(1), "label": "b", "lv": 9.0, "rv": values.Null},
+			}),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			fn, err := fnFromSrc(`(l, r) => {
+				label = if exists l.label then l.label else r.id
+				return {label: label, group: l.group, lv: l._value, rv: r._value}
+			}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec := join.HashJoinProcedureSpec{
+				EquiJoinProcedureSpec: join.EquiJoinProcedureSpec{
+					On:     []join.ColumnPair{{Left: "label", Right: "id"}},
+					As:     *fn,
+					Method: tc.method,
+				},
+				BuildLeft: tc.buildLeft,
+			}
+			checked := arrowmem.NewCheckedAllocator(memory.DefaultAllocator)
+			mem := memory.NewResourceAllocator(checked)
+			defer checked.AssertSize(t, 0)
+
+			hjt, err := join.NewHashJoinTransformation(
+				context.Background(),
+				executetest.RandomDatasetID(),
+				&spec,
+				leftID,
+				rightID,
+				mem,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store := executetest.NewDataStore()
+			hjt.Dataset().AddTransformation(store)
+			tr := execute.NewTransformationFromTransport(hjt)
+
+			leftDataset := execute.NewTransportDataset(leftID, mem)
+			leftDataset.AddTransformation(tr)
+			rightDataset := execute.NewTransportDataset(rightID, mem)
+			rightDataset.AddTransformation(tr)
+
+			for _, chunk := range left {
+				chunk.Retain()
+				if err := leftDataset.Process(chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tr.Finish(leftID, nil)
+			for _, chunk := range right {
+				chunk.Retain()
+				if err := rightDataset.Process(chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tr.Finish(rightID, nil)
+
+			for _, tbl := range tc.wantTables {
+				wantBuf := tbl.Buffer()
+				gotTbl, err := store.Table(wantBuf.Key())
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := table.Stringify(table.FromBuffer(&wantBuf))
+				got := table.Stringify(gotTbl)
+				if !cmp.Equal(want, got) {
+					t.Errorf("table chunks differ, -want/+got:\n%v", cmp.Diff(want, got))
+				}
+			}
+		})
+	}
+}
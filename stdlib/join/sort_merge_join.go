@@ -13,7 +13,8 @@ import (
 const SortMergeJoinKind = "sortmergejoin"
 
 func init() {
-	plan.RegisterPhysicalRules(SortMergeJoinPredicateRule{})
+	// SortMergeJoinPredicateRule is applied by the JoinStrategyRule
+	// when a hash join is not expected to be cheaper.
 	execute.RegisterTransformation(SortMergeJoinKind, createJoinTransformation)
 }
 
@@ -51,8 +52,15 @@ func (p *SortMergeJoinProcedureSpec) RequiredAttributes() []plan.PhysicalAttribu
 	}
 }
 
+// Cost estimates a single pass over both sorted inputs. Only the rows
+// for the current join key are held in memory.
 func (p *SortMergeJoinProcedureSpec) Cost(inStats []plan.Statistics) (cost plan.Cost, outStats plan.Statistics) {
-	return plan.Cost{}, plan.Statistics{}
+	if len(inStats) != 2 || !inStats[0].IsKnown() || !inStats[1].IsKnown() {
+		return plan.Cost{}, plan.Statistics{}
+	}
+	return plan.Cost{
+		CPU: inStats[0].Cardinality + inStats[1].Cardinality,
+	}, joinStatistics(inStats)
 }
 
 type SortMergeJoinPredicateRule struct{}
@@ -0,0 +1,487 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/dependencies/filesystem"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+const FromJSONKind = "fromJSON"
+
+// columnTypes maps the type names that can be used
+// in the schema parameter to column types.
+var columnTypes = map[string]flux.ColType{
+	"int":    flux.TInt,
+	"uint":   flux.TUInt,
+	"float":  flux.TFloat,
+	"string": flux.TString,
+	"bool":   flux.TBool,
+	"time":   flux.TTime,
+}
+
+type FromJSONOpSpec struct {
+	File   string                  `json:"file"`
+	Data   string                  `json:"data"`
+	Path   string                  `json:"path"`
+	Schema map[string]flux.ColType `json:"schema"`
+}
+
+func init() {
+	fromJSONSignature := runtime.MustLookupBuiltinType("json", "from")
+	runtime.RegisterPackageValue("json", "from", flux.MustValue(flux.FunctionValue(FromJSONKind, createFromJSONOpSpec, fromJSONSignature)))
+	plan.RegisterProcedureSpec(FromJSONKind, newFromJSONProcedure, FromJSONKind)
+	execute.RegisterSource(FromJSONKind, createFromJSONSource)
+}
+
+func createFromJSONOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(FromJSONOpSpec)
+
+	if file, ok, err := args.GetString("file"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.File = file
+	}
+
+	if data, ok, err := args.GetString("data"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Data = data
+	}
+
+	if spec.Data == "" && spec.File == "" {
+		return nil, errors.New(codes.Invalid, "must provide json data or filename")
+	}
+
+	if spec.Data != "" && spec.File != "" {
+		return nil, errors.New(codes.Invalid, "must provide exactly one of the parameters data or file")
+	}
+
+	if path, ok, err := args.GetString("path"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Path = path
+	}
+
+	if schema, ok, err := args.GetDictionary("schema"); err != nil {
+		return nil, err
+	} else if ok {
+		if keyType, err := schema.Type().KeyType(); err != nil {
+			return nil, err
+		} else if got := keyType.Nature(); got != semantic.String {
+			return nil, errors.Newf(codes.Invalid, "schema keys must be strings, got %s", got)
+		}
+		spec.Schema = make(map[string]flux.ColType, schema.Len())
+		schema.Range(func(key, value values.Value) {
+			if err != nil {
+				return
+			}
+			typ, ok := columnTypes[value.Str()]
+			if !ok {
+				err = errors.Newf(codes.Invalid, "unsupported type %q for column %q", value.Str(), key.Str())
+				return
+			}
+			spec.Schema[key.Str()] = typ
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return spec, nil
+}
+
+func (s *FromJSONOpSpec) Kind() flux.OperationKind {
+	return FromJSONKind
+}
+
+type FromJSONProcedureSpec struct {
+	plan.DefaultCost
+	File   string
+	Data   string
+	Path   string
+	Schema map[string]flux.ColType
+}
+
+func newFromJSONProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*FromJSONOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &FromJSONProcedureSpec{
+		File:   spec.File,
+		Data:   spec.Data,
+		Path:   spec.Path,
+		Schema: spec.Schema,
+	}, nil
+}
+
+func (s *FromJSONProcedureSpec) Kind() plan.ProcedureKind {
+	return FromJSONKind
+}
+
+func (s *FromJSONProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(FromJSONProcedureSpec)
+	*ns = *s
+	if s.Schema != nil {
+		ns.Schema = make(map[string]flux.ColType, len(s.Schema))
+		for k, v := range s.Schema {
+			ns.Schema[k] = v
+		}
+	}
+	return ns
+}
+
+func createFromJSONSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*FromJSONProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	return CreateSource(spec, dsid, a)
+}
+
+func CreateSource(spec *FromJSONProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	return execute.CreateSourceFromIterator(&jsonSource{
+		spec:  spec,
+		alloc: a.Allocator(),
+	}, dsid)
+}
+
+type jsonSource struct {
+	spec  *FromJSONProcedureSpec
+	alloc memory.Allocator
+}
+
+// field is a property of a row object.
+type field struct {
+	name  string
+	value json.RawMessage
+}
+
+func (s *jsonSource) Do(ctx context.Context, f func(flux.Table) error) error {
+	var r io.Reader
+	if s.spec.File != "" {
+		fp, err := filesystem.OpenFile(ctx, s.spec.File)
+		if err != nil {
+			return errors.Wrap(err, codes.Inherit, "json.from() failed to read file")
+		}
+		defer func() { _ = fp.Close() }()
+		r = fp
+	} else {
+		r = strings.NewReader(s.spec.Data)
+	}
+
+	rows, err := readRows(r, s.spec.Path)
+	if err != nil {
+		return err
+	}
+	cols, err := inferColumns(rows, s.spec.Schema)
+	if err != nil {
+		return err
+	}
+
+	b := execute.NewColListTableBuilder(execute.NewGroupKey(nil, nil), s.alloc)
+	for _, c := range cols {
+		if _, err := b.AddCol(c); err != nil {
+			return err
+		}
+	}
+	colIdx := make(map[string]int, len(cols))
+	for j, c := range cols {
+		colIdx[c.Label] = j
+	}
+	for _, row := range rows {
+		// Missing properties are nulls.
+		vs := make([]values.Value, len(cols))
+		for _, fld := range row {
+			j := colIdx[fld.name]
+			v, err := parseValue(fld.value, cols[j].Type)
+			if err != nil {
+				return errors.Wrapf(err, codes.Invalid, "json.from() found an invalid value for column %q", fld.name)
+			}
+			vs[j] = v
+		}
+		for j, v := range vs {
+			if v == nil {
+				v = values.NewNull(flux.SemanticType(cols[j].Type))
+			}
+			if err := b.AppendValue(j, v); err != nil {
+				return err
+			}
+		}
+	}
+	tbl, err := b.Table()
+	if err != nil {
+		return err
+	}
+	return f(tbl)
+}
+
+// readRows reads the row objects from a JSON array or from NDJSON.
+func readRows(r io.Reader, path string) ([][]field, error) {
+	br := bufio.NewReader(r)
+	isArray, err := startsWithArray(br)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(br)
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return nil, errors.Wrap(err, codes.Invalid, "json.from() failed to read data")
+		}
+	}
+	var rows [][]field
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, errors.Wrap(err, codes.Invalid, "json.from() failed to read data")
+		}
+		row, err := readRow(raw, path)
+		if err != nil {
+			return nil, errors.Wrapf(err, codes.Invalid, "json.from() failed to read row %d", len(rows))
+		}
+		rows = append(rows, row)
+	}
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return nil, errors.Wrap(err, codes.Invalid, "json.from() failed to read data")
+		}
+	}
+	return rows, nil
+}
+
+// startsWithArray reports whether the first value in the data is an array.
+func startsWithArray(br *bufio.Reader) (bool, error) {
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, errors.Wrap(err, codes.Invalid, "json.from() failed to read data")
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c == '[', br.UnreadByte()
+	}
+}
+
+// readRow follows the path inside of the row and
+// reads the properties of the object it leads to
+// in the order that they appear.
+func readRow(raw json.RawMessage, path string) ([]field, error) {
+	if path != "" {
+		for _, name := range strings.Split(path, ".") {
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+				return nil, errors.Newf(codes.Invalid, "path %q does not lead to an object", path)
+			}
+			v, ok := obj[name]
+			if !ok {
+				return nil, errors.Newf(codes.Invalid, "path %q does not lead to an object", path)
+			}
+			raw = v
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if t, err := dec.Token(); err != nil {
+		return nil, err
+	} else if d, ok := t.(json.Delim); !ok || d != '{' {
+		return nil, errors.New(codes.Invalid, "row is not an object")
+	}
+	var row []field
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		row = append(row, field{name: t.(string), value: v})
+	}
+	return row, nil
+}
+
+// inferColumns returns the columns of the rows in the order that they
+// first appear. The types from the schema are used when they are given
+// and all other types are inferred from the values.
+func inferColumns(rows [][]field, schema map[string]flux.ColType) ([]flux.ColMeta, error) {
+	var cols []flux.ColMeta
+	colIdx := make(map[string]int)
+	// hasNonFinite marks the columns with the strings that
+	// are written for NaN and infinite floats.
+	var hasNonFinite []bool
+	for _, row := range rows {
+		for _, fld := range row {
+			j, ok := colIdx[fld.name]
+			if !ok {
+				j = len(cols)
+				colIdx[fld.name] = j
+				cols = append(cols, flux.ColMeta{Label: fld.name, Type: schema[fld.name]})
+				hasNonFinite = append(hasNonFinite, false)
+			}
+			if _, ok := schema[fld.name]; ok {
+				continue
+			}
+			if _, ok := nonFinite(fld.value); ok {
+				// These strings are floats in a numeric column
+				// and strings otherwise, so they do not decide the type.
+				hasNonFinite[j] = true
+				continue
+			}
+			typ := valueType(fld.value)
+			switch prev := cols[j].Type; {
+			case typ == flux.TInvalid || typ == prev:
+			case prev == flux.TInvalid:
+				cols[j].Type = typ
+			case typ == flux.TInt && prev == flux.TFloat:
+			case typ == flux.TFloat && prev == flux.TInt:
+				cols[j].Type = flux.TFloat
+			default:
+				return nil, errors.Newf(codes.Invalid, "json.from() found column %q with both %s and %s values", fld.name, prev, typ)
+			}
+		}
+	}
+
+	// Columns in the schema that are not in the data
+	// are added in sorted order.
+	var missing []string
+	for label := range schema {
+		if _, ok := colIdx[label]; !ok {
+			missing = append(missing, label)
+		}
+	}
+	sort.Strings(missing)
+	for _, label := range missing {
+		cols = append(cols, flux.ColMeta{Label: label, Type: schema[label]})
+		hasNonFinite = append(hasNonFinite, false)
+	}
+
+	for j := range cols {
+		switch {
+		case hasNonFinite[j] && (cols[j].Type == flux.TInvalid || cols[j].Type == flux.TInt):
+			cols[j].Type = flux.TFloat
+		case cols[j].Type == flux.TInvalid:
+			cols[j].Type = flux.TString
+		}
+	}
+	return cols, nil
+}
+
+// valueType returns the column type of a JSON value
+// or an invalid type for null.
+func valueType(raw json.RawMessage) flux.ColType {
+	switch raw[0] {
+	case 'n':
+		return flux.TInvalid
+	case 't', 'f':
+		return flux.TBool
+	case '"', '{', '[':
+		return flux.TString
+	default:
+		if _, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return flux.TInt
+		}
+		return flux.TFloat
+	}
+}
+
+// nonFinite returns the float for the strings that the JSON encoder
+// writes for NaN and infinite floats since JSON has no numbers for them.
+func nonFinite(raw json.RawMessage) (float64, bool) {
+	switch string(raw) {
+	case `"NaN"`:
+		return math.NaN(), true
+	case `"+Inf"`:
+		return math.Inf(1), true
+	case `"-Inf"`:
+		return math.Inf(-1), true
+	default:
+		return 0, false
+	}
+}
+
+// parseValue converts a JSON value to a value of the column type.
+func parseValue(raw json.RawMessage, typ flux.ColType) (values.Value, error) {
+	if raw[0] == 'n' {
+		return values.NewNull(flux.SemanticType(typ)), nil
+	}
+	switch typ {
+	case flux.TInt:
+		var v int64
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return values.NewInt(v), nil
+	case flux.TUInt:
+		var v uint64
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return values.NewUInt(v), nil
+	case flux.TFloat:
+		if v, ok := nonFinite(raw); ok {
+			return values.NewFloat(v), nil
+		}
+		var v float64
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return values.NewFloat(v), nil
+	case flux.TBool:
+		var v bool
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return values.NewBool(v), nil
+	case flux.TTime:
+		if raw[0] != '"' {
+			var v int64
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, err
+			}
+			return values.NewTime(values.Time(v)), nil
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		return values.NewTime(values.ConvertTime(t)), nil
+	default:
+		// Objects and arrays keep their JSON text.
+		if raw[0] != '"' {
+			return values.NewString(string(raw)), nil
+		}
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return values.NewString(v), nil
+	}
+}
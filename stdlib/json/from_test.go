@@ -0,0 +1,246 @@
+package json_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/dependencies/filesystem"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	fluxjson "github.com/influxdata/flux/json"
+	"github.com/influxdata/flux/mock"
+	"github.com/influxdata/flux/stdlib/json"
+	"github.com/influxdata/flux/values"
+)
+
+func TestFromJSON_Run(t *testing.T) {
+	t0 := values.ConvertTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	t1 := values.ConvertTime(time.Date(2021, 1, 1, 0, 1, 0, 0, time.UTC))
+
+	testCases := []struct {
+		name    string
+		spec    *json.FromJSONProcedureSpec
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "array",
+			spec: &json.FromJSONProcedureSpec{
+				Data: ` [
+					{"host": "A", "_value": 1.5, "n": 1, "ok": true},
+					{"host": "B", "_value": 2, "n": null, "extra": {"a": [1, 2]}}
+				]`,
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "host", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "n", Type: flux.TInt},
+					{Label: "ok", Type: flux.TBool},
+					{Label: "extra", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{"A", 1.5, int64(1), true, nil},
+					{"B", 2.0, nil, nil, `{"a": [1, 2]}`},
+				},
+			}},
+		},
+		{
+			name: "ndjson with schema",
+			spec: &json.FromJSONProcedureSpec{
+				Data: `{"_time": "2021-01-01T00:00:00Z", "_value": 1}
+{"_time": 1609459260000000000, "_value": 2}
+`,
+				Schema: map[string]flux.ColType{
+					"_time":  flux.TTime,
+					"_value": flux.TUInt,
+					"tag":    flux.TString,
+				},
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TUInt},
+					{Label: "tag", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{t0, uint64(1), nil},
+					{t1, uint64(2), nil},
+				},
+			}},
+		},
+		{
+			name: "path",
+			spec: &json.FromJSONProcedureSpec{
+				Data: `{"result":"_result","table":0,"group":[],"record":{"_time":"2021-01-01T00:00:00Z","_value":1}}
+{"result":"_result","table":0,"group":[],"record":{"_time":"2021-01-01T00:01:00Z","_value":2}}
+`,
+				Path:   "record",
+				Schema: map[string]flux.ColType{"_time": flux.TTime},
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{t0, int64(1)},
+					{t1, int64(2)},
+				},
+			}},
+		},
+		{
+			name: "empty",
+			spec: &json.FromJSONProcedureSpec{Data: `[]`},
+			want: []*executetest.Table{{}},
+		},
+		{
+			name: "non-finite floats",
+			spec: &json.FromJSONProcedureSpec{
+				Data: `[{"a": 1, "b": "NaN", "c": "x"}, {"a": "-Inf", "b": "+Inf", "c": "NaN"}]`,
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "a", Type: flux.TFloat},
+					{Label: "b", Type: flux.TFloat},
+					{Label: "c", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{1.0, math.NaN(), "x"},
+					{math.Inf(-1), math.Inf(1), "NaN"},
+				},
+			}},
+		},
+		{
+			name: "conflicting types",
+			spec: &json.FromJSONProcedureSpec{
+				Data: `[{"a": 1}, {"a": "x"}]`,
+			},
+			wantErr: errors.New(codes.Invalid, `json.from() found column "a" with both int and string values`),
+		},
+		{
+			name: "invalid value",
+			spec: &json.FromJSONProcedureSpec{
+				Data:   `[{"a": "x"}]`,
+				Schema: map[string]flux.ColType{"a": flux.TInt},
+			},
+			wantErr: errors.New(codes.Invalid, `json.from() found an invalid value for column "a": json: cannot unmarshal string into Go value of type int64`),
+		},
+		{
+			name: "missing path",
+			spec: &json.FromJSONProcedureSpec{
+				Data: `[{"a": 1}]`,
+				Path: "record.values",
+			},
+			wantErr: errors.New(codes.Invalid, `json.from() failed to read row 0: path "record.values" does not lead to an object`),
+		},
+		{
+			name: "row is not an object",
+			spec: &json.FromJSONProcedureSpec{
+				Data: `[1]`,
+			},
+			wantErr: errors.New(codes.Invalid, `json.from() failed to read row 0: row is not an object`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			executetest.RunSourceHelper(t,
+				ctx,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID) execute.Source {
+					a := mock.AdministrationWithContext(ctx)
+					s, err := json.CreateSource(tc.spec, id, a)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return s
+				},
+			)
+		})
+	}
+}
+
+func TestFromJSON_File(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "data.ndjson")
+	if err := os.WriteFile(fpath, []byte("{\"a\": 1}\n{\"a\": 2.5}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+	want := []*executetest.Table{{
+		ColMeta: []flux.ColMeta{
+			{Label: "a", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{1.0},
+			{2.5},
+		},
+	}}
+	executetest.RunSourceHelper(t,
+		ctx,
+		want,
+		nil,
+		func(id execute.DatasetID) execute.Source {
+			a := mock.AdministrationWithContext(ctx)
+			s, err := json.CreateSource(&json.FromJSONProcedureSpec{File: fpath}, id, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	)
+}
+
+func TestFromJSON_RoundTrip(t *testing.T) {
+	// Floats that JSON cannot represent are encoded as strings
+	// and read back as floats.
+	tables := func() []*executetest.Table {
+		return []*executetest.Table{{
+			ColMeta: []flux.ColMeta{
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "host", Type: flux.TString},
+			},
+			Data: [][]interface{}{
+				{1.5, "A"},
+				{math.NaN(), "NaN"},
+				{math.Inf(1), "B"},
+				{math.Inf(-1), "C"},
+				{nil, "D"},
+			},
+		}}
+	}
+	var buf bytes.Buffer
+	results := []flux.Result{executetest.NewResult(tables())}
+	encoder := fluxjson.NewMultiResultEncoder(fluxjson.ResultEncoderConfig{NDJSON: true})
+	if _, err := encoder.Encode(&buf, flux.NewSliceResultIterator(results)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	executetest.RunSourceHelper(t,
+		ctx,
+		tables(),
+		nil,
+		func(id execute.DatasetID) execute.Source {
+			a := mock.AdministrationWithContext(ctx)
+			s, err := json.CreateSource(&json.FromJSONProcedureSpec{
+				Data: buf.String(),
+				Path: "record",
+			}, id, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	)
+}
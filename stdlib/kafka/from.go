@@ -0,0 +1,306 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// FromKafkaKind is the Kind for the kafka.from Flux function
+	FromKafkaKind = "fromKafka"
+
+	// fromKafkaBatchSize is the maximum number of messages in a table.
+	fromKafkaBatchSize = 1000
+	// fromKafkaFlushInterval is how long the source waits for more
+	// messages before it sends the messages it has read.
+	fromKafkaFlushInterval = 100 * time.Millisecond
+)
+
+var fromKafkaDecoders = []string{"csv", "line"}
+
+type FromKafkaOpSpec struct {
+	Brokers   []string `json:"brokers"`
+	Topic     string   `json:"topic"`
+	GroupID   string   `json:"groupID"`
+	Partition int      `json:"partition"`
+	Decoder   string   `json:"decoder"`
+}
+
+func init() {
+	fromKafkaSignature := runtime.MustLookupBuiltinType("kafka", "from")
+	runtime.RegisterPackageValue("kafka", "from", flux.MustValue(flux.FunctionValue(FromKafkaKind, createFromKafkaOpSpec, fromKafkaSignature)))
+	plan.RegisterProcedureSpec(FromKafkaKind, newFromKafkaProcedure, FromKafkaKind)
+	execute.RegisterSource(FromKafkaKind, createFromKafkaSource)
+}
+
+// DefaultKafkaReaderFactory creates the KafkaReader used by kafka.from.
+// It can be replaced for testing.
+var DefaultKafkaReaderFactory = func(conf kafka.ReaderConfig) KafkaReader {
+	return kafka.NewReader(conf)
+}
+
+// KafkaReader is an interface for what we need from DefaultKafkaReaderFactory
+type KafkaReader interface {
+	io.Closer
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+}
+
+func createFromKafkaOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(FromKafkaOpSpec)
+
+	brokers, err := args.GetRequiredArray("brokers", semantic.String)
+	if err != nil {
+		return nil, err
+	}
+	if brokers.Len() < 1 {
+		return nil, errors.New(codes.Invalid, "at least one broker is required")
+	}
+	spec.Brokers = make([]string, brokers.Len())
+	for i := range spec.Brokers {
+		spec.Brokers[i] = brokers.Get(i).Str()
+	}
+
+	if spec.Topic, err = args.GetRequiredString("topic"); err != nil {
+		return nil, err
+	} else if len(spec.Topic) == 0 {
+		return nil, errors.New(codes.Invalid, "invalid topic name")
+	}
+
+	if spec.GroupID, _, err = args.GetString("groupID"); err != nil {
+		return nil, err
+	}
+
+	if partition, ok, err := args.GetInt("partition"); err != nil {
+		return nil, err
+	} else if ok {
+		if partition < 0 {
+			return nil, errors.Newf(codes.Invalid, "invalid partition %d", partition)
+		}
+		if spec.GroupID != "" && partition != 0 {
+			return nil, errors.New(codes.Invalid, "partition cannot be set with groupID")
+		}
+		spec.Partition = int(partition)
+	}
+
+	if d, ok, err := args.GetString("decoder"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Decoder = d
+	} else {
+		spec.Decoder = "line"
+	}
+	if spec.Decoder != fromKafkaDecoders[0] && spec.Decoder != fromKafkaDecoders[1] {
+		return nil, errors.Newf(codes.Invalid, "invalid decoder %s, must be one of %v", spec.Decoder, fromKafkaDecoders)
+	}
+	return spec, nil
+}
+
+func (s *FromKafkaOpSpec) Kind() flux.OperationKind {
+	return FromKafkaKind
+}
+
+type FromKafkaProcedureSpec struct {
+	plan.DefaultCost
+	Spec *FromKafkaOpSpec
+}
+
+func newFromKafkaProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*FromKafkaOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &FromKafkaProcedureSpec{Spec: spec}, nil
+}
+
+func (s *FromKafkaProcedureSpec) Kind() plan.ProcedureKind {
+	return FromKafkaKind
+}
+
+func (s *FromKafkaProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s.Spec
+	ns.Brokers = append([]string(nil), s.Spec.Brokers...)
+	return &FromKafkaProcedureSpec{Spec: &ns}
+}
+
+func createFromKafkaSource(s plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := s.(*FromKafkaProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", s)
+	}
+	if !execute.StreamingEnabled(a.Context()) {
+		return nil, errors.New(codes.Invalid, "kafka.from can only be used in queries that run in streaming mode")
+	}
+
+	deps := flux.GetDependencies(a.Context())
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range spec.Spec.Brokers {
+		u, err := url.Parse(b)
+		if err != nil {
+			return nil, errors.Newf(codes.Invalid, "invalid kafka broker url: %v", err)
+		}
+		if err := validator.Validate(u); err != nil {
+			return nil, errors.Newf(codes.Invalid, "kafka broker url did not pass validation: %v", err)
+		}
+	}
+
+	reader := DefaultKafkaReaderFactory(kafka.ReaderConfig{
+		Brokers:   spec.Spec.Brokers,
+		Topic:     spec.Spec.Topic,
+		GroupID:   spec.Spec.GroupID,
+		Partition: spec.Spec.Partition,
+	})
+	return NewKafkaSource(spec, reader, dsid, a.Allocator()), nil
+}
+
+// NewKafkaSource creates a source that reads messages from
+// the reader until the query is cancelled.
+func NewKafkaSource(spec *FromKafkaProcedureSpec, reader KafkaReader, dsid execute.DatasetID, mem memory.Allocator) execute.Source {
+	return &kafkaSource{
+		d:      dsid,
+		spec:   spec,
+		reader: reader,
+		mem:    mem,
+	}
+}
+
+type kafkaSource struct {
+	execute.ExecutionNode
+	d      execute.DatasetID
+	spec   *FromKafkaProcedureSpec
+	reader KafkaReader
+	mem    memory.Allocator
+	ts     []execute.Transformation
+
+	watermark execute.Watermark
+}
+
+func (s *kafkaSource) AddTransformation(t execute.Transformation) {
+	s.ts = append(s.ts, t)
+}
+
+func (s *kafkaSource) Run(ctx context.Context) {
+	err := s.run(ctx)
+	if cerr := s.reader.Close(); err == nil && cerr != nil {
+		err = errors.Wrap(cerr, codes.Inherit, "error closing kafka reader")
+	}
+	for _, t := range s.ts {
+		t.Finish(s.d, err)
+	}
+}
+
+func (s *kafkaSource) run(ctx context.Context) error {
+	for {
+		batch, err := s.readBatch(ctx)
+		if len(batch) > 0 {
+			if perr := s.processBatch(batch); perr != nil {
+				return perr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readBatch blocks until a message is available and then reads the
+// messages that arrive within the flush interval up to the batch size.
+func (s *kafkaSource) readBatch(ctx context.Context) ([]kafka.Message, error) {
+	msg, err := s.reader.ReadMessage(ctx)
+	if err != nil {
+		return nil, s.readErr(ctx, err)
+	}
+	batch := []kafka.Message{msg}
+
+	flushCtx, cancel := context.WithTimeout(ctx, fromKafkaFlushInterval)
+	defer cancel()
+	for len(batch) < fromKafkaBatchSize {
+		msg, err := s.reader.ReadMessage(flushCtx)
+		if err != nil {
+			if ctx.Err() == nil && flushCtx.Err() != nil {
+				// The flush interval has passed.
+				return batch, nil
+			}
+			return batch, s.readErr(ctx, err)
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+func (s *kafkaSource) readErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Wrap(err, codes.Unavailable, "error reading from kafka")
+}
+
+func (s *kafkaSource) processBatch(batch []kafka.Message) error {
+	if s.spec.Spec.Decoder == "csv" {
+		for _, msg := range batch {
+			decoder := csv.NewResultDecoder(csv.ResultDecoderConfig{Allocator: s.mem})
+			result, err := decoder.Decode(bytes.NewReader(msg.Value))
+			if err != nil {
+				return errors.Wrap(err, codes.Inherit, "decode error")
+			}
+			if err := result.Tables().Do(s.process); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	key := execute.NewGroupKey(nil, nil)
+	builder := execute.NewColListTableBuilder(key, s.mem)
+	timeIdx, err := builder.AddCol(flux.ColMeta{Label: execute.DefaultTimeColLabel, Type: flux.TTime})
+	if err != nil {
+		return err
+	}
+	valueIdx, err := builder.AddCol(flux.ColMeta{Label: execute.DefaultValueColLabel, Type: flux.TString})
+	if err != nil {
+		return err
+	}
+	for _, msg := range batch {
+		if err := builder.AppendTime(timeIdx, values.ConvertTime(msg.Time)); err != nil {
+			return err
+		}
+		if err := builder.AppendString(valueIdx, string(msg.Value)); err != nil {
+			return err
+		}
+	}
+	tbl, err := builder.Table()
+	if err != nil {
+		return err
+	}
+	return s.process(tbl)
+}
+
+func (s *kafkaSource) process(tbl flux.Table) error {
+	tbl, err := s.watermark.Observe(tbl)
+	if err != nil {
+		return err
+	}
+	for _, t := range s.ts {
+		if err := t.Process(s.d, tbl); err != nil {
+			return err
+		}
+	}
+	return s.watermark.Update(s.d, s.ts)
+}
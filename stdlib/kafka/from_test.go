@@ -0,0 +1,192 @@
+package kafka_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/querytest"
+	fkafka "github.com/influxdata/flux/stdlib/kafka"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestFromKafka_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name:    "from no brokers",
+			Raw:     `import "kafka" kafka.from(brokers: [], topic: "events")`,
+			WantErr: true,
+		},
+		{
+			Name:    "from wrong decoder",
+			Raw:     `import "kafka" kafka.from(brokers: ["brokerurl:8989"], topic: "events", decoder: "wrong")`,
+			WantErr: true,
+		},
+		{
+			Name: "from ok",
+			Raw:  `import "kafka" kafka.from(brokers: ["brokerurl:8989"], topic: "events", groupID: "flux")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "fromKafka0",
+						Spec: &fkafka.FromKafkaOpSpec{
+							Brokers: []string{"brokerurl:8989"},
+							Topic:   "events",
+							GroupID: "flux",
+							Decoder: "line",
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+// kafkaReaderMock returns its messages and then blocks until
+// the context is cancelled like a reader waiting for new messages.
+type kafkaReaderMock struct {
+	mu     sync.Mutex
+	msgs   []kafka.Message
+	closed bool
+	done   chan struct{}
+}
+
+func (k *kafkaReaderMock) Close() error {
+	k.mu.Lock()
+	k.closed = true
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *kafkaReaderMock) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	k.mu.Lock()
+	if len(k.msgs) > 0 {
+		msg := k.msgs[0]
+		k.msgs = k.msgs[1:]
+		k.mu.Unlock()
+		return msg, nil
+	}
+	if k.done != nil {
+		close(k.done)
+		k.done = nil
+	}
+	k.mu.Unlock()
+
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func TestFromKafkaSource_Run(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec *fkafka.FromKafkaOpSpec
+		msgs []kafka.Message
+		want []*executetest.Table
+	}{
+		{
+			name: "line",
+			spec: &fkafka.FromKafkaOpSpec{Decoder: "line"},
+			msgs: []kafka.Message{
+				{Time: time.Unix(0, 1), Value: []byte("first")},
+				{Time: time.Unix(0, 2), Value: []byte("second")},
+			},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), "first"},
+					{execute.Time(2), "second"},
+				},
+			}},
+		},
+		{
+			name: "csv",
+			spec: &fkafka.FromKafkaOpSpec{Decoder: "csv"},
+			msgs: []kafka.Message{
+				{Value: []byte(`#datatype,string,long,dateTime:RFC3339,string,double
+#group,false,false,false,true,false
+#default,,,,,
+,result,table,_time,host,_value
+,,0,1970-01-01T00:00:10Z,a,1.5
+,,0,1970-01-01T00:00:20Z,a,2.5
+`)},
+			},
+			want: []*executetest.Table{{
+				KeyCols: []string{"host"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "host", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(10 * time.Second), "a", 1.5},
+					{execute.Time(20 * time.Second), "a", 2.5},
+				},
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			id := executetest.RandomDatasetID()
+			d := executetest.NewDataset(id)
+			c := execute.NewTableBuilderCache(executetest.UnlimitedAllocator)
+			c.SetTriggerSpec(plan.DefaultTriggerSpec)
+
+			reader := &kafkaReaderMock{
+				msgs: tc.msgs,
+				done: make(chan struct{}),
+			}
+			done := reader.done
+			spec := &fkafka.FromKafkaProcedureSpec{Spec: tc.spec}
+			ks := fkafka.NewKafkaSource(spec, reader, id, executetest.UnlimitedAllocator)
+
+			// Add `yield` in order to add `from` output tables to cache.
+			ks.AddTransformation(executetest.NewYieldTransformation(d, c))
+
+			// The source reads until it is cancelled.
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-done
+				cancel()
+			}()
+			ks.Run(ctx)
+
+			if want, got := context.Canceled, d.FinishedErr; want != got {
+				t.Errorf("unexpected finish error -want/+got:\n\t- %v\n\t+ %v", want, got)
+			}
+			if !reader.closed {
+				t.Error("expected the reader to be closed")
+			}
+
+			// Retrieve tables from cache.
+			got, err := executetest.TablesFromCache(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			executetest.NormalizeTables(got)
+			executetest.NormalizeTables(tc.want)
+			if !cmp.Equal(tc.want, got) {
+				t.Errorf("unexpected tables -want/+got\n%s", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package line
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/dependencies/filesystem"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/values"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+const FromLineKind = "fromLine"
+
+type FromLineOpSpec struct {
+	File string `json:"file"`
+	Data string `json:"data"`
+}
+
+func init() {
+	fromLineSignature := runtime.MustLookupBuiltinType("line", "from")
+	runtime.RegisterPackageValue("line", "from", flux.MustValue(flux.FunctionValue(FromLineKind, createFromLineOpSpec, fromLineSignature)))
+	plan.RegisterProcedureSpec(FromLineKind, newFromLineProcedure, FromLineKind)
+	execute.RegisterSource(FromLineKind, createFromLineSource)
+}
+
+func createFromLineOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	spec := new(FromLineOpSpec)
+
+	if file, ok, err := args.GetString("file"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.File = file
+	}
+
+	if data, ok, err := args.GetString("data"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Data = data
+	}
+
+	if spec.Data == "" && spec.File == "" {
+		return nil, errors.New(codes.Invalid, "must provide line protocol data or filename")
+	}
+
+	if spec.Data != "" && spec.File != "" {
+		return nil, errors.New(codes.Invalid, "must provide exactly one of the parameters data or file")
+	}
+	return spec, nil
+}
+
+func (s *FromLineOpSpec) Kind() flux.OperationKind {
+	return FromLineKind
+}
+
+type FromLineProcedureSpec struct {
+	plan.DefaultCost
+	File string
+	Data string
+}
+
+func newFromLineProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*FromLineOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &FromLineProcedureSpec{
+		File: spec.File,
+		Data: spec.Data,
+	}, nil
+}
+
+func (s *FromLineProcedureSpec) Kind() plan.ProcedureKind {
+	return FromLineKind
+}
+
+func (s *FromLineProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(FromLineProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+func createFromLineSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	spec, ok := prSpec.(*FromLineProcedureSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", prSpec)
+	}
+	return CreateSource(spec, dsid, a)
+}
+
+func CreateSource(spec *FromLineProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	return execute.CreateSourceFromIterator(&lineSource{
+		spec:  spec,
+		alloc: a.Allocator(),
+	}, dsid)
+}
+
+type lineSource struct {
+	spec  *FromLineProcedureSpec
+	alloc memory.Allocator
+}
+
+// tag is a tag of a point.
+type tag struct {
+	key, value string
+}
+
+// field is a field of a point.
+type field struct {
+	key   string
+	value values.Value
+}
+
+// seriesTable is the table that holds the
+// values of one field of a series.
+type seriesTable struct {
+	key     flux.GroupKey
+	builder *execute.ColListTableBuilder
+}
+
+func (s *lineSource) Do(ctx context.Context, f func(flux.Table) error) error {
+	var r io.Reader
+	if s.spec.File != "" {
+		fp, err := filesystem.OpenFile(ctx, s.spec.File)
+		if err != nil {
+			return errors.Wrap(err, codes.Inherit, "line.from() failed to read file")
+		}
+		defer func() { _ = fp.Close() }()
+		r = fp
+	} else {
+		r = strings.NewReader(s.spec.Data)
+	}
+
+	// The points of a series can appear anywhere in the data
+	// so every table is kept until all of the data has been read.
+	var (
+		tables = make(map[string]*seriesTable)
+		order  []*seriesTable
+	)
+	dec := lineprotocol.NewDecoder(r)
+	for n := 0; dec.Next(); n++ {
+		measurement, tags, fields, ts, err := readPoint(dec)
+		if err != nil {
+			return errors.Wrapf(err, codes.Invalid, "line.from() failed to read point %d", n)
+		}
+
+		var sb strings.Builder
+		sb.WriteString(measurement)
+		for _, t := range tags {
+			sb.WriteString("\x00" + t.key + "\x00" + t.value)
+		}
+		seriesKey := sb.String()
+
+		for _, fld := range fields {
+			id := seriesKey + "\x01" + fld.key
+			st, ok := tables[id]
+			if !ok {
+				st, err = s.newTable(measurement, tags, fld.key, flux.ColumnType(fld.value.Type()))
+				if err != nil {
+					return err
+				}
+				tables[id] = st
+				order = append(order, st)
+			} else if want, got := st.builder.Cols()[1].Type, flux.ColumnType(fld.value.Type()); want != got {
+				return errors.Newf(codes.Invalid, "line.from() found field %q of measurement %q with both %s and %s values", fld.key, measurement, want, got)
+			}
+			if err := appendRow(st, ts, fld.value); err != nil {
+				return err
+			}
+		}
+	}
+	if err := dec.Err(); err != nil {
+		return errors.Wrap(err, codes.Invalid, "line.from() failed to read data")
+	}
+
+	for _, st := range order {
+		tbl, err := st.builder.Table()
+		if err != nil {
+			return err
+		}
+		if err := f(tbl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readPoint reads the current point from the decoder.
+// The tags are sorted by key and a zero time is returned
+// for points that do not have a timestamp.
+func readPoint(dec *lineprotocol.Decoder) (string, []tag, []field, time.Time, error) {
+	m, err := dec.Measurement()
+	if err != nil {
+		return "", nil, nil, time.Time{}, err
+	}
+	measurement := string(m)
+
+	var tags []tag
+	for {
+		k, v, err := dec.NextTag()
+		if err != nil {
+			return "", nil, nil, time.Time{}, err
+		} else if k == nil {
+			break
+		}
+		tags = append(tags, tag{key: string(k), value: string(v)})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].key < tags[j].key
+	})
+
+	var fields []field
+	for {
+		k, v, err := dec.NextField()
+		if err != nil {
+			return "", nil, nil, time.Time{}, err
+		} else if k == nil {
+			break
+		}
+		fields = append(fields, field{key: string(k), value: fieldValue(v)})
+	}
+
+	ts, err := dec.Time(lineprotocol.Nanosecond, time.Time{})
+	if err != nil {
+		return "", nil, nil, time.Time{}, err
+	}
+	return measurement, tags, fields, ts, nil
+}
+
+// fieldValue converts a line protocol value to a flux value.
+func fieldValue(v lineprotocol.Value) values.Value {
+	switch v.Kind() {
+	case lineprotocol.Int:
+		return values.NewInt(v.IntV())
+	case lineprotocol.Uint:
+		return values.NewUInt(v.UintV())
+	case lineprotocol.Float:
+		return values.NewFloat(v.FloatV())
+	case lineprotocol.Bool:
+		return values.NewBool(v.BoolV())
+	default:
+		return values.NewString(v.StringV())
+	}
+}
+
+// newTable creates the table for one field of a series.
+// The columns are ordered like the tables from influxdb.from().
+func (s *lineSource) newTable(measurement string, tags []tag, fieldKey string, typ flux.ColType) (*seriesTable, error) {
+	gkb := execute.NewGroupKeyBuilder(nil)
+	gkb.AddKeyValue("_field", values.NewString(fieldKey))
+	gkb.AddKeyValue("_measurement", values.NewString(measurement))
+	for _, t := range tags {
+		gkb.AddKeyValue(t.key, values.NewString(t.value))
+	}
+	key, err := gkb.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	b := execute.NewColListTableBuilder(key, s.alloc)
+	if _, err := b.AddCol(flux.ColMeta{Label: execute.DefaultTimeColLabel, Type: flux.TTime}); err != nil {
+		return nil, err
+	}
+	if _, err := b.AddCol(flux.ColMeta{Label: execute.DefaultValueColLabel, Type: typ}); err != nil {
+		return nil, err
+	}
+	if err := execute.AddTableKeyCols(key, b); err != nil {
+		return nil, err
+	}
+	return &seriesTable{key: key, builder: b}, nil
+}
+
+// appendRow appends a value to the table. A zero time is appended as null.
+func appendRow(st *seriesTable, ts time.Time, v values.Value) error {
+	t := values.NewNull(flux.SemanticType(flux.TTime))
+	if !ts.IsZero() {
+		t = values.NewTime(values.ConvertTime(ts))
+	}
+	if err := st.builder.AppendValue(0, t); err != nil {
+		return err
+	}
+	if err := st.builder.AppendValue(1, v); err != nil {
+		return err
+	}
+	return execute.AppendKeyValues(st.key, st.builder)
+}
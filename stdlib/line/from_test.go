@@ -0,0 +1,203 @@
+package line_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/dependencies/filesystem"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/mock"
+	"github.com/influxdata/flux/stdlib/line"
+	"github.com/influxdata/flux/values"
+)
+
+func TestFromLine_Run(t *testing.T) {
+	t0 := values.ConvertTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	t1 := values.ConvertTime(time.Date(2021, 1, 1, 0, 1, 0, 0, time.UTC))
+
+	testCases := []struct {
+		name    string
+		spec    *line.FromLineProcedureSpec
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "series",
+			spec: &line.FromLineProcedureSpec{
+				Data: `cpu,region=west,host=A usage_user=1.5,usage_system=2i 1609459200000000000
+# comment
+cpu,host=B,region=west usage_user=0.5 1609459200000000000
+cpu,host=A,region=west usage_user=2.5 1609459260000000000
+`,
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"_field", "_measurement", "host", "region"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_field", Type: flux.TString},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+						{Label: "region", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{t0, 1.5, "usage_user", "cpu", "A", "west"},
+						{t1, 2.5, "usage_user", "cpu", "A", "west"},
+					},
+				},
+				{
+					KeyCols: []string{"_field", "_measurement", "host", "region"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+						{Label: "_field", Type: flux.TString},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+						{Label: "region", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{t0, int64(2), "usage_system", "cpu", "A", "west"},
+					},
+				},
+				{
+					KeyCols: []string{"_field", "_measurement", "host", "region"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_field", Type: flux.TString},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+						{Label: "region", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{t0, 0.5, "usage_user", "cpu", "B", "west"},
+					},
+				},
+			},
+		},
+		{
+			name: "field types",
+			spec: &line.FromLineProcedureSpec{
+				Data: `sys u=7u,ok=true,msg="say \"hi\""
+`,
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"_field", "_measurement"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TUInt},
+						{Label: "_field", Type: flux.TString},
+						{Label: "_measurement", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{nil, uint64(7), "u", "sys"},
+					},
+				},
+				{
+					KeyCols: []string{"_field", "_measurement"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TBool},
+						{Label: "_field", Type: flux.TString},
+						{Label: "_measurement", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{nil, true, "ok", "sys"},
+					},
+				},
+				{
+					KeyCols: []string{"_field", "_measurement"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TString},
+						{Label: "_field", Type: flux.TString},
+						{Label: "_measurement", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{nil, `say "hi"`, "msg", "sys"},
+					},
+				},
+			},
+		},
+		{
+			name: "conflicting types",
+			spec: &line.FromLineProcedureSpec{
+				Data: `cpu value=1 1
+cpu value=1i 2
+`,
+			},
+			wantErr: errors.New(codes.Invalid, `line.from() found field "value" of measurement "cpu" with both float and int values`),
+		},
+		{
+			name: "invalid line",
+			spec: &line.FromLineProcedureSpec{
+				Data: `cpu value=1 1
+cpu
+`,
+			},
+			wantErr: errors.New(codes.Invalid, `line.from() failed to read point 1: at line 2:4: expected tag key or field but found '\n' instead`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			executetest.RunSourceHelper(t,
+				ctx,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID) execute.Source {
+					a := mock.AdministrationWithContext(ctx)
+					s, err := line.CreateSource(tc.spec, id, a)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return s
+				},
+			)
+		})
+	}
+}
+
+func TestFromLine_File(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "data.lp")
+	if err := os.WriteFile(fpath, []byte("m f=1i 1\nm f=2i 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := filesystem.Inject(context.Background(), filesystem.SystemFS)
+	want := []*executetest.Table{{
+		KeyCols: []string{"_field", "_measurement"},
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TInt},
+			{Label: "_field", Type: flux.TString},
+			{Label: "_measurement", Type: flux.TString},
+		},
+		Data: [][]interface{}{
+			{execute.Time(1), int64(1), "f", "m"},
+			{execute.Time(2), int64(2), "f", "m"},
+		},
+	}}
+	executetest.RunSourceHelper(t,
+		ctx,
+		want,
+		nil,
+		func(id execute.DatasetID) execute.Source {
+			a := mock.AdministrationWithContext(ctx)
+			s, err := line.CreateSource(&line.FromLineProcedureSpec{File: fpath}, id, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	)
+}
@@ -46,6 +46,7 @@ import (
 	_ "github.com/influxdata/flux/stdlib/experimental/json"
 	_ "github.com/influxdata/flux/stdlib/experimental/mqtt"
 	_ "github.com/influxdata/flux/stdlib/experimental/oee"
+	_ "github.com/influxdata/flux/stdlib/experimental/parquet"
 	_ "github.com/influxdata/flux/stdlib/experimental/polyline"
 	_ "github.com/influxdata/flux/stdlib/experimental/prometheus"
 	_ "github.com/influxdata/flux/stdlib/experimental/query"
@@ -74,6 +75,7 @@ import (
 	_ "github.com/influxdata/flux/stdlib/join"
 	_ "github.com/influxdata/flux/stdlib/json"
 	_ "github.com/influxdata/flux/stdlib/kafka"
+	_ "github.com/influxdata/flux/stdlib/line"
 	_ "github.com/influxdata/flux/stdlib/math"
 	_ "github.com/influxdata/flux/stdlib/pagerduty"
 	_ "github.com/influxdata/flux/stdlib/planner"
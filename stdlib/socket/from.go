@@ -1,6 +1,7 @@
 // Package socket implements a source that gets input from a socket connection and produces tables given a decoder.
-// This is a good candidate for streaming use cases. For now, it produces a single table for everything
-// that it receives from the start to the end of the connection.
+// In batch mode, the line decoder produces a single table for everything that it receives from the start
+// to the end of the connection. When the query runs in streaming mode, the source produces tables as data
+// arrives and advances the watermark until the query is cancelled.
 package socket
 
 import (
@@ -150,10 +151,24 @@ func createFromSocketSource(s plan.ProcedureSpec, dsid execute.DatasetID, a exec
 		return nil, errors.Wrap(err, codes.Inherit, "error in creating socket source")
 	}
 
+	if execute.StreamingEnabled(a.Context()) {
+		return NewStreamingSocketSource(spec, conn, &nowTimeProvider{}, dsid)
+	}
 	return NewSocketSource(spec, conn, &nowTimeProvider{}, dsid)
 }
 
 func NewSocketSource(spec *FromSocketProcedureSpec, rc io.ReadCloser, tp line.TimeProvider, dsid execute.DatasetID) (execute.Source, error) {
+	return newSocketSource(spec, rc, tp, dsid, false)
+}
+
+// NewStreamingSocketSource creates a socket source that produces tables
+// as data arrives and updates the watermark after each table.
+// It reads until the connection is closed or the query is cancelled.
+func NewStreamingSocketSource(spec *FromSocketProcedureSpec, rc io.ReadCloser, tp line.TimeProvider, dsid execute.DatasetID) (execute.Source, error) {
+	return newSocketSource(spec, rc, tp, dsid, true)
+}
+
+func newSocketSource(spec *FromSocketProcedureSpec, rc io.ReadCloser, tp line.TimeProvider, dsid execute.DatasetID, streaming bool) (execute.Source, error) {
 	var decoder flux.ResultDecoder
 	switch spec.Decoder {
 	case "csv":
@@ -162,6 +177,7 @@ func NewSocketSource(spec *FromSocketProcedureSpec, rc io.ReadCloser, tp line.Ti
 		decoder = line.NewResultDecoder(&line.ResultDecoderConfig{
 			Separator:    '\n',
 			TimeProvider: tp,
+			Flush:        streaming,
 		})
 	}
 
@@ -170,9 +186,10 @@ func NewSocketSource(spec *FromSocketProcedureSpec, rc io.ReadCloser, tp line.Ti
 	}
 
 	return &socketSource{
-		d:       dsid,
-		rc:      rc,
-		decoder: decoder,
+		d:         dsid,
+		rc:        rc,
+		decoder:   decoder,
+		streaming: streaming,
 	}, nil
 }
 
@@ -182,6 +199,9 @@ type socketSource struct {
 	rc      io.ReadCloser
 	decoder flux.ResultDecoder
 	ts      []execute.Transformation
+
+	streaming bool
+	watermark execute.Watermark
 }
 
 func (ss *socketSource) AddTransformation(t execute.Transformation) {
@@ -190,21 +210,49 @@ func (ss *socketSource) AddTransformation(t execute.Transformation) {
 
 func (ss *socketSource) Run(ctx context.Context) {
 	defer ss.rc.Close()
+	if ss.streaming {
+		// Close the connection when the query is cancelled
+		// so the decoder stops waiting for more data.
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = ss.rc.Close()
+			case <-done:
+			}
+		}()
+	}
+
 	result, err := ss.decoder.Decode(ss.rc)
 	if err != nil {
 		err = errors.Wrap(err, codes.Inherit, "decode error")
 	} else {
-		err = result.Tables().Do(func(tbl flux.Table) error {
-			for _, t := range ss.ts {
-				if err := t.Process(ss.d, tbl); err != nil {
-					return err
-				}
-			}
-			return nil
-		})
+		err = result.Tables().Do(ss.process)
+	}
+	if ss.streaming && ctx.Err() != nil {
+		err = ctx.Err()
 	}
 
 	for _, t := range ss.ts {
 		t.Finish(ss.d, err)
 	}
 }
+
+func (ss *socketSource) process(tbl flux.Table) error {
+	if ss.streaming {
+		var err error
+		if tbl, err = ss.watermark.Observe(tbl); err != nil {
+			return err
+		}
+	}
+	for _, t := range ss.ts {
+		if err := t.Process(ss.d, tbl); err != nil {
+			return err
+		}
+	}
+	if ss.streaming {
+		return ss.watermark.Update(ss.d, ss.ts)
+	}
+	return nil
+}
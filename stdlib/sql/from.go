@@ -3,6 +3,8 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"strings"
 
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/codes"
@@ -90,6 +92,80 @@ func (s *FromSQLProcedureSpec) Copy() plan.ProcedureSpec {
 	return ns
 }
 
+// EstimateStatistics implements plan.StatisticsEstimator.
+// Only postgres is supported, by asking the query planner for the
+// number of rows it expects the query to return. The database is
+// only contacted when statistics probes are enabled in the planner.
+// The result of sql.from is always a single table.
+//
+// Postgres runs every statement after the one that is explained,
+// so queries with more than one statement are not probed and the
+// probe runs in a read only transaction that is always rolled back.
+func (s *FromSQLProcedureSpec) EstimateStatistics(ctx context.Context) plan.Statistics {
+	if s.DriverName != "postgres" || !plan.StatisticsProbesEnabled(ctx) {
+		return plan.Statistics{}
+	}
+	if hasMultipleStatements(s.Query) {
+		return plan.Statistics{}
+	}
+
+	deps := flux.GetDependencies(ctx)
+	validator, err := deps.URLValidator()
+	if err != nil {
+		return plan.Statistics{}
+	}
+	if err := validateDataSource(validator, s.DriverName, s.DataSourceName); err != nil {
+		return plan.Statistics{}
+	}
+
+	db, err := getOpenFunc(s.DriverName, s.DataSourceName)()
+	if err != nil {
+		return plan.Statistics{}
+	}
+	defer func() { _ = db.Close() }()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return plan.Statistics{}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var plans string
+	if err := tx.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+s.Query).Scan(&plans); err != nil {
+		return plan.Statistics{}
+	}
+	rows, err := parsePostgresPlanRows([]byte(plans))
+	if err != nil {
+		return plan.Statistics{}
+	}
+	return plan.Statistics{Cardinality: rows, GroupCardinality: 1}
+}
+
+// hasMultipleStatements reports whether the query may contain more than
+// one statement. Any semicolon before the end of the query counts, including
+// one in a string literal, so some single statements are reported too.
+func hasMultipleStatements(query string) bool {
+	query = strings.TrimRight(query, "; \t\r\n")
+	return strings.Contains(query, ";")
+}
+
+// parsePostgresPlanRows reads the estimated number of rows
+// from the output of a postgres EXPLAIN (FORMAT JSON) statement.
+func parsePostgresPlanRows(data []byte) (int64, error) {
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		}
+	}
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return 0, err
+	}
+	if len(plans) == 0 {
+		return 0, errors.New(codes.Internal, "explain returned no plan")
+	}
+	return int64(plans[0].Plan.Rows), nil
+}
+
 func createFromSQLSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
 	spec, ok := prSpec.(*FromSQLProcedureSpec)
 	if !ok {
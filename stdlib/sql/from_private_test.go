@@ -258,3 +258,20 @@ func TestFromSqliteUrlValidation(t *testing.T) {
 	}
 	testCases.Run(t, createFromSQLSource)
 }
+
+func TestHasMultipleStatements(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  bool
+	}{
+		{query: "SELECT * FROM t", want: false},
+		{query: "SELECT * FROM t;\n", want: false},
+		{query: "SELECT * FROM t; DELETE FROM t", want: true},
+		{query: "SELECT 1; COMMIT; DROP TABLE t;", want: true},
+		{query: "SELECT * FROM t WHERE s = 'a;b'", want: true},
+	} {
+		if got := hasMultipleStatements(tc.query); got != tc.want {
+			t.Errorf("unexpected result for %q: want %v, got %v", tc.query, tc.want, got)
+		}
+	}
+}
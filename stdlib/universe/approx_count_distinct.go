@@ -0,0 +1,300 @@
+package universe
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/cespare/xxhash/v2"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/hll"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+)
+
+const ApproxCountDistinctKind = "approxCountDistinct"
+
+type ApproxCountDistinctOpSpec struct {
+	Column    string `json:"column"`
+	Precision int64  `json:"precision"`
+}
+
+func init() {
+	approxCountDistinctSignature := runtime.MustLookupBuiltinType("universe", "approxCountDistinct")
+
+	runtime.RegisterPackageValue("universe", ApproxCountDistinctKind, flux.MustValue(flux.FunctionValue(ApproxCountDistinctKind, createApproxCountDistinctOpSpec, approxCountDistinctSignature)))
+	plan.RegisterProcedureSpec(ApproxCountDistinctKind, newApproxCountDistinctProcedure, ApproxCountDistinctKind)
+	execute.RegisterTransformation(ApproxCountDistinctKind, createApproxCountDistinctTransformation)
+	plan.RegisterParallelizeRules(ParallelizeApproxCountDistinctRule{})
+}
+
+func createApproxCountDistinctOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := &ApproxCountDistinctOpSpec{
+		Column:    execute.DefaultValueColLabel,
+		Precision: hll.DefaultPrecision,
+	}
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	}
+
+	if precision, ok, err := args.GetInt("precision"); err != nil {
+		return nil, err
+	} else if ok {
+		if precision < hll.MinPrecision || precision > hll.MaxPrecision {
+			return nil, errors.Newf(codes.Invalid, "parameter \"precision\" must be between %d and %d", hll.MinPrecision, hll.MaxPrecision)
+		}
+		spec.Precision = precision
+	}
+	return spec, nil
+}
+
+func (s *ApproxCountDistinctOpSpec) Kind() flux.OperationKind {
+	return ApproxCountDistinctKind
+}
+
+type ApproxCountDistinctProcedureSpec struct {
+	plan.DefaultCost
+	Column              string
+	Precision           int64
+	ParallelMergeFactor int
+}
+
+// RequiredAttributes will reflect that this operation can behave as a parallel merge,
+// and require that predecessors are run in parallel, if the merge factor is greater
+// than one. The sketches built from each partition are merged by group key.
+func (s *ApproxCountDistinctProcedureSpec) RequiredAttributes() []plan.PhysicalAttributes {
+	if s.ParallelMergeFactor > 1 {
+		return []plan.PhysicalAttributes{
+			{
+				plan.ParallelRunKey: plan.ParallelRunAttribute{Factor: s.ParallelMergeFactor},
+			},
+		}
+	}
+	return nil
+}
+
+// OutputAttributes will reflect that this operation can behave as a parallel merge,
+// and produce the parallel merge attribute if the merge factor is greater than one.
+func (s *ApproxCountDistinctProcedureSpec) OutputAttributes() plan.PhysicalAttributes {
+	if s.ParallelMergeFactor > 1 {
+		return plan.PhysicalAttributes{
+			plan.ParallelMergeKey: plan.ParallelMergeAttribute{Factor: s.ParallelMergeFactor},
+		}
+	}
+	return nil
+}
+
+func newApproxCountDistinctProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*ApproxCountDistinctOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &ApproxCountDistinctProcedureSpec{
+		Column:    spec.Column,
+		Precision: spec.Precision,
+	}, nil
+}
+
+func (s *ApproxCountDistinctProcedureSpec) Kind() plan.ProcedureKind {
+	return ApproxCountDistinctKind
+}
+
+func (s *ApproxCountDistinctProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// ParallelizeApproxCountDistinctRule removes the partition merge in
+// front of approxCountDistinct so that each partition builds its own
+// sketches, which approxCountDistinct then merges by group key.
+type ParallelizeApproxCountDistinctRule struct{}
+
+func (ParallelizeApproxCountDistinctRule) Name() string {
+	return "ParallelizeApproxCountDistinctRule"
+}
+
+func (ParallelizeApproxCountDistinctRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessor(ApproxCountDistinctKind, plan.SingleSuccessor(ParallelMergeKind, plan.AnyMultiSuccessor()))
+}
+
+func (ParallelizeApproxCountDistinctRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	spec := node.ProcedureSpec().(*ApproxCountDistinctProcedureSpec)
+	if spec.ParallelMergeFactor > 1 {
+		return node, false, nil
+	}
+	merge := node.Predecessors()[0]
+	parallel := merge.Predecessors()[0]
+
+	newSpec := spec.Copy().(*ApproxCountDistinctProcedureSpec)
+	newSpec.ParallelMergeFactor = merge.ProcedureSpec().(*PartitionMergeProcedureSpec).Factor
+
+	// The planner attaches the successors of the node to the node
+	// that is returned, so the node is replaced by a copy that reads
+	// directly from the partitions.
+	newNode := plan.CreatePhysicalNode(node.ID(), newSpec)
+	i := plan.IndexOfNode(merge, parallel.Successors())
+	parallel.Successors()[i] = newNode
+	newNode.AddPredecessors(parallel)
+	return newNode, true, nil
+}
+
+func createApproxCountDistinctTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*ApproxCountDistinctProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewApproxCountDistinctTransformation(id, a.Parents(), s, a.Allocator())
+}
+
+type approxCountDistinctTransformation struct {
+	column    string
+	precision int
+}
+
+// NewApproxCountDistinctTransformation creates a transformation that estimates
+// the number of distinct non-null values in a column of each table with
+// a HyperLogLog sketch. When there are multiple parents, each of them
+// builds its own sketches and the sketches for a group key are merged.
+func NewApproxCountDistinctTransformation(id execute.DatasetID, parents []execute.DatasetID, spec *ApproxCountDistinctProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &approxCountDistinctTransformation{
+		column:    spec.Column,
+		precision: int(spec.Precision),
+	}
+	return execute.NewAggregateParallelTransformation(id, parents, t, mem)
+}
+
+type approxCountDistinctState struct {
+	inType flux.ColType
+	sketch *hll.Sketch
+}
+
+func (s *approxCountDistinctState) Close() error {
+	s.sketch.Release()
+	return nil
+}
+
+func (t *approxCountDistinctTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	if chunk.Key().HasCol(t.column) {
+		return nil, false, errors.New(codes.FailedPrecondition, "cannot aggregate columns that are part of the group key")
+	}
+	typ := chunk.Col(idx).Type
+
+	s, _ := state.(*approxCountDistinctState)
+	if s == nil {
+		sketch, err := hll.New(t.precision, mem)
+		if err != nil {
+			return nil, false, err
+		}
+		s = &approxCountDistinctState{
+			inType: typ,
+			sketch: sketch,
+		}
+	} else if s.inType != typ {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "schema collision detected: column %q is both of type %s and %s", t.column, s.inType, typ)
+	}
+
+	addValues(s.sketch, chunk.Values(idx))
+	return s, true, nil
+}
+
+// addValues adds a hash of each non-null value in the array to the sketch.
+func addValues(s *hll.Sketch, vs array.Array) {
+	var buf [8]byte
+	addUint64 := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		s.Add(xxhash.Sum64(buf[:]))
+	}
+	switch vs := vs.(type) {
+	case *array.Int:
+		for i, n := 0, vs.Len(); i < n; i++ {
+			if vs.IsValid(i) {
+				addUint64(uint64(vs.Value(i)))
+			}
+		}
+	case *array.Uint:
+		for i, n := 0, vs.Len(); i < n; i++ {
+			if vs.IsValid(i) {
+				addUint64(vs.Value(i))
+			}
+		}
+	case *array.Float:
+		for i, n := 0, vs.Len(); i < n; i++ {
+			if vs.IsValid(i) {
+				addUint64(math.Float64bits(vs.Value(i)))
+			}
+		}
+	case *array.String:
+		for i, n := 0, vs.Len(); i < n; i++ {
+			if vs.IsValid(i) {
+				s.AddString(vs.Value(i))
+			}
+		}
+	case *array.Boolean:
+		for i, n := 0, vs.Len(); i < n; i++ {
+			if vs.IsValid(i) {
+				if vs.Value(i) {
+					addUint64(1)
+				} else {
+					addUint64(0)
+				}
+			}
+		}
+	}
+}
+
+func (t *approxCountDistinctTransformation) Merge(into, from interface{}, mem memory.Allocator) (interface{}, error) {
+	intoState := into.(*approxCountDistinctState)
+	fromState := from.(*approxCountDistinctState)
+	if intoState.inType != fromState.inType {
+		return nil, errors.Newf(codes.FailedPrecondition, "schema collision detected: column %q is both of type %s and %s", t.column, intoState.inType, fromState.inType)
+	}
+	if err := intoState.sketch.Merge(fromState.sketch); err != nil {
+		return nil, err
+	}
+	return intoState, nil
+}
+
+func (t *approxCountDistinctTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	s := state.(*approxCountDistinctState)
+
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+1),
+	}
+	buffer.Values = make([]array.Array, 0, cap(buffer.Columns))
+	for j, col := range key.Cols() {
+		buffer.Columns = append(buffer.Columns, col)
+		buffer.Values = append(buffer.Values, arrow.Repeat(col.Type, key.Value(j), 1, mem))
+	}
+
+	b := array.NewIntBuilder(mem)
+	b.Append(int64(s.sketch.Count()))
+	buffer.Columns = append(buffer.Columns, flux.ColMeta{
+		Label: t.column,
+		Type:  flux.TInt,
+	})
+	buffer.Values = append(buffer.Values, b.NewArray())
+	return d.Process(table.ChunkFromBuffer(buffer))
+}
+
+func (t *approxCountDistinctTransformation) Close() error {
+	return nil
+}
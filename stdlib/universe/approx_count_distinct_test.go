@@ -0,0 +1,328 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestApproxCountDistinct_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "defaults",
+			Raw:  `from(bucket:"mybucket") |> approxCountDistinct()`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "approxCountDistinct1",
+						Spec: &universe.ApproxCountDistinctOpSpec{
+							Column:    "_value",
+							Precision: 14,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "approxCountDistinct1"},
+				},
+			},
+		},
+		{
+			Name: "column and precision",
+			Raw:  `from(bucket:"mybucket") |> approxCountDistinct(column: "host", precision: 10)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "approxCountDistinct1",
+						Spec: &universe.ApproxCountDistinctOpSpec{
+							Column:    "host",
+							Precision: 10,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "approxCountDistinct1"},
+				},
+			},
+		},
+		{
+			Name:    "precision out of range",
+			Raw:     `from(bucket:"mybucket") |> approxCountDistinct(precision: 20)`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestApproxCountDistinct_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.ApproxCountDistinctProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "strings",
+			spec: &universe.ApproxCountDistinctProcedureSpec{
+				Column:    "host",
+				Precision: 14,
+			},
+			data: []flux.Table{
+				&executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "t0", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), "a", "h1"},
+						{execute.Time(2), "a", "h2"},
+						{execute.Time(3), "a", "h1"},
+						{execute.Time(4), "a", nil},
+						{execute.Time(5), "a", "h3"},
+					},
+				},
+				&executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "t0", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), "b", "h1"},
+						{execute.Time(2), "b", "h1"},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "host", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"a", int64(3)},
+					},
+				},
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "t0", Type: flux.TString},
+						{Label: "host", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{"b", int64(1)},
+					},
+				},
+			},
+		},
+		{
+			name: "floats",
+			spec: &universe.ApproxCountDistinctProcedureSpec{
+				Column:    "_value",
+				Precision: 4,
+			},
+			data: []flux.Table{
+				&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), 1.5},
+						{execute.Time(2), 1.5},
+						{execute.Time(3), nil},
+						{execute.Time(4), 2.5},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{int64(2)},
+					},
+				},
+			},
+		},
+		{
+			name: "only nulls",
+			spec: &universe.ApproxCountDistinctProcedureSpec{
+				Column:    "_value",
+				Precision: 14,
+			},
+			data: []flux.Table{
+				&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), nil},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					ColMeta: []flux.ColMeta{
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{int64(0)},
+					},
+				},
+			},
+		},
+		{
+			name: "missing column",
+			spec: &universe.ApproxCountDistinctProcedureSpec{
+				Column:    "host",
+				Precision: 14,
+			},
+			data: []flux.Table{
+				&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{execute.Time(1), int64(1)},
+					},
+				},
+			},
+			wantErr: errors.New(codes.FailedPrecondition, `column "host" does not exist`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+					parents := []execute.DatasetID{executetest.RandomDatasetID()}
+					tr, d, err := universe.NewApproxCountDistinctTransformation(id, parents, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+func TestApproxCountDistinct_ProcessMerge(t *testing.T) {
+	spec := &universe.ApproxCountDistinctProcedureSpec{
+		Column:              "_value",
+		Precision:           14,
+		ParallelMergeFactor: 2,
+	}
+	newTable := func(vs ...string) flux.Table {
+		tbl := &executetest.Table{
+			KeyCols: []string{"t0"},
+			ColMeta: []flux.ColMeta{
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TString},
+			},
+		}
+		for _, v := range vs {
+			tbl.Data = append(tbl.Data, []interface{}{"a", v})
+		}
+		return tbl
+	}
+
+	parents := []execute.DatasetID{
+		executetest.RandomDatasetID(),
+		executetest.RandomDatasetID(),
+	}
+	alloc := &memory.ResourceAllocator{}
+	tr, d, err := universe.NewApproxCountDistinctTransformation(executetest.RandomDatasetID(), parents, spec, alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := executetest.NewDataStore()
+	d.SetTriggerSpec(plan.DefaultTriggerSpec)
+	d.AddTransformation(store)
+
+	// Each partition sees some of the values and the sketches
+	// are merged into one count for the group key.
+	if err := tr.Process(parents[0], newTable("x", "y", "z")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Process(parents[1], newTable("y", "z", "w")); err != nil {
+		t.Fatal(err)
+	}
+	tr.Finish(parents[0], nil)
+	tr.Finish(parents[1], nil)
+	if err := store.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := executetest.TablesFromCache(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*executetest.Table{{
+		KeyCols: []string{"t0"},
+		ColMeta: []flux.ColMeta{
+			{Label: "t0", Type: flux.TString},
+			{Label: "_value", Type: flux.TInt},
+		},
+		Data: [][]interface{}{
+			{"a", int64(4)},
+		},
+	}}
+	executetest.NormalizeTables(got)
+	executetest.NormalizeTables(want)
+	if !cmp.Equal(want, got) {
+		t.Errorf("unexpected tables -want/+got\n%s", cmp.Diff(want, got))
+	}
+
+	// The procedure requires its predecessors to run in
+	// parallel so the planner does not merge them first.
+	if attrs := spec.RequiredAttributes(); len(attrs) != 1 {
+		t.Fatalf("expected one set of required attributes, got %d", len(attrs))
+	} else if _, ok := attrs[0][plan.ParallelRunKey]; !ok {
+		t.Error("expected the parallel run attribute to be required")
+	}
+	if _, ok := spec.OutputAttributes()[plan.ParallelMergeKey]; !ok {
+		t.Error("expected the parallel merge attribute to be produced")
+	}
+}
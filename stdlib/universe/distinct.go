@@ -5,6 +5,7 @@ import (
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/spill"
 	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/runtime"
 	"github.com/influxdata/flux/values"
@@ -84,6 +85,7 @@ func createDistinctTransformation(id execute.DatasetID, mode execute.Accumulatio
 		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
 	}
 	cache := execute.NewTableBuilderCache(a.Allocator())
+	cache.SetSpillDir(execute.GetSpillDir(a.Context()))
 	d := execute.NewDataset(id, mode, cache)
 	t := NewDistinctTransformation(d, cache, s)
 	return t, d, nil
@@ -218,6 +220,12 @@ func (t *distinctTransformation) Process(id execute.DatasetID, tbl flux.Table) e
 
 	j := execute.ColIdx(t.column, tbl.Cols())
 	return tbl.Do(func(cr flux.ColReader) error {
+		// Write the rows that have been built to disk if
+		// there is not enough memory to hold more of them.
+		if err := execute.SpillTableBuilders(t.cache, 2*spill.Size(cr)); err != nil {
+			return err
+		}
+
 		l := cr.Len()
 
 		for i := 0; i < l; i++ {
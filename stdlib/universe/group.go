@@ -14,6 +14,7 @@ import (
 	"github.com/influxdata/flux/internal/execute/dataset"
 	"github.com/influxdata/flux/internal/execute/table"
 	"github.com/influxdata/flux/internal/feature"
+	"github.com/influxdata/flux/internal/spill"
 	"github.com/influxdata/flux/interpreter"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
@@ -185,25 +186,31 @@ type groupTransformation struct {
 	d     execute.Dataset
 	cache table.BuilderCache
 	mem   memory.Allocator
+	dir   string
 
 	mode flux.GroupMode
 	keys []string
 }
 
 func NewGroupTransformation(ctx context.Context, spec *GroupProcedureSpec, id execute.DatasetID, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	dir := execute.GetSpillDir(ctx)
 	t := &groupTransformation{
 		cache: table.BuilderCache{
 			New: func(key flux.GroupKey) table.Builder {
-				return table.NewBufferedBuilder(key, mem)
+				return spill.NewBuilder(key, dir, mem)
 			},
 		},
 		mem:  mem,
+		dir:  dir,
 		mode: spec.GroupMode,
 		keys: spec.GroupKeys,
 	}
 	t.d = dataset.New(id, &t.cache)
 	sort.Strings(t.keys)
-	if feature.GroupTransformationGroup().Enabled(ctx) {
+	// The group transformation interface passes each chunk on to the
+	// next transformation so it cannot spill. The groups are buffered
+	// here instead when spilling is enabled.
+	if dir == "" && feature.GroupTransformationGroup().Enabled(ctx) {
 		a := &groupTransformationAdapter{t: t}
 		gt, d, err := execute.NewGroupTransformation(id, a, mem)
 		if err != nil {
@@ -224,8 +231,8 @@ func (t *groupTransformation) Process(id execute.DatasetID, tbl flux.Table) erro
 	if key, ok, err := t.getTableKey(tbl.Key(), tbl.Cols()); err != nil {
 		return err
 	} else if ok {
-		ab, _ := table.GetBufferedBuilder(key, &t.cache)
-		return t.appendTable(ab, tbl)
+		b, _ := spill.GetBuilder(key, &t.cache)
+		return t.appendTable(b, tbl)
 	}
 
 	// We are grouping by something that is not within the group key,
@@ -285,9 +292,22 @@ func (t *groupTransformation) getTableKey(key flux.GroupKey, cols []flux.ColMeta
 	return execute.NewGroupKey(cols, vs), true, nil
 }
 
-func (t *groupTransformation) appendTable(ab *table.BufferedBuilder, tbl flux.Table) error {
-	// Read the table and append each of the columns.
-	return ab.AppendTable(tbl)
+func (t *groupTransformation) appendTable(b *spill.Builder, tbl flux.Table) error {
+	if err := b.AppendCols(tbl.Cols()); err != nil {
+		return err
+	}
+
+	// Read the table and append each of the buffers. If there is
+	// not enough memory to read two more buffers like this one,
+	// the buffers of every group are written to disk first.
+	return tbl.Do(func(cr flux.ColReader) error {
+		if spill.ShouldSpill(t.dir, t.mem, 2*spill.Size(cr)) {
+			if err := spill.SpillCache(&t.cache); err != nil {
+				return err
+			}
+		}
+		return b.AppendBuffer(cr)
+	})
 }
 
 func (t *groupTransformation) groupChunkByRow(tbl table.Chunk, d *execute.TransportDataset, mem arrowmem.Allocator) error {
@@ -390,8 +410,8 @@ func (t *groupTransformation) groupByRow(tbl flux.Table) error {
 			return err
 		}
 
-		ab, _ := table.GetBufferedBuilder(key, &t.cache)
-		return t.appendTable(ab, tbl)
+		b, _ := spill.GetBuilder(key, &t.cache)
+		return t.appendTable(b, tbl)
 	})
 }
 
@@ -771,6 +771,73 @@ func TestGroup_Process(t *testing.T) {
 	}
 }
 
+func TestGroup_Spill(t *testing.T) {
+	// Limit the memory so the input does not fit
+	// and the groups have to be spilled to disk.
+	limit := int64(256 * 1024)
+	mem := &memory.ResourceAllocator{Limit: &limit}
+
+	seed := int64(1)
+	input, err := gen.Input(context.Background(), gen.Schema{
+		NumPoints: 10000,
+		Seed:      &seed,
+		Alloc:     mem,
+		Tags: []gen.Tag{
+			{Name: "_measurement", Cardinality: 1},
+			{Name: "t0", Cardinality: 8},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, d, err := universe.NewGroupTransformation(
+		spillContext(t),
+		&universe.GroupProcedureSpec{
+			GroupMode: flux.GroupModeBy,
+			GroupKeys: []string{"_measurement"},
+		},
+		executetest.RandomDatasetID(),
+		mem,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ntables, n int
+	sink := &spillSink{
+		fn: func(tbl flux.Table) error {
+			ntables++
+			return tbl.Do(func(cr flux.ColReader) error {
+				n += cr.Len()
+				return nil
+			})
+		},
+	}
+	d.AddTransformation(sink)
+
+	parentID := executetest.RandomDatasetID()
+	if err := input.Do(func(tbl flux.Table) error {
+		return tr.Process(parentID, tbl)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tr.Finish(parentID, nil)
+
+	if sink.err != nil {
+		t.Fatal(sink.err)
+	}
+	if want, got := 1, ntables; want != got {
+		t.Errorf("unexpected number of tables -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if want, got := 80000, n; want != got {
+		t.Errorf("unexpected number of rows -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("memory was not released: %d bytes", got)
+	}
+}
+
 func TestMergeGroupRule(t *testing.T) {
 	var (
 		from      = &influxdb.FromProcedureSpec{}
@@ -58,7 +58,6 @@ func (s *LimitOpSpec) Kind() flux.OperationKind {
 }
 
 type LimitProcedureSpec struct {
-	plan.DefaultCost
 	N      int64 `json:"n"`
 	Offset int64 `json:"offset"`
 }
@@ -83,6 +82,28 @@ func (s *LimitProcedureSpec) Copy() plan.ProcedureSpec {
 	return ns
 }
 
+func (s *LimitProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	in := plan.SumStatistics(inStats)
+	if !in.IsKnown() {
+		return plan.Cost{}, plan.Statistics{}
+	}
+	rows := in.RowsPerGroup() - s.Offset
+	if rows > s.N {
+		rows = s.N
+	} else if rows < 0 {
+		rows = 0
+	}
+	groups := in.GroupCardinality
+	if groups < 1 {
+		groups = 1
+	}
+	out := plan.Statistics{
+		Cardinality:      rows * groups,
+		GroupCardinality: in.GroupCardinality,
+	}
+	return plan.Cost{CPU: in.Cardinality}, out
+}
+
 // TriggerSpec implements plan.TriggerAwareProcedureSpec
 func (s *LimitProcedureSpec) TriggerSpec() plan.TriggerSpec {
 	return plan.NarrowTransformationTriggerSpec{}
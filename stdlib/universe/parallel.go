@@ -3,12 +3,20 @@ package universe
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"sync"
 
+	"github.com/apache/arrow/go/v7/arrow/bitutil"
+	arrowmem "github.com/apache/arrow/go/v7/arrow/memory"
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
 	"github.com/opentracing/opentracing-go"
@@ -16,11 +24,16 @@ import (
 
 const (
 	ParallelMergeKind = "ParallelMergeKind"
+	PartitionKind     = "partition"
 )
 
 type PartitionMergeProcedureSpec struct {
 	plan.DefaultCost
 	Factor int
+	// Mode is the way the merged data was partitioned.
+	// Data partitioned by time is split across the partitions
+	// so the tables for each group key are combined in partition order.
+	Mode plan.PartitionMode
 }
 
 func (o *PartitionMergeProcedureSpec) OutputAttributes() plan.PhysicalAttributes {
@@ -45,11 +58,17 @@ func (o *PartitionMergeProcedureSpec) Copy() plan.ProcedureSpec {
 	return &PartitionMergeProcedureSpec{
 		DefaultCost: o.DefaultCost,
 		Factor:      o.Factor,
+		Mode:        o.Mode,
 	}
 }
 
 func init() {
 	execute.RegisterTransformation(ParallelMergeKind, createPartitionMergeTransformation)
+	execute.RegisterTransformation(PartitionKind, createPartitionTransformation)
+	plan.RegisterParallelizeRules(
+		ParallelizeSourceRule{},
+		ParallelMergeRule{},
+	)
 }
 
 func createPartitionMergeTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
@@ -80,12 +99,21 @@ type PartitionMergeTransformation struct {
 	mu               sync.Mutex
 	predecessorState map[execute.DatasetID]*parallelPredecessorState
 	finished         bool
+
+	// combine is set when the tables for a group key are split
+	// across the partitions. The partitions of each table are
+	// buffered in groups and combined once every predecessor
+	// has finished.
+	combine bool
+	groups  *execute.GroupLookup
 }
 
 type parallelPredecessorState struct {
 	mark       execute.Time
 	processing execute.Time
 	finished   bool
+	// index is the partition read from the predecessor.
+	index int
 }
 
 func (t *PartitionMergeTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
@@ -97,20 +125,29 @@ func NewPartitionMergeTransformation(ctx context.Context, dataset *execute.Passt
 	span, ctx = opentracing.StartSpanFromContext(ctx, "PartitionMergeTransformation.Process")
 
 	predecessorState := make(map[execute.DatasetID]*parallelPredecessorState, len(predecessors))
-	for _, id := range predecessors {
-		predecessorState[id] = new(parallelPredecessorState)
+	for i, id := range predecessors {
+		predecessorState[id] = &parallelPredecessorState{index: i}
 	}
 
-	return &PartitionMergeTransformation{
+	t := &PartitionMergeTransformation{
 		ctx:              ctx,
 		dataset:          dataset,
 		span:             span,
 		alloc:            alloc,
 		predecessorState: predecessorState,
-	}, nil
+	}
+	if spec.Mode == plan.PartitionByTime {
+		t.combine = true
+		t.groups = execute.NewGroupLookup()
+	}
+	return t, nil
 }
 
 func (t *PartitionMergeTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	if t.combine {
+		return t.buffer(id, tbl)
+	}
+
 	passthroughBuilder := table.NewBufferedBuilder(tbl.Key(), t.alloc)
 
 	err := tbl.Do(func(er flux.ColReader) error {
@@ -199,6 +236,371 @@ func (t *PartitionMergeTransformation) Finish(id execute.DatasetID, err error) {
 	}
 
 	if t.finished {
+		if t.combine {
+			if err == nil {
+				err = t.flush()
+			}
+			t.release()
+		}
 		t.dataset.Finish(err)
 	}
 }
+
+// buffer appends the table to the buffered partition
+// of its group key that is read from the predecessor.
+func (t *PartitionMergeTransformation) buffer(id execute.DatasetID, tbl flux.Table) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.finished {
+		tbl.Done()
+		return nil
+	}
+
+	partitions := t.groups.LookupOrCreate(tbl.Key(), func() interface{} {
+		return make([]*table.BufferedBuilder, len(t.predecessorState))
+	}).([]*table.BufferedBuilder)
+
+	index := t.predecessorState[id].index
+	if partitions[index] == nil {
+		partitions[index] = table.NewBufferedBuilder(tbl.Key(), t.alloc)
+	}
+	return partitions[index].AppendTable(tbl)
+}
+
+// flush combines the partitions of each group key in
+// partition order and sends the resulting tables to the dataset.
+func (t *PartitionMergeTransformation) flush() error {
+	return t.groups.Range(func(key flux.GroupKey, value interface{}) error {
+		b := table.NewBufferedBuilder(key, t.alloc)
+		for _, p := range value.([]*table.BufferedBuilder) {
+			if p == nil {
+				continue
+			}
+			tbl, err := p.Table()
+			if err != nil {
+				b.Release()
+				return err
+			}
+			if err := b.AppendTable(tbl); err != nil {
+				b.Release()
+				return err
+			}
+		}
+		out, err := b.Table()
+		if err != nil {
+			return err
+		}
+		return t.dataset.Process(out)
+	})
+}
+
+func (t *PartitionMergeTransformation) release() {
+	_ = t.groups.Range(func(key flux.GroupKey, value interface{}) error {
+		for _, p := range value.([]*table.BufferedBuilder) {
+			if p != nil {
+				p.Release()
+			}
+		}
+		return nil
+	})
+	t.groups.Clear()
+}
+
+// PartitionProcedureSpec divides the tables produced by its predecessor
+// between Factor parallel copies of the plan that follows it.
+type PartitionProcedureSpec struct {
+	plan.DefaultCost
+	Factor int
+	Mode   plan.PartitionMode
+}
+
+func (o *PartitionProcedureSpec) OutputAttributes() plan.PhysicalAttributes {
+	return plan.PhysicalAttributes{
+		plan.ParallelRunKey: plan.ParallelRunAttribute{Factor: o.Factor},
+	}
+}
+
+func (o *PartitionProcedureSpec) Kind() plan.ProcedureKind {
+	return PartitionKind
+}
+
+func (o *PartitionProcedureSpec) Copy() plan.ProcedureSpec {
+	return &PartitionProcedureSpec{
+		DefaultCost: o.DefaultCost,
+		Factor:      o.Factor,
+		Mode:        o.Mode,
+	}
+}
+
+func (o *PartitionProcedureSpec) PlanDetails() string {
+	return fmt.Sprintf("partitionBy: %v", o.Mode)
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (o *PartitionProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createPartitionTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*PartitionProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	popts := a.ParallelOpts()
+	t := &partitionTransformation{
+		group:  popts.Group,
+		factor: popts.Factor,
+	}
+	if s.Mode == plan.PartitionByTime {
+		t.bounds = a.StreamContext().Bounds()
+	}
+	return execute.NewNarrowTransformation(id, t, a.Allocator())
+}
+
+// partitionTransformation passes on the part of its input
+// that belongs to a single partition.
+type partitionTransformation struct {
+	group  int
+	factor int
+	// bounds is set when the rows of each table
+	// are partitioned by time.
+	bounds *execute.Bounds
+}
+
+func (t *partitionTransformation) Process(chunk table.Chunk, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	if t.bounds != nil {
+		if idx := chunk.Index(execute.DefaultTimeColLabel); idx >= 0 {
+			return t.processTime(chunk, idx, d, mem)
+		}
+	}
+
+	if partitionForKey(chunk.Key(), t.factor) != t.group {
+		return nil
+	}
+	chunk.Retain()
+	return d.Process(chunk)
+}
+
+// processTime passes on the rows of the chunk whose time
+// falls in the part of the bounds covered by this partition.
+func (t *partitionTransformation) processTime(chunk table.Chunk, timeIdx int, d *execute.TransportDataset, mem arrowmem.Allocator) error {
+	if chunk.Len() == 0 {
+		// Empty tables are only sent to the first partition.
+		if t.group != 0 {
+			return nil
+		}
+		chunk.Retain()
+		return d.Process(chunk)
+	}
+
+	times := chunk.Ints(timeIdx)
+	bitset := arrowmem.NewResizableBuffer(mem)
+	bitset.Resize(times.Len())
+	defer bitset.Release()
+
+	n := 0
+	for i := 0; i < times.Len(); i++ {
+		in := times.IsValid(i) && partitionForTime(execute.Time(times.Value(i)), *t.bounds, t.factor) == t.group
+		if !times.IsValid(i) {
+			// Rows without a time belong to the first partition.
+			in = t.group == 0
+		}
+		if in {
+			n++
+		}
+		bitutil.SetBitTo(bitset.Buf(), i, in)
+	}
+
+	if n == 0 {
+		return nil
+	} else if n == chunk.Len() {
+		chunk.Retain()
+		return d.Process(chunk)
+	}
+
+	vs := make([]array.Array, chunk.NCols())
+	for j, col := range chunk.Cols() {
+		arr := chunk.Values(j)
+		if chunk.Key().HasCol(col.Label) {
+			vs[j] = arrow.Slice(arr, 0, int64(n))
+			continue
+		}
+		vs[j] = arrowutil.Filter(arr, bitset.Bytes(), mem)
+	}
+	return d.Process(table.ChunkFromBuffer(arrow.TableBuffer{
+		GroupKey: chunk.Key(),
+		Columns:  chunk.Cols(),
+		Values:   vs,
+	}))
+}
+
+func (t *partitionTransformation) Close() error { return nil }
+
+// partitionForKey returns the partition that the tables with
+// the given group key are sent to.
+func partitionForKey(key flux.GroupKey, factor int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key.String()))
+	return int(h.Sum64() % uint64(factor))
+}
+
+// partitionForTime returns the partition whose equal
+// share of the bounds contains the given time.
+func partitionForTime(ts execute.Time, bounds execute.Bounds, factor int) int {
+	width := (bounds.Stop - bounds.Start) / execute.Time(factor)
+	if width <= 0 || ts < bounds.Start {
+		return 0
+	}
+	p := int((ts - bounds.Start) / width)
+	if p >= factor {
+		return factor - 1
+	}
+	return p
+}
+
+// parallelNarrowKinds are the kinds of the stateless narrow transformations
+// that can process each partition of the data independently.
+var parallelNarrowKinds = []plan.ProcedureKind{
+	FilterKind,
+	MapKind,
+	vectorizedMapKind,
+	SchemaMutationKind,
+}
+
+func isParallelNarrow(node plan.Node) bool {
+	for _, kind := range parallelNarrowKinds {
+		if node.Kind() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// requiresParallelRun reports whether the node requires
+// its predecessors to run in parallel.
+func requiresParallelRun(node plan.Node) bool {
+	ra, ok := node.ProcedureSpec().(plan.RequiredAttributer)
+	if !ok {
+		return false
+	}
+	for _, attrs := range ra.RequiredAttributes() {
+		if _, ok := attrs[plan.ParallelRunKey]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParallelizeSourceRule inserts a partition node between a source and
+// a stateless narrow transformation that reads from it so that the
+// transformation runs in parallel. The rule only applies when
+// parallelism is enabled for the planner.
+type ParallelizeSourceRule struct{}
+
+func (ParallelizeSourceRule) Name() string {
+	return "ParallelizeSourceRule"
+}
+
+func (ParallelizeSourceRule) Pattern() plan.Pattern {
+	return plan.MultiSuccessorOneOf(parallelNarrowKinds, plan.AnyMultiSuccessor())
+}
+
+func (ParallelizeSourceRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	p := plan.ParallelismFromContext(ctx)
+	if !p.Enabled() {
+		return node, false, nil
+	}
+
+	source := node.Predecessors()[0]
+	if len(source.Predecessors()) > 0 {
+		return node, false, nil
+	}
+	if attr := plan.GetOutputAttribute(source, plan.ParallelRunKey); attr != nil {
+		// The source already produces parallel data.
+		return node, false, nil
+	}
+
+	partition := plan.CreateUniquePhysicalNode(ctx, "partition", &PartitionProcedureSpec{
+		Factor: p.Factor,
+		Mode:   p.Mode,
+	})
+	i := plan.IndexOfNode(node, source.Successors())
+	source.Successors()[i] = partition
+	partition.AddPredecessors(source)
+	partition.AddSuccessors(node)
+	node.Predecessors()[0] = partition
+	return node, true, nil
+}
+
+// ParallelMergeRule merges the partitions of a parallel plan before they
+// reach a transformation that is sensitive to the order of its input or
+// the tables in each group. The rule only applies when parallelism is
+// enabled for the planner.
+type ParallelMergeRule struct{}
+
+func (ParallelMergeRule) Name() string {
+	return "ParallelMergeRule"
+}
+
+func (ParallelMergeRule) Pattern() plan.Pattern {
+	kinds := append([]plan.ProcedureKind{PartitionKind}, parallelNarrowKinds...)
+	return plan.MultiSuccessorOneOf(kinds, plan.AnyMultiSuccessor())
+}
+
+func (ParallelMergeRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node, bool, error) {
+	if !plan.ParallelismFromContext(ctx).Enabled() {
+		return node, false, nil
+	}
+
+	attr := plan.GetOutputAttribute(node, plan.ParallelRunKey)
+	if attr == nil {
+		return node, false, nil
+	}
+
+	merge := len(node.Successors()) == 0
+	for _, succ := range node.Successors() {
+		if requiresParallelRun(succ) {
+			// The partitions are already merged.
+			return node, false, nil
+		} else if !isParallelNarrow(succ) {
+			merge = true
+		}
+	}
+	if !merge {
+		return node, false, nil
+	}
+
+	// The planner attaches the successors of the node to the node that is
+	// returned, so the node is replaced by a copy whose only successor is
+	// the merge node.
+	parallelNode := plan.CreatePhysicalNode(node.ID(), node.ProcedureSpec().(plan.PhysicalProcedureSpec))
+	parallelNode.Source = node.(*plan.PhysicalPlanNode).Source
+	parallelNode.AddPredecessors(node.Predecessors()...)
+	for _, pred := range node.Predecessors() {
+		i := plan.IndexOfNode(node, pred.Successors())
+		pred.Successors()[i] = parallelNode
+	}
+
+	mergeNode := plan.CreateUniquePhysicalNode(ctx, "partitionMerge", &PartitionMergeProcedureSpec{
+		Factor: attr.(plan.ParallelRunAttribute).Factor,
+		Mode:   partitionMode(node),
+	})
+	parallelNode.AddSuccessors(mergeNode)
+	mergeNode.AddPredecessors(parallelNode)
+	return mergeNode, true, nil
+}
+
+// partitionMode returns the mode of the partition node that
+// the parallel data produced by the node was read from.
+func partitionMode(node plan.Node) plan.PartitionMode {
+	for {
+		if spec, ok := node.ProcedureSpec().(*PartitionProcedureSpec); ok {
+			return spec.Mode
+		}
+		if len(node.Predecessors()) != 1 {
+			return plan.PartitionByGroupKey
+		}
+		node = node.Predecessors()[0]
+	}
+}
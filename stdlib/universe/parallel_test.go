@@ -0,0 +1,274 @@
+package universe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/plan/plantest"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+// parallelSourceSpec is a source that already produces parallel data.
+type parallelSourceSpec struct {
+	plantest.MockProcedureSpec
+}
+
+func (parallelSourceSpec) OutputAttributes() plan.PhysicalAttributes {
+	return plan.PhysicalAttributes{
+		plan.ParallelRunKey: plan.ParallelRunAttribute{Factor: 2},
+	}
+}
+
+func (s parallelSourceSpec) Copy() plan.ProcedureSpec {
+	return s
+}
+
+func TestParallelizeRules(t *testing.T) {
+	ctx := plan.ContextWithParallelism(context.Background(), plan.Parallelism{Factor: 4})
+	timeCtx := plan.ContextWithParallelism(context.Background(), plan.Parallelism{
+		Factor: 4,
+		Mode:   plan.PartitionByTime,
+	})
+
+	from := plantest.MockProcedureSpec{}
+	drop := &universe.SchemaMutationProcedureSpec{Mutations: []universe.SchemaMutation{}}
+	mapSpec := &universe.MapProcedureSpec{}
+	sum := &universe.SumProcedureSpec{}
+	approx := &universe.ApproxCountDistinctProcedureSpec{Column: "_value", Precision: 14}
+	rules := []plan.Rule{
+		universe.ParallelizeSourceRule{},
+		universe.ParallelMergeRule{},
+		universe.ParallelizeApproxCountDistinctRule{},
+	}
+
+	tests := []plantest.RuleTestCase{
+		{
+			Name:    "Disabled",
+			Context: context.Background(),
+			Rules:   rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("drop", drop),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+			},
+			NoChange: true,
+		},
+		{
+			Name:    "NarrowChain",
+			Context: ctx,
+			Rules:   rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("drop", drop),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+				},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("drop", drop),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+					{3, 4},
+					{4, 5},
+				},
+			},
+		},
+		{
+			Name:    "MergeResult",
+			Context: ctx,
+			Rules:   rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("drop", drop),
+				},
+				Edges: [][2]int{
+					{0, 1},
+				},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("drop", drop),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4}),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+				},
+			},
+		},
+		{
+			Name:    "PartitionByTime",
+			Context: timeCtx,
+			Rules:   rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{
+						Factor: 4,
+						Mode:   plan.PartitionByTime,
+					}),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{
+						Factor: 4,
+						Mode:   plan.PartitionByTime,
+					}),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+					{3, 4},
+				},
+			},
+		},
+		{
+			Name:    "ApproxCountDistinct",
+			Context: ctx,
+			Rules:   rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("approxCountDistinct", approx),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+			},
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("approxCountDistinct", &universe.ApproxCountDistinctProcedureSpec{
+						Column:              "_value",
+						Precision:           14,
+						ParallelMergeFactor: 4,
+					}),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+				},
+			},
+		},
+		{
+			Name:    "ApproxCountDistinctSharedInput",
+			Context: ctx,
+			Rules:   rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("approxCountDistinct", approx),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{1, 3},
+				},
+			},
+			// The merged partitions are also read by sum
+			// so the merge cannot be removed.
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("partition", &universe.PartitionProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 4}),
+					plan.CreatePhysicalNode("approxCountDistinct", approx),
+					plan.CreatePhysicalNode("sum", sum),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+					{2, 3},
+					{3, 4},
+					{3, 5},
+				},
+			},
+		},
+		{
+			Name:    "NotNarrow",
+			Context: ctx,
+			Rules:   rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", from),
+					plan.CreatePhysicalNode("sum", sum),
+					plan.CreatePhysicalNode("map", mapSpec),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+			},
+			NoChange: true,
+		},
+		{
+			Name:    "SourceAlreadyParallel",
+			Context: ctx,
+			Rules:   rules,
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("from", parallelSourceSpec{}),
+					plan.CreatePhysicalNode("map", mapSpec),
+					plan.CreatePhysicalNode("partitionMerge", &universe.PartitionMergeProcedureSpec{Factor: 2}),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+			},
+			NoChange: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			plantest.PhysicalRuleTestHelper(t, &tc)
+		})
+	}
+}
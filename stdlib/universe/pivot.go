@@ -3,6 +3,7 @@ package universe
 import (
 	"context"
 	"fmt"
+	"io"
 	"sort"
 	"strconv"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/table"
 	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/spill"
 	"github.com/influxdata/flux/interpreter"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/plan"
@@ -153,10 +155,7 @@ func createPivotTransformation(id execute.DatasetID, mode execute.AccumulationMo
 	if !ok {
 		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
 	}
-
-	cache := execute.NewTableBuilderCache(a.Allocator())
-	d := execute.NewDataset(id, mode, cache)
-	t := NewPivotTransformation(d, cache, s)
+	t, d := newPivotTransformation(a.Context(), s, id, a.Allocator())
 	return t, d, nil
 }
 
@@ -165,22 +164,51 @@ type rowCol struct {
 	nextRow int
 }
 
+// pivotTransformation buffers the input of each output table until
+// all of the input has been read. The buffers are written to disk
+// when the allocator runs out of memory. When the transformation
+// finishes, the tables are pivoted one at a time.
 type pivotTransformation struct {
 	execute.ExecutionNode
 	d     execute.Dataset
 	cache execute.TableBuilderCache
 	spec  PivotProcedureSpec
+	mem   memory.Allocator
+	dir   string
+
+	// out and tables are set when each table is passed downstream
+	// as soon as it has been pivoted instead of through the dataset.
+	// tables is the cache that holds the pivoted tables.
+	out    *execute.PassthroughDataset
+	tables execute.DataCache
+
+	// groups holds the buffered input for each output group key.
+	groups *execute.GroupLookup
+
+	watermark  execute.Time
+	processing execute.Time
+
 	// for each table, we need to store a map to keep track of which rows/columns have already been created.
 	colKeyMaps map[string]map[string]int
 	rowKeyMaps map[string]map[string]int
 	nextRowCol map[string]rowCol
 }
 
+// pivotGroup holds the buffered input of one output table.
+type pivotGroup struct {
+	buf *spill.Buffer
+	// size is the number of bytes of input that were buffered.
+	// It is used to estimate the size of the pivoted table.
+	size int
+}
+
 func NewPivotTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *PivotProcedureSpec) *pivotTransformation {
 	t := &pivotTransformation{
 		d:          d,
 		cache:      cache,
 		spec:       *spec,
+		mem:        memory.DefaultAllocator,
+		groups:     execute.NewGroupLookup(),
 		colKeyMaps: make(map[string]map[string]int),
 		rowKeyMaps: make(map[string]map[string]int),
 		nextRowCol: make(map[string]rowCol),
@@ -188,16 +216,45 @@ func NewPivotTransformation(d execute.Dataset, cache execute.TableBuilderCache,
 	return t
 }
 
+// newPivotTransformation constructs a pivot that spills its buffered
+// input to the spill directory and passes each table downstream as
+// soon as it has been pivoted.
+func newPivotTransformation(ctx context.Context, spec *PivotProcedureSpec, id execute.DatasetID, mem memory.Allocator) (*pivotTransformation, *execute.PassthroughDataset) {
+	d := execute.NewPassthroughDataset(id)
+	cache := execute.NewTableBuilderCache(mem)
+	cache.SetTriggerSpec(plan.DefaultTriggerSpec)
+	t := NewPivotTransformation(d, cache, spec)
+	t.mem = mem
+	t.dir = execute.GetSpillDir(ctx)
+	t.out = d
+	t.tables = cache
+	return t, d
+}
+
 func (t *pivotTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
 	return t.d.RetractTable(key)
 }
 
-func (t *pivotTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+// pivotLayout holds the location of the pivot columns in a table.
+type pivotLayout struct {
+	rowKeyIndex   map[string]int
+	colKeyIndex   map[string]int
+	valueColIndex int
+	valueColType  flux.ColType
+	// cols are the columns that are copied to the output
+	// and colMap holds their index in the input.
+	cols   []flux.ColMeta
+	colMap []int
+	key    flux.GroupKey
+}
+
+// layout finds the pivot columns in a table with the given columns and key.
+func (t *pivotTransformation) layout(tblCols []flux.ColMeta, tblKey flux.GroupKey) (*pivotLayout, error) {
 	rowKeyIndex := make(map[string]int)
 	for _, v := range t.spec.RowKey {
-		idx := execute.ColIdx(v, tbl.Cols())
+		idx := execute.ColIdx(v, tblCols)
 		if idx < 0 {
-			return errors.Newf(codes.Invalid, "specified row key column does not exist in table: %v", v)
+			return nil, errors.Newf(codes.Invalid, "specified row key column does not exist in table: %v", v)
 		}
 		rowKeyIndex[v] = idx
 	}
@@ -211,29 +268,29 @@ func (t *pivotTransformation) Process(id execute.DatasetID, tbl flux.Table) erro
 		colKeyIndex[v] = -1
 	}
 
-	cols := make([]flux.ColMeta, 0, len(tbl.Cols()))
-	keyCols := make([]flux.ColMeta, 0, len(tbl.Key().Cols()))
-	keyValues := make([]values.Value, 0, len(tbl.Key().Cols()))
+	cols := make([]flux.ColMeta, 0, len(tblCols))
+	keyCols := make([]flux.ColMeta, 0, len(tblKey.Cols()))
+	keyValues := make([]values.Value, 0, len(tblKey.Cols()))
 	newIDX := 0
-	colMap := make([]int, len(tbl.Cols()))
+	colMap := make([]int, len(tblCols))
 
-	for colIDX, v := range tbl.Cols() {
+	for colIDX, v := range tblCols {
 		if _, ok := colKeyIndex[v.Label]; !ok && v.Label != t.spec.ValueColumn {
 			// the columns we keep are: group key columns not in the column key and row key columns
-			if tbl.Key().HasCol(v.Label) {
+			if tblKey.HasCol(v.Label) {
 				colMap[newIDX] = colIDX
 				newIDX++
-				keyCols = append(keyCols, tbl.Cols()[colIDX])
-				cols = append(cols, tbl.Cols()[colIDX])
-				keyValues = append(keyValues, tbl.Key().LabelValue(v.Label))
+				keyCols = append(keyCols, tblCols[colIDX])
+				cols = append(cols, tblCols[colIDX])
+				keyValues = append(keyValues, tblKey.LabelValue(v.Label))
 			} else if _, ok := rowKeyIndex[v.Label]; ok {
-				cols = append(cols, tbl.Cols()[colIDX])
+				cols = append(cols, tblCols[colIDX])
 				colMap[newIDX] = colIDX
 				newIDX++
 			}
 		} else if v.Label == t.spec.ValueColumn {
 			valueColIndex = colIDX
-			valueColType = tbl.Cols()[colIDX].Type
+			valueColType = tblCols[colIDX].Type
 		} else {
 			// we need the location of the colKey columns in the original table
 			colKeyIndex[v.Label] = colIDX
@@ -241,104 +298,163 @@ func (t *pivotTransformation) Process(id execute.DatasetID, tbl flux.Table) erro
 	}
 
 	if valueColIndex < 0 {
-		return errors.Newf(codes.Invalid, "specified value column does not exist in table: %v", t.spec.ValueColumn)
+		return nil, errors.Newf(codes.Invalid, "specified value column does not exist in table: %v", t.spec.ValueColumn)
 	}
 
 	for k, v := range colKeyIndex {
 		if v < 0 {
-			return errors.Newf(codes.Invalid, "specified column does not exist in table: %v", k)
+			return nil, errors.Newf(codes.Invalid, "specified column does not exist in table: %v", k)
 		}
 	}
 
-	newGroupKey := execute.NewGroupKey(keyCols, keyValues)
-	builder, created := t.cache.TableBuilder(newGroupKey)
-	groupKeyString := newGroupKey.String()
-	if created {
-		for _, c := range cols {
-			_, err := builder.AddCol(c)
-			if err != nil {
-				return err
-			}
+	return &pivotLayout{
+		rowKeyIndex:   rowKeyIndex,
+		colKeyIndex:   colKeyIndex,
+		valueColIndex: valueColIndex,
+		valueColType:  valueColType,
+		cols:          cols,
+		colMap:        colMap,
+		key:           execute.NewGroupKey(keyCols, keyValues),
+	}, nil
+}
 
-		}
-		t.colKeyMaps[groupKeyString] = make(map[string]int)
-		t.rowKeyMaps[groupKeyString] = make(map[string]int)
-		t.nextRowCol[groupKeyString] = rowCol{nextCol: len(cols), nextRow: 0}
+func (t *pivotTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	l, err := t.layout(tbl.Cols(), tbl.Key())
+	if err != nil {
+		return err
 	}
 
+	g := t.groups.LookupOrCreate(l.key, func() interface{} {
+		return &pivotGroup{buf: spill.NewBuffer(l.key, t.dir, t.mem)}
+	}).(*pivotGroup)
+
 	return tbl.Do(func(cr flux.ColReader) error {
-		for row := 0; row < cr.Len(); row++ {
-			rowKey := ""
-			colKey := ""
-			for _, rk := range t.spec.RowKey {
-				j := rowKeyIndex[rk]
-				c := cr.Cols()[j]
-				rowKey += valueToStr(cr, c, row, j)
-			}
+		// Write the buffered input to disk if there is
+		// not enough memory left to hold this buffer too.
+		size := spill.Size(cr)
+		if err := t.spill(2 * size); err != nil {
+			return err
+		}
+		g.buf.Append(cr)
+		g.size += size
+		return nil
+	})
+}
 
-			for _, ck := range t.spec.ColumnKey {
-				j := colKeyIndex[ck]
-				c := cr.Cols()[j]
-				if colKey == "" {
-					colKey = valueToStr(cr, c, row, j)
-				} else {
-					colKey = colKey + "_" + valueToStr(cr, c, row, j)
-				}
-			}
+// spill writes the buffered input of every group to disk
+// if the allocator cannot provide size more bytes.
+func (t *pivotTransformation) spill(size int) error {
+	if !spill.ShouldSpill(t.dir, t.mem, size) {
+		return nil
+	}
+	return t.groups.Range(func(key flux.GroupKey, value interface{}) error {
+		return value.(*pivotGroup).buf.Spill()
+	})
+}
 
-			// we have columns for the copy-over in place;
-			// we know the row key;
-			// we know the col key;
-			//  0.  If we've not seen the colKey before, then we need to add a new column and backfill it.
-			if _, ok := t.colKeyMaps[groupKeyString][colKey]; !ok {
-				newCol := flux.ColMeta{
-					Label: colKey,
-					Type:  valueColType,
-				}
-				nextCol, err := builder.AddCol(newCol)
+// pivot reads the buffered input of a group and
+// pivots it into the table builder for the group.
+func (t *pivotTransformation) pivot(key flux.GroupKey, g *pivotGroup) error {
+	builder, created := t.cache.TableBuilder(key)
+	groupKeyString := key.String()
+	return g.buf.Do(func(cr flux.ColReader) error {
+		// The buffers have the output group key so the layout
+		// is found using the output key instead of the input key.
+		// Both keys have the same columns outside of the column key
+		// and the value column.
+		l, err := t.layout(cr.Cols(), key)
+		if err != nil {
+			return err
+		}
+		if created {
+			for _, c := range l.cols {
+				_, err := builder.AddCol(c)
 				if err != nil {
-					// column already exists
-					return errors.Newf(
-						codes.Invalid,
-						"value %q appears in a column key column, but a column named %q already exists; consider renaming %q to something else before pivoting",
-						colKey, colKey, colKey,
-					)
+					return err
 				}
-				t.colKeyMaps[groupKeyString][colKey] = nextCol
+
 			}
-			//  1.  if we've not seen rowKey before, then we need to append a new row, with copied values for the
-			//  existing columns, as well as zero values for the pivoted columns.
-			if _, ok := t.rowKeyMaps[groupKeyString][rowKey]; !ok {
-				// rowkey U groupKey cols
-				for cidx := range cols {
-					if err := builder.AppendValue(cidx, execute.ValueForRow(cr, row, colMap[cidx])); err != nil {
-						return err
-					}
-				}
+			t.colKeyMaps[groupKeyString] = make(map[string]int)
+			t.rowKeyMaps[groupKeyString] = make(map[string]int)
+			t.nextRowCol[groupKeyString] = rowCol{nextCol: len(l.cols), nextRow: 0}
+			created = false
+		}
+		return t.pivotRows(builder, groupKeyString, l, cr)
+	})
+}
+
+func (t *pivotTransformation) pivotRows(builder execute.TableBuilder, groupKeyString string, l *pivotLayout, cr flux.ColReader) error {
+	for row := 0; row < cr.Len(); row++ {
+		rowKey := ""
+		colKey := ""
+		for _, rk := range t.spec.RowKey {
+			j := l.rowKeyIndex[rk]
+			c := cr.Cols()[j]
+			rowKey += valueToStr(cr, c, row, j)
+		}
+
+		for _, ck := range t.spec.ColumnKey {
+			j := l.colKeyIndex[ck]
+			c := cr.Cols()[j]
+			if colKey == "" {
+				colKey = valueToStr(cr, c, row, j)
+			} else {
+				colKey = colKey + "_" + valueToStr(cr, c, row, j)
+			}
+		}
 
-				// zero-out the known key columns we've already discovered.
-				for _, v := range t.colKeyMaps[groupKeyString] {
-					if err := growColumn(builder, v, 1); err != nil {
-						return err
-					}
+		// we have columns for the copy-over in place;
+		// we know the row key;
+		// we know the col key;
+		//  0.  If we've not seen the colKey before, then we need to add a new column and backfill it.
+		if _, ok := t.colKeyMaps[groupKeyString][colKey]; !ok {
+			newCol := flux.ColMeta{
+				Label: colKey,
+				Type:  l.valueColType,
+			}
+			nextCol, err := builder.AddCol(newCol)
+			if err != nil {
+				// column already exists
+				return errors.Newf(
+					codes.Invalid,
+					"value %q appears in a column key column, but a column named %q already exists; consider renaming %q to something else before pivoting",
+					colKey, colKey, colKey,
+				)
+			}
+			t.colKeyMaps[groupKeyString][colKey] = nextCol
+		}
+		//  1.  if we've not seen rowKey before, then we need to append a new row, with copied values for the
+		//  existing columns, as well as zero values for the pivoted columns.
+		if _, ok := t.rowKeyMaps[groupKeyString][rowKey]; !ok {
+			// rowkey U groupKey cols
+			for cidx := range l.cols {
+				if err := builder.AppendValue(cidx, execute.ValueForRow(cr, row, l.colMap[cidx])); err != nil {
+					return err
 				}
-				nextRowCol := t.nextRowCol[groupKeyString]
-				t.rowKeyMaps[groupKeyString][rowKey] = nextRowCol.nextRow
-				nextRowCol.nextRow++
-				t.nextRowCol[groupKeyString] = nextRowCol
 			}
 
-			// at this point, we've created, added and back-filled all the columns we know about
-			// if we found a new row key, we added a new row with zeroes set for all the value columns
-			// so in all cases we know the row exists, and the column exists.  we need to grab the
-			// value from valueCol and assign it to its pivoted position.
-			if err := builder.SetValue(t.rowKeyMaps[groupKeyString][rowKey], t.colKeyMaps[groupKeyString][colKey], execute.ValueForRow(cr, row, valueColIndex)); err != nil {
-				return err
+			// zero-out the known key columns we've already discovered.
+			for _, v := range t.colKeyMaps[groupKeyString] {
+				if err := growColumn(builder, v, 1); err != nil {
+					return err
+				}
 			}
+			nextRowCol := t.nextRowCol[groupKeyString]
+			t.rowKeyMaps[groupKeyString][rowKey] = nextRowCol.nextRow
+			nextRowCol.nextRow++
+			t.nextRowCol[groupKeyString] = nextRowCol
+		}
 
+		// at this point, we've created, added and back-filled all the columns we know about
+		// if we found a new row key, we added a new row with zeroes set for all the value columns
+		// so in all cases we know the row exists, and the column exists.  we need to grab the
+		// value from valueCol and assign it to its pivoted position.
+		if err := builder.SetValue(t.rowKeyMaps[groupKeyString][rowKey], t.colKeyMaps[groupKeyString][colKey], execute.ValueForRow(cr, row, l.valueColIndex)); err != nil {
+			return err
 		}
-		return nil
-	})
+
+	}
+	return nil
 }
 
 func growColumn(builder execute.TableBuilder, colIdx, nRows int) error {
@@ -398,16 +514,61 @@ func valueToStr(cr flux.ColReader, c flux.ColMeta, row, col int) string {
 }
 
 func (t *pivotTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
-	return t.d.UpdateWatermark(mark)
+	t.watermark = mark
+	return nil
 }
 
 func (t *pivotTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
-	return t.d.UpdateProcessingTime(pt)
+	t.processing = pt
+	return nil
 }
 
 func (t *pivotTransformation) Finish(id execute.DatasetID, err error) {
+	defer func() { t.d.Finish(err) }()
+
+	if err == nil {
+		err = t.groups.Range(func(key flux.GroupKey, value interface{}) error {
+			// The pivoted table and the copy of it that is passed
+			// downstream are about as large as the input so the
+			// input is moved to disk if they would not fit.
+			g := value.(*pivotGroup)
+			if err := t.spill(2 * g.size); err != nil {
+				return err
+			}
+			if err := t.pivot(key, g); err != nil {
+				return err
+			}
+			return t.flush(key)
+		})
+	}
+	_ = t.groups.Range(func(key flux.GroupKey, value interface{}) error {
+		value.(*pivotGroup).buf.Release()
+		return nil
+	})
+	t.groups.Clear()
+	if err != nil {
+		return
+	}
 
-	t.d.Finish(err)
+	if err = t.d.UpdateWatermark(t.watermark); err != nil {
+		return
+	}
+	err = t.d.UpdateProcessingTime(t.processing)
+}
+
+// flush passes the pivoted table downstream and releases it.
+// When the transformation writes to a dataset, the table is
+// left in the cache for the dataset.
+func (t *pivotTransformation) flush(key flux.GroupKey) error {
+	if t.out == nil {
+		return nil
+	}
+	tbl, err := t.tables.Table(key)
+	if err != nil {
+		return err
+	}
+	defer t.tables.ExpireTable(key)
+	return t.out.Process(tbl)
 }
 
 type SortedPivotProcedureSpec struct {
@@ -463,6 +624,7 @@ type sortedPivotTransformation struct {
 	ctx   context.Context
 	alloc memory.Allocator
 	spec  SortedPivotProcedureSpec
+	dir   string
 
 	watermark  execute.Time
 	processing execute.Time
@@ -477,6 +639,7 @@ func newSortedPivotTransformation(ctx context.Context, spec SortedPivotProcedure
 		ctx:   ctx,
 		alloc: alloc,
 		spec:  spec,
+		dir:   execute.GetSpillDir(ctx),
 	}
 	return t, t.d, nil
 }
@@ -508,13 +671,7 @@ func (t *sortedPivotTransformation) Process(id execute.DatasetID, tbl flux.Table
 	// If this is the first group, create a pivotTableGroup and
 	// instantiate buffer map
 	if t.prevOutputKey == nil {
-		t.group = &pivotTableGroup{
-			rowCol: flux.ColMeta{
-				Label: t.spec.RowKey[0],
-				Type:  rowType,
-			},
-			buffers: make(map[string]*pivotTableBuffer),
-		}
+		t.group = t.newTableGroup(rowType)
 
 		// Assign comparison key for subsequent passthroughs
 		t.prevOutputKey = currOutputKey
@@ -542,13 +699,7 @@ func (t *sortedPivotTransformation) Process(id execute.DatasetID, tbl flux.Table
 		t.prevOutputKey = currOutputKey
 
 		// Flush data by making a new table group with empty buffers
-		t.group = &pivotTableGroup{
-			rowCol: flux.ColMeta{
-				Label: t.spec.RowKey[0],
-				Type:  rowType,
-			},
-			buffers: make(map[string]*pivotTableBuffer),
-		}
+		t.group = t.newTableGroup(rowType)
 	}
 
 	// Read the table and insert each of the column readers
@@ -583,6 +734,14 @@ func (t *sortedPivotTransformation) Process(id execute.DatasetID, tbl flux.Table
 			return errors.New(codes.FailedPrecondition, "value columns with the same column key have different types")
 		}
 
+		// Write the buffered columns to disk if there is
+		// not enough memory left to hold this buffer too.
+		if spill.ShouldSpill(t.dir, t.alloc, 2*spill.Size(cr)) {
+			if err := t.group.spill(); err != nil {
+				return err
+			}
+		}
+
 		// Insert the array associated with the row
 		// key and the value column into the buffer.
 		k, v := t.getColumn(cr, rowIndex), t.getColumn(cr, valueIndex)
@@ -593,6 +752,18 @@ func (t *sortedPivotTransformation) Process(id execute.DatasetID, tbl flux.Table
 
 }
 
+func (t *sortedPivotTransformation) newTableGroup(rowType flux.ColType) *pivotTableGroup {
+	return &pivotTableGroup{
+		rowCol: flux.ColMeta{
+			Label: t.spec.RowKey[0],
+			Type:  rowType,
+		},
+		buffers: make(map[string]*pivotTableBuffer),
+		dir:     t.dir,
+		mem:     t.alloc,
+	}
+}
+
 func (t *sortedPivotTransformation) validateTable(tbl flux.Table) error {
 	if missingColumn, ok := func() (string, bool) {
 		for _, v := range t.spec.RowKey {
@@ -686,7 +857,9 @@ func (t *sortedPivotTransformation) Finish(id execute.DatasetID, err error) {
 	defer func() { t.d.Finish(err) }()
 
 	if err != nil {
-
+		if t.group != nil {
+			t.group.Release()
+		}
 		return
 	}
 
@@ -714,6 +887,14 @@ type pivotTableBuffer struct {
 	keys      []array.Array
 	valueType flux.ColType
 	values    []array.Array
+
+	// files holds the keys and values that were spilled to disk.
+	// The contents of the files come before the arrays
+	// held in memory.
+	files []*spill.File
+	// loaded is the number of arrays at the beginning of
+	// keys and values that were read back from files.
+	loaded int
 }
 
 func (b *pivotTableBuffer) Insert(k, v array.Array) {
@@ -723,23 +904,159 @@ func (b *pivotTableBuffer) Insert(k, v array.Array) {
 	b.values = append(b.values, v)
 }
 
+// spill writes the keys and values held in memory to a file
+// and releases them.
+func (b *pivotTableBuffer) spill(rowCol flux.ColMeta, dir string) error {
+	if len(b.keys) == 0 {
+		return nil
+	}
+	key, cols := execute.NewGroupKey(nil, nil), b.spillCols(rowCol)
+	w, err := spill.Create(dir, key, cols)
+	if err != nil {
+		return err
+	}
+	for i := range b.keys {
+		buf := &arrow.TableBuffer{
+			GroupKey: key,
+			Columns:  cols,
+			Values:   []array.Array{b.keys[i], b.values[i]},
+		}
+		if err := w.Write(buf); err != nil {
+			w.Abort()
+			return err
+		}
+	}
+	f, err := w.Close()
+	if err != nil {
+		return err
+	}
+	b.files = append(b.files, f)
+	b.release()
+	return nil
+}
+
+func (b *pivotTableBuffer) spillCols(rowCol flux.ColMeta) []flux.ColMeta {
+	return []flux.ColMeta{
+		{Label: rowCol.Label, Type: rowCol.Type},
+		{Label: "_value", Type: b.valueType},
+	}
+}
+
+// load reads the spilled keys and the spilled values if withValues
+// is set back into memory so they are in front of the arrays
+// that were never spilled. The arrays are released by unload.
+func (b *pivotTableBuffer) load(withValues bool, mem arrowmemory.Allocator) error {
+	if len(b.files) == 0 {
+		return nil
+	}
+	var keys, values []array.Array
+	for _, f := range b.files {
+		r, err := f.Open(mem)
+		if err != nil {
+			releaseArrays(keys)
+			releaseArrays(values)
+			return err
+		}
+		for {
+			cr, err := r.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				_ = r.Close()
+				releaseArrays(keys)
+				releaseArrays(values)
+				return err
+			}
+			k := table.Values(cr, 0)
+			k.Retain()
+			keys = append(keys, k)
+			if withValues {
+				v := table.Values(cr, 1)
+				v.Retain()
+				values = append(values, v)
+			}
+			cr.Release()
+		}
+		if err := r.Close(); err != nil {
+			releaseArrays(keys)
+			releaseArrays(values)
+			return errors.Wrap(err, codes.Internal, "could not close spill file")
+		}
+	}
+	b.loaded = len(keys)
+	b.keys = append(keys, b.keys...)
+	if withValues {
+		b.values = append(values, b.values...)
+	}
+	return nil
+}
+
+// unload releases the keys that were read by load.
+func (b *pivotTableBuffer) unload() {
+	releaseArrays(b.keys[:b.loaded])
+	b.keys = b.keys[b.loaded:]
+	b.loaded = 0
+}
+
+func (b *pivotTableBuffer) release() {
+	releaseArrays(b.keys)
+	releaseArrays(b.values)
+	b.keys, b.values = nil, nil
+}
+
 func (b *pivotTableBuffer) Release() {
-	for _, k := range b.keys {
-		k.Release()
+	b.release()
+	for _, f := range b.files {
+		_ = f.Remove()
 	}
-	for _, v := range b.values {
-		v.Release()
+	b.files = nil
+}
+
+func releaseArrays(arrs []array.Array) {
+	for _, arr := range arrs {
+		arr.Release()
 	}
 }
 
 type pivotTableGroup struct {
 	rowCol  flux.ColMeta
 	buffers map[string]*pivotTableBuffer
+	dir     string
+	mem     memory.Allocator
+}
+
+// spill writes every buffer in the group to disk.
+func (gr *pivotTableGroup) spill() error {
+	for _, buf := range gr.buffers {
+		if err := buf.spill(gr.rowCol, gr.dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gr *pivotTableGroup) Release() {
+	for _, buf := range gr.buffers {
+		buf.Release()
+	}
 }
 
 func (gr *pivotTableGroup) doPivot(key flux.GroupKey, mem arrowmemory.Allocator) (flux.Table, error) {
+	// Read back the keys that were spilled to disk
+	// so they can be merged. The values are read one
+	// column at a time when the column is built.
+	for _, buf := range gr.buffers {
+		if err := buf.load(false, mem); err != nil {
+			gr.Release()
+			return nil, err
+		}
+	}
+
 	// Merge all of the keys from each buffer.
 	keys := gr.mergeKeys(mem)
+	for _, buf := range gr.buffers {
+		buf.unload()
+	}
 
 	// Create the table buffer that will be used for the final table.
 	ncols := len(key.Cols()) + len(gr.buffers)
@@ -767,6 +1084,11 @@ func (gr *pivotTableGroup) doPivot(key flux.GroupKey, mem arrowmemory.Allocator)
 
 	for _, label := range labels {
 		buf := gr.buffers[label]
+		if err := buf.load(true, mem); err != nil {
+			tb.Release()
+			gr.Release()
+			return nil, err
+		}
 		vs := gr.buildColumn(keys, buf, mem)
 		tb.Columns = append(tb.Columns, flux.ColMeta{
 			Label: label,
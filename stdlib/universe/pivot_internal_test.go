@@ -14,3 +14,15 @@ import (
 func NewSortedPivotTransformation(ctx context.Context, spec SortedPivotProcedureSpec, id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset, error) {
 	return newSortedPivotTransformation(ctx, spec, id, alloc)
 }
+
+// NewSpillingSortTransformation exposes the sort transformation
+// with a spill directory so the tests can limit its memory.
+func NewSpillingSortTransformation(id execute.DatasetID, spec *SortProcedureSpec, mem memory.Allocator, dir string) (execute.Transformation, execute.Dataset, error) {
+	return newSortTransformation(id, spec, mem, dir)
+}
+
+// NewSpillingPivotTransformation exposes the pivot transformation
+// that is created by the planner so the tests can limit its memory.
+func NewSpillingPivotTransformation(ctx context.Context, spec *PivotProcedureSpec, id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+	return newPivotTransformation(ctx, spec, id, alloc)
+}
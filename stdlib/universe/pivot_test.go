@@ -1621,3 +1621,145 @@ func benchmarkPivot(b *testing.B, n int) {
 		},
 	)
 }
+
+func TestPivot_Spill(t *testing.T) {
+	// Limit the memory so the input does not fit
+	// and the buffered tables have to be spilled to disk.
+	// Each pivoted table is held in memory while it is
+	// built so the limit has to leave room for one of them.
+	limit := int64(1024 * 1024)
+	mem := &memory.ResourceAllocator{Limit: &limit}
+
+	seed := int64(1)
+	input, err := gen.Input(context.Background(), gen.Schema{
+		NumPoints: 5000,
+		Seed:      &seed,
+		Alloc:     mem,
+		Tags: []gen.Tag{
+			{Name: "_measurement", Cardinality: 1},
+			{Name: "_field", Cardinality: 8},
+			{Name: "t0", Cardinality: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &universe.PivotProcedureSpec{
+		RowKey:      []string{execute.DefaultTimeColLabel},
+		ColumnKey:   []string{"_field"},
+		ValueColumn: execute.DefaultValueColLabel,
+	}
+	tr, d := universe.NewSpillingPivotTransformation(spillContext(t), spec, executetest.RandomDatasetID(), mem)
+
+	var ntables, n int
+	sink := &spillSink{
+		fn: func(tbl flux.Table) error {
+			ntables++
+			return tbl.Do(func(cr flux.ColReader) error {
+				for j, col := range cr.Cols() {
+					if col.Label == execute.DefaultTimeColLabel || tbl.Key().HasCol(col.Label) {
+						continue
+					}
+					arr := cr.Floats(j)
+					n += arr.Len() - arr.NullN()
+				}
+				return nil
+			})
+		},
+	}
+	d.AddTransformation(sink)
+
+	parentID := executetest.RandomDatasetID()
+	if err := input.Do(func(tbl flux.Table) error {
+		return tr.Process(parentID, tbl)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tr.Finish(parentID, nil)
+
+	if sink.err != nil {
+		t.Fatal(sink.err)
+	}
+	if want, got := 2, ntables; want != got {
+		t.Errorf("unexpected number of tables -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if want, got := 80000, n; want != got {
+		t.Errorf("unexpected number of values -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("memory was not released: %d bytes", got)
+	}
+}
+
+func TestSortedPivot_Spill(t *testing.T) {
+	// Limit the memory so the input does not fit
+	// and the buffered columns have to be spilled to disk.
+	// The pivoted table is held in memory so the limit
+	// has to leave room for it.
+	limit := int64(1024 * 1024)
+	mem := &memory.ResourceAllocator{Limit: &limit}
+
+	seed := int64(1)
+	input, err := gen.Input(context.Background(), gen.Schema{
+		NumPoints: 10000,
+		Seed:      &seed,
+		Alloc:     mem,
+		Tags: []gen.Tag{
+			{Name: "_measurement", Cardinality: 1},
+			{Name: "_field", Cardinality: 8},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := universe.SortedPivotProcedureSpec{
+		RowKey:      []string{execute.DefaultTimeColLabel},
+		ColumnKey:   []string{"_field"},
+		ValueColumn: execute.DefaultValueColLabel,
+	}
+	tr, d, err := universe.NewSortedPivotTransformation(spillContext(t), spec, executetest.RandomDatasetID(), mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ntables, n int
+	sink := &spillSink{
+		fn: func(tbl flux.Table) error {
+			ntables++
+			return tbl.Do(func(cr flux.ColReader) error {
+				for j, col := range cr.Cols() {
+					if col.Label == execute.DefaultTimeColLabel || tbl.Key().HasCol(col.Label) {
+						continue
+					}
+					arr := cr.Floats(j)
+					n += arr.Len() - arr.NullN()
+				}
+				return nil
+			})
+		},
+	}
+	d.AddTransformation(sink)
+
+	parentID := executetest.RandomDatasetID()
+	if err := input.Do(func(tbl flux.Table) error {
+		return tr.Process(parentID, tbl)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tr.Finish(parentID, nil)
+
+	if sink.err != nil {
+		t.Fatal(sink.err)
+	}
+	if want, got := 1, ntables; want != got {
+		t.Errorf("unexpected number of tables -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if want, got := 80000, n; want != got {
+		t.Errorf("unexpected number of values -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("memory was not released: %d bytes", got)
+	}
+}
@@ -91,6 +91,11 @@ func (s *ReduceProcedureSpec) Copy() plan.ProcedureSpec {
 	return ns
 }
 
+// CPUIntensive reports that the function is evaluated for each row.
+func (s *ReduceProcedureSpec) CPUIntensive() bool {
+	return true
+}
+
 func createReduceTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
 	s, ok := spec.(*ReduceProcedureSpec)
 	if !ok {
@@ -0,0 +1,354 @@
+package universe
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/stdlib/universe/regression"
+	"github.com/influxdata/flux/values"
+)
+
+const (
+	LinearRegressionKind = "linearRegressionKind"
+	PolyfitKind          = "polyfitKind"
+	ExponentialFitKind   = "exponentialFitKind"
+
+	fittedColLabel   = "fitted"
+	residualColLabel = "residual"
+)
+
+// regressionModels maps the name of each regression function to its kind.
+var regressionModels = map[string]plan.ProcedureKind{
+	"linearRegression": LinearRegressionKind,
+	"polyfit":          PolyfitKind,
+	"exponentialFit":   ExponentialFitKind,
+}
+
+// RegressionOpSpec is the operation spec of linearRegression,
+// polyfit and exponentialFit. The model selects the function.
+type RegressionOpSpec struct {
+	Model      string `json:"model"`
+	Degree     int64  `json:"degree"`
+	Column     string `json:"column"`
+	TimeColumn string `json:"time_column"`
+}
+
+func init() {
+	for model, kind := range regressionModels {
+		model := model
+		signature := runtime.MustLookupBuiltinType("universe", model)
+		runtime.RegisterPackageValue("universe", model, flux.MustValue(flux.FunctionValue(model, func(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+			return createRegressionOpSpec(model, args, a)
+		}, signature)))
+		plan.RegisterProcedureSpec(kind, newRegressionProcedure, flux.OperationKind(kind))
+		execute.RegisterTransformation(kind, createRegressionTransformation)
+	}
+}
+
+func createRegressionOpSpec(model string, args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := &RegressionOpSpec{
+		Model: model,
+	}
+	switch model {
+	case "linearRegression", "exponentialFit":
+		spec.Degree = 1
+	case "polyfit":
+		degree, err := args.GetRequiredInt("degree")
+		if err != nil {
+			return nil, err
+		}
+		if degree < 0 {
+			return nil, errors.Newf(codes.Invalid, "degree must not be negative, got %d", degree)
+		}
+		spec.Degree = degree
+	}
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+	if col, ok, err := args.GetString("timeColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.TimeColumn = col
+	} else {
+		spec.TimeColumn = execute.DefaultTimeColLabel
+	}
+	return spec, nil
+}
+
+func (s *RegressionOpSpec) Kind() flux.OperationKind {
+	return flux.OperationKind(regressionModels[s.Model])
+}
+
+type RegressionProcedureSpec struct {
+	plan.DefaultCost
+	Model      string
+	Degree     int64
+	Column     string
+	TimeColumn string
+}
+
+func newRegressionProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*RegressionOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &RegressionProcedureSpec{
+		Model:      spec.Model,
+		Degree:     spec.Degree,
+		Column:     spec.Column,
+		TimeColumn: spec.TimeColumn,
+	}, nil
+}
+
+func (s *RegressionProcedureSpec) Kind() plan.ProcedureKind {
+	return regressionModels[s.Model]
+}
+func (s *RegressionProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(RegressionProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *RegressionProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createRegressionTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*RegressionProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewRegressionTransformation(d, cache, s)
+	return t, d, nil
+}
+
+type regressionTransformation struct {
+	execute.ExecutionNode
+	d     execute.Dataset
+	cache execute.TableBuilderCache
+
+	model      string
+	degree     int
+	column     string
+	timeColumn string
+}
+
+func NewRegressionTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *RegressionProcedureSpec) *regressionTransformation {
+	return &regressionTransformation{
+		d:          d,
+		cache:      cache,
+		model:      spec.Model,
+		degree:     int(spec.Degree),
+		column:     spec.Column,
+		timeColumn: spec.TimeColumn,
+	}
+}
+
+func (rt *regressionTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	builder, created := rt.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "%s found duplicate table with key: %v", rt.model, tbl.Key())
+	}
+	s, err := readSeries(rt.model, tbl, builder, rt.timeColumn, rt.column)
+	if err != nil {
+		return err
+	}
+
+	// The model is a function of the number of seconds
+	// since the time of the first row that has a value.
+	var xs, ys []float64
+	var origin int64
+	for i := range s.times {
+		if !s.valid[i] {
+			continue
+		}
+		if len(xs) == 0 {
+			origin = s.times[i]
+		}
+		xs = append(xs, seconds(s.times[i]-origin))
+		ys = append(ys, s.values[i])
+	}
+
+	var model regression.Model
+	switch regressionModels[rt.model] {
+	case LinearRegressionKind:
+		model, err = regression.Linear(xs, ys)
+	case PolyfitKind:
+		model, err = regression.Polyfit(xs, ys, rt.degree)
+	case ExponentialFitKind:
+		model, err = regression.ExponentialFit(xs, ys)
+	default:
+		err = errors.Newf(codes.Internal, "unknown regression model %q", rt.model)
+	}
+	if err != nil {
+		return err
+	}
+
+	coefficients := model.Coefficients()
+	labels := make([]string, 0, len(coefficients)+2)
+	labels = append(labels, fittedColLabel, residualColLabel)
+	for i := range coefficients {
+		labels = append(labels, coefficientColLabel(i))
+	}
+	idxs, err := addFloatCols(builder, labels...)
+	if err != nil {
+		return err
+	}
+
+	// The new columns are filled with nulls for the existing rows.
+	for i, t := range s.times {
+		if s.timeValid[i] {
+			fitted := model.Predict(seconds(t - origin))
+			if err := builder.SetValue(i, idxs[0], values.NewFloat(fitted)); err != nil {
+				return err
+			}
+			if s.valid[i] {
+				if err := builder.SetValue(i, idxs[1], values.NewFloat(s.values[i]-fitted)); err != nil {
+					return err
+				}
+			}
+		}
+		for k, c := range coefficients {
+			if err := builder.SetValue(i, idxs[2+k], values.NewFloat(c)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (rt *regressionTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return rt.d.RetractTable(key)
+}
+
+func (rt *regressionTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return rt.d.UpdateWatermark(mark)
+}
+func (rt *regressionTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return rt.d.UpdateProcessingTime(pt)
+}
+func (rt *regressionTransformation) Finish(id execute.DatasetID, err error) {
+	rt.d.Finish(err)
+}
+
+// series is the time and value of each row of a table.
+type series struct {
+	times  []int64
+	values []float64
+	// timeValid reports whether the time of a row is not null
+	// and valid reports whether both the time and value are not null.
+	timeValid []bool
+	valid     []bool
+}
+
+// readSeries copies the rows of the table to the builder, if it is not nil,
+// and returns the time and value of each row. The value column must be numeric.
+func readSeries(fn string, tbl flux.Table, builder execute.TableBuilder, timeColumn, column string) (*series, error) {
+	cols := tbl.Cols()
+	timeIdx := execute.ColIdx(timeColumn, cols)
+	if timeIdx < 0 {
+		return nil, errors.Newf(codes.FailedPrecondition, "cannot find time column %s", timeColumn)
+	}
+	if typ := cols[timeIdx].Type; typ != flux.TTime {
+		return nil, errors.Newf(codes.FailedPrecondition, "time column %s must be of type time, got %s", timeColumn, typ)
+	}
+	colIdx := execute.ColIdx(column, cols)
+	if colIdx < 0 {
+		return nil, errors.Newf(codes.FailedPrecondition, "cannot find column %s", column)
+	}
+	typ := cols[colIdx].Type
+	if typ != flux.TInt &&
+		typ != flux.TUInt &&
+		typ != flux.TFloat {
+		return nil, errors.Newf(codes.FailedPrecondition, "%s can work only on numerical types, got %s", fn, typ.String())
+	}
+
+	if builder != nil {
+		if err := execute.AddTableCols(tbl, builder); err != nil {
+			return nil, err
+		}
+	}
+	s := new(series)
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		if builder != nil {
+			if err := execute.AppendCols(cr, builder); err != nil {
+				return err
+			}
+		}
+		times := cr.Times(timeIdx)
+		for i := 0; i < cr.Len(); i++ {
+			var v float64
+			valid := times.IsValid(i)
+			switch typ {
+			case flux.TInt:
+				vs := cr.Ints(colIdx)
+				valid = valid && vs.IsValid(i)
+				v = float64(vs.Value(i))
+			case flux.TUInt:
+				vs := cr.UInts(colIdx)
+				valid = valid && vs.IsValid(i)
+				v = float64(vs.Value(i))
+			case flux.TFloat:
+				vs := cr.Floats(colIdx)
+				valid = valid && vs.IsValid(i)
+				v = vs.Value(i)
+				if valid && (math.IsNaN(v) || math.IsInf(v, 0)) {
+					return errors.New(codes.Invalid, "NaN/Inf in input")
+				}
+			}
+			s.times = append(s.times, times.Value(i))
+			s.values = append(s.values, v)
+			s.timeValid = append(s.timeValid, times.IsValid(i))
+			s.valid = append(s.valid, valid)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// addFloatCols adds a float column for each label to the builder
+// and returns their indexes. The labels must not be existing columns.
+// The columns are filled with nulls for the rows in the builder.
+func addFloatCols(builder execute.TableBuilder, labels ...string) ([]int, error) {
+	idxs := make([]int, len(labels))
+	for i, label := range labels {
+		if execute.ColIdx(label, builder.Cols()) >= 0 {
+			return nil, errors.Newf(codes.FailedPrecondition, "column %q already exists", label)
+		}
+		j, err := builder.AddCol(flux.ColMeta{
+			Label: label,
+			Type:  flux.TFloat,
+		})
+		if err != nil {
+			return nil, err
+		}
+		idxs[i] = j
+	}
+	return idxs, nil
+}
+
+func coefficientColLabel(i int) string {
+	return "c" + strconv.Itoa(i)
+}
+
+func seconds(ns int64) float64 {
+	return float64(ns) / 1e9
+}
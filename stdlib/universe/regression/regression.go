@@ -0,0 +1,107 @@
+// Package regression fits models to a series with least squares.
+package regression
+
+import (
+	"math"
+
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Model is a model fitted to a series.
+type Model interface {
+	// Coefficients returns the coefficients of the model.
+	Coefficients() []float64
+	// Predict returns the value of the model at x.
+	Predict(x float64) float64
+}
+
+// Polynomial is a polynomial where the coefficient
+// at index i is the coefficient of x^i.
+type Polynomial []float64
+
+func (p Polynomial) Coefficients() []float64 {
+	return p
+}
+
+func (p Polynomial) Predict(x float64) float64 {
+	// Horner's method.
+	var y float64
+	for i := len(p) - 1; i >= 0; i-- {
+		y = y*x + p[i]
+	}
+	return y
+}
+
+// Exponential is the model y = A * e^(B * x).
+type Exponential struct {
+	A, B float64
+}
+
+func (e Exponential) Coefficients() []float64 {
+	return []float64{e.A, e.B}
+}
+
+func (e Exponential) Predict(x float64) float64 {
+	return e.A * math.Exp(e.B*x)
+}
+
+// Linear fits a line to the points with ordinary least squares.
+func Linear(xs, ys []float64) (Polynomial, error) {
+	return Polyfit(xs, ys, 1)
+}
+
+// Polyfit fits a polynomial of the given degree to the points
+// with least squares. There must be more points than the degree.
+func Polyfit(xs, ys []float64, degree int) (Polynomial, error) {
+	if degree < 0 {
+		return nil, errors.Newf(codes.Invalid, "polynomial degree must not be negative, got %d", degree)
+	}
+	n := len(xs)
+	if n <= degree {
+		return nil, errors.Newf(codes.FailedPrecondition, "fitting a polynomial of degree %d requires at least %d points, got %d", degree, degree+1, n)
+	}
+
+	// Solve the Vandermonde system in the least squares sense with
+	// a QR decomposition, which is more stable than the normal equations.
+	a := mat.NewDense(n, degree+1, nil)
+	for i, x := range xs {
+		v := 1.0
+		for j := 0; j <= degree; j++ {
+			a.Set(i, j, v)
+			v *= x
+		}
+	}
+	b := mat.NewVecDense(n, append([]float64(nil), ys...))
+
+	var qr mat.QR
+	qr.Factorize(a)
+	var c mat.VecDense
+	if err := qr.SolveVecTo(&c, false, b); err != nil {
+		return nil, errors.Wrap(err, codes.FailedPrecondition, "cannot fit polynomial")
+	}
+	p := make(Polynomial, degree+1)
+	for j := range p {
+		p[j] = c.AtVec(j)
+	}
+	return p, nil
+}
+
+// ExponentialFit fits the model y = A * e^(B * x) to the points.
+// The model is fitted with least squares on the logarithm of the values
+// so all of the values must be positive.
+func ExponentialFit(xs, ys []float64) (Exponential, error) {
+	logs := make([]float64, len(ys))
+	for i, y := range ys {
+		if y <= 0 {
+			return Exponential{}, errors.Newf(codes.FailedPrecondition, "exponential fit requires positive values, got %v", y)
+		}
+		logs[i] = math.Log(y)
+	}
+	p, err := Linear(xs, logs)
+	if err != nil {
+		return Exponential{}, err
+	}
+	return Exponential{A: math.Exp(p[0]), B: p[1]}, nil
+}
@@ -0,0 +1,70 @@
+package regression_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/influxdata/flux/stdlib/universe/regression"
+)
+
+func TestPolyfit(t *testing.T) {
+	xs := []float64{-2, -1, 0, 1, 2, 3}
+	ys := make([]float64, len(xs))
+	for i, x := range xs {
+		ys[i] = 1 - 2*x + 0.5*x*x*x
+	}
+
+	for degree, want := range map[int][]float64{
+		3: {1, -2, 0, 0.5},
+		5: {1, -2, 0, 0.5, 0, 0},
+	} {
+		p, err := regression.Polyfit(xs, ys, degree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(want, p.Coefficients(), cmpopts.EquateApprox(0, 1e-9)) {
+			t.Errorf("unexpected coefficients of degree %d -want/+got:\n%s", degree, cmp.Diff(want, p.Coefficients()))
+		}
+		if got := p.Predict(4); math.Abs(got-25) > 1e-9 {
+			t.Errorf("unexpected prediction -want/+got:\n\t- %v\n\t+ %v", 25.0, got)
+		}
+	}
+
+	if _, err := regression.Polyfit(xs, ys, 6); err == nil {
+		t.Error("expected an error with fewer points than coefficients")
+	}
+}
+
+func TestLinear(t *testing.T) {
+	p, err := regression.Linear([]float64{0, 1, 2, 3}, []float64{1, 2, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{1.1, 0.6}
+	if !cmp.Equal(want, p.Coefficients(), cmpopts.EquateApprox(0, 1e-9)) {
+		t.Errorf("unexpected coefficients -want/+got:\n%s", cmp.Diff(want, p.Coefficients()))
+	}
+}
+
+func TestExponentialFit(t *testing.T) {
+	xs := []float64{0, 1, 2, 3}
+	ys := make([]float64, len(xs))
+	for i, x := range xs {
+		ys[i] = 3 * math.Exp(-0.25*x)
+	}
+	e, err := regression.ExponentialFit(xs, ys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{3, -0.25}
+	if !cmp.Equal(want, e.Coefficients(), cmpopts.EquateApprox(0, 1e-9)) {
+		t.Errorf("unexpected coefficients -want/+got:\n%s", cmp.Diff(want, e.Coefficients()))
+	}
+
+	ys[2] = 0
+	if _, err := regression.ExponentialFit(xs, ys); err == nil {
+		t.Error("expected an error with a value that is not positive")
+	}
+}
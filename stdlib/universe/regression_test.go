@@ -0,0 +1,378 @@
+package universe_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestRegression_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "linearRegression defaults",
+			Raw:  `from(bucket:"mydb") |> range(start:-1h) |> linearRegression()`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mydb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop:        flux.Now,
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "linearRegressionKind2",
+						Spec: &universe.RegressionOpSpec{
+							Model:      "linearRegression",
+							Degree:     1,
+							Column:     execute.DefaultValueColLabel,
+							TimeColumn: execute.DefaultTimeColLabel,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "linearRegressionKind2"},
+				},
+			},
+		},
+		{
+			Name: "polyfit no defaults",
+			Raw:  `from(bucket:"mydb") |> range(start:-1h) |> polyfit(degree: 3, column: "v", timeColumn: "t")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mydb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop:        flux.Now,
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "polyfitKind2",
+						Spec: &universe.RegressionOpSpec{
+							Model:      "polyfit",
+							Degree:     3,
+							Column:     "v",
+							TimeColumn: "t",
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "polyfitKind2"},
+				},
+			},
+		},
+		{
+			Name:    "polyfit without degree",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> polyfit()`,
+			WantErr: true,
+		},
+		{
+			Name:    "polyfit negative degree",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> polyfit(degree: -1)`,
+			WantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestRegression_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    *universe.RegressionProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "linear",
+			spec: &universe.RegressionProcedureSpec{
+				Model:      "linearRegression",
+				Degree:     1,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(10 * time.Second), 1.0, "a"},
+					{execute.Time(20 * time.Second), 21.0, "a"},
+					{execute.Time(30 * time.Second), nil, "a"},
+					{nil, 5.0, "a"},
+					{execute.Time(40 * time.Second), 61.0, "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "c0", Type: flux.TFloat},
+					{Label: "c1", Type: flux.TFloat},
+					{Label: "fitted", Type: flux.TFloat},
+					{Label: "residual", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(10 * time.Second), 1.0, 1.0, 2.0, 1.0, 0.0, "a"},
+					{execute.Time(20 * time.Second), 21.0, 1.0, 2.0, 21.0, 0.0, "a"},
+					{execute.Time(30 * time.Second), nil, 1.0, 2.0, 41.0, nil, "a"},
+					{nil, 5.0, 1.0, 2.0, nil, nil, "a"},
+					{execute.Time(40 * time.Second), 61.0, 1.0, 2.0, 61.0, 0.0, "a"},
+				},
+			}},
+		},
+		{
+			name: "linear with residuals",
+			spec: &universe.RegressionProcedureSpec{
+				Model:      "linearRegression",
+				Degree:     1,
+				Column:     "v",
+				TimeColumn: "t",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "t", Type: flux.TTime},
+					{Label: "v", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), uint64(1)},
+					{execute.Time(1 * time.Second), uint64(3)},
+					{execute.Time(2 * time.Second), uint64(2)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "c0", Type: flux.TFloat},
+					{Label: "c1", Type: flux.TFloat},
+					{Label: "fitted", Type: flux.TFloat},
+					{Label: "residual", Type: flux.TFloat},
+					{Label: "t", Type: flux.TTime},
+					{Label: "v", Type: flux.TUInt},
+				},
+				Data: [][]interface{}{
+					{1.5, 0.5, 1.5, -0.5, execute.Time(0), uint64(1)},
+					{1.5, 0.5, 2.0, 1.0, execute.Time(1 * time.Second), uint64(3)},
+					{1.5, 0.5, 2.5, -0.5, execute.Time(2 * time.Second), uint64(2)},
+				},
+			}},
+		},
+		{
+			name: "polyfit",
+			spec: &universe.RegressionProcedureSpec{
+				Model:      "polyfit",
+				Degree:     2,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), int64(3)},
+					{execute.Time(1 * time.Second), int64(4)},
+					{execute.Time(2 * time.Second), int64(7)},
+					{execute.Time(3 * time.Second), int64(12)},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "c0", Type: flux.TFloat},
+					{Label: "c1", Type: flux.TFloat},
+					{Label: "c2", Type: flux.TFloat},
+					{Label: "fitted", Type: flux.TFloat},
+					{Label: "residual", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), int64(3), 3.0, 0.0, 1.0, 3.0, 0.0},
+					{execute.Time(1 * time.Second), int64(4), 3.0, 0.0, 1.0, 4.0, 0.0},
+					{execute.Time(2 * time.Second), int64(7), 3.0, 0.0, 1.0, 7.0, 0.0},
+					{execute.Time(3 * time.Second), int64(12), 3.0, 0.0, 1.0, 12.0, 0.0},
+				},
+			}},
+		},
+		{
+			name: "exponential",
+			spec: &universe.RegressionProcedureSpec{
+				Model:      "exponentialFit",
+				Degree:     1,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), 2.0},
+					{execute.Time(2 * time.Second), 5.43656365691809},
+					{execute.Time(4 * time.Second), 14.7781121978613},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "c0", Type: flux.TFloat},
+					{Label: "c1", Type: flux.TFloat},
+					{Label: "fitted", Type: flux.TFloat},
+					{Label: "residual", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), 2.0, 2.0, 0.5, 2.0, 0.0},
+					{execute.Time(2 * time.Second), 5.43656365691809, 2.0, 0.5, 5.43656365691809, 0.0},
+					{execute.Time(4 * time.Second), 14.7781121978613, 2.0, 0.5, 14.7781121978613, 0.0},
+				},
+			}},
+		},
+		{
+			name: "polyfit too few points",
+			spec: &universe.RegressionProcedureSpec{
+				Model:      "polyfit",
+				Degree:     2,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), 1.0},
+					{execute.Time(1 * time.Second), 2.0},
+					{execute.Time(2 * time.Second), nil},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "fitting a polynomial of degree 2 requires at least 3 points, got 2"),
+		},
+		{
+			name: "exponential non-positive value",
+			spec: &universe.RegressionProcedureSpec{
+				Model:      "exponentialFit",
+				Degree:     1,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), 1.0},
+					{execute.Time(1 * time.Second), -2.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "exponential fit requires positive values, got -2"),
+		},
+		{
+			name: "string column",
+			spec: &universe.RegressionProcedureSpec{
+				Model:      "linearRegression",
+				Degree:     1,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), "a"},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "linearRegression can work only on numerical types, got string"),
+		},
+		{
+			name: "existing output column",
+			spec: &universe.RegressionProcedureSpec{
+				Model:      "linearRegression",
+				Degree:     1,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "fitted", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(0), 1.0, 1.0},
+					{execute.Time(1 * time.Second), 2.0, 2.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `column "fitted" already exists`),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return universe.NewRegressionTransformation(d, c, tc.spec)
+				},
+				floatOptions,
+			)
+		})
+	}
+}
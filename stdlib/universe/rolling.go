@@ -0,0 +1,606 @@
+package universe
+
+import (
+	"context"
+	"math"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/compiler"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+const RollingKind = "rolling"
+
+const (
+	rollingMin      = "min"
+	rollingMax      = "max"
+	rollingQuantile = "quantile"
+)
+
+// RollingOpSpec computes an aggregate over sliding windows of rows.
+// The windows are either the last N rows or the rows in time windows
+// that start every Every and last for Period. The aggregate is either
+// the function named by Fn or the Reducer function.
+type RollingOpSpec struct {
+	N          int64                        `json:"n"`
+	Every      flux.Duration                `json:"every"`
+	Period     flux.Duration                `json:"period"`
+	Fn         string                       `json:"fn"`
+	Quantile   float64                      `json:"quantile"`
+	Reducer    interpreter.ResolvedFunction `json:"reducer"`
+	Column     string                       `json:"column"`
+	TimeColumn string                       `json:"time_column"`
+}
+
+func init() {
+	rollingSignature := runtime.MustLookupBuiltinType("universe", RollingKind)
+	runtime.RegisterPackageValue("universe", RollingKind, flux.MustValue(flux.FunctionValue(RollingKind, createRollingOpSpec, rollingSignature)))
+	plan.RegisterProcedureSpec(RollingKind, newRollingProcedure, RollingKind)
+	execute.RegisterTransformation(RollingKind, createRollingTransformation)
+}
+
+func createRollingOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := new(RollingOpSpec)
+
+	n, hasN, err := args.GetInt("n")
+	if err != nil {
+		return nil, err
+	}
+	every, hasEvery, err := args.GetDuration("every")
+	if err != nil {
+		return nil, err
+	}
+	period, hasPeriod, err := args.GetDuration("period")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hasN == hasEvery:
+		return nil, errors.New(codes.Invalid, "rolling requires exactly one of n or every")
+	case hasN:
+		if n < 1 {
+			return nil, errors.Newf(codes.Invalid, "n must be at least 1, got %d", n)
+		}
+		if hasPeriod {
+			return nil, errors.New(codes.Invalid, "period can only be used with every")
+		}
+		spec.N = n
+	default:
+		if !hasPeriod {
+			period = every
+		}
+		for name, d := range map[string]flux.Duration{"every": every, "period": period} {
+			if d.IsNegative() || d.IsZero() {
+				return nil, errors.Newf(codes.Invalid, "%s must be positive", name)
+			}
+			if d.Months() != 0 {
+				return nil, errors.Newf(codes.Invalid, "%s must not contain months or years", name)
+			}
+		}
+		spec.Every = every
+		spec.Period = period
+	}
+
+	fn, hasFn, err := args.GetString("fn")
+	if err != nil {
+		return nil, err
+	}
+	if f, ok, err := args.GetFunction("reducer"); err != nil {
+		return nil, err
+	} else if ok {
+		if hasFn {
+			return nil, errors.New(codes.Invalid, "rolling requires at most one of fn or reducer")
+		}
+		reducer, err := interpreter.ResolveFunction(f)
+		if err != nil {
+			return nil, err
+		}
+		spec.Reducer = reducer
+	} else {
+		if !hasFn {
+			fn = MeanKind
+		}
+		switch fn {
+		case SumKind, MeanKind, CountKind, StddevKind, rollingMin, rollingMax:
+		case rollingQuantile:
+			q, err := args.GetRequiredFloat("q")
+			if err != nil {
+				return nil, err
+			}
+			if q < 0 || q > 1 {
+				return nil, errors.New(codes.Invalid, "quantile must be between 0 and 1")
+			}
+			spec.Quantile = q
+		default:
+			if _, ok := execute.LookupSimpleAggregate(fn); !ok {
+				return nil, errors.Newf(codes.Invalid, "rolling does not support the aggregate %q", fn)
+			}
+		}
+		spec.Fn = fn
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+	if col, ok, err := args.GetString("timeColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.TimeColumn = col
+	} else {
+		spec.TimeColumn = execute.DefaultTimeColLabel
+	}
+	return spec, nil
+}
+
+func (s *RollingOpSpec) Kind() flux.OperationKind {
+	return RollingKind
+}
+
+type RollingProcedureSpec struct {
+	plan.DefaultCost
+	N          int64
+	Every      flux.Duration
+	Period     flux.Duration
+	Fn         string
+	Quantile   float64
+	Reducer    interpreter.ResolvedFunction
+	Column     string
+	TimeColumn string
+}
+
+func newRollingProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*RollingOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &RollingProcedureSpec{
+		N:          spec.N,
+		Every:      spec.Every,
+		Period:     spec.Period,
+		Fn:         spec.Fn,
+		Quantile:   spec.Quantile,
+		Reducer:    spec.Reducer,
+		Column:     spec.Column,
+		TimeColumn: spec.TimeColumn,
+	}, nil
+}
+
+func (s *RollingProcedureSpec) Kind() plan.ProcedureKind {
+	return RollingKind
+}
+func (s *RollingProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(RollingProcedureSpec)
+	*ns = *s
+	ns.Reducer = s.Reducer.Copy()
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *RollingProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createRollingTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*RollingProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t, err := NewRollingTransformation(a.Context(), d, cache, a.Allocator(), s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, d, nil
+}
+
+type rollingTransformation struct {
+	execute.ExecutionNode
+	d     execute.Dataset
+	cache execute.TableBuilderCache
+	ctx   context.Context
+	mem   memory.Allocator
+
+	n          int
+	every      int64
+	period     int64
+	column     string
+	timeColumn string
+
+	// newAggregate creates the aggregate of the windows of a table
+	// and typ is the type of its values.
+	newAggregate func() rollingAggregate
+	typ          flux.ColType
+}
+
+func NewRollingTransformation(ctx context.Context, d execute.Dataset, cache execute.TableBuilderCache, mem memory.Allocator, spec *RollingProcedureSpec) (*rollingTransformation, error) {
+	t := &rollingTransformation{
+		d:          d,
+		cache:      cache,
+		ctx:        ctx,
+		mem:        mem,
+		n:          int(spec.N),
+		every:      spec.Every.Duration().Nanoseconds(),
+		period:     spec.Period.Duration().Nanoseconds(),
+		column:     spec.Column,
+		timeColumn: spec.TimeColumn,
+		typ:        flux.TFloat,
+	}
+
+	if spec.Reducer.Fn != nil {
+		input := semantic.NewObjectType([]semantic.PropertyType{
+			{Key: []byte("values"), Value: semantic.NewArrayType(semantic.BasicFloat)},
+		})
+		fn, err := compiler.Compile(ctx, compiler.ToScope(spec.Reducer.Scope), spec.Reducer.Fn, input)
+		if err != nil {
+			return nil, err
+		}
+		t.typ = execute.ConvertFromKind(fn.Type().Nature())
+		if t.typ == flux.TInvalid {
+			return nil, errors.Newf(codes.Invalid, "reducer must return a basic type, got %s", fn.Type())
+		}
+		t.newAggregate = func() rollingAggregate {
+			return &rollingReducer{ctx: ctx, fn: fn, input: values.NewObject(input)}
+		}
+		return t, nil
+	}
+
+	switch spec.Fn {
+	case SumKind:
+		t.newAggregate = func() rollingAggregate { return new(rollingSum) }
+	case MeanKind:
+		t.newAggregate = func() rollingAggregate { return new(rollingMean) }
+	case CountKind:
+		t.newAggregate = func() rollingAggregate { return new(rollingCount) }
+		t.typ = flux.TInt
+	case StddevKind:
+		t.newAggregate = func() rollingAggregate { return new(rollingStddev) }
+	case rollingMin:
+		t.newAggregate = func() rollingAggregate {
+			return &rollingExtreme{less: func(a, b float64) bool { return a < b }}
+		}
+	case rollingMax:
+		t.newAggregate = func() rollingAggregate {
+			return &rollingExtreme{less: func(a, b float64) bool { return a > b }}
+		}
+	case SpreadKind:
+		t.newAggregate = func() rollingAggregate {
+			return &rollingSpread{
+				min: rollingExtreme{less: func(a, b float64) bool { return a < b }},
+				max: rollingExtreme{less: func(a, b float64) bool { return a > b }},
+			}
+		}
+	default:
+		var agg execute.SimpleAggregate
+		if spec.Fn == rollingQuantile {
+			agg = &ExactQuantileAgg{Quantile: spec.Quantile}
+		} else if newAgg, ok := execute.LookupSimpleAggregate(spec.Fn); ok {
+			agg = newAgg()
+		} else {
+			return nil, errors.Newf(codes.Invalid, "rolling does not support the aggregate %q", spec.Fn)
+		}
+		vf := agg.NewFloatAgg()
+		if vf == nil {
+			return nil, errors.Newf(codes.Invalid, "rolling does not support the aggregate %q", spec.Fn)
+		}
+		t.typ = vf.Type()
+		t.newAggregate = func() rollingAggregate {
+			return &rollingSimpleAggregate{agg: agg, mem: mem}
+		}
+	}
+	return t, nil
+}
+
+func (t *rollingTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	builder, created := t.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "rolling found duplicate table with key: %v", tbl.Key())
+	}
+	for _, label := range []string{t.timeColumn, t.column} {
+		if tbl.Key().HasCol(label) {
+			return errors.Newf(codes.FailedPrecondition, "rolling cannot aggregate over group key column %s", label)
+		}
+	}
+	s, err := readSeries(RollingKind, tbl, nil, t.timeColumn, t.column)
+	if err != nil {
+		return err
+	}
+
+	if err := execute.AddTableKeyCols(tbl.Key(), builder); err != nil {
+		return err
+	}
+	timeIdx, err := builder.AddCol(flux.ColMeta{Label: t.timeColumn, Type: flux.TTime})
+	if err != nil {
+		return err
+	}
+	valueIdx, err := builder.AddCol(flux.ColMeta{Label: t.column, Type: t.typ})
+	if err != nil {
+		return err
+	}
+
+	// Rows with a null time or value are not part of any window.
+	ts := make([]int64, 0, len(s.times))
+	vs := make([]float64, 0, len(s.values))
+	for i, valid := range s.valid {
+		if !valid {
+			continue
+		}
+		if t.n == 0 && len(ts) > 0 && s.times[i] < ts[len(ts)-1] {
+			return errors.Newf(codes.FailedPrecondition, "rolling requires the times in column %s to be sorted", t.timeColumn)
+		}
+		ts = append(ts, s.times[i])
+		vs = append(vs, s.values[i])
+	}
+
+	nrows := 0
+	emit := func(stop int64, v values.Value) error {
+		if err := builder.AppendTime(timeIdx, execute.Time(stop)); err != nil {
+			return err
+		}
+		nrows++
+		return builder.AppendValue(valueIdx, v)
+	}
+	if t.n > 0 {
+		err = t.rollRows(ts, vs, emit)
+	} else {
+		err = t.rollTime(ts, vs, emit)
+	}
+	if err != nil {
+		return err
+	}
+	return execute.AppendKeyValuesN(tbl.Key(), builder, nrows)
+}
+
+// rollRows emits the aggregate of each window of n rows
+// with the time of the last row in the window.
+func (t *rollingTransformation) rollRows(ts []int64, vs []float64, emit func(int64, values.Value) error) error {
+	agg := t.newAggregate()
+	for i, v := range vs {
+		agg.push(v)
+		start := i - t.n + 1
+		if start > 0 {
+			agg.pop(vs[start-1])
+		}
+		if start < 0 {
+			continue
+		}
+		value, err := agg.value(vs[start : i+1])
+		if err != nil {
+			return err
+		}
+		if err := emit(ts[i], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollTime emits the aggregate of each time window that contains
+// at least one row with the stop time of the window.
+// The windows start every t.every since the Unix epoch and last for t.period.
+func (t *rollingTransformation) rollTime(ts []int64, vs []float64, emit func(int64, values.Value) error) error {
+	if len(ts) == 0 {
+		return nil
+	}
+	agg := t.newAggregate()
+	last := ts[len(ts)-1]
+	// k is the index of the first window that contains the first row.
+	k := floorDiv(ts[0]-t.period, t.every) + 1
+	lo, hi := 0, 0
+	for k*t.every <= last {
+		start, stop := k*t.every, k*t.every+t.period
+		for ; hi < len(ts) && ts[hi] < stop; hi++ {
+			agg.push(vs[hi])
+		}
+		for ; lo < hi && ts[lo] < start; lo++ {
+			agg.pop(vs[lo])
+		}
+		if lo < hi {
+			value, err := agg.value(vs[lo:hi])
+			if err != nil {
+				return err
+			}
+			if err := emit(stop, value); err != nil {
+				return err
+			}
+		}
+		k++
+
+		// Skip the empty windows before the next row.
+		if lo == hi && hi < len(ts) {
+			if next := floorDiv(ts[hi]-t.period, t.every) + 1; next > k {
+				k = next
+			}
+		}
+	}
+	return nil
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func (t *rollingTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return t.d.RetractTable(key)
+}
+
+func (t *rollingTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return t.d.UpdateWatermark(mark)
+}
+func (t *rollingTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return t.d.UpdateProcessingTime(pt)
+}
+func (t *rollingTransformation) Finish(id execute.DatasetID, err error) {
+	t.d.Finish(err)
+}
+
+// rollingAggregate is the aggregate of a sliding window of values.
+// Values are pushed to the window in order and popped from it
+// in the same order.
+type rollingAggregate interface {
+	push(v float64)
+	pop(v float64)
+	// value returns the aggregate of the values in the window,
+	// which is never empty.
+	value(window []float64) (values.Value, error)
+}
+
+type rollingSum struct {
+	sum float64
+}
+
+func (a *rollingSum) push(v float64) { a.sum += v }
+func (a *rollingSum) pop(v float64)  { a.sum -= v }
+func (a *rollingSum) value([]float64) (values.Value, error) {
+	return values.NewFloat(a.sum), nil
+}
+
+type rollingMean struct {
+	sum float64
+	n   int
+}
+
+func (a *rollingMean) push(v float64) { a.sum += v; a.n++ }
+func (a *rollingMean) pop(v float64)  { a.sum -= v; a.n-- }
+func (a *rollingMean) value([]float64) (values.Value, error) {
+	return values.NewFloat(a.sum / float64(a.n)), nil
+}
+
+type rollingCount struct {
+	n int64
+}
+
+func (a *rollingCount) push(float64) { a.n++ }
+func (a *rollingCount) pop(float64)  { a.n-- }
+func (a *rollingCount) value([]float64) (values.Value, error) {
+	return values.NewInt(a.n), nil
+}
+
+// rollingStddev is the sample standard deviation of the window
+// updated with Welford's method as values are pushed and popped.
+type rollingStddev struct {
+	n, mean, m2 float64
+}
+
+func (a *rollingStddev) push(v float64) {
+	a.n++
+	delta := v - a.mean
+	a.mean += delta / a.n
+	a.m2 += delta * (v - a.mean)
+}
+func (a *rollingStddev) pop(v float64) {
+	a.n--
+	if a.n == 0 {
+		a.mean, a.m2 = 0, 0
+		return
+	}
+	delta := v - a.mean
+	a.mean -= delta / a.n
+	a.m2 -= delta * (v - a.mean)
+}
+func (a *rollingStddev) value([]float64) (values.Value, error) {
+	if a.n < 2 {
+		return values.NewNull(semantic.BasicFloat), nil
+	}
+	return values.NewFloat(math.Sqrt(math.Max(a.m2, 0) / (a.n - 1))), nil
+}
+
+// rollingExtreme is the smallest or largest value of the window.
+// It keeps a deque of the values that can still become the extreme
+// value, which are the values that are not preceded by a more extreme value.
+type rollingExtreme struct {
+	less  func(a, b float64) bool
+	deque []rollingEntry
+	// pushed and popped count the values that were pushed and popped.
+	pushed, popped int
+}
+
+type rollingEntry struct {
+	seq int
+	v   float64
+}
+
+func (a *rollingExtreme) push(v float64) {
+	for len(a.deque) > 0 && !a.less(a.deque[len(a.deque)-1].v, v) {
+		a.deque = a.deque[:len(a.deque)-1]
+	}
+	a.deque = append(a.deque, rollingEntry{seq: a.pushed, v: v})
+	a.pushed++
+}
+func (a *rollingExtreme) pop(float64) {
+	if len(a.deque) > 0 && a.deque[0].seq == a.popped {
+		a.deque = a.deque[1:]
+	}
+	a.popped++
+}
+func (a *rollingExtreme) value([]float64) (values.Value, error) {
+	return values.NewFloat(a.deque[0].v), nil
+}
+
+// rollingSpread is the difference between the largest
+// and smallest values of the window.
+type rollingSpread struct {
+	min, max rollingExtreme
+}
+
+func (a *rollingSpread) push(v float64) {
+	a.min.push(v)
+	a.max.push(v)
+}
+func (a *rollingSpread) pop(v float64) {
+	a.min.pop(v)
+	a.max.pop(v)
+}
+func (a *rollingSpread) value([]float64) (values.Value, error) {
+	return values.NewFloat(a.max.deque[0].v - a.min.deque[0].v), nil
+}
+
+// rollingSimpleAggregate computes a simple aggregate
+// from all of the values in each window.
+type rollingSimpleAggregate struct {
+	agg execute.SimpleAggregate
+	mem memory.Allocator
+}
+
+func (a *rollingSimpleAggregate) push(float64) {}
+func (a *rollingSimpleAggregate) pop(float64)  {}
+func (a *rollingSimpleAggregate) value(window []float64) (values.Value, error) {
+	return execute.AggregateFloats(a.agg, window, a.mem)
+}
+
+// rollingReducer calls a Flux function with the values of each window.
+type rollingReducer struct {
+	ctx   context.Context
+	fn    compiler.Func
+	input values.Object
+}
+
+func (a *rollingReducer) push(float64) {}
+func (a *rollingReducer) pop(float64)  {}
+func (a *rollingReducer) value(window []float64) (values.Value, error) {
+	elements := make([]values.Value, len(window))
+	for i, v := range window {
+		elements[i] = values.NewFloat(v)
+	}
+	a.input.Set("values", values.NewArrayWithBacking(semantic.NewArrayType(semantic.BasicFloat), elements))
+	return a.fn.Eval(a.ctx, a.input)
+}
@@ -0,0 +1,349 @@
+package universe_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestRolling_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "time windows",
+			Raw:  `from(bucket:"mydb") |> range(start:-1h) |> rolling(every: 1m, period: 5m, fn: "max")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mydb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop:        flux.Now,
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "rolling2",
+						Spec: &universe.RollingOpSpec{
+							Every:      flux.ConvertDuration(time.Minute),
+							Period:     flux.ConvertDuration(5 * time.Minute),
+							Fn:         "max",
+							Column:     execute.DefaultValueColLabel,
+							TimeColumn: execute.DefaultTimeColLabel,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "rolling2"},
+				},
+			},
+		},
+		{
+			Name:    "n and every",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> rolling(n: 3, every: 1m)`,
+			WantErr: true,
+		},
+		{
+			Name:    "period without every",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> rolling(n: 3, period: 1m)`,
+			WantErr: true,
+		},
+		{
+			Name:    "quantile without q",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> rolling(n: 3, fn: "quantile")`,
+			WantErr: true,
+		},
+		{
+			Name:    "unknown aggregate",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> rolling(n: 3, fn: "median")`,
+			WantErr: true,
+		},
+		{
+			Name:    "fn and reducer",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> rolling(n: 3, fn: "sum", reducer: (values) => values[0])`,
+			WantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestRolling_Process(t *testing.T) {
+	sec := func(n int) execute.Time {
+		return execute.Time(time.Duration(n) * time.Second)
+	}
+	testCases := []struct {
+		name    string
+		spec    *universe.RollingProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "rows mean",
+			spec: &universe.RollingProcedureSpec{
+				N:          2,
+				Fn:         "mean",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{sec(1), int64(1), "a"},
+					{sec(2), int64(2), "a"},
+					{sec(3), nil, "a"},
+					{sec(4), int64(4), "a"},
+					{sec(5), int64(6), "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{sec(2), 1.5, "a"},
+					{sec(4), 3.0, "a"},
+					{sec(5), 5.0, "a"},
+				},
+			}},
+		},
+		{
+			name: "rows stddev",
+			spec: &universe.RollingProcedureSpec{
+				N:          3,
+				Fn:         "stddev",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(1), 1.0},
+					{sec(2), 2.0},
+					{sec(3), 3.0},
+					{sec(4), 5.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(3), 1.0},
+					{sec(4), math.Sqrt(7.0 / 3)},
+				},
+			}},
+		},
+		{
+			name: "rows spread",
+			spec: &universe.RollingProcedureSpec{
+				N:          3,
+				Fn:         "spread",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(1), 3.0},
+					{sec(2), 1.0},
+					{sec(3), 2.0},
+					{sec(4), 5.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(3), 2.0},
+					{sec(4), 4.0},
+				},
+			}},
+		},
+		{
+			name: "rows registered aggregate",
+			spec: &universe.RollingProcedureSpec{
+				N:          3,
+				Fn:         "skew",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(1), 3.0},
+					{sec(2), 1.0},
+					{sec(3), 2.0},
+					{sec(4), 5.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(3), 0.0},
+					{sec(4), math.Sqrt(3) * (70.0 / 9) / math.Pow(26.0/3, 1.5)},
+				},
+			}},
+		},
+		{
+			name: "time windows max",
+			spec: &universe.RollingProcedureSpec{
+				Every:      flux.ConvertDuration(10 * time.Second),
+				Period:     flux.ConvertDuration(20 * time.Second),
+				Fn:         "max",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(0), 1.0},
+					{sec(5), 5.0},
+					{sec(12), 3.0},
+					{sec(25), 2.0},
+					{sec(78), 7.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(10), 5.0},
+					{sec(20), 5.0},
+					{sec(30), 3.0},
+					{sec(40), 2.0},
+					{sec(80), 7.0},
+					{sec(90), 7.0},
+				},
+			}},
+		},
+		{
+			name: "time windows count",
+			spec: &universe.RollingProcedureSpec{
+				Every:      flux.ConvertDuration(10 * time.Second),
+				Period:     flux.ConvertDuration(10 * time.Second),
+				Fn:         "count",
+				Column:     "v",
+				TimeColumn: "t",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "t", Type: flux.TTime},
+					{Label: "v", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(1), 1.0},
+					{sec(2), 2.0},
+					{sec(15), 3.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "t", Type: flux.TTime},
+					{Label: "v", Type: flux.TInt},
+				},
+				Data: [][]interface{}{
+					{sec(10), int64(2)},
+					{sec(20), int64(1)},
+				},
+			}},
+		},
+		{
+			name: "unsorted times",
+			spec: &universe.RollingProcedureSpec{
+				Every:      flux.ConvertDuration(10 * time.Second),
+				Period:     flux.ConvertDuration(10 * time.Second),
+				Fn:         "sum",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{sec(2), 1.0},
+					{sec(1), 2.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "rolling requires the times in column _time to be sorted"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					tr, err := universe.NewRollingTransformation(context.Background(), d, c, executetest.UnlimitedAllocator, tc.spec)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr
+				},
+				floatOptions,
+			)
+		})
+	}
+}
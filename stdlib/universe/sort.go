@@ -3,7 +3,10 @@ package universe
 import (
 	"container/heap"
 	"context"
+	"io"
+	"math"
 	"sort"
+	"sync/atomic"
 
 	"github.com/apache/arrow/go/v7/arrow/memory"
 	"github.com/influxdata/flux"
@@ -15,6 +18,7 @@ import (
 	"github.com/influxdata/flux/internal/arrowutil"
 	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/internal/mutable"
+	"github.com/influxdata/flux/internal/spill"
 	"github.com/influxdata/flux/interpreter"
 	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/runtime"
@@ -70,7 +74,6 @@ func (s *SortOpSpec) Kind() flux.OperationKind {
 }
 
 type SortProcedureSpec struct {
-	plan.DefaultCost
 	Columns []string
 	Desc    bool
 }
@@ -97,6 +100,22 @@ func (s *SortProcedureSpec) Copy() plan.ProcedureSpec {
 	return &ns
 }
 
+// Cost estimates the cost of sorting every table in memory.
+func (s *SortProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	in := plan.SumStatistics(inStats)
+	return plan.Cost{
+		CPU: in.Cardinality * log2(in.RowsPerGroup()),
+		MEM: in.Cardinality,
+	}, in
+}
+
+func log2(n int64) int64 {
+	if n <= 1 {
+		return 1
+	}
+	return int64(math.Ceil(math.Log2(float64(n))))
+}
+
 func (s *SortProcedureSpec) OutputAttributes() plan.PhysicalAttributes {
 	return plan.PhysicalAttributes{
 		plan.CollationKey: &plan.CollationAttr{
@@ -116,21 +135,27 @@ func createSortTransformation(id execute.DatasetID, mode execute.AccumulationMod
 	if !ok {
 		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
 	}
-	return NewSortTransformation(id, s, a.Allocator())
+	return newSortTransformation(id, s, a.Allocator(), execute.GetSpillDir(a.Context()))
 }
 
 type sortTransformation struct {
 	execute.ExecutionNode
 	d       *execute.PassthroughDataset
 	mem     memory.Allocator
+	dir     string
 	cols    []string
 	compare arrowutil.CompareFunc
 }
 
 func NewSortTransformation(id execute.DatasetID, spec *SortProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	return newSortTransformation(id, spec, mem, "")
+}
+
+func newSortTransformation(id execute.DatasetID, spec *SortProcedureSpec, mem memory.Allocator, dir string) (execute.Transformation, execute.Dataset, error) {
 	t := &sortTransformation{
 		d:       execute.NewPassthroughDataset(id),
 		mem:     mem,
+		dir:     dir,
 		cols:    spec.Columns,
 		compare: arrowutil.Compare,
 	}
@@ -149,17 +174,62 @@ func (s *sortTransformation) Process(id execute.DatasetID, tbl flux.Table) error
 		sortCols: sortCols,
 		compare:  s.compare,
 	}
+
+	// When the allocator runs out of memory, the buffers that
+	// have been read so far are merged into a sorted run and
+	// written to disk. The runs are merged with the remaining
+	// buffers when the table is read.
+	var runs []*spill.File
 	if err := tbl.Do(func(cr flux.ColReader) error {
+		if len(mh.items) > 0 && spill.ShouldSpill(s.dir, s.mem, spill.Size(cr)+mh.mergeSize()) {
+			run, err := mh.spill(s.dir, s.mem)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+		}
 		return s.processView(mh, cr)
 	}); err != nil {
+		for _, run := range runs {
+			_ = run.Remove()
+		}
+		mh.Release()
 		return err
 	}
 
-	out, err := mh.Table(-1, s.mem)
-	if err != nil {
-		return err
+	if len(runs) == 0 {
+		out, err := mh.Table(-1, s.mem)
+		if err != nil {
+			return err
+		}
+		return s.d.Process(out)
 	}
-	return s.d.Process(out)
+
+	// Spill the remaining buffers too so there is memory
+	// to read each of the runs while they are merged.
+	if len(mh.items) > 0 {
+		run, err := mh.spill(s.dir, s.mem)
+		if err != nil {
+			for _, run := range runs {
+				_ = run.Remove()
+			}
+			return err
+		}
+		runs = append(runs, run)
+	}
+
+	for i, run := range runs {
+		item, err := openSortRun(run, s.mem)
+		if err != nil {
+			for _, run := range runs[i+1:] {
+				_ = run.Remove()
+			}
+			mh.Release()
+			return err
+		}
+		mh.items = append(mh.items, item)
+	}
+	return s.d.Process(&sortedRunsTable{mh: mh, mem: s.mem})
 }
 
 func (s *sortTransformation) sortCols(key flux.GroupKey, cols []flux.ColMeta) []int {
@@ -265,18 +335,87 @@ type sortTableMergeHeapItem struct {
 	cr        flux.ColReader
 	indices   *array.Int
 	i, offset int
+
+	// run is set when the rows are read from a sorted run
+	// that was spilled to disk.
+	run *sortRun
+}
+
+// sortRun reads the buffers of a sorted run from disk.
+type sortRun struct {
+	file *spill.File
+	r    *spill.Reader
+
+	// pending is the number of rows in the file
+	// that have not been read yet.
+	pending int
+}
+
+// openSortRun opens the sorted run in the file and reads
+// its first buffer into a new item.
+func openSortRun(file *spill.File, mem memory.Allocator) (*sortTableMergeHeapItem, error) {
+	r, err := file.Open(mem)
+	if err != nil {
+		_ = file.Remove()
+		return nil, err
+	}
+	item := &sortTableMergeHeapItem{
+		run: &sortRun{
+			file:    file,
+			r:       r,
+			pending: file.Len(),
+		},
+	}
+	if ok, err := item.nextBuffer(); !ok {
+		item.Release()
+		if err == nil {
+			err = errors.New(codes.Internal, "sort run is empty")
+		}
+		return nil, err
+	}
+	return item, nil
 }
 
-func (s *sortTableMergeHeapItem) Next() bool {
+// Len returns the number of rows that have not been merged yet.
+func (s *sortTableMergeHeapItem) Len() int {
+	n := s.cr.Len() - s.i
+	if s.run != nil {
+		n += s.run.pending
+	}
+	return n
+}
+
+func (s *sortTableMergeHeapItem) Next() (bool, error) {
 	s.i++
 	if s.i >= s.cr.Len() {
-		return false
+		if s.run == nil {
+			return false, nil
+		}
+		return s.nextBuffer()
 	}
 	s.offset = s.i
 	if s.indices != nil {
 		s.offset = int(s.indices.Value(s.i))
 	}
-	return true
+	return true, nil
+}
+
+// nextBuffer reads the next buffer of the sorted run.
+// The buffers in a run are already sorted.
+func (s *sortTableMergeHeapItem) nextBuffer() (bool, error) {
+	cr, err := s.run.r.Read()
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if s.cr != nil {
+		s.cr.Release()
+	}
+	s.cr = cr
+	s.i, s.offset = 0, 0
+	s.run.pending -= cr.Len()
+	return true, nil
 }
 
 func (s *sortTableMergeHeapItem) Release() {
@@ -288,6 +427,11 @@ func (s *sortTableMergeHeapItem) Release() {
 		s.cr.Release()
 		s.cr = nil
 	}
+	if s.run != nil {
+		_ = s.run.r.Close()
+		_ = s.run.file.Remove()
+		s.run = nil
+	}
 }
 
 type sortTableMergeHeap struct {
@@ -331,11 +475,26 @@ func (s *sortTableMergeHeap) Pop() interface{} {
 func (s *sortTableMergeHeap) ValueLen() int {
 	var n int
 	for _, item := range s.items {
-		n += item.cr.Len() - item.i
+		n += item.Len()
 	}
 	return n
 }
 
+// mergeSize estimates the memory needed to merge the items
+// in the heap. Merging needs builders for every column and the
+// buffers that are created from them.
+func (s *sortTableMergeHeap) mergeSize() int {
+	return 2 * len(s.cols) * table.BufferSize * 8
+}
+
+// Release releases the items in the heap.
+func (s *sortTableMergeHeap) Release() {
+	for _, item := range s.items {
+		item.Release()
+	}
+	s.items = s.items[:0]
+}
+
 func (s *sortTableMergeHeap) Table(limit int, mem memory.Allocator) (flux.Table, error) {
 	if s.ValueLen() == 0 {
 		// Degenerate case where there are no rows to merge sort.
@@ -347,6 +506,41 @@ func (s *sortTableMergeHeap) Table(limit int, mem memory.Allocator) (flux.Table,
 
 	// Construct the buffered builder that will contain the full table.
 	builder := table.NewBufferedBuilder(s.key, mem)
+	if err := s.merge(limit, mem, func(buffer *arrow.TableBuffer) error {
+		return builder.AppendBuffer(buffer)
+	}); err != nil {
+		return nil, err
+	}
+
+	// Determine the next buffer size.
+	return builder.Table()
+}
+
+// spill merges the items in the heap into a sorted run
+// that is written to a new file in dir.
+func (s *sortTableMergeHeap) spill(dir string, mem memory.Allocator) (*spill.File, error) {
+	w, err := spill.Create(dir, s.key, s.cols)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.merge(-1, mem, func(buffer *arrow.TableBuffer) error {
+		return w.Write(buffer)
+	}); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	return w.Close()
+}
+
+// merge merges the items in the heap and passes each merged buffer to fn.
+// The buffer is released after fn returns. Merging stops after limit rows
+// if limit is not negative.
+//
+// All of the items are released when merge returns.
+func (s *sortTableMergeHeap) merge(limit int, mem memory.Allocator, fn func(buffer *arrow.TableBuffer) error) error {
+	// Release the remaining items and clear the items.
+	// There are either none left or the remaining ones were filtered.
+	defer s.Release()
 
 	// Initialize the heap now that we have all of the data.
 	heap.Init(s)
@@ -391,29 +585,23 @@ func (s *sortTableMergeHeap) Table(limit int, mem memory.Allocator) (flux.Table,
 			n = limit
 		}
 
-		buffer := s.NextBuffer(builders, keys, n, mem)
+		buffer, err := s.NextBuffer(builders, keys, n, mem)
+		if err != nil {
+			return err
+		}
 		if limit > 0 {
 			limit -= buffer.Len()
 		}
-		if err := builder.AppendBuffer(&buffer); err != nil {
-			buffer.Release()
-			return nil, err
-		}
+		err = fn(&buffer)
 		buffer.Release()
+		if err != nil {
+			return err
+		}
 	}
-
-	// Release the remaining items and clear the items.
-	// There are either none left or the remaining ones were filtered.
-	for _, item := range s.items {
-		item.Release()
-	}
-	s.items = s.items[:0]
-
-	// Determine the next buffer size.
-	return builder.Table()
+	return nil
 }
 
-func (s *sortTableMergeHeap) NextBuffer(builders []array.Builder, keys []array.Array, n int, mem memory.Allocator) arrow.TableBuffer {
+func (s *sortTableMergeHeap) NextBuffer(builders []array.Builder, keys []array.Array, n int, mem memory.Allocator) (arrow.TableBuffer, error) {
 	// Ensure there is enough space in each builder
 	for _, b := range builders {
 		if b == nil {
@@ -433,7 +621,9 @@ func (s *sortTableMergeHeap) NextBuffer(builders []array.Builder, keys []array.A
 		}
 
 		// Move to the next row.
-		if item.Next() {
+		if ok, err := item.Next(); err != nil {
+			return arrow.TableBuffer{}, err
+		} else if ok {
 			// Fix the heap to ensure the next value.
 			heap.Fix(s, 0)
 		} else {
@@ -471,7 +661,35 @@ func (s *sortTableMergeHeap) NextBuffer(builders []array.Builder, keys []array.A
 		}
 		buffer.Values[i] = builders[i].NewArray()
 	}
-	return buffer
+	return buffer, nil
+}
+
+// sortedRunsTable is a table that merges the sorted runs
+// that were spilled to disk with the buffers held in memory
+// while it is read.
+type sortedRunsTable struct {
+	used int32
+	mh   *sortTableMergeHeap
+	mem  memory.Allocator
+}
+
+func (t *sortedRunsTable) Key() flux.GroupKey   { return t.mh.key }
+func (t *sortedRunsTable) Cols() []flux.ColMeta { return t.mh.cols }
+func (t *sortedRunsTable) Empty() bool          { return false }
+
+func (t *sortedRunsTable) Do(f func(flux.ColReader) error) error {
+	if !atomic.CompareAndSwapInt32(&t.used, 0, 1) {
+		return errors.New(codes.Internal, "table already read")
+	}
+	return t.mh.merge(-1, t.mem, func(buffer *arrow.TableBuffer) error {
+		return f(buffer)
+	})
+}
+
+func (t *sortedRunsTable) Done() {
+	if atomic.CompareAndSwapInt32(&t.used, 0, 1) {
+		t.mh.Release()
+	}
 }
 
 // RemoveRedundantSort is a planner rule that will remove a sort
@@ -16,7 +16,7 @@ import (
 )
 
 func init() {
-	plan.RegisterPhysicalRules(SortLimitRule{})
+	plan.RegisterCostBasedRules(SortLimitRule{})
 	execute.RegisterTransformation(SortLimitKind, createSortLimitTransformation)
 }
 
@@ -37,6 +37,34 @@ func (s *SortLimitProcedureSpec) Copy() plan.ProcedureSpec {
 	return &ns
 }
 
+// Cost estimates the cost of keeping the first N rows of each table.
+// Every incoming chunk is sorted on its own and then merged with the
+// rows retained so far, so memory is bounded by N but the retained
+// rows are copied once per chunk.
+func (s *SortLimitProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	in := plan.SumStatistics(inStats)
+	if !in.IsKnown() {
+		return plan.Cost{}, plan.Statistics{}
+	}
+
+	groups := in.GroupCardinality
+	if groups < 1 {
+		groups = 1
+	}
+	rows := in.RowsPerGroup()
+	if rows > s.N {
+		rows = s.N
+	}
+	chunks := in.Cardinality/table.BufferSize + groups
+	return plan.Cost{
+		CPU: in.Cardinality*log2(table.BufferSize) + chunks*rows,
+		MEM: rows * groups,
+	}, plan.Statistics{
+		Cardinality:      rows * groups,
+		GroupCardinality: in.GroupCardinality,
+	}
+}
+
 func createSortLimitTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
 	s, ok := spec.(*SortLimitProcedureSpec)
 	if !ok {
@@ -195,6 +223,10 @@ func (s *sortLimitTransformation) Close() error {
 	return nil
 }
 
+// SortLimitRule merges a limit into the sort that precedes it when
+// the statistics of the input show that it is cheaper than sorting
+// every table in full. When nothing is known about the input,
+// the merge is always done.
 type SortLimitRule struct{}
 
 func (s SortLimitRule) Name() string {
@@ -218,6 +250,18 @@ func (s SortLimitRule) Rewrite(ctx context.Context, node plan.Node) (plan.Node,
 		N:                 limitSpec.N,
 	}
 
+	if preds := sortNode.Predecessors(); len(preds) == 1 {
+		if _, inStats := plan.EstimateCost(ctx, preds[0]); inStats.IsKnown() {
+			in := []plan.Statistics{inStats}
+			sortCost, sortStats := sortSpec.Cost(in)
+			limitCost, _ := limitSpec.Cost([]plan.Statistics{sortStats})
+			mergedCost, _ := sortLimitSpec.Cost(in)
+			if !mergedCost.Less(plan.Add(sortCost, limitCost)) {
+				return node, false, nil
+			}
+		}
+	}
+
 	n, err := plan.MergeToPhysicalNode(node, sortNode, sortLimitSpec)
 	if err != nil {
 		return nil, false, err
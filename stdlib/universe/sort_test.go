@@ -1,11 +1,14 @@
 package universe_test
 
 import (
+	"context"
+	"math"
 	"testing"
 
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/gen"
 	"github.com/influxdata/flux/memory"
 	"github.com/influxdata/flux/stdlib/universe"
 )
@@ -384,3 +387,104 @@ func TestSort_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestSort_Spill(t *testing.T) {
+	// Limit the memory so the input does not fit
+	// and the sort has to spill sorted runs to disk.
+	limit := int64(256 * 1024)
+	mem := &memory.ResourceAllocator{Limit: &limit}
+
+	seed := int64(1)
+	input, err := gen.Input(context.Background(), gen.Schema{
+		NumPoints: 50000,
+		Seed:      &seed,
+		Alloc:     mem,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, d, err := universe.NewSpillingSortTransformation(
+		executetest.RandomDatasetID(),
+		&universe.SortProcedureSpec{Columns: []string{"_value"}},
+		mem,
+		t.TempDir(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	sink := &spillSink{
+		fn: func(tbl flux.Table) error {
+			prev := math.Inf(-1)
+			return tbl.Do(func(cr flux.ColReader) error {
+				vs := cr.Floats(execute.ColIdx("_value", cr.Cols()))
+				for i := 0; i < vs.Len(); i++ {
+					if v := vs.Value(i); v < prev {
+						t.Fatalf("table is not sorted at row %d: %v < %v", n+i, v, prev)
+					} else {
+						prev = v
+					}
+				}
+				n += cr.Len()
+				return nil
+			})
+		},
+	}
+	d.AddTransformation(sink)
+
+	parentID := executetest.RandomDatasetID()
+	if err := input.Do(func(tbl flux.Table) error {
+		return tr.Process(parentID, tbl)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tr.Finish(parentID, nil)
+
+	if sink.err != nil {
+		t.Fatal(sink.err)
+	}
+	if want, got := 50000, n; want != got {
+		t.Errorf("unexpected number of rows -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("memory was not released: %d bytes", got)
+	}
+}
+
+// spillContext returns a context with a spill directory
+// so the transformations can write buffered data to disk.
+func spillContext(t *testing.T) context.Context {
+	deps := execute.DefaultExecutionDependencies()
+	deps.SpillDir = t.TempDir()
+	return deps.Inject(context.Background())
+}
+
+// spillSink is a transformation that passes each table it receives
+// to fn without buffering it so the output of a transformation that
+// spills does not count towards the memory limit.
+type spillSink struct {
+	fn  func(tbl flux.Table) error
+	err error
+}
+
+func (s *spillSink) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return nil
+}
+
+func (s *spillSink) Process(id execute.DatasetID, tbl flux.Table) error {
+	return s.fn(tbl)
+}
+
+func (s *spillSink) UpdateWatermark(id execute.DatasetID, t execute.Time) error {
+	return nil
+}
+
+func (s *spillSink) UpdateProcessingTime(id execute.DatasetID, t execute.Time) error {
+	return nil
+}
+
+func (s *spillSink) Finish(id execute.DatasetID, err error) {
+	s.err = err
+}
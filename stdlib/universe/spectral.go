@@ -0,0 +1,327 @@
+package universe
+
+import (
+	"math/cmplx"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+const (
+	FFTKind             = "fft"
+	PeriodogramKind     = "periodogram"
+	AutocorrelationKind = "autocorrelation"
+
+	frequencyColLabel       = "frequency"
+	magnitudeColLabel       = "magnitude"
+	phaseColLabel           = "phase"
+	powerColLabel           = "power"
+	lagColLabel             = "lag"
+	autocorrelationColLabel = "autocorrelation"
+)
+
+// SpectralOpSpec is the operation spec of fft, periodogram
+// and autocorrelation. The function selects the analysis.
+type SpectralOpSpec struct {
+	Function   string `json:"function"`
+	MaxLag     int64  `json:"max_lag"`
+	Column     string `json:"column"`
+	TimeColumn string `json:"time_column"`
+}
+
+func init() {
+	for _, fn := range []string{FFTKind, PeriodogramKind, AutocorrelationKind} {
+		fn := fn
+		signature := runtime.MustLookupBuiltinType("universe", fn)
+		runtime.RegisterPackageValue("universe", fn, flux.MustValue(flux.FunctionValue(fn, func(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+			return createSpectralOpSpec(fn, args, a)
+		}, signature)))
+		plan.RegisterProcedureSpec(plan.ProcedureKind(fn), newSpectralProcedure, flux.OperationKind(fn))
+		execute.RegisterTransformation(plan.ProcedureKind(fn), createSpectralTransformation)
+	}
+}
+
+func createSpectralOpSpec(fn string, args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := &SpectralOpSpec{
+		Function: fn,
+	}
+	if fn == AutocorrelationKind {
+		maxLag, err := args.GetRequiredInt("maxLag")
+		if err != nil {
+			return nil, err
+		}
+		if maxLag < 0 {
+			return nil, errors.Newf(codes.Invalid, "maxLag must not be negative, got %d", maxLag)
+		}
+		spec.MaxLag = maxLag
+	}
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+	if col, ok, err := args.GetString("timeColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.TimeColumn = col
+	} else {
+		spec.TimeColumn = execute.DefaultTimeColLabel
+	}
+	return spec, nil
+}
+
+func (s *SpectralOpSpec) Kind() flux.OperationKind {
+	return flux.OperationKind(s.Function)
+}
+
+type SpectralProcedureSpec struct {
+	plan.DefaultCost
+	Function   string
+	MaxLag     int64
+	Column     string
+	TimeColumn string
+}
+
+func newSpectralProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*SpectralOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &SpectralProcedureSpec{
+		Function:   spec.Function,
+		MaxLag:     spec.MaxLag,
+		Column:     spec.Column,
+		TimeColumn: spec.TimeColumn,
+	}, nil
+}
+
+func (s *SpectralProcedureSpec) Kind() plan.ProcedureKind {
+	return plan.ProcedureKind(s.Function)
+}
+func (s *SpectralProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(SpectralProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *SpectralProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createSpectralTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*SpectralProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewSpectralTransformation(d, cache, s)
+	return t, d, nil
+}
+
+type spectralTransformation struct {
+	execute.ExecutionNode
+	d     execute.Dataset
+	cache execute.TableBuilderCache
+
+	fn         string
+	maxLag     int
+	column     string
+	timeColumn string
+}
+
+func NewSpectralTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *SpectralProcedureSpec) *spectralTransformation {
+	return &spectralTransformation{
+		d:          d,
+		cache:      cache,
+		fn:         spec.Function,
+		maxLag:     int(spec.MaxLag),
+		column:     spec.Column,
+		timeColumn: spec.TimeColumn,
+	}
+}
+
+func (st *spectralTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	builder, created := st.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "%s found duplicate table with key: %v", st.fn, tbl.Key())
+	}
+	s, err := readSeries(st.fn, tbl, nil, st.timeColumn, st.column)
+	if err != nil {
+		return err
+	}
+
+	// The analysis assumes that the points are evenly spaced
+	// so there must be a value for every time and the same
+	// interval between each time.
+	var interval int64
+	for i := range s.times {
+		if !s.valid[i] {
+			return errors.Newf(codes.FailedPrecondition, "%s found null in column %s or %s", st.fn, st.column, st.timeColumn)
+		}
+		if i == 1 {
+			interval = s.times[1] - s.times[0]
+		}
+		if i > 0 && (interval <= 0 || s.times[i]-s.times[i-1] != interval) {
+			return errors.Newf(codes.FailedPrecondition, "%s requires regularly spaced times in column %s; resample the data with aggregateWindow() first", st.fn, st.timeColumn)
+		}
+	}
+
+	if err := execute.AddTableKeyCols(tbl.Key(), builder); err != nil {
+		return err
+	}
+	var n int
+	switch st.fn {
+	case FFTKind, PeriodogramKind:
+		n, err = st.spectrum(builder, s.values, interval)
+	case AutocorrelationKind:
+		n, err = st.autocorrelation(builder, s.values)
+	default:
+		err = errors.Newf(codes.Internal, "unknown spectral function %q", st.fn)
+	}
+	if err != nil {
+		return err
+	}
+	return execute.AppendKeyValuesN(tbl.Key(), builder, n)
+}
+
+// spectrum appends the frequency in hertz of each bin of the discrete
+// Fourier transform of the values with the magnitude and phase of the bin
+// for fft or its power spectral density for periodogram.
+// It returns the number of rows that were appended.
+func (st *spectralTransformation) spectrum(builder execute.TableBuilder, vs []float64, interval int64) (int, error) {
+	labels := []string{frequencyColLabel, magnitudeColLabel, phaseColLabel}
+	if st.fn == PeriodogramKind {
+		labels = []string{frequencyColLabel, powerColLabel}
+	}
+	idxs, err := addFloatCols(builder, labels...)
+	if err != nil {
+		return 0, err
+	}
+	n := len(vs)
+	if n < 2 {
+		return 0, nil
+	}
+
+	// The periodogram is the spectrum of the deviation from the mean.
+	fs := 1 / seconds(interval)
+	if st.fn == PeriodogramKind {
+		var mean float64
+		for _, v := range vs {
+			mean += v
+		}
+		mean /= float64(n)
+		detrended := make([]float64, n)
+		for i, v := range vs {
+			detrended[i] = v - mean
+		}
+		vs = detrended
+	}
+
+	fft := fourier.NewFFT(n)
+	coeffs := fft.Coefficients(nil, vs)
+	for k, c := range coeffs {
+		if err := builder.AppendFloat(idxs[0], fft.Freq(k)*fs); err != nil {
+			return 0, err
+		}
+		if st.fn == FFTKind {
+			if err := builder.AppendFloat(idxs[1], cmplx.Abs(c)); err != nil {
+				return 0, err
+			}
+			if err := builder.AppendFloat(idxs[2], cmplx.Phase(c)); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		// The one-sided density counts the power of the negative
+		// frequencies in every bin except the zero and Nyquist bins.
+		power := real(c)*real(c) + imag(c)*imag(c)
+		power /= fs * float64(n)
+		if k > 0 && (n%2 == 1 || k < n/2) {
+			power *= 2
+		}
+		if err := builder.AppendFloat(idxs[1], power); err != nil {
+			return 0, err
+		}
+	}
+	return len(coeffs), nil
+}
+
+// autocorrelation appends the autocorrelation of the values at each lag
+// from zero to the maximum lag, or the last lag if there are fewer values.
+// It returns the number of rows that were appended.
+func (st *spectralTransformation) autocorrelation(builder execute.TableBuilder, vs []float64) (int, error) {
+	lagIdx, err := builder.AddCol(flux.ColMeta{
+		Label: lagColLabel,
+		Type:  flux.TInt,
+	})
+	if err != nil {
+		return 0, err
+	}
+	idxs, err := addFloatCols(builder, autocorrelationColLabel)
+	if err != nil {
+		return 0, err
+	}
+	n := len(vs)
+	maxLag := st.maxLag
+	if maxLag > n-1 {
+		maxLag = n - 1
+	}
+
+	var mean float64
+	for _, v := range vs {
+		mean += v
+	}
+	mean /= float64(n)
+	var variance float64
+	for _, v := range vs {
+		variance += (v - mean) * (v - mean)
+	}
+
+	for lag := 0; lag <= maxLag; lag++ {
+		if err := builder.AppendInt(lagIdx, int64(lag)); err != nil {
+			return 0, err
+		}
+		// A constant series has no autocorrelation.
+		if variance == 0 {
+			if err := builder.AppendNil(idxs[0]); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		var sum float64
+		for i := 0; i+lag < n; i++ {
+			sum += (vs[i] - mean) * (vs[i+lag] - mean)
+		}
+		if err := builder.AppendFloat(idxs[0], sum/variance); err != nil {
+			return 0, err
+		}
+	}
+	return maxLag + 1, nil
+}
+
+func (st *spectralTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return st.d.RetractTable(key)
+}
+
+func (st *spectralTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return st.d.UpdateWatermark(mark)
+}
+func (st *spectralTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return st.d.UpdateProcessingTime(pt)
+}
+func (st *spectralTransformation) Finish(id execute.DatasetID, err error) {
+	st.d.Finish(err)
+}
@@ -0,0 +1,273 @@
+package universe_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestSpectral_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "autocorrelation",
+			Raw:  `from(bucket:"mydb") |> range(start:-1h) |> autocorrelation(maxLag: 10, column: "v")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mydb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop:        flux.Now,
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "autocorrelation2",
+						Spec: &universe.SpectralOpSpec{
+							Function:   "autocorrelation",
+							MaxLag:     10,
+							Column:     "v",
+							TimeColumn: execute.DefaultTimeColLabel,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "autocorrelation2"},
+				},
+			},
+		},
+		{
+			Name:    "autocorrelation negative maxLag",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> autocorrelation(maxLag: -1)`,
+			WantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestSpectral_Process(t *testing.T) {
+	ms := func(n int) execute.Time {
+		return execute.Time(time.Duration(n) * time.Millisecond)
+	}
+	testCases := []struct {
+		name    string
+		spec    *universe.SpectralProcedureSpec
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "fft",
+			spec: &universe.SpectralProcedureSpec{
+				Function:   "fft",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TInt},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{ms(0), int64(1), "a"},
+					{ms(500), int64(2), "a"},
+					{ms(1000), int64(4), "a"},
+				},
+			}},
+			want: []*executetest.Table{{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "frequency", Type: flux.TFloat},
+					{Label: "magnitude", Type: flux.TFloat},
+					{Label: "phase", Type: flux.TFloat},
+					{Label: "t0", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{0.0, 7.0, 0.0, "a"},
+					{2.0 / 3, math.Sqrt(7), math.Atan2(math.Sqrt(3), -2), "a"},
+				},
+			}},
+		},
+		{
+			name: "periodogram",
+			spec: &universe.SpectralProcedureSpec{
+				Function:   "periodogram",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{ms(0), 1.0},
+					{ms(500), 2.0},
+					{ms(1000), 3.0},
+					{ms(1500), 4.0},
+				},
+			}},
+			want: []*executetest.Table{{
+				ColMeta: []flux.ColMeta{
+					{Label: "frequency", Type: flux.TFloat},
+					{Label: "power", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{0.0, 0.0},
+					{0.5, 2.0},
+					{1.0, 0.5},
+				},
+			}},
+		},
+		{
+			name: "autocorrelation",
+			spec: &universe.SpectralProcedureSpec{
+				Function:   "autocorrelation",
+				MaxLag:     5,
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{
+				&executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TUInt},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{ms(0), uint64(1), "a"},
+						{ms(10), uint64(2), "a"},
+						{ms(20), uint64(3), "a"},
+						{ms(30), uint64(4), "a"},
+					},
+				},
+				&executetest.Table{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TUInt},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{ms(0), uint64(1), "b"},
+						{ms(10), uint64(1), "b"},
+					},
+				},
+			},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "autocorrelation", Type: flux.TFloat},
+						{Label: "lag", Type: flux.TInt},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{1.0, int64(0), "a"},
+						{0.25, int64(1), "a"},
+						{-0.3, int64(2), "a"},
+						{-0.45, int64(3), "a"},
+					},
+				},
+				{
+					KeyCols: []string{"t0"},
+					ColMeta: []flux.ColMeta{
+						{Label: "autocorrelation", Type: flux.TFloat},
+						{Label: "lag", Type: flux.TInt},
+						{Label: "t0", Type: flux.TString},
+					},
+					Data: [][]interface{}{
+						{nil, int64(0), "b"},
+						{nil, int64(1), "b"},
+					},
+				},
+			},
+		},
+		{
+			name: "irregular times",
+			spec: &universe.SpectralProcedureSpec{
+				Function:   "fft",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{ms(0), 1.0},
+					{ms(10), 2.0},
+					{ms(30), 3.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "fft requires regularly spaced times in column _time; resample the data with aggregateWindow() first"),
+		},
+		{
+			name: "null value",
+			spec: &universe.SpectralProcedureSpec{
+				Function:   "periodogram",
+				Column:     "_value",
+				TimeColumn: "_time",
+			},
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{ms(0), 1.0},
+					{ms(10), nil},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, "periodogram found null in column _value or _time"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return universe.NewSpectralTransformation(d, c, tc.spec)
+				},
+				floatOptions,
+			)
+		})
+	}
+}
@@ -30,6 +30,7 @@ func init() {
 	runtime.RegisterPackageValue("universe", StddevKind, flux.MustValue(flux.FunctionValue(StddevKind, CreateStddevOpSpec, stddevSignature)))
 	plan.RegisterProcedureSpec(StddevKind, newStddevProcedure, StddevKind)
 	execute.RegisterTransformation(StddevKind, createStddevTransformation)
+	execute.RegisterSimpleAggregate(StddevKind, func() execute.SimpleAggregate { return &StddevAgg{Mode: modeSample} })
 }
 func CreateStddevOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
 	if err := a.AddParentFromArgs(args); err != nil {
@@ -0,0 +1,225 @@
+package universe
+
+import (
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/stdlib/universe/stl"
+	"github.com/influxdata/flux/values"
+)
+
+const STLKind = "stl"
+
+const (
+	trendColLabel    = "trend"
+	seasonalColLabel = "seasonal"
+)
+
+type STLOpSpec struct {
+	Period     int64  `json:"period"`
+	Seasonal   int64  `json:"seasonal"`
+	Trend      int64  `json:"trend"`
+	Robust     bool   `json:"robust"`
+	Column     string `json:"column"`
+	TimeColumn string `json:"time_column"`
+}
+
+func init() {
+	stlSignature := runtime.MustLookupBuiltinType("universe", STLKind)
+	runtime.RegisterPackageValue("universe", STLKind, flux.MustValue(flux.FunctionValue(STLKind, createSTLOpSpec, stlSignature)))
+	plan.RegisterProcedureSpec(STLKind, newSTLProcedure, STLKind)
+	execute.RegisterTransformation(STLKind, createSTLTransformation)
+}
+
+func createSTLOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+	spec := new(STLOpSpec)
+
+	period, err := args.GetRequiredInt("period")
+	if err != nil {
+		return nil, err
+	}
+	if period < 2 {
+		return nil, errors.Newf(codes.Invalid, "period must be at least 2, got %d", period)
+	}
+	spec.Period = period
+
+	if seasonal, ok, err := args.GetInt("seasonal"); err != nil {
+		return nil, err
+	} else if ok {
+		if seasonal < 3 || seasonal%2 == 0 {
+			return nil, errors.Newf(codes.Invalid, "seasonal must be an odd number of at least 3, got %d", seasonal)
+		}
+		spec.Seasonal = seasonal
+	}
+	if trend, ok, err := args.GetInt("trend"); err != nil {
+		return nil, err
+	} else if ok {
+		if trend < 3 || trend%2 == 0 {
+			return nil, errors.Newf(codes.Invalid, "trend must be an odd number of at least 3, got %d", trend)
+		}
+		spec.Trend = trend
+	}
+	if robust, ok, err := args.GetBool("robust"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Robust = robust
+	}
+
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	} else {
+		spec.Column = execute.DefaultValueColLabel
+	}
+	if col, ok, err := args.GetString("timeColumn"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.TimeColumn = col
+	} else {
+		spec.TimeColumn = execute.DefaultTimeColLabel
+	}
+	return spec, nil
+}
+
+func (s *STLOpSpec) Kind() flux.OperationKind {
+	return STLKind
+}
+
+type STLProcedureSpec struct {
+	plan.DefaultCost
+	Period     int64
+	Seasonal   int64
+	Trend      int64
+	Robust     bool
+	Column     string
+	TimeColumn string
+}
+
+func newSTLProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*STLOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &STLProcedureSpec{
+		Period:     spec.Period,
+		Seasonal:   spec.Seasonal,
+		Trend:      spec.Trend,
+		Robust:     spec.Robust,
+		Column:     spec.Column,
+		TimeColumn: spec.TimeColumn,
+	}, nil
+}
+
+func (s *STLProcedureSpec) Kind() plan.ProcedureKind {
+	return STLKind
+}
+func (s *STLProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := new(STLProcedureSpec)
+	*ns = *s
+	return ns
+}
+
+// TriggerSpec implements plan.TriggerAwareProcedureSpec
+func (s *STLProcedureSpec) TriggerSpec() plan.TriggerSpec {
+	return plan.NarrowTransformationTriggerSpec{}
+}
+
+func createSTLTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*STLProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewSTLTransformation(d, cache, s)
+	return t, d, nil
+}
+
+type stlTransformation struct {
+	execute.ExecutionNode
+	d     execute.Dataset
+	cache execute.TableBuilderCache
+
+	config     stl.Config
+	column     string
+	timeColumn string
+}
+
+func NewSTLTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *STLProcedureSpec) *stlTransformation {
+	return &stlTransformation{
+		d:     d,
+		cache: cache,
+		config: stl.Config{
+			Period:   int(spec.Period),
+			Seasonal: int(spec.Seasonal),
+			Trend:    int(spec.Trend),
+			Robust:   spec.Robust,
+		},
+		column:     spec.Column,
+		timeColumn: spec.TimeColumn,
+	}
+}
+
+func (st *stlTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	builder, created := st.cache.TableBuilder(tbl.Key())
+	if !created {
+		return errors.Newf(codes.FailedPrecondition, "stl found duplicate table with key: %v", tbl.Key())
+	}
+	s, err := readSeries(STLKind, tbl, builder, st.timeColumn, st.column)
+	if err != nil {
+		return err
+	}
+
+	// The decomposition assumes that the points are evenly spaced
+	// so there must be a point for every time.
+	for i := range s.times {
+		if !s.timeValid[i] {
+			return errors.Newf(codes.FailedPrecondition, "stl found null in time column %s", st.timeColumn)
+		}
+		if !s.valid[i] {
+			return errors.Newf(codes.FailedPrecondition, "stl found null in column %s", st.column)
+		}
+		if i > 0 && s.times[i] <= s.times[i-1] {
+			return errors.Newf(codes.FailedPrecondition, "stl requires the times in column %s to be increasing", st.timeColumn)
+		}
+	}
+
+	res, err := stl.Decompose(s.values, st.config)
+	if err != nil {
+		return err
+	}
+
+	idxs, err := addFloatCols(builder, trendColLabel, seasonalColLabel, residualColLabel)
+	if err != nil {
+		return err
+	}
+	for i, component := range [][]float64{res.Trend, res.Seasonal, res.Residual} {
+		for k, v := range component {
+			if err := builder.SetValue(k, idxs[i], values.NewFloat(v)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (st *stlTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return st.d.RetractTable(key)
+}
+
+func (st *stlTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	return st.d.UpdateWatermark(mark)
+}
+func (st *stlTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return st.d.UpdateProcessingTime(pt)
+}
+func (st *stlTransformation) Finish(id execute.DatasetID, err error) {
+	st.d.Finish(err)
+}
@@ -0,0 +1,315 @@
+// Package stl implements the seasonal-trend decomposition
+// of a series using loess (STL) described by Cleveland et al. in
+// "STL: A Seasonal-Trend Decomposition Procedure Based on Loess" (1990).
+package stl
+
+import (
+	"math"
+	"sort"
+
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/internal/errors"
+)
+
+// Config is the configuration of a decomposition.
+type Config struct {
+	// Period is the number of points in a seasonal cycle.
+	Period int
+	// Seasonal is the span of the loess smoother of the cycle-subseries.
+	// It must be odd and at least 3. It defaults to 7 when it is zero.
+	Seasonal int
+	// Trend is the span of the loess smoother of the trend.
+	// It must be odd and at least 3. A default is computed
+	// from the period and the seasonal span when it is zero.
+	Trend int
+	// Robust enables the robustness iterations that reduce the
+	// influence of outliers on the trend and seasonal components.
+	Robust bool
+}
+
+// Result is the decomposition of a series into
+// the sum of its trend, seasonal and residual components.
+type Result struct {
+	Trend    []float64
+	Seasonal []float64
+	Residual []float64
+}
+
+const defaultSeasonal = 7
+
+// Decompose decomposes the evenly spaced series ys.
+// The series must contain at least two periods.
+func Decompose(ys []float64, c Config) (*Result, error) {
+	n := len(ys)
+	if c.Period < 2 {
+		return nil, errors.Newf(codes.Invalid, "period must be at least 2, got %d", c.Period)
+	}
+	if n < 2*c.Period {
+		return nil, errors.Newf(codes.FailedPrecondition, "decomposition requires at least two periods of %d points, got %d points", c.Period, n)
+	}
+	ns := c.Seasonal
+	if ns == 0 {
+		ns = defaultSeasonal
+	}
+	if ns < 3 || ns%2 == 0 {
+		return nil, errors.Newf(codes.Invalid, "seasonal span must be an odd number of at least 3, got %d", ns)
+	}
+	nt := c.Trend
+	if nt == 0 {
+		nt = nextOdd(1.5 * float64(c.Period) / (1 - 1.5/float64(ns)))
+	}
+	if nt < 3 || nt%2 == 0 {
+		return nil, errors.Newf(codes.Invalid, "trend span must be an odd number of at least 3, got %d", nt)
+	}
+	nl := nextOdd(float64(c.Period) + 1)
+
+	inner, outer := 2, 0
+	if c.Robust {
+		inner, outer = 1, 15
+	}
+
+	d := &decomposition{
+		ys:       ys,
+		np:       c.Period,
+		ns:       ns,
+		nt:       nt,
+		nl:       nl,
+		trend:    make([]float64, n),
+		seasonal: make([]float64, n),
+	}
+	for i := 0; i <= outer; i++ {
+		for j := 0; j < inner; j++ {
+			d.innerLoop()
+		}
+		if i < outer {
+			d.updateWeights()
+		}
+	}
+
+	residual := make([]float64, n)
+	for i, y := range ys {
+		residual[i] = y - d.trend[i] - d.seasonal[i]
+	}
+	return &Result{
+		Trend:    d.trend,
+		Seasonal: d.seasonal,
+		Residual: residual,
+	}, nil
+}
+
+type decomposition struct {
+	ys         []float64
+	np         int
+	ns, nt, nl int
+
+	// weights are the robustness weights of each point.
+	// All points have the same weight when it is nil.
+	weights []float64
+
+	trend    []float64
+	seasonal []float64
+}
+
+func (d *decomposition) innerLoop() {
+	n, np := len(d.ys), d.np
+
+	// Detrend the series.
+	detrended := make([]float64, n)
+	for i, y := range d.ys {
+		detrended[i] = y - d.trend[i]
+	}
+
+	// Smooth each cycle-subseries and extend it
+	// by one point in each direction.
+	cycle := make([]float64, n+2*np)
+	for k := 0; k < np; k++ {
+		var sub, w []float64
+		for i := k; i < n; i += np {
+			sub = append(sub, detrended[i])
+			if d.weights != nil {
+				w = append(w, d.weights[i])
+			}
+		}
+		m := len(sub)
+		for j := -1; j <= m; j++ {
+			v, ok := loess(sub, w, d.ns, float64(j))
+			if !ok {
+				// Keep the value of the nearest point if the
+				// weights of the window are all zero.
+				v = sub[clamp(j, 0, m-1)]
+			}
+			cycle[k+(j+1)*np] = v
+		}
+	}
+
+	// Remove the low frequencies of the smoothed subseries
+	// so they end up in the trend instead.
+	low := movingAverage(movingAverage(movingAverage(cycle, np), np), 3)
+	lowpass := make([]float64, n)
+	for i := range lowpass {
+		v, ok := loess(low, nil, d.nl, float64(i))
+		if !ok {
+			v = low[i]
+		}
+		lowpass[i] = v
+	}
+	for i := range d.seasonal {
+		d.seasonal[i] = cycle[np+i] - lowpass[i]
+	}
+
+	// Smooth the deseasonalized series to get the trend.
+	deseasonalized := make([]float64, n)
+	for i, y := range d.ys {
+		deseasonalized[i] = y - d.seasonal[i]
+	}
+	for i := range d.trend {
+		v, ok := loess(deseasonalized, d.weights, d.nt, float64(i))
+		if !ok {
+			v = deseasonalized[i]
+		}
+		d.trend[i] = v
+	}
+}
+
+// updateWeights computes the robustness weights from the residuals
+// with the bisquare function.
+func (d *decomposition) updateWeights() {
+	n := len(d.ys)
+	abs := make([]float64, n)
+	for i, y := range d.ys {
+		abs[i] = math.Abs(y - d.trend[i] - d.seasonal[i])
+	}
+	sorted := append([]float64(nil), abs...)
+	sort.Float64s(sorted)
+	var median float64
+	if n%2 == 1 {
+		median = sorted[n/2]
+	} else {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	h := 6 * median
+	if d.weights == nil {
+		d.weights = make([]float64, n)
+	}
+	for i, r := range abs {
+		switch u := r / h; {
+		case h == 0 || u <= 0.001:
+			d.weights[i] = 1
+		case u >= 0.999:
+			d.weights[i] = 0
+		default:
+			d.weights[i] = (1 - u*u) * (1 - u*u)
+		}
+	}
+}
+
+// loess estimates the value at position x of the series with
+// a locally weighted linear regression of the q nearest points.
+// The weights of the points are multiplied with the robustness
+// weights if they are not nil. It returns false if all of the
+// points in the window have a weight of zero.
+func loess(ys, weights []float64, q int, x float64) (float64, bool) {
+	n := len(ys)
+
+	// Find the window of the q nearest points and
+	// the distance to the farthest point in it.
+	var left, right int
+	var h float64
+	if q >= n {
+		left, right = 0, n-1
+		h = math.Max(x, float64(n-1)-x) + float64(q-n)/2
+	} else {
+		left = clamp(int(math.Floor(x+0.5))-q/2, 0, n-q)
+		right = left + q - 1
+		h = math.Max(x-float64(left), float64(right)-x)
+	}
+
+	w := make([]float64, right-left+1)
+	var sum float64
+	for j := left; j <= right; j++ {
+		r := math.Abs(float64(j) - x)
+		var v float64
+		switch {
+		case r <= 0.001*h:
+			v = 1
+		case r <= 0.999*h:
+			u := r / h
+			v = 1 - u*u*u
+			v = v * v * v
+		}
+		if weights != nil {
+			v *= weights[j]
+		}
+		w[j-left] = v
+		sum += v
+	}
+	if sum <= 0 {
+		return 0, false
+	}
+	for i := range w {
+		w[i] /= sum
+	}
+
+	// Adjust the weights so the weighted average
+	// is the value of the local linear fit.
+	if h > 0 {
+		var a float64
+		for j := left; j <= right; j++ {
+			a += w[j-left] * float64(j)
+		}
+		b := x - a
+		var c float64
+		for j := left; j <= right; j++ {
+			c += w[j-left] * (float64(j) - a) * (float64(j) - a)
+		}
+		if math.Sqrt(c) > 0.001*float64(n-1) {
+			b /= c
+			for j := left; j <= right; j++ {
+				w[j-left] *= b*(float64(j)-a) + 1
+			}
+		}
+	}
+
+	var v float64
+	for j := left; j <= right; j++ {
+		v += w[j-left] * ys[j]
+	}
+	return v, true
+}
+
+// movingAverage returns the averages of each run
+// of l consecutive values.
+func movingAverage(xs []float64, l int) []float64 {
+	avg := make([]float64, len(xs)-l+1)
+	var sum float64
+	for i := 0; i < l; i++ {
+		sum += xs[i]
+	}
+	avg[0] = sum / float64(l)
+	for i := 1; i < len(avg); i++ {
+		sum += xs[i+l-1] - xs[i-1]
+		avg[i] = sum / float64(l)
+	}
+	return avg
+}
+
+// nextOdd returns the smallest odd integer
+// that is greater than or equal to x.
+func nextOdd(x float64) int {
+	i := int(math.Ceil(x))
+	if i%2 == 0 {
+		i++
+	}
+	return i
+}
+
+func clamp(i, lo, hi int) int {
+	if i < lo {
+		return lo
+	}
+	if i > hi {
+		return hi
+	}
+	return i
+}
@@ -0,0 +1,73 @@
+package stl_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/influxdata/flux/stdlib/universe/stl"
+)
+
+// series returns a linear trend with a sinusoidal seasonal component
+// and some noise.
+func series(n, period int) (ys, trend, seasonal []float64) {
+	r := rand.New(rand.NewSource(1))
+	ys = make([]float64, n)
+	trend = make([]float64, n)
+	seasonal = make([]float64, n)
+	for i := range ys {
+		trend[i] = 10 + 0.1*float64(i)
+		seasonal[i] = 5 * math.Sin(2*math.Pi*float64(i)/float64(period))
+		ys[i] = trend[i] + seasonal[i] + r.Float64() - 0.5
+	}
+	return ys, trend, seasonal
+}
+
+func TestDecompose(t *testing.T) {
+	for _, robust := range []bool{false, true} {
+		ys, trend, seasonal := series(120, 12)
+		if robust {
+			// A single outlier should end up in the residual.
+			ys[60] += 50
+		}
+
+		res, err := stl.Decompose(ys, stl.Config{Period: 12, Robust: robust})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, y := range ys {
+			if got := res.Trend[i] + res.Seasonal[i] + res.Residual[i]; math.Abs(got-y) > 1e-9 {
+				t.Fatalf("components at %d do not add up to the value -want/+got:\n\t- %v\n\t+ %v", i, y, got)
+			}
+			if robust && i == 60 {
+				continue
+			}
+			if d := math.Abs(res.Trend[i] - trend[i]); d > 1 {
+				t.Errorf("unexpected trend at %d (robust=%v) -want/+got:\n\t- %v\n\t+ %v", i, robust, trend[i], res.Trend[i])
+			}
+			if d := math.Abs(res.Seasonal[i] - seasonal[i]); d > 1 {
+				t.Errorf("unexpected seasonal at %d (robust=%v) -want/+got:\n\t- %v\n\t+ %v", i, robust, seasonal[i], res.Seasonal[i])
+			}
+		}
+		if robust {
+			if r := res.Residual[60]; r < 45 {
+				t.Errorf("expected the outlier in the residual, got %v", r)
+			}
+		}
+	}
+}
+
+func TestDecompose_Invalid(t *testing.T) {
+	ys, _, _ := series(20, 12)
+	for _, c := range []stl.Config{
+		// Fewer than two periods.
+		{Period: 12},
+		{Period: 1},
+		{Period: 4, Seasonal: 4},
+		{Period: 4, Trend: 1},
+	} {
+		if _, err := stl.Decompose(ys, c); err == nil {
+			t.Errorf("expected an error for %+v", c)
+		}
+	}
+}
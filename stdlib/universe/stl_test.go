@@ -0,0 +1,191 @@
+package universe_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/stdlib/universe/stl"
+)
+
+func TestSTL_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "stl defaults",
+			Raw:  `from(bucket:"mydb") |> range(start:-1h) |> stl(period: 12)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mydb"},
+						},
+					},
+					{
+						ID: "range1",
+						Spec: &universe.RangeOpSpec{
+							Start: flux.Time{
+								Relative:   -1 * time.Hour,
+								IsRelative: true,
+							},
+							Stop:        flux.Now,
+							TimeColumn:  "_time",
+							StartColumn: "_start",
+							StopColumn:  "_stop",
+						},
+					},
+					{
+						ID: "stl2",
+						Spec: &universe.STLOpSpec{
+							Period:     12,
+							Column:     execute.DefaultValueColLabel,
+							TimeColumn: execute.DefaultTimeColLabel,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "range1"},
+					{Parent: "range1", Child: "stl2"},
+				},
+			},
+		},
+		{
+			Name:    "stl even seasonal",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> stl(period: 12, seasonal: 4)`,
+			WantErr: true,
+		},
+		{
+			Name:    "stl without period",
+			Raw:     `from(bucket:"mydb") |> range(start:-1h) |> stl()`,
+			WantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestSTL_Process(t *testing.T) {
+	const n, period = 36, 6
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = 10 + 0.5*float64(i) + 3*math.Sin(2*math.Pi*float64(i)/period)
+	}
+	res, err := stl.Decompose(values, stl.Config{Period: period})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := &executetest.Table{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+		},
+	}
+	want := &executetest.Table{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+			{Label: "residual", Type: flux.TFloat},
+			{Label: "seasonal", Type: flux.TFloat},
+			{Label: "trend", Type: flux.TFloat},
+		},
+	}
+	for i, v := range values {
+		ts := execute.Time(time.Duration(i) * time.Minute)
+		input.Data = append(input.Data, []interface{}{ts, v})
+		want.Data = append(want.Data, []interface{}{ts, v, res.Residual[i], res.Seasonal[i], res.Trend[i]})
+	}
+
+	executetest.ProcessTestHelper(
+		t,
+		[]flux.Table{input},
+		[]*executetest.Table{want},
+		nil,
+		func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+			return universe.NewSTLTransformation(d, c, &universe.STLProcedureSpec{
+				Period:     period,
+				Column:     "_value",
+				TimeColumn: "_time",
+			})
+		},
+		floatOptions,
+	)
+}
+
+func TestSTL_Error_Process(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    [][]interface{}
+		wantErr error
+	}{
+		{
+			name: "null value",
+			data: [][]interface{}{
+				{execute.Time(0), 1.0},
+				{execute.Time(1), nil},
+				{execute.Time(2), 1.0},
+				{execute.Time(3), 1.0},
+			},
+			wantErr: errors.New(codes.FailedPrecondition, "stl found null in column _value"),
+		},
+		{
+			name: "times not increasing",
+			data: [][]interface{}{
+				{execute.Time(0), 1.0},
+				{execute.Time(2), 1.0},
+				{execute.Time(1), 1.0},
+				{execute.Time(3), 1.0},
+			},
+			wantErr: errors.New(codes.FailedPrecondition, "stl requires the times in column _time to be increasing"),
+		},
+		{
+			name: "fewer than two periods",
+			data: [][]interface{}{
+				{execute.Time(0), 1.0},
+				{execute.Time(1), 1.0},
+				{execute.Time(2), 1.0},
+			},
+			wantErr: errors.New(codes.FailedPrecondition, "decomposition requires at least two periods of 2 points, got 3 points"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				[]flux.Table{&executetest.Table{
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: tc.data,
+				}},
+				nil,
+				tc.wantErr,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return universe.NewSTLTransformation(d, c, &universe.STLProcedureSpec{
+						Period:     2,
+						Column:     "_value",
+						TimeColumn: "_time",
+					})
+				},
+			)
+		})
+	}
+}
@@ -0,0 +1,455 @@
+package universe
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/tdigest"
+)
+
+const (
+	TDigestKind            = "tdigest"
+	MergeSketchesKind      = "mergeSketches"
+	QuantileFromSketchKind = "quantileFromSketch"
+
+	defaultSketchCompression = 1000
+)
+
+type TDigestOpSpec struct {
+	Column      string  `json:"column"`
+	Compression float64 `json:"compression"`
+}
+
+type MergeSketchesOpSpec struct {
+	Column string `json:"column"`
+}
+
+type QuantileFromSketchOpSpec struct {
+	Column   string  `json:"column"`
+	Quantile float64 `json:"quantile"`
+}
+
+func init() {
+	tdigestSignature := runtime.MustLookupBuiltinType("universe", "tdigest")
+	mergeSketchesSignature := runtime.MustLookupBuiltinType("universe", "mergeSketches")
+	quantileFromSketchSignature := runtime.MustLookupBuiltinType("universe", "quantileFromSketch")
+
+	runtime.RegisterPackageValue("universe", TDigestKind, flux.MustValue(flux.FunctionValue(TDigestKind, createTDigestOpSpec, tdigestSignature)))
+	runtime.RegisterPackageValue("universe", MergeSketchesKind, flux.MustValue(flux.FunctionValue(MergeSketchesKind, createMergeSketchesOpSpec, mergeSketchesSignature)))
+	runtime.RegisterPackageValue("universe", QuantileFromSketchKind, flux.MustValue(flux.FunctionValue(QuantileFromSketchKind, createQuantileFromSketchOpSpec, quantileFromSketchSignature)))
+	plan.RegisterProcedureSpec(TDigestKind, newTDigestProcedure, TDigestKind)
+	plan.RegisterProcedureSpec(MergeSketchesKind, newMergeSketchesProcedure, MergeSketchesKind)
+	plan.RegisterProcedureSpec(QuantileFromSketchKind, newQuantileFromSketchProcedure, QuantileFromSketchKind)
+	execute.RegisterTransformation(TDigestKind, createTDigestTransformation)
+	execute.RegisterTransformation(MergeSketchesKind, createMergeSketchesTransformation)
+	execute.RegisterTransformation(QuantileFromSketchKind, createQuantileFromSketchTransformation)
+}
+
+func createTDigestOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := &TDigestOpSpec{
+		Column:      execute.DefaultValueColLabel,
+		Compression: defaultSketchCompression,
+	}
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	}
+
+	if c, ok, err := args.GetFloat("compression"); err != nil {
+		return nil, err
+	} else if ok {
+		if c <= 0 {
+			return nil, errors.New(codes.Invalid, "compression must be greater than 0")
+		}
+		spec.Compression = c
+	}
+	return spec, nil
+}
+
+func (s *TDigestOpSpec) Kind() flux.OperationKind {
+	return TDigestKind
+}
+
+func createMergeSketchesOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := &MergeSketchesOpSpec{
+		Column: execute.DefaultValueColLabel,
+	}
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	}
+	return spec, nil
+}
+
+func (s *MergeSketchesOpSpec) Kind() flux.OperationKind {
+	return MergeSketchesKind
+}
+
+func createQuantileFromSketchOpSpec(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+	if err := a.AddParentFromArgs(args); err != nil {
+		return nil, err
+	}
+
+	spec := &QuantileFromSketchOpSpec{
+		Column: execute.DefaultValueColLabel,
+	}
+	if col, ok, err := args.GetString("column"); err != nil {
+		return nil, err
+	} else if ok {
+		spec.Column = col
+	}
+
+	q, err := args.GetRequiredFloat("q")
+	if err != nil {
+		return nil, err
+	}
+	if q < 0 || q > 1 {
+		return nil, errors.New(codes.Invalid, "quantile must be between 0 and 1")
+	}
+	spec.Quantile = q
+	return spec, nil
+}
+
+func (s *QuantileFromSketchOpSpec) Kind() flux.OperationKind {
+	return QuantileFromSketchKind
+}
+
+type TDigestProcedureSpec struct {
+	plan.DefaultCost
+	Column      string
+	Compression float64
+}
+
+func newTDigestProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*TDigestOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &TDigestProcedureSpec{
+		Column:      spec.Column,
+		Compression: spec.Compression,
+	}, nil
+}
+
+func (s *TDigestProcedureSpec) Kind() plan.ProcedureKind {
+	return TDigestKind
+}
+
+func (s *TDigestProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// MergeSketchesProcedureSpec is the procedure spec for both mergeSketches
+// and quantileFromSketch. Both merge the sketches in a table and
+// differ only in the value they produce from the merged sketch.
+type MergeSketchesProcedureSpec struct {
+	plan.DefaultCost
+	Column string
+
+	// Quantile is only used by quantileFromSketch.
+	Quantile float64
+
+	// Function is either MergeSketchesKind or QuantileFromSketchKind.
+	Function plan.ProcedureKind
+}
+
+func newMergeSketchesProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*MergeSketchesOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &MergeSketchesProcedureSpec{
+		Column:   spec.Column,
+		Function: MergeSketchesKind,
+	}, nil
+}
+
+func newQuantileFromSketchProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*QuantileFromSketchOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+	return &MergeSketchesProcedureSpec{
+		Column:   spec.Column,
+		Quantile: spec.Quantile,
+		Function: QuantileFromSketchKind,
+	}, nil
+}
+
+func (s *MergeSketchesProcedureSpec) Kind() plan.ProcedureKind {
+	return s.Function
+}
+
+func (s *MergeSketchesProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func createTDigestTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*TDigestProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewTDigestTransformation(id, s, a.Allocator())
+}
+
+func createMergeSketchesTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*MergeSketchesProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewMergeSketchesTransformation(id, s, a.Allocator())
+}
+
+func createQuantileFromSketchTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	return createMergeSketchesTransformation(id, mode, spec, a)
+}
+
+type tdigestTransformation struct {
+	column      string
+	compression float64
+}
+
+// NewTDigestTransformation creates a transformation that builds
+// a t-digest of the values in a column of each table and outputs
+// the serialized sketch.
+func NewTDigestTransformation(id execute.DatasetID, spec *TDigestProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t := &tdigestTransformation{
+		column:      spec.Column,
+		compression: spec.Compression,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+func (t *tdigestTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.column)
+	}
+	if chunk.Key().HasCol(t.column) {
+		return nil, false, errors.New(codes.FailedPrecondition, "cannot aggregate columns that are part of the group key")
+	}
+
+	digest, _ := state.(*tdigest.TDigest)
+	if digest == nil {
+		digest = tdigest.NewWithCompression(t.compression)
+	}
+
+	switch vs := chunk.Values(idx).(type) {
+	case *array.Float:
+		for i, n := 0, vs.Len(); i < n; i++ {
+			if vs.IsValid(i) {
+				digest.Add(vs.Value(i), 1)
+			}
+		}
+	case *array.Int:
+		for i, n := 0, vs.Len(); i < n; i++ {
+			if vs.IsValid(i) {
+				digest.Add(float64(vs.Value(i)), 1)
+			}
+		}
+	case *array.Uint:
+		for i, n := 0, vs.Len(); i < n; i++ {
+			if vs.IsValid(i) {
+				digest.Add(float64(vs.Value(i)), 1)
+			}
+		}
+	default:
+		return nil, false, errors.Newf(codes.FailedPrecondition, "unsupported type for tdigest: %s", chunk.Col(idx).Type)
+	}
+	return digest, true, nil
+}
+
+func (t *tdigestTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	digest := state.(*tdigest.TDigest)
+
+	b := array.NewStringBuilder(mem)
+	b.Append(encodeSketch(digest))
+	return d.Process(aggregateChunk(key, flux.ColMeta{
+		Label: t.column,
+		Type:  flux.TString,
+	}, b.NewArray(), mem))
+}
+
+func (t *tdigestTransformation) Close() error {
+	return nil
+}
+
+type mergeSketchesTransformation struct {
+	spec *MergeSketchesProcedureSpec
+}
+
+// NewMergeSketchesTransformation creates a transformation that merges
+// the serialized sketches in a column of each table. It outputs either
+// the merged sketch or a quantile of the merged sketch depending on
+// the function in the spec.
+func NewMergeSketchesTransformation(id execute.DatasetID, spec *MergeSketchesProcedureSpec, mem memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	switch spec.Function {
+	case MergeSketchesKind, QuantileFromSketchKind:
+	default:
+		return nil, nil, errors.Newf(codes.Internal, "unknown sketch function %q", spec.Function)
+	}
+	t := &mergeSketchesTransformation{
+		spec: spec,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+func (t *mergeSketchesTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	idx := chunk.Index(t.spec.Column)
+	if idx < 0 {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.spec.Column)
+	}
+	if chunk.Key().HasCol(t.spec.Column) {
+		return nil, false, errors.New(codes.FailedPrecondition, "cannot aggregate columns that are part of the group key")
+	}
+	vs, ok := chunk.Values(idx).(*array.String)
+	if !ok {
+		return nil, false, errors.Newf(codes.FailedPrecondition, "sketch column %q must be of type string, got %s", t.spec.Column, chunk.Col(idx).Type)
+	}
+
+	// The merged digest is created from the first sketch
+	// so it keeps the compression of the input sketches.
+	digest, _ := state.(*tdigest.TDigest)
+	for i, n := 0, vs.Len(); i < n; i++ {
+		if vs.IsNull(i) {
+			continue
+		}
+		sketch, err := decodeSketch(vs.Value(i))
+		if err != nil {
+			return nil, false, errors.Wrapf(err, codes.Invalid, "invalid sketch in column %q", t.spec.Column)
+		}
+		if digest == nil {
+			digest = sketch
+		} else {
+			digest.Merge(sketch)
+		}
+	}
+	if digest == nil {
+		digest = tdigest.NewWithCompression(defaultSketchCompression)
+	}
+	return digest, true, nil
+}
+
+func (t *mergeSketchesTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	digest := state.(*tdigest.TDigest)
+
+	if t.spec.Function == MergeSketchesKind {
+		b := array.NewStringBuilder(mem)
+		b.Append(encodeSketch(digest))
+		return d.Process(aggregateChunk(key, flux.ColMeta{
+			Label: t.spec.Column,
+			Type:  flux.TString,
+		}, b.NewArray(), mem))
+	}
+
+	// An empty sketch has no quantiles.
+	b := array.NewFloatBuilder(mem)
+	if digest.Count() == 0 {
+		b.AppendNull()
+	} else {
+		b.Append(digest.Quantile(t.spec.Quantile))
+	}
+	return d.Process(aggregateChunk(key, flux.ColMeta{
+		Label: t.spec.Column,
+		Type:  flux.TFloat,
+	}, b.NewArray(), mem))
+}
+
+func (t *mergeSketchesTransformation) Close() error {
+	return nil
+}
+
+// aggregateChunk creates a chunk with a single row that has
+// the group key columns and the aggregated value.
+func aggregateChunk(key flux.GroupKey, col flux.ColMeta, value array.Array, mem memory.Allocator) table.Chunk {
+	buffer := arrow.TableBuffer{
+		GroupKey: key,
+		Columns:  make([]flux.ColMeta, 0, len(key.Cols())+1),
+	}
+	buffer.Values = make([]array.Array, 0, cap(buffer.Columns))
+	for j, c := range key.Cols() {
+		buffer.Columns = append(buffer.Columns, c)
+		buffer.Values = append(buffer.Values, arrow.Repeat(c.Type, key.Value(j), 1, mem))
+	}
+	buffer.Columns = append(buffer.Columns, col)
+	buffer.Values = append(buffer.Values, value)
+	return table.ChunkFromBuffer(buffer)
+}
+
+// sketchVersion is the version of the serialized sketch format.
+// It is the first byte of every serialized sketch so that sketches
+// stored by earlier versions can still be read if the format changes.
+const sketchVersion = 1
+
+// encodeSketch serializes a t-digest as a base64 string. The encoded
+// bytes are the version, the compression and the number of centroids
+// followed by the mean and weight of each centroid.
+func encodeSketch(digest *tdigest.TDigest) string {
+	centroids := digest.Centroids(nil)
+	buf := make([]byte, 1+8+4+16*len(centroids))
+	buf[0] = sketchVersion
+	binary.LittleEndian.PutUint64(buf[1:], math.Float64bits(digest.Compression))
+	binary.LittleEndian.PutUint32(buf[9:], uint32(len(centroids)))
+	off := 13
+	for _, c := range centroids {
+		binary.LittleEndian.PutUint64(buf[off:], math.Float64bits(c.Mean))
+		binary.LittleEndian.PutUint64(buf[off+8:], math.Float64bits(c.Weight))
+		off += 16
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// decodeSketch deserializes a t-digest that was encoded by encodeSketch.
+func decodeSketch(s string) (*tdigest.TDigest, error) {
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) < 13 {
+		return nil, errors.New(codes.Invalid, "sketch is too short")
+	}
+	if buf[0] != sketchVersion {
+		return nil, errors.Newf(codes.Invalid, "unsupported sketch version %d", buf[0])
+	}
+	compression := math.Float64frombits(binary.LittleEndian.Uint64(buf[1:]))
+	n := int(binary.LittleEndian.Uint32(buf[9:]))
+	if compression <= 0 || len(buf) != 13+16*n {
+		return nil, errors.New(codes.Invalid, "sketch is corrupt")
+	}
+
+	centroids := make(tdigest.CentroidList, n)
+	off := 13
+	for i := range centroids {
+		centroids[i] = tdigest.Centroid{
+			Mean:   math.Float64frombits(binary.LittleEndian.Uint64(buf[off:])),
+			Weight: math.Float64frombits(binary.LittleEndian.Uint64(buf[off+8:])),
+		}
+		off += 16
+	}
+	digest := tdigest.NewWithCompression(compression)
+	digest.AddCentroidList(centroids)
+	return digest, nil
+}
@@ -0,0 +1,45 @@
+package universe
+
+import (
+	"testing"
+
+	"github.com/influxdata/tdigest"
+)
+
+func TestSketch_EncodeDecode(t *testing.T) {
+	digest := tdigest.NewWithCompression(100)
+	for i := 0; i < 1000; i++ {
+		digest.Add(float64(i%250), 1)
+	}
+
+	got, err := decodeSketch(encodeSketch(digest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := digest.Compression, got.Compression; want != got {
+		t.Errorf("unexpected compression -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	if want, got := digest.Count(), got.Count(); want != got {
+		t.Errorf("unexpected count -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		if want, got := digest.Quantile(q), got.Quantile(q); want != got {
+			t.Errorf("unexpected quantile %v -want/+got:\n\t- %v\n\t+ %v", q, want, got)
+		}
+	}
+}
+
+func TestSketch_DecodeInvalid(t *testing.T) {
+	for _, s := range []string{
+		"not base64!",
+		"",
+		// Version 2 is unknown.
+		"AgAAAAAAAFlAAAAAAA==",
+		// The sketch has one centroid, but no centroid data.
+		"AQAAAAAAAFlAAQAAAA==",
+	} {
+		if _, err := decodeSketch(s); err == nil {
+			t.Errorf("expected an error when decoding %q", s)
+		}
+	}
+}
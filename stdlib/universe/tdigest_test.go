@@ -0,0 +1,315 @@
+package universe_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestTDigest_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "tdigest",
+			Raw:  `from(bucket:"mybucket") |> tdigest(compression: 100.0)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "tdigest1",
+						Spec: &universe.TDigestOpSpec{
+							Column:      "_value",
+							Compression: 100,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "tdigest1"},
+				},
+			},
+		},
+		{
+			Name: "quantileFromSketch",
+			Raw:  `from(bucket:"mybucket") |> quantileFromSketch(q: 0.99, column: "sketch")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "quantileFromSketch1",
+						Spec: &universe.QuantileFromSketchOpSpec{
+							Column:   "sketch",
+							Quantile: 0.99,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "quantileFromSketch1"},
+				},
+			},
+		},
+		{
+			Name:    "tdigest invalid compression",
+			Raw:     `from(bucket:"mybucket") |> tdigest(compression: 0.0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "quantileFromSketch invalid quantile",
+			Raw:     `from(bucket:"mybucket") |> quantileFromSketch(q: 1.5)`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+// runTransformation processes the tables with the transformation
+// and returns the output tables.
+func runTransformation(t *testing.T, data []flux.Table, create func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset, error)) ([]*executetest.Table, error) {
+	t.Helper()
+
+	alloc := &memory.ResourceAllocator{}
+	tr, d, err := create(executetest.RandomDatasetID(), alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := executetest.NewDataStore()
+	d.SetTriggerSpec(plan.DefaultTriggerSpec)
+	d.AddTransformation(store)
+
+	parentID := executetest.RandomDatasetID()
+	for _, tbl := range data {
+		if err := tr.Process(parentID, tbl); err != nil {
+			tr.Finish(parentID, err)
+			return nil, err
+		}
+	}
+	tr.Finish(parentID, nil)
+	if err := store.Err(); err != nil {
+		return nil, err
+	}
+	got, err := executetest.TablesFromCache(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executetest.NormalizeTables(got)
+	return got, nil
+}
+
+// sketchesOf builds one t-digest sketch for each set of values.
+func sketchesOf(t *testing.T, values ...[]float64) []string {
+	t.Helper()
+
+	data := make([]flux.Table, len(values))
+	for i, vs := range values {
+		tbl := &executetest.Table{
+			KeyCols: []string{"i"},
+			ColMeta: []flux.ColMeta{
+				{Label: "i", Type: flux.TInt},
+				{Label: "_value", Type: flux.TFloat},
+			},
+		}
+		for _, v := range vs {
+			tbl.Data = append(tbl.Data, []interface{}{int64(i), v})
+		}
+		data[i] = tbl
+	}
+
+	spec := &universe.TDigestProcedureSpec{
+		Column:      "_value",
+		Compression: 1000,
+	}
+	got, err := runTransformation(t, data, func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+		return universe.NewTDigestTransformation(id, spec, alloc)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sketches := make([]string, len(values))
+	for _, tbl := range got {
+		if len(tbl.Data) != 1 {
+			t.Fatalf("expected one row per table, got %d", len(tbl.Data))
+		}
+		i := tbl.KeyValues[0].(int64)
+		sketches[i] = tbl.Data[0][0].(string)
+	}
+	return sketches
+}
+
+func floatRange(from, to int) []float64 {
+	vs := make([]float64, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		vs = append(vs, float64(i))
+	}
+	return vs
+}
+
+func sketchTable(sketches ...interface{}) flux.Table {
+	tbl := &executetest.Table{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TString},
+		},
+	}
+	for i, s := range sketches {
+		tbl.Data = append(tbl.Data, []interface{}{execute.Time(i), s})
+	}
+	return tbl
+}
+
+func TestQuantileFromSketch_Process(t *testing.T) {
+	// Each window of the rollup has a sketch for
+	// a part of the values from 1 to 100.
+	sketches := sketchesOf(t, floatRange(1, 10), floatRange(11, 60), floatRange(61, 100))
+
+	testCases := []struct {
+		name string
+		q    float64
+		want float64
+	}{
+		{name: "median", q: 0.5, want: 50.5},
+		{name: "p90", q: 0.9, want: 90.5},
+		{name: "min", q: 0, want: 1},
+		{name: "max", q: 1, want: 100},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &universe.MergeSketchesProcedureSpec{
+				Column:   "_value",
+				Quantile: tc.q,
+				Function: universe.QuantileFromSketchKind,
+			}
+			got, err := runTransformation(t, []flux.Table{
+				sketchTable(sketches[0], sketches[1], nil, sketches[2]),
+			}, func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+				return universe.NewMergeSketchesTransformation(id, spec, alloc)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || len(got[0].Data) != 1 {
+				t.Fatalf("expected a single row, got %v", got)
+			}
+			if v := got[0].Data[0][0].(float64); math.Abs(v-tc.want) > 1 {
+				t.Errorf("unexpected quantile -want/+got:\n\t- %v\n\t+ %v", tc.want, v)
+			}
+		})
+	}
+}
+
+func TestMergeSketches_Process(t *testing.T) {
+	sketches := sketchesOf(t, floatRange(1, 50), floatRange(51, 100), floatRange(1, 100))
+
+	spec := &universe.MergeSketchesProcedureSpec{
+		Column:   "_value",
+		Function: universe.MergeSketchesKind,
+	}
+	got, err := runTransformation(t, []flux.Table{
+		sketchTable(sketches[0], sketches[1]),
+	}, func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+		return universe.NewMergeSketchesTransformation(id, spec, alloc)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0].Data) != 1 {
+		t.Fatalf("expected a single row, got %v", got)
+	}
+	merged := got[0].Data[0][0].(string)
+
+	// The quantiles of the merged sketch are the same
+	// as the quantiles of a sketch of all of the values.
+	quantile := func(sketch string, q float64) float64 {
+		spec := &universe.MergeSketchesProcedureSpec{
+			Column:   "_value",
+			Quantile: q,
+			Function: universe.QuantileFromSketchKind,
+		}
+		got, err := runTransformation(t, []flux.Table{
+			sketchTable(sketch),
+		}, func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+			return universe.NewMergeSketchesTransformation(id, spec, alloc)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got[0].Data[0][0].(float64)
+	}
+	for _, q := range []float64{0.25, 0.5, 0.75, 0.99} {
+		if want, got := quantile(sketches[2], q), quantile(merged, q); math.Abs(want-got) > 1e-9 {
+			t.Errorf("unexpected quantile %v -want/+got:\n\t- %v\n\t+ %v", q, want, got)
+		}
+	}
+}
+
+func TestQuantileFromSketch_ProcessEmpty(t *testing.T) {
+	spec := &universe.MergeSketchesProcedureSpec{
+		Column:   "_value",
+		Quantile: 0.5,
+		Function: universe.QuantileFromSketchKind,
+	}
+	executetest.ProcessTestHelper2(
+		t,
+		[]flux.Table{sketchTable(nil)},
+		[]*executetest.Table{{
+			ColMeta: []flux.ColMeta{
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{nil},
+			},
+		}},
+		nil,
+		func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+			tr, d, err := universe.NewMergeSketchesTransformation(id, spec, alloc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr, d
+		},
+	)
+}
+
+func TestQuantileFromSketch_ProcessInvalid(t *testing.T) {
+	spec := &universe.MergeSketchesProcedureSpec{
+		Column:   "_value",
+		Quantile: 0.5,
+		Function: universe.QuantileFromSketchKind,
+	}
+	_, err := runTransformation(t, []flux.Table{
+		sketchTable("not a sketch"),
+	}, func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+		return universe.NewMergeSketchesTransformation(id, spec, alloc)
+	})
+	if err == nil {
+		t.Fatal("expected an error for an invalid sketch")
+	} else if want, got := codes.Invalid, errors.Code(err); want != got {
+		t.Fatalf("unexpected error code -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+}
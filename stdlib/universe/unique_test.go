@@ -1,11 +1,15 @@
 package universe_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/gen"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/flux/stdlib/universe"
 )
 
@@ -172,3 +176,60 @@ func TestUnique_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestUnique_Spill(t *testing.T) {
+	// Limit the memory so the unique rows do not fit
+	// and the built rows have to be spilled to disk.
+	limit := int64(256 * 1024)
+	mem := &memory.ResourceAllocator{Limit: &limit}
+
+	seed := int64(1)
+	input, err := gen.Input(context.Background(), gen.Schema{
+		NumPoints: 50000,
+		Seed:      &seed,
+		Alloc:     mem,
+		Tags: []gen.Tag{
+			{Name: "_measurement", Cardinality: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := execute.NewTableBuilderCache(mem)
+	cache.SetTriggerSpec(plan.DefaultTriggerSpec)
+	cache.SetSpillDir(t.TempDir())
+	d := execute.NewDataset(executetest.RandomDatasetID(), execute.DiscardingMode, cache)
+	tr := universe.NewUniqueTransformation(d, cache, &universe.UniqueProcedureSpec{
+		Column: "_time",
+	})
+
+	var n int
+	sink := &spillSink{
+		fn: func(tbl flux.Table) error {
+			return tbl.Do(func(cr flux.ColReader) error {
+				n += cr.Len()
+				return nil
+			})
+		},
+	}
+	d.AddTransformation(sink)
+
+	parentID := executetest.RandomDatasetID()
+	if err := input.Do(func(tbl flux.Table) error {
+		return tr.Process(parentID, tbl)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tr.Finish(parentID, nil)
+
+	if sink.err != nil {
+		t.Fatal(sink.err)
+	}
+	if want, got := 50000, n; want != got {
+		t.Errorf("unexpected number of rows -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if got := mem.Allocated(); got != 0 {
+		t.Errorf("memory was not released: %d bytes", got)
+	}
+}
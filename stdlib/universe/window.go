@@ -26,6 +26,13 @@ type WindowOpSpec struct {
 	StopColumn  string
 	StartColumn string
 	CreateEmpty bool
+
+	// Gap is the period of inactivity that ends a session window.
+	Gap flux.Duration
+	// Count is the number of rows in a count window and CountEvery
+	// is the number of rows between the start of each count window.
+	Count      int64
+	CountEvery int64
 }
 
 var infinityVar = values.NewDuration(values.ConvertDurationNsecs(math.MaxInt64))
@@ -96,11 +103,46 @@ func CreateWindowOpSpec(args flux.Arguments, a *flux.Administration) (flux.Opera
 		}
 	}
 
-	if spec.Every.IsZero() && spec.Period.IsZero() {
+	if gap, ok, err := args.GetDuration("gap"); err != nil {
+		return nil, err
+	} else if ok {
+		if gap.IsNegative() || gap.Months() != 0 {
+			return nil, errors.New(codes.Invalid, `parameter "gap" must be non-negative and cannot use months or years`)
+		}
+		spec.Gap = gap
+	}
+
+	if count, ok, err := args.GetInt("count"); err != nil {
+		return nil, err
+	} else if ok {
+		if count < 0 {
+			return nil, errors.New(codes.Invalid, `parameter "count" must be non-negative`)
+		}
+		spec.Count = count
+	}
+	if countEvery, ok, err := args.GetInt("countEvery"); err != nil {
+		return nil, err
+	} else if ok {
+		if countEvery < 0 {
+			return nil, errors.New(codes.Invalid, `parameter "countEvery" must be non-negative`)
+		}
+		spec.CountEvery = countEvery
+	}
+
+	fixed := !spec.Every.IsZero() || !spec.Period.IsZero()
+	session := !spec.Gap.IsZero()
+	count := spec.Count > 0
+	if !fixed && !session && !count {
 		const docURL = "https://v2.docs.influxdata.com/v2.0/reference/flux/stdlib/built-in/transformations/window/"
-		return nil, errors.New(codes.Invalid, `window function requires at least one of "every" or "period" to be set and non-zero`).
+		return nil, errors.New(codes.Invalid, `window function requires one of "every", "period", "gap" or "count" to be set and non-zero`).
 			WithDocURL(docURL)
 	}
+	if fixed && session || fixed && count || session && count {
+		return nil, errors.New(codes.Invalid, `window function accepts only one of "every" and "period", "gap" or "count"`)
+	}
+	if spec.CountEvery > 0 && !count {
+		return nil, errors.New(codes.Invalid, `parameter "countEvery" requires "count" to be set`)
+	}
 
 	if label, ok, err := args.GetString("timeColumn"); err != nil {
 		return nil, err
@@ -136,6 +178,9 @@ func CreateWindowOpSpec(args flux.Arguments, a *flux.Administration) (flux.Opera
 	if spec.Period.IsZero() {
 		spec.Period = spec.Every
 	}
+	if count && spec.CountEvery == 0 {
+		spec.CountEvery = spec.Count
+	}
 	return spec, nil
 }
 
@@ -160,6 +205,23 @@ func newWindowProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.Pro
 	if !ok {
 		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
 	}
+	switch {
+	case !s.Gap.IsZero():
+		return &SessionWindowProcedureSpec{
+			Gap:         s.Gap,
+			TimeColumn:  s.TimeColumn,
+			StartColumn: s.StartColumn,
+			StopColumn:  s.StopColumn,
+		}, nil
+	case s.Count > 0:
+		return &CountWindowProcedureSpec{
+			Count:       s.Count,
+			Every:       s.CountEvery,
+			TimeColumn:  s.TimeColumn,
+			StartColumn: s.StartColumn,
+			StopColumn:  s.StopColumn,
+		}, nil
+	}
 	p := &WindowProcedureSpec{
 		Window: plan.WindowSpec{
 			Every:    s.Every,
@@ -189,7 +251,10 @@ func createWindowTransformation(id execute.DatasetID, mode execute.AccumulationM
 		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
 	}
 
-	if s.Optimize {
+	// Streaming queries use the window transformation that emits
+	// each window once the watermark has passed the end of the window.
+	streaming := execute.StreamingEnabled(a.Context())
+	if s.Optimize && !streaming {
 		return newWindowTransformation2(id, s, a.StreamContext().Bounds(), a)
 	}
 
@@ -197,6 +262,10 @@ func createWindowTransformation(id execute.DatasetID, mode execute.AccumulationM
 	d := execute.NewDataset(id, mode, cache)
 
 	bounds := a.StreamContext().Bounds()
+	if bounds == nil && streaming && !s.CreateEmpty {
+		// The data read by a streaming source is not bounded.
+		bounds = &execute.Bounds{Start: interval.MinTime, Stop: interval.MaxTime}
+	}
 	if bounds == nil {
 		const docURL = "https://v2.docs.influxdata.com/v2.0/reference/flux/stdlib/built-in/transformations/window/#nil-bounds-passed-to-window"
 		return nil, nil, errors.New(codes.Invalid, "nil bounds passed to window; use range to set the window range").
@@ -245,6 +314,11 @@ type fixedWindowTransformation struct {
 	startCol,
 	stopCol string
 	createEmpty bool
+
+	// watermark is the latest watermark received from upstream.
+	// Windows that end at or before it have already been emitted.
+	watermark    execute.Time
+	hasWatermark bool
 }
 
 func NewFixedWindowTransformation(
@@ -355,6 +429,10 @@ func (t *fixedWindowTransformation) Process(id execute.DatasetID, tbl flux.Table
 			bounds := t.getWindowBounds(tm)
 
 			for _, bnds := range bounds {
+				if t.hasWatermark && bnds.Stop() <= t.watermark {
+					// The row arrived after its window was emitted.
+					continue
+				}
 				key := t.newWindowGroupKey(tbl, keyCols, bnds, keyColMap)
 				builder, created := t.cache.TableBuilder(key)
 				if created {
@@ -432,6 +510,7 @@ func (t *fixedWindowTransformation) generateWindowsWithinBounds() {
 }
 
 func (t *fixedWindowTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	t.watermark, t.hasWatermark = mark, true
 	return t.d.UpdateWatermark(mark)
 }
 func (t *fixedWindowTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
@@ -459,6 +538,11 @@ func (WindowTriggerPhysicalRule) Pattern() plan.Pattern {
 // window descendents that occur earlier in the plan and as long as none
 // of its descendents merge multiple streams together like union and join.
 func (WindowTriggerPhysicalRule) Rewrite(ctx context.Context, window plan.Node) (plan.Node, bool, error) {
+	// Streaming queries must wait for the watermark before
+	// emitting a window because the data is not bounded.
+	if execute.StreamingEnabled(ctx) {
+		return window, false, nil
+	}
 	// This rule's pattern ensures us only one predecessor
 	if !hasValidPredecessors(window.Predecessors()[0]) {
 		return window, false, nil
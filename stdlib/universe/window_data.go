@@ -0,0 +1,432 @@
+package universe
+
+import (
+	"sort"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/values"
+)
+
+const (
+	// SessionWindowKind is the procedure kind of window() when it is
+	// called with a gap. It is a separate kind from WindowKind so the
+	// rules that rewrite fixed windows do not match session windows.
+	SessionWindowKind = "sessionWindow"
+
+	// CountWindowKind is the procedure kind of window() when it is
+	// called with a count.
+	CountWindowKind = "countWindow"
+)
+
+func init() {
+	plan.RegisterProcedureSpec(SessionWindowKind, newWindowProcedure)
+	plan.RegisterProcedureSpec(CountWindowKind, newWindowProcedure)
+	execute.RegisterTransformation(SessionWindowKind, createSessionWindowTransformation)
+	execute.RegisterTransformation(CountWindowKind, createCountWindowTransformation)
+}
+
+// SessionWindowProcedureSpec groups the rows of each table into sessions.
+// A session ends when there are no rows for the duration of the gap.
+type SessionWindowProcedureSpec struct {
+	plan.DefaultCost
+	Gap flux.Duration
+	TimeColumn,
+	StartColumn,
+	StopColumn string
+}
+
+func (s *SessionWindowProcedureSpec) Kind() plan.ProcedureKind {
+	return SessionWindowKind
+}
+
+func (s *SessionWindowProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+// CountWindowProcedureSpec groups the rows of each table into windows
+// with a fixed number of rows. A new window starts every Every rows.
+type CountWindowProcedureSpec struct {
+	plan.DefaultCost
+	Count int64
+	Every int64
+	TimeColumn,
+	StartColumn,
+	StopColumn string
+}
+
+func (s *CountWindowProcedureSpec) Kind() plan.ProcedureKind {
+	return CountWindowKind
+}
+
+func (s *CountWindowProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	return &ns
+}
+
+func createSessionWindowTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*SessionWindowProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewSessionWindowTransformation(d, cache, s, a.Allocator())
+	return t, d, nil
+}
+
+func createCountWindowTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*CountWindowProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t := NewCountWindowTransformation(d, cache, s, a.Allocator())
+	return t, d, nil
+}
+
+// NewSessionWindowTransformation creates a transformation that assigns each
+// row to the session that contains it. A session starts at the time of its
+// first row and stops at the time of its last row plus the gap.
+func NewSessionWindowTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *SessionWindowProcedureSpec, mem memory.Allocator) execute.Transformation {
+	gap := execute.Time(spec.Gap.Duration())
+	return newDataWindowTransformation(d, cache, mem, spec.TimeColumn, spec.StartColumn, spec.StopColumn,
+		func(times []int64, mark execute.Time, final bool) []dataWindow {
+			var windows []dataWindow
+			for lo := 0; lo < len(times); {
+				hi := lo + 1
+				for hi < len(times) && execute.Time(times[hi]-times[hi-1]) < gap {
+					hi++
+				}
+				// Rows that arrive later are newer than the watermark
+				// so they cannot extend a session that stops before it.
+				stop := execute.Time(times[hi-1]) + gap
+				if !final && stop > mark {
+					break
+				}
+				windows = append(windows, dataWindow{
+					start: execute.Time(times[lo]),
+					stop:  stop,
+					lo:    lo,
+					hi:    hi,
+					next:  hi,
+				})
+				lo = hi
+			}
+			return windows
+		},
+	)
+}
+
+// NewCountWindowTransformation creates a transformation that assigns the rows
+// of each table, sorted by time, to windows with a fixed number of rows.
+// A window starts at the time of its first row and stops
+// immediately after the time of its last row. Windows are identified
+// by their bounds so an error is returned when rows with duplicate
+// times would give two windows the same bounds.
+func NewCountWindowTransformation(d execute.Dataset, cache execute.TableBuilderCache, spec *CountWindowProcedureSpec, mem memory.Allocator) execute.Transformation {
+	count, every := int(spec.Count), int(spec.Every)
+	if every <= 0 {
+		every = count
+	}
+	return newDataWindowTransformation(d, cache, mem, spec.TimeColumn, spec.StartColumn, spec.StopColumn,
+		func(times []int64, mark execute.Time, final bool) []dataWindow {
+			// Rows that arrive later are newer than the watermark so
+			// the rows that are older than it cannot move to another window.
+			settled := sort.Search(len(times), func(i int) bool {
+				return execute.Time(times[i]) >= mark
+			})
+			var windows []dataWindow
+			for lo := 0; lo < len(times); lo += every {
+				hi, next := lo+count, lo+every
+				if !final && (hi > settled || next > settled) {
+					break
+				}
+				if hi > len(times) {
+					hi = len(times)
+				}
+				if next > len(times) {
+					next = len(times)
+				}
+				windows = append(windows, dataWindow{
+					start: execute.Time(times[lo]),
+					stop:  execute.Time(times[hi-1]) + 1,
+					lo:    lo,
+					hi:    hi,
+					next:  next,
+				})
+				// The following windows would only
+				// contain rows from this window.
+				if hi == len(times) {
+					break
+				}
+			}
+			return windows
+		},
+	)
+}
+
+// dataWindow is a window over the rows of a table sorted by time.
+type dataWindow struct {
+	start, stop execute.Time
+	// lo and hi are the first row in the window and the row after
+	// the last row in the window. The rows before next are not
+	// needed for the windows that follow this window.
+	lo, hi, next int
+}
+
+// dataWindowFunc computes the windows for the sorted times of a table.
+// If final is false, only the windows that cannot change when rows
+// that are newer than the watermark are added are returned.
+type dataWindowFunc func(times []int64, mark execute.Time, final bool) []dataWindow
+
+// dataWindowTransformation assigns rows to windows whose bounds
+// depend on the rows in the table instead of on the time.
+//
+// Rows are buffered for each group key until the windows that contain
+// them are complete. This happens when the transformation finishes or,
+// when the watermark is updated, for the windows that are older than
+// the watermark.
+type dataWindowTransformation struct {
+	execute.ExecutionNode
+	d       execute.Dataset
+	cache   execute.TableBuilderCache
+	mem     memory.Allocator
+	windows dataWindowFunc
+
+	timeCol,
+	startCol,
+	stopCol string
+
+	// buffers holds the rows of each group key
+	// that have not been assigned to a window.
+	buffers *execute.GroupLookup
+	// last holds the last window that was emitted for each group key.
+	last *execute.GroupLookup
+
+	// Rows older than the watermark are late and are dropped.
+	watermark    execute.Time
+	hasWatermark bool
+}
+
+func newDataWindowTransformation(d execute.Dataset, cache execute.TableBuilderCache, mem memory.Allocator, timeCol, startCol, stopCol string, windows dataWindowFunc) *dataWindowTransformation {
+	return &dataWindowTransformation{
+		d:        d,
+		cache:    cache,
+		mem:      mem,
+		windows:  windows,
+		timeCol:  timeCol,
+		startCol: startCol,
+		stopCol:  stopCol,
+		buffers:  execute.NewGroupLookup(),
+		last:     execute.NewGroupLookup(),
+	}
+}
+
+func (t *dataWindowTransformation) RetractTable(id execute.DatasetID, key flux.GroupKey) error {
+	return t.d.RetractTable(key)
+}
+
+func (t *dataWindowTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	timeIdx := execute.ColIdx(t.timeCol, tbl.Cols())
+	if timeIdx < 0 {
+		return errors.Newf(codes.FailedPrecondition, "missing time column %q", t.timeCol)
+	} else if typ := tbl.Cols()[timeIdx].Type; typ != flux.TTime {
+		return errors.Newf(codes.FailedPrecondition, "time column %q has type %s, expected %s", t.timeCol, typ, flux.TTime)
+	}
+
+	buffer := t.buffers.LookupOrCreate(tbl.Key(), func() interface{} {
+		return execute.NewColListTableBuilder(tbl.Key(), t.mem)
+	}).(*execute.ColListTableBuilder)
+	colMap, err := execute.AddNewTableCols(tbl, buffer, nil)
+	if err != nil {
+		return err
+	}
+
+	return tbl.Do(func(cr flux.ColReader) error {
+		times := cr.Times(timeIdx)
+		for i, n := 0, cr.Len(); i < n; i++ {
+			// Rows without a time cannot be assigned to a window.
+			if times.IsNull(i) {
+				continue
+			}
+			if t.hasWatermark && execute.Time(times.Value(i)) < t.watermark {
+				continue
+			}
+			if err := execute.AppendMappedRecordWithNulls(i, cr, buffer, colMap); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// flush assigns the buffered rows to their windows. If final is false,
+// only the windows that are complete are emitted and the rows that
+// are still needed stay in the buffer.
+func (t *dataWindowTransformation) flush(final bool) error {
+	var keys []flux.GroupKey
+	_ = t.buffers.Range(func(key flux.GroupKey, value interface{}) error {
+		keys = append(keys, key)
+		return nil
+	})
+	for _, key := range keys {
+		value, _ := t.buffers.Lookup(key)
+		buffer := value.(*execute.ColListTableBuilder)
+		remaining, err := t.flushBuffer(buffer, final)
+		if err != nil {
+			return err
+		}
+		buffer.Release()
+		if remaining == nil {
+			t.buffers.Delete(key)
+		} else {
+			t.buffers.Set(key, remaining)
+		}
+	}
+	return nil
+}
+
+// flushBuffer emits the windows for the rows in the buffer
+// and returns a buffer with the rows that were not consumed.
+func (t *dataWindowTransformation) flushBuffer(buffer *execute.ColListTableBuilder, final bool) (*execute.ColListTableBuilder, error) {
+	if buffer.NRows() == 0 {
+		return nil, nil
+	}
+	buffer.Sort([]string{t.timeCol}, false)
+	tbl, err := buffer.Table()
+	if err != nil {
+		return nil, err
+	}
+	defer tbl.Done()
+
+	var remaining *execute.ColListTableBuilder
+	err = tbl.Do(func(cr flux.ColReader) error {
+		vs := cr.Times(execute.ColIdx(t.timeCol, cr.Cols()))
+		times := make([]int64, vs.Len())
+		for i := range times {
+			times[i] = vs.Value(i)
+		}
+
+		consumed := 0
+		if final {
+			consumed = len(times)
+		}
+		for _, w := range t.windows(times, t.watermark, final) {
+			if err := t.emit(tbl.Key(), cr, w); err != nil {
+				return err
+			}
+			if !final {
+				consumed = w.next
+			}
+		}
+		if consumed == len(times) {
+			return nil
+		}
+
+		remaining = execute.NewColListTableBuilder(tbl.Key(), t.mem)
+		if err := execute.AddTableCols(tbl, remaining); err != nil {
+			return err
+		}
+		for i := consumed; i < len(times); i++ {
+			if err := execute.AppendRecord(i, cr, remaining); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return remaining, err
+}
+
+// emit appends the rows in the window to the table for the window.
+func (t *dataWindowTransformation) emit(key flux.GroupKey, cr flux.ColReader, w dataWindow) error {
+	// The windows of a table are emitted in order and their bounds
+	// never decrease, so windows with the same bounds follow each other.
+	// Their rows would be written to the same table.
+	if v, ok := t.last.Lookup(key); ok {
+		if last := v.(dataWindow); last.start == w.start && last.stop == w.stop {
+			return errors.Newf(codes.FailedPrecondition, "two windows have the same bounds [%v, %v) because rows have duplicate %q values", w.start, w.stop, t.timeCol)
+		}
+	}
+	t.last.Set(key, w)
+
+	windowKey, err := execute.NewGroupKeyBuilder(key).
+		SetKeyValue(t.startCol, values.NewTime(w.start)).
+		SetKeyValue(t.stopCol, values.NewTime(w.stop)).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	builder, created := t.cache.TableBuilder(windowKey)
+	if created {
+		for _, c := range cr.Cols() {
+			if _, err := builder.AddCol(c); err != nil {
+				return err
+			}
+		}
+		for _, label := range []string{t.startCol, t.stopCol} {
+			if execute.ColIdx(label, builder.Cols()) < 0 {
+				if _, err := builder.AddCol(flux.ColMeta{Label: label, Type: flux.TTime}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for j, c := range builder.Cols() {
+		switch c.Label {
+		case t.startCol:
+			for i := w.lo; i < w.hi; i++ {
+				if err := builder.AppendTime(j, w.start); err != nil {
+					return err
+				}
+			}
+		case t.stopCol:
+			for i := w.lo; i < w.hi; i++ {
+				if err := builder.AppendTime(j, w.stop); err != nil {
+					return err
+				}
+			}
+		default:
+			cj := execute.ColIdx(c.Label, cr.Cols())
+			for i := w.lo; i < w.hi; i++ {
+				if err := builder.AppendValue(j, execute.ValueForRow(cr, i, cj)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (t *dataWindowTransformation) UpdateWatermark(id execute.DatasetID, mark execute.Time) error {
+	t.watermark, t.hasWatermark = mark, true
+	if err := t.flush(false); err != nil {
+		return err
+	}
+	return t.d.UpdateWatermark(mark)
+}
+
+func (t *dataWindowTransformation) UpdateProcessingTime(id execute.DatasetID, pt execute.Time) error {
+	return t.d.UpdateProcessingTime(pt)
+}
+
+func (t *dataWindowTransformation) Finish(id execute.DatasetID, err error) {
+	if err == nil {
+		err = t.flush(true)
+	}
+	_ = t.buffers.Range(func(key flux.GroupKey, value interface{}) error {
+		value.(*execute.ColListTableBuilder).Release()
+		return nil
+	})
+	t.buffers.Clear()
+	t.last.Clear()
+	t.d.Finish(err)
+}
@@ -0,0 +1,436 @@
+package universe
+
+import (
+	"github.com/apache/arrow/go/v7/arrow/memory"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/array"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/table"
+	"github.com/influxdata/flux/internal/arrowutil"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/interpreter"
+	fluxmemory "github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/runtime"
+	"github.com/influxdata/flux/semantic"
+)
+
+// WindowFunctionKind is the procedure kind shared by the functions that
+// compute a value for each row from its position in the sorted table,
+// like the window functions in SQL. Each table is a partition.
+const WindowFunctionKind = "windowFunction"
+
+const (
+	RowNumberKind   = "rowNumber"
+	RankKind        = "rank"
+	DenseRankKind   = "denseRank"
+	PercentRankKind = "percentRank"
+	NtileKind       = "ntile"
+	LagKind         = "lag"
+	LeadKind        = "lead"
+)
+
+var windowFunctionKinds = []flux.OperationKind{
+	RowNumberKind,
+	RankKind,
+	DenseRankKind,
+	PercentRankKind,
+	NtileKind,
+	LagKind,
+	LeadKind,
+}
+
+type WindowFunctionOpSpec struct {
+	Function string   `json:"function"`
+	Columns  []string `json:"columns"`
+	Desc     bool     `json:"desc"`
+	As       string   `json:"as"`
+	// Column and N are the source column and the offset for lag and lead
+	// and N is the number of buckets for ntile.
+	Column string `json:"column,omitempty"`
+	N      int64  `json:"n,omitempty"`
+}
+
+func init() {
+	for _, kind := range windowFunctionKinds {
+		name := string(kind)
+		signature := runtime.MustLookupBuiltinType("universe", name)
+		runtime.RegisterPackageValue("universe", name, flux.MustValue(flux.FunctionValue(name, createWindowFunctionOpSpec(name), signature)))
+	}
+	plan.RegisterProcedureSpec(WindowFunctionKind, newWindowFunctionProcedure, windowFunctionKinds...)
+	execute.RegisterTransformation(WindowFunctionKind, createWindowFunctionTransformation)
+}
+
+func createWindowFunctionOpSpec(name string) flux.CreateOperationSpec {
+	return func(args flux.Arguments, a *flux.Administration) (flux.OperationSpec, error) {
+		if err := a.AddParentFromArgs(args); err != nil {
+			return nil, err
+		}
+
+		spec := &WindowFunctionOpSpec{
+			Function: name,
+			Columns:  []string{execute.DefaultTimeColLabel},
+			As:       name,
+		}
+		if cols, ok, err := args.GetArray("columns", semantic.String); err != nil {
+			return nil, err
+		} else if ok {
+			columns, err := interpreter.ToStringArray(cols)
+			if err != nil {
+				return nil, err
+			}
+			spec.Columns = columns
+		}
+
+		if desc, ok, err := args.GetBool("desc"); err != nil {
+			return nil, err
+		} else if ok {
+			spec.Desc = desc
+		}
+
+		if as, ok, err := args.GetString("as"); err != nil {
+			return nil, err
+		} else if ok {
+			spec.As = as
+		}
+
+		switch name {
+		case NtileKind:
+			n, err := args.GetRequiredInt("n")
+			if err != nil {
+				return nil, err
+			}
+			if n <= 0 {
+				return nil, errors.New(codes.Invalid, `parameter "n" must be greater than zero`)
+			}
+			spec.N = n
+		case LagKind, LeadKind:
+			spec.Column = execute.DefaultValueColLabel
+			if col, ok, err := args.GetString("column"); err != nil {
+				return nil, err
+			} else if ok {
+				spec.Column = col
+			}
+
+			spec.N = 1
+			if n, ok, err := args.GetInt("n"); err != nil {
+				return nil, err
+			} else if ok {
+				if n < 0 {
+					return nil, errors.New(codes.Invalid, `parameter "n" must be non-negative`)
+				}
+				spec.N = n
+			}
+		}
+		return spec, nil
+	}
+}
+
+func (s *WindowFunctionOpSpec) Kind() flux.OperationKind {
+	return flux.OperationKind(s.Function)
+}
+
+type WindowFunctionProcedureSpec struct {
+	Function string
+	Columns  []string
+	Desc     bool
+	As       string
+	Column   string
+	N        int64
+}
+
+func newWindowFunctionProcedure(qs flux.OperationSpec, pa plan.Administration) (plan.ProcedureSpec, error) {
+	spec, ok := qs.(*WindowFunctionOpSpec)
+	if !ok {
+		return nil, errors.Newf(codes.Internal, "invalid spec type %T", qs)
+	}
+
+	return &WindowFunctionProcedureSpec{
+		Function: spec.Function,
+		Columns:  spec.Columns,
+		Desc:     spec.Desc,
+		As:       spec.As,
+		Column:   spec.Column,
+		N:        spec.N,
+	}, nil
+}
+
+func (s *WindowFunctionProcedureSpec) Kind() plan.ProcedureKind {
+	return WindowFunctionKind
+}
+
+func (s *WindowFunctionProcedureSpec) Copy() plan.ProcedureSpec {
+	ns := *s
+	ns.Columns = make([]string, len(s.Columns))
+	copy(ns.Columns, s.Columns)
+	return &ns
+}
+
+// Cost estimates the cost of sorting every table in memory
+// which is the same as the cost of sort.
+func (s *WindowFunctionProcedureSpec) Cost(inStats []plan.Statistics) (plan.Cost, plan.Statistics) {
+	return (&SortProcedureSpec{Columns: s.Columns, Desc: s.Desc}).Cost(inStats)
+}
+
+func (s *WindowFunctionProcedureSpec) OutputAttributes() plan.PhysicalAttributes {
+	return plan.PhysicalAttributes{
+		plan.CollationKey: &plan.CollationAttr{
+			Columns: s.Columns,
+			Desc:    s.Desc,
+		},
+	}
+}
+
+func createWindowFunctionTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*WindowFunctionProcedureSpec)
+	if !ok {
+		return nil, nil, errors.Newf(codes.Internal, "invalid spec type %T", spec)
+	}
+	return NewWindowFunctionTransformation(id, s, a.Allocator())
+}
+
+type windowFunctionTransformation struct {
+	spec  *WindowFunctionProcedureSpec
+	alloc fluxmemory.Allocator
+}
+
+// NewWindowFunctionTransformation creates a transformation that sorts each
+// table by the columns in the spec and adds a column with the result of
+// the function for each row.
+func NewWindowFunctionTransformation(id execute.DatasetID, spec *WindowFunctionProcedureSpec, mem fluxmemory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	switch spec.Function {
+	case RowNumberKind, RankKind, DenseRankKind, PercentRankKind, NtileKind, LagKind, LeadKind:
+	default:
+		return nil, nil, errors.Newf(codes.Internal, "unknown window function %q", spec.Function)
+	}
+	t := &windowFunctionTransformation{
+		spec:  spec,
+		alloc: mem,
+	}
+	return execute.NewAggregateTransformation(id, t, mem)
+}
+
+// windowFunctionState buffers the rows of a table until
+// the whole table has been read.
+type windowFunctionState struct {
+	builder *execute.ColListTableBuilder
+}
+
+func (s *windowFunctionState) Close() error {
+	s.builder.Release()
+	return nil
+}
+
+func (t *windowFunctionTransformation) Aggregate(chunk table.Chunk, state interface{}, mem memory.Allocator) (interface{}, bool, error) {
+	var s *windowFunctionState
+	if state != nil {
+		s = state.(*windowFunctionState)
+	} else {
+		s = &windowFunctionState{
+			builder: execute.NewColListTableBuilder(chunk.Key(), t.alloc),
+		}
+	}
+
+	b := s.builder
+	colMap := make([]int, len(b.Cols()))
+	for j := range colMap {
+		colMap[j] = -1
+	}
+	for j, c := range chunk.Cols() {
+		idx := execute.ColIdx(c.Label, b.Cols())
+		if idx < 0 {
+			var err error
+			if idx, err = b.AddCol(c); err != nil {
+				return nil, false, err
+			}
+			colMap = append(colMap, -1)
+		} else if typ := b.Cols()[idx].Type; typ != c.Type {
+			return nil, false, errors.Newf(codes.FailedPrecondition, "schema collision detected: column \"%s\" is both of type %s and %s", c.Label, c.Type, typ)
+		}
+		colMap[idx] = j
+	}
+
+	buffer := chunk.Buffer()
+	if err := execute.AppendMappedCols(&buffer, b, colMap); err != nil {
+		return nil, false, err
+	}
+	if err := b.LevelColumns(); err != nil {
+		return nil, false, err
+	}
+	return s, true, nil
+}
+
+func (t *windowFunctionTransformation) Compute(key flux.GroupKey, state interface{}, d *execute.TransportDataset, mem memory.Allocator) error {
+	b := state.(*windowFunctionState).builder
+	typ, err := t.columnType(b.Cols())
+	if err != nil {
+		return err
+	}
+
+	// The new column replaces a column with the same label
+	// like the duplicate function.
+	cols := make([]flux.ColMeta, 0, len(b.Cols())+1)
+	for _, c := range b.Cols() {
+		if c.Label != t.spec.As {
+			cols = append(cols, c)
+		}
+	}
+	cols = append(cols, flux.ColMeta{Label: t.spec.As, Type: typ})
+
+	if b.NRows() == 0 {
+		return d.Process(table.ChunkFromBuffer(arrow.EmptyBuffer(key, cols)))
+	}
+
+	b.Sort(t.spec.Columns, t.spec.Desc)
+	tbl, err := b.Table()
+	if err != nil {
+		return err
+	}
+	return tbl.Do(func(cr flux.ColReader) error {
+		buffer := arrow.TableBuffer{
+			GroupKey: key,
+			Columns:  cols,
+			Values:   make([]array.Array, 0, len(cols)),
+		}
+		for j, c := range cr.Cols() {
+			if c.Label != t.spec.As {
+				vs := table.Values(cr, j)
+				vs.Retain()
+				buffer.Values = append(buffer.Values, vs)
+			}
+		}
+		arr, err := t.compute(cr, mem)
+		if err != nil {
+			buffer.Release()
+			return err
+		}
+		buffer.Values = append(buffer.Values, arr)
+		return d.Process(table.ChunkFromBuffer(buffer))
+	})
+}
+
+// columnType returns the type of the new column.
+func (t *windowFunctionTransformation) columnType(cols []flux.ColMeta) (flux.ColType, error) {
+	switch t.spec.Function {
+	case PercentRankKind:
+		return flux.TFloat, nil
+	case LagKind, LeadKind:
+		j := execute.ColIdx(t.spec.Column, cols)
+		if j < 0 {
+			return flux.TInvalid, errors.Newf(codes.FailedPrecondition, "column %q does not exist", t.spec.Column)
+		}
+		return cols[j].Type, nil
+	default:
+		return flux.TInt, nil
+	}
+}
+
+// compute returns the values of the new column for the rows in cr.
+func (t *windowFunctionTransformation) compute(cr flux.ColReader, mem memory.Allocator) (array.Array, error) {
+	n := cr.Len()
+	switch t.spec.Function {
+	case RowNumberKind:
+		b := array.NewIntBuilder(mem)
+		b.Resize(n)
+		for i := 0; i < n; i++ {
+			b.Append(int64(i + 1))
+		}
+		return b.NewArray(), nil
+	case RankKind, DenseRankKind:
+		peers := t.peerCols(cr)
+		b := array.NewIntBuilder(mem)
+		b.Resize(n)
+		var rank int64
+		for i := 0; i < n; i++ {
+			if i == 0 || !isPeer(peers, i-1, i) {
+				if t.spec.Function == RankKind {
+					rank = int64(i + 1)
+				} else {
+					rank++
+				}
+			}
+			b.Append(rank)
+		}
+		return b.NewArray(), nil
+	case PercentRankKind:
+		peers := t.peerCols(cr)
+		b := array.NewFloatBuilder(mem)
+		b.Resize(n)
+		var rank int
+		for i := 0; i < n; i++ {
+			if i == 0 || !isPeer(peers, i-1, i) {
+				rank = i
+			}
+			if n == 1 {
+				b.Append(0)
+			} else {
+				b.Append(float64(rank) / float64(n-1))
+			}
+		}
+		return b.NewArray(), nil
+	case NtileKind:
+		// The first n % buckets buckets have one more row
+		// than the others.
+		buckets := int(t.spec.N)
+		size, rem := n/buckets, n%buckets
+		b := array.NewIntBuilder(mem)
+		b.Resize(n)
+		for i := 0; i < n; i++ {
+			if i < rem*(size+1) {
+				b.Append(int64(i/(size+1) + 1))
+			} else {
+				b.Append(int64((i-rem)/size + 1))
+			}
+		}
+		return b.NewArray(), nil
+	case LagKind, LeadKind:
+		j := execute.ColIdx(t.spec.Column, cr.Cols())
+		offset := int(t.spec.N)
+		if t.spec.Function == LagKind {
+			offset = -offset
+		}
+		b := arrow.NewBuilder(cr.Cols()[j].Type, mem)
+		b.Resize(n)
+		for i := 0; i < n; i++ {
+			k := i + offset
+			if k < 0 || k >= n {
+				b.AppendNull()
+				continue
+			}
+			if err := arrow.AppendValue(b, execute.ValueForRow(cr, k, j)); err != nil {
+				b.Release()
+				return nil, err
+			}
+		}
+		return b.NewArray(), nil
+	default:
+		return nil, errors.Newf(codes.Internal, "unknown window function %q", t.spec.Function)
+	}
+}
+
+// peerCols returns the values of the sort columns that are in cr.
+// Rows with equal values in all of them are peers and have the same rank.
+func (t *windowFunctionTransformation) peerCols(cr flux.ColReader) []array.Array {
+	var cols []array.Array
+	for _, label := range t.spec.Columns {
+		if j := execute.ColIdx(label, cr.Cols()); j >= 0 {
+			cols = append(cols, table.Values(cr, j))
+		}
+	}
+	return cols
+}
+
+func isPeer(cols []array.Array, i, j int) bool {
+	for _, vs := range cols {
+		if arrowutil.Compare(vs, vs, i, j) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *windowFunctionTransformation) Close() error {
+	return nil
+}
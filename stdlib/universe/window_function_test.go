@@ -0,0 +1,352 @@
+package universe_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
+	"github.com/influxdata/flux/internal/operation"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/querytest"
+	"github.com/influxdata/flux/stdlib/influxdata/influxdb"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestWindowFunction_NewQuery(t *testing.T) {
+	tests := []querytest.NewQueryTestCase{
+		{
+			Name: "rank",
+			Raw:  `from(bucket:"mybucket") |> rank(columns: ["_value"], desc: true)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "rank1",
+						Spec: &universe.WindowFunctionOpSpec{
+							Function: universe.RankKind,
+							Columns:  []string{"_value"},
+							Desc:     true,
+							As:       "rank",
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "rank1"},
+				},
+			},
+		},
+		{
+			Name: "lag",
+			Raw:  `from(bucket:"mybucket") |> lag(n: 2, as: "prev")`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "lag1",
+						Spec: &universe.WindowFunctionOpSpec{
+							Function: universe.LagKind,
+							Columns:  []string{"_time"},
+							As:       "prev",
+							Column:   "_value",
+							N:        2,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "lag1"},
+				},
+			},
+		},
+		{
+			Name:    "ntile zero buckets",
+			Raw:     `from(bucket:"mybucket") |> ntile(n: 0)`,
+			WantErr: true,
+		},
+		{
+			Name:    "lead negative offset",
+			Raw:     `from(bucket:"mybucket") |> lead(n: -1)`,
+			WantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			querytest.NewQueryTestHelper(t, tc)
+		})
+	}
+}
+
+func TestWindowFunction_Process(t *testing.T) {
+	// The input is not sorted by time and has two equal rows
+	// so the order of the rows in the output is known.
+	data := func() []flux.Table {
+		return []flux.Table{
+			&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(3), "a", 5.0},
+					{execute.Time(1), "a", 2.0},
+					{execute.Time(4), "a", 1.0},
+					{execute.Time(3), "a", 5.0},
+					{execute.Time(5), "a", 3.0},
+				},
+			},
+			&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1), "b", 7.0},
+				},
+			},
+		}
+	}
+	// want builds the expected tables for rows sorted by
+	// time or by value from the values of the new column.
+	want := func(byValue bool, typ flux.ColType, a, b []interface{}) []*executetest.Table {
+		rowsA := [][]interface{}{
+			{execute.Time(1), "a", 2.0},
+			{execute.Time(3), "a", 5.0},
+			{execute.Time(3), "a", 5.0},
+			{execute.Time(4), "a", 1.0},
+			{execute.Time(5), "a", 3.0},
+		}
+		if byValue {
+			rowsA = [][]interface{}{
+				{execute.Time(3), "a", 5.0},
+				{execute.Time(3), "a", 5.0},
+				{execute.Time(5), "a", 3.0},
+				{execute.Time(1), "a", 2.0},
+				{execute.Time(4), "a", 1.0},
+			}
+		}
+		rowsB := [][]interface{}{
+			{execute.Time(1), "b", 7.0},
+		}
+		tables := []*executetest.Table{
+			{KeyCols: []string{"t0"}, Data: rowsA},
+			{KeyCols: []string{"t0"}, Data: rowsB},
+		}
+		for i, vs := range [][]interface{}{a, b} {
+			tables[i].ColMeta = []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "t0", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "out", Type: typ},
+			}
+			for j, v := range vs {
+				tables[i].Data[j] = append(tables[i].Data[j], v)
+			}
+		}
+		return tables
+	}
+
+	testCases := []struct {
+		name string
+		spec *universe.WindowFunctionProcedureSpec
+		want []*executetest.Table
+	}{
+		{
+			name: "rowNumber",
+			spec: &universe.WindowFunctionProcedureSpec{
+				Function: universe.RowNumberKind,
+				Columns:  []string{"_time"},
+				As:       "out",
+			},
+			want: want(false, flux.TInt,
+				[]interface{}{int64(1), int64(2), int64(3), int64(4), int64(5)},
+				[]interface{}{int64(1)},
+			),
+		},
+		{
+			name: "rank",
+			spec: &universe.WindowFunctionProcedureSpec{
+				Function: universe.RankKind,
+				Columns:  []string{"_value"},
+				Desc:     true,
+				As:       "out",
+			},
+			want: want(true, flux.TInt,
+				[]interface{}{int64(1), int64(1), int64(3), int64(4), int64(5)},
+				[]interface{}{int64(1)},
+			),
+		},
+		{
+			name: "denseRank",
+			spec: &universe.WindowFunctionProcedureSpec{
+				Function: universe.DenseRankKind,
+				Columns:  []string{"_value"},
+				Desc:     true,
+				As:       "out",
+			},
+			want: want(true, flux.TInt,
+				[]interface{}{int64(1), int64(1), int64(2), int64(3), int64(4)},
+				[]interface{}{int64(1)},
+			),
+		},
+		{
+			name: "percentRank",
+			spec: &universe.WindowFunctionProcedureSpec{
+				Function: universe.PercentRankKind,
+				Columns:  []string{"_value"},
+				Desc:     true,
+				As:       "out",
+			},
+			want: want(true, flux.TFloat,
+				[]interface{}{0.0, 0.0, 0.5, 0.75, 1.0},
+				[]interface{}{0.0},
+			),
+		},
+		{
+			name: "ntile",
+			spec: &universe.WindowFunctionProcedureSpec{
+				Function: universe.NtileKind,
+				Columns:  []string{"_time"},
+				As:       "out",
+				N:        3,
+			},
+			want: want(false, flux.TInt,
+				[]interface{}{int64(1), int64(1), int64(2), int64(2), int64(3)},
+				[]interface{}{int64(1)},
+			),
+		},
+		{
+			name: "lag",
+			spec: &universe.WindowFunctionProcedureSpec{
+				Function: universe.LagKind,
+				Columns:  []string{"_time"},
+				As:       "out",
+				Column:   "_value",
+				N:        1,
+			},
+			want: want(false, flux.TFloat,
+				[]interface{}{nil, 2.0, 5.0, 5.0, 1.0},
+				[]interface{}{nil},
+			),
+		},
+		{
+			name: "lead",
+			spec: &universe.WindowFunctionProcedureSpec{
+				Function: universe.LeadKind,
+				Columns:  []string{"_time"},
+				As:       "out",
+				Column:   "_time",
+				N:        2,
+			},
+			want: want(false, flux.TTime,
+				[]interface{}{execute.Time(3), execute.Time(4), execute.Time(5), nil, nil},
+				[]interface{}{nil},
+			),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper2(
+				t,
+				data(),
+				tc.want,
+				nil,
+				func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+					tr, d, err := universe.NewWindowFunctionTransformation(id, tc.spec, alloc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return tr, d
+				},
+			)
+		})
+	}
+}
+
+func TestWindowFunction_ProcessOverwrite(t *testing.T) {
+	spec := &universe.WindowFunctionProcedureSpec{
+		Function: universe.RowNumberKind,
+		Columns:  []string{"_time"},
+		As:       "_value",
+	}
+	executetest.ProcessTestHelper2(
+		t,
+		[]flux.Table{&executetest.Table{
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{execute.Time(2), 5.0},
+				{execute.Time(1), 2.0},
+			},
+		}},
+		[]*executetest.Table{{
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TInt},
+			},
+			Data: [][]interface{}{
+				{execute.Time(1), int64(1)},
+				{execute.Time(2), int64(2)},
+			},
+		}},
+		nil,
+		func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+			tr, d, err := universe.NewWindowFunctionTransformation(id, spec, alloc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr, d
+		},
+	)
+}
+
+func TestWindowFunction_ProcessMissingColumn(t *testing.T) {
+	spec := &universe.WindowFunctionProcedureSpec{
+		Function: universe.LagKind,
+		Columns:  []string{"_time"},
+		As:       "lag",
+		Column:   "missing",
+		N:        1,
+	}
+	executetest.ProcessTestHelper2(
+		t,
+		[]flux.Table{&executetest.Table{
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{execute.Time(1), 2.0},
+			},
+		}},
+		nil,
+		errors.New(codes.FailedPrecondition, `column "missing" does not exist`),
+		func(id execute.DatasetID, alloc memory.Allocator) (execute.Transformation, execute.Dataset) {
+			tr, d, err := universe.NewWindowFunctionTransformation(id, spec, alloc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return tr, d
+		},
+	)
+}
@@ -9,8 +9,10 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/internal/errors"
 	"github.com/influxdata/flux/internal/operation"
 	"github.com/influxdata/flux/interval"
 	"github.com/influxdata/flux/plan"
@@ -64,6 +66,80 @@ func TestWindow_NewQuery(t *testing.T) {
 			Raw:     `from(bucket:"mybucket") |> window(every:0s)`,
 			WantErr: true,
 		},
+		{
+			Name: "from with session window",
+			Raw:  `from(bucket:"mybucket") |> window(gap: 15s)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "window1",
+						Spec: &universe.WindowOpSpec{
+							Gap: flux.ConvertDuration(15 * time.Second),
+							Location: plan.Location{
+								Name: "UTC",
+							},
+							TimeColumn:  execute.DefaultTimeColLabel,
+							StartColumn: execute.DefaultStartColLabel,
+							StopColumn:  execute.DefaultStopColLabel,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "window1"},
+				},
+			},
+		},
+		{
+			Name: "from with count window",
+			Raw:  `from(bucket:"mybucket") |> window(count: 4, countEvery: 2)`,
+			Want: &operation.Spec{
+				Operations: []*operation.Node{
+					{
+						ID: "from0",
+						Spec: &influxdb.FromOpSpec{
+							Bucket: influxdb.NameOrID{Name: "mybucket"},
+						},
+					},
+					{
+						ID: "window1",
+						Spec: &universe.WindowOpSpec{
+							Count:      4,
+							CountEvery: 2,
+							Location: plan.Location{
+								Name: "UTC",
+							},
+							TimeColumn:  execute.DefaultTimeColLabel,
+							StartColumn: execute.DefaultStartColLabel,
+							StopColumn:  execute.DefaultStopColLabel,
+						},
+					},
+				},
+				Edges: []operation.Edge{
+					{Parent: "from0", Child: "window1"},
+				},
+			},
+		},
+		{
+			Name:    "every and gap window",
+			Raw:     `from(bucket:"mybucket") |> window(every: 1h, gap: 5m)`,
+			WantErr: true,
+		},
+		{
+			Name:    "countEvery without count window",
+			Raw:     `from(bucket:"mybucket") |> window(gap: 5m, countEvery: 2)`,
+			WantErr: true,
+		},
+		{
+			Name:    "negative gap window",
+			Raw:     `from(bucket:"mybucket") |> window(gap: -5m)`,
+			WantErr: true,
+		},
 	}
 	for _, tc := range tests {
 		tc := tc
@@ -1180,3 +1256,392 @@ func TestWindowRewriteRule(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionWindow_Process(t *testing.T) {
+	spec := &universe.SessionWindowProcedureSpec{
+		Gap:         flux.ConvertDuration(10 * time.Second),
+		TimeColumn:  execute.DefaultTimeColLabel,
+		StartColumn: execute.DefaultStartColLabel,
+		StopColumn:  execute.DefaultStopColLabel,
+	}
+	testCases := []struct {
+		name    string
+		data    []flux.Table
+		want    []*executetest.Table
+		wantErr error
+	}{
+		{
+			name: "sessions",
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"t0"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "t0", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{seconds(1), "a", 1.0},
+					{seconds(5), "a", 2.0},
+					{seconds(14), "a", 3.0},
+					// The gap between these rows ends the session.
+					{seconds(30), "a", 4.0},
+					{nil, "a", 5.0},
+				},
+			}},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"t0", "_start", "_stop"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_stop", Type: flux.TTime},
+					},
+					Data: [][]interface{}{
+						{seconds(1), "a", 1.0, seconds(1), seconds(24)},
+						{seconds(5), "a", 2.0, seconds(1), seconds(24)},
+						{seconds(14), "a", 3.0, seconds(1), seconds(24)},
+					},
+				},
+				{
+					KeyCols: []string{"t0", "_start", "_stop"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "t0", Type: flux.TString},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_stop", Type: flux.TTime},
+					},
+					Data: [][]interface{}{
+						{seconds(30), "a", 4.0, seconds(30), seconds(40)},
+					},
+				},
+			},
+		},
+		{
+			name: "unsorted with existing bounds",
+			data: []flux.Table{&executetest.Table{
+				KeyCols: []string{"_start", "_stop"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_start", Type: flux.TTime},
+					{Label: "_stop", Type: flux.TTime},
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{seconds(0), seconds(100), seconds(25), 3.0},
+					{seconds(0), seconds(100), seconds(2), 1.0},
+					{seconds(0), seconds(100), seconds(10), 2.0},
+				},
+			}},
+			want: []*executetest.Table{
+				{
+					KeyCols: []string{"_start", "_stop"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_stop", Type: flux.TTime},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{seconds(2), seconds(20), seconds(2), 1.0},
+						{seconds(2), seconds(20), seconds(10), 2.0},
+					},
+				},
+				{
+					KeyCols: []string{"_start", "_stop"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_stop", Type: flux.TTime},
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+					},
+					Data: [][]interface{}{
+						{seconds(25), seconds(35), seconds(25), 3.0},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid time column",
+			data: []flux.Table{&executetest.Table{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TInt},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{int64(1), 1.0},
+				},
+			}},
+			wantErr: errors.New(codes.FailedPrecondition, `time column "_time" has type int, expected time`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			executetest.ProcessTestHelper(
+				t,
+				tc.data,
+				tc.want,
+				tc.wantErr,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return universe.NewSessionWindowTransformation(d, c, spec, executetest.UnlimitedAllocator)
+				},
+			)
+		})
+	}
+}
+
+func TestCountWindow_Process(t *testing.T) {
+	data := func() []flux.Table {
+		return []flux.Table{&executetest.Table{
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{seconds(4), 4.0},
+				{seconds(1), 1.0},
+				{seconds(2), 2.0},
+				{seconds(3), 3.0},
+				{seconds(5), 5.0},
+			},
+		}}
+	}
+	// The stop of a count window is just after the time of its last row.
+	window := func(start, last int64, rows ...int64) *executetest.Table {
+		tbl := &executetest.Table{
+			KeyCols: []string{"_start", "_stop"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+				{Label: "_start", Type: flux.TTime},
+				{Label: "_stop", Type: flux.TTime},
+			},
+		}
+		for _, sec := range rows {
+			tbl.Data = append(tbl.Data, []interface{}{seconds(sec), float64(sec), seconds(start), seconds(last) + 1})
+		}
+		return tbl
+	}
+	testCases := []struct {
+		name  string
+		count int64
+		every int64
+		want  []*executetest.Table
+	}{
+		{
+			name:  "tumbling",
+			count: 2,
+			every: 2,
+			want: []*executetest.Table{
+				window(1, 2, 1, 2),
+				window(3, 4, 3, 4),
+				window(5, 5, 5),
+			},
+		},
+		{
+			name:  "sliding",
+			count: 3,
+			every: 2,
+			want: []*executetest.Table{
+				window(1, 3, 1, 2, 3),
+				window(3, 5, 3, 4, 5),
+			},
+		},
+		{
+			name:  "skipping",
+			count: 1,
+			every: 2,
+			want: []*executetest.Table{
+				window(1, 1, 1),
+				window(3, 3, 3),
+				window(5, 5, 5),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &universe.CountWindowProcedureSpec{
+				Count:       tc.count,
+				Every:       tc.every,
+				TimeColumn:  execute.DefaultTimeColLabel,
+				StartColumn: execute.DefaultStartColLabel,
+				StopColumn:  execute.DefaultStopColLabel,
+			}
+			executetest.ProcessTestHelper(
+				t,
+				data(),
+				tc.want,
+				nil,
+				func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+					return universe.NewCountWindowTransformation(d, c, spec, executetest.UnlimitedAllocator)
+				},
+			)
+		})
+	}
+}
+
+func TestCountWindow_DuplicateTimes(t *testing.T) {
+	data := func(times ...int64) []flux.Table {
+		tbl := &executetest.Table{
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "_value", Type: flux.TFloat},
+			},
+		}
+		for _, sec := range times {
+			tbl.Data = append(tbl.Data, []interface{}{seconds(sec), float64(sec)})
+		}
+		return []flux.Table{tbl}
+	}
+	spec := &universe.CountWindowProcedureSpec{
+		Count:       2,
+		TimeColumn:  execute.DefaultTimeColLabel,
+		StartColumn: execute.DefaultStartColLabel,
+		StopColumn:  execute.DefaultStopColLabel,
+	}
+	create := func(d execute.Dataset, c execute.TableBuilderCache) execute.Transformation {
+		return universe.NewCountWindowTransformation(d, c, spec, executetest.UnlimitedAllocator)
+	}
+
+	t.Run("same bounds", func(t *testing.T) {
+		executetest.ProcessTestHelper(
+			t,
+			data(1, 1, 1, 1),
+			nil,
+			errors.Newf(codes.FailedPrecondition, "two windows have the same bounds [%v, %v) because rows have duplicate \"_time\" values", seconds(1), seconds(1)+1),
+			create,
+		)
+	})
+	t.Run("different bounds", func(t *testing.T) {
+		// Duplicate times are allowed as long as
+		// the windows have different bounds.
+		executetest.ProcessTestHelper(
+			t,
+			data(1, 1, 1, 2),
+			[]*executetest.Table{
+				{
+					KeyCols: []string{"_start", "_stop"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_stop", Type: flux.TTime},
+					},
+					Data: [][]interface{}{
+						{seconds(1), 1.0, seconds(1), seconds(1) + 1},
+						{seconds(1), 1.0, seconds(1), seconds(1) + 1},
+					},
+				},
+				{
+					KeyCols: []string{"_start", "_stop"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_time", Type: flux.TTime},
+						{Label: "_value", Type: flux.TFloat},
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_stop", Type: flux.TTime},
+					},
+					Data: [][]interface{}{
+						{seconds(1), 1.0, seconds(1), seconds(2) + 1},
+						{seconds(2), 2.0, seconds(1), seconds(2) + 1},
+					},
+				},
+			},
+			nil,
+			create,
+		)
+	})
+}
+
+func TestSessionWindow_UpdateWatermark(t *testing.T) {
+	d := executetest.NewDataset(executetest.RandomDatasetID())
+	c := execute.NewTableBuilderCache(executetest.UnlimitedAllocator)
+	c.SetTriggerSpec(plan.DefaultTriggerSpec)
+
+	tx := universe.NewSessionWindowTransformation(d, c, &universe.SessionWindowProcedureSpec{
+		Gap:         flux.ConvertDuration(10 * time.Second),
+		TimeColumn:  execute.DefaultTimeColLabel,
+		StartColumn: execute.DefaultStartColLabel,
+		StopColumn:  execute.DefaultStopColLabel,
+	}, executetest.UnlimitedAllocator)
+
+	parentID := executetest.RandomDatasetID()
+	if err := tx.Process(parentID, &executetest.Table{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{seconds(1), 1.0},
+			{seconds(5), 2.0},
+			{seconds(30), 3.0},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first session stops at 15s so it is complete
+	// once the watermark passes it.
+	if err := tx.UpdateWatermark(parentID, seconds(20)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := executetest.TablesFromCache(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(got); want != got {
+		t.Fatalf("unexpected number of tables after the watermark -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+	if want, got := 2, len(got[0].Data); want != got {
+		t.Fatalf("unexpected number of rows in the first session -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+
+	// The remaining session is emitted when the transformation finishes.
+	tx.Finish(parentID, nil)
+	got, err = executetest.TablesFromCache(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(got); want != got {
+		t.Fatalf("unexpected number of tables after finish -want/+got:\n\t- %d\n\t+ %d", want, got)
+	}
+}
+
+func TestWindowRules_DataWindows(t *testing.T) {
+	// Session and count windows do not have fixed bounds
+	// so the rules for fixed windows must not apply to them.
+	for _, spec := range []plan.PhysicalProcedureSpec{
+		&universe.SessionWindowProcedureSpec{Gap: flux.ConvertDuration(time.Minute)},
+		&universe.CountWindowProcedureSpec{Count: 10, Every: 10},
+	} {
+		tc := plantest.RuleTestCase{
+			Name:  string(spec.Kind()),
+			Rules: []plan.Rule{universe.OptimizeWindowRule{}, universe.WindowTriggerPhysicalRule{}},
+			Before: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					mockPred("0"),
+					plan.CreatePhysicalNode("window", spec),
+					plan.CreatePhysicalNode("count", &universe.CountProcedureSpec{
+						SimpleAggregateConfig: execute.DefaultSimpleAggregateConfig,
+					}),
+				},
+				Edges: [][2]int{
+					{0, 1},
+					{1, 2},
+				},
+			},
+			NoChange: true,
+		}
+		t.Run(tc.Name, func(t *testing.T) {
+			plantest.PhysicalRuleTestHelper(t, &tc)
+		})
+	}
+}
+
+// seconds returns the time that is n seconds after the epoch.
+func seconds(n int64) execute.Time {
+	return execute.Time(n * int64(time.Second))
+}